@@ -0,0 +1,292 @@
+/**
+ * API 扫描：上传 OpenAPI / Swagger 文档、GraphQL schema、Postman 集合或 HAR、Burp、proxify（jsonl / yaml）等流量导出，
+ * 通过 nuclei 的 input formats 解析为请求；扫描时以 DAST 模式对这些请求执行 fuzzing 模板
+ */
+package apispec
+
+import (
+	"demo/db/mysqldb"
+	"demo/models"
+	"demo/scope"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/input/formats"
+	httpprovider "github.com/projectdiscovery/nuclei/v3/pkg/input/provider/http"
+	"github.com/projectdiscovery/nuclei/v3/pkg/protocols/common/contextargs"
+	"gorm.io/gorm"
+)
+
+// TaskType API 扫描任务在 tasks.type 中的取值
+const TaskType = "api"
+
+// MaxSize 上传文件的大小上限
+const MaxSize = 20 << 20
+
+// 解析结果中最多返回的示例请求数
+const sampleLimit = 20
+
+// Formats 支持的格式，取值与 nuclei input formats 的名称相同
+var Formats = []string{"openapi", "swagger", "graphql", "postman", "har", "burp", "jsonl", "yaml"}
+
+// 常用写法到格式名称的映射
+var aliases = map[string]string{
+	"json":    "jsonl",
+	"proxify": "jsonl",
+	"gql":     "graphql",
+	"sdl":     "graphql",
+}
+
+// 文件名没有扩展名时使用的扩展名；swagger 按扩展名区分 yaml 与 json
+var defaultExt = map[string]string{
+	"openapi": ".yaml",
+	"swagger": ".json",
+	"graphql": ".graphql",
+	"postman": ".json",
+	"har":     ".har",
+	"burp":    ".xml",
+	"jsonl":   ".jsonl",
+	"yaml":    ".yaml",
+}
+
+// NormalizeFormat 校验并返回格式名称
+func NormalizeFormat(format string) (string, error) {
+	f := strings.ToLower(strings.TrimSpace(format))
+	if a, ok := aliases[f]; ok {
+		f = a
+	}
+	for _, s := range Formats {
+		if s == f {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("invalid format %q (%s)", format, strings.Join(Formats, "/"))
+}
+
+// Options 生成请求的参数：variables 为文档中参数的取值，requiredOnly 只使用必填参数，filters 只作用于 HAR
+type Options struct {
+	Variables    map[string]string `json:"variables,omitempty"`
+	RequiredOnly bool              `json:"requiredOnly"`
+	Filters      Filters           `json:"filters"`
+}
+
+// Filters HAR 文件的过滤条件：只保留指定主机（支持 *.example.com）与响应类型（支持 image/*）的请求，
+// 未指定响应类型时跳过静态资源
+type Filters struct {
+	Hosts     []string `json:"hosts,omitempty"`
+	MimeTypes []string `json:"mimeTypes,omitempty"`
+}
+
+func (f *Filters) empty() bool {
+	return len(f.Hosts) == 0 && len(f.MimeTypes) == 0
+}
+
+// Vars 转为 nuclei WithVars 使用的 key=value 列表，按 key 排序
+func (o *Options) Vars() []string {
+	keys := make([]string, 0, len(o.Variables))
+	for k := range o.Variables {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	vars := make([]string, 0, len(keys))
+	for _, k := range keys {
+		vars = append(vars, k+"="+o.Variables[k])
+	}
+	return vars
+}
+
+func (o *Options) variables() map[string]interface{} {
+	vars := make(map[string]interface{}, len(o.Variables))
+	for k, v := range o.Variables {
+		vars[k] = v
+	}
+	return vars
+}
+
+// ParseVariables 解析上传表单中的 variables：JSON 对象，或每行一个 key=value
+func ParseVariables(raw string) (map[string]string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	vars := map[string]string{}
+	if strings.HasPrefix(raw, "{") {
+		if err := json.Unmarshal([]byte(raw), &vars); err != nil {
+			return nil, fmt.Errorf("invalid variables: %v", err)
+		}
+		return vars, nil
+	}
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid variable %q (key=value)", line)
+		}
+		vars[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return vars, nil
+}
+
+// ParseList 解析以逗号或换行分隔的列表
+func ParseList(raw string) []string {
+	var list []string
+	for _, s := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// Summary 解析结果：请求数、涉及的地址（scheme://host）与前 sampleLimit 个请求
+type Summary struct {
+	Format       string   `json:"format"`
+	RequestCount int64    `json:"requestCount"`
+	Hosts        []string `json:"hosts"`
+	Requests     []string `json:"requests"`
+}
+
+// WriteFile 把文件内容写入 dir，保留原扩展名（没有时按格式补上），返回文件路径
+func WriteFile(dir, format, fileName string, data []byte) (string, error) {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if ext == "" {
+		ext = defaultExt[format]
+	}
+	path := filepath.Join(dir, "input"+ext)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Parse 用 nuclei 的 input formats 解析文件，与扫描时生成请求的方式相同
+func Parse(format, fileName string, data []byte, opts Options) (*Summary, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty file")
+	}
+	dir, err := os.MkdirTemp("", "dast-apispec-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	path, err := WriteFile(dir, format, fileName, data)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := httpprovider.NewHttpInputProvider(&httpprovider.HttpMultiFormatOptions{
+		InputFile: path,
+		InputMode: format,
+		Options: formats.InputFormatOptions{
+			Variables:       opts.variables(),
+			RequiredOnly:    opts.RequiredOnly,
+			FilterHosts:     opts.Filters.Hosts,
+			FilterMimeTypes: opts.Filters.MimeTypes,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer provider.Close()
+
+	s := &Summary{Format: format, RequestCount: provider.Count(), Hosts: []string{}, Requests: []string{}}
+	seen := map[string]bool{}
+	provider.Iterate(func(in *contextargs.MetaInput) bool {
+		if in.ReqResp == nil || in.ReqResp.URL.URL == nil {
+			return true
+		}
+		u := in.ReqResp.URL
+		if base := u.Scheme + "://" + u.Host; u.Host != "" && !seen[base] {
+			seen[base] = true
+			s.Hosts = append(s.Hosts, base)
+		}
+		if len(s.Requests) < sampleLimit {
+			method := "GET"
+			if in.ReqResp.Request != nil && in.ReqResp.Request.Method != "" {
+				method = in.ReqResp.Request.Method
+			}
+			s.Requests = append(s.Requests, method+" "+u.String())
+		}
+		return true
+	})
+	return s, nil
+}
+
+// NewModel 生成保存到 api_specs 的记录（TaskID 由调用方填写）
+func NewModel(fileName string, data []byte, opts Options, s *Summary, creator string) *models.APISpec {
+	spec := &models.APISpec{
+		Format:       s.Format,
+		FileName:     filepath.Base(fileName),
+		Content:      string(data),
+		Size:         int64(len(data)),
+		RequiredOnly: opts.RequiredOnly,
+		RequestCount: s.RequestCount,
+		Creator:      creator,
+	}
+	if len(opts.Variables) > 0 {
+		b, _ := json.Marshal(opts.Variables)
+		spec.Variables = string(b)
+	}
+	if !opts.Filters.empty() {
+		b, _ := json.Marshal(opts.Filters)
+		spec.Filters = string(b)
+	}
+	if len(s.Hosts) > 0 {
+		b, _ := json.Marshal(s.Hosts)
+		spec.Hosts = string(b)
+	}
+	return spec
+}
+
+// Load 读取任务的 API 输入；不是 API 扫描任务时返回 nil
+func Load(taskId string) (*models.APISpec, error) {
+	var spec models.APISpec
+	err := mysqldb.DB.Where("task_id = ?", taskId).First(&spec).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// OptionsOf 读取保存的生成请求参数
+func OptionsOf(spec *models.APISpec) Options {
+	opts := Options{RequiredOnly: spec.RequiredOnly}
+	if spec.Variables != "" {
+		_ = json.Unmarshal([]byte(spec.Variables), &opts.Variables)
+	}
+	if spec.Filters != "" {
+		_ = json.Unmarshal([]byte(spec.Filters), &opts.Filters)
+	}
+	return opts
+}
+
+// HostsOf 读取保存的请求地址
+func HostsOf(spec *models.APISpec) []string {
+	var hosts []string
+	if spec.Hosts != "" {
+		_ = json.Unmarshal([]byte(spec.Hosts), &hosts)
+	}
+	return hosts
+}
+
+// ExcludedHosts 返回请求地址中命中任务排除列表的地址；
+// 请求由 nuclei 从文件生成，无法只跳过其中一部分，命中时拒绝保存或执行
+func ExcludedHosts(spec *models.APISpec, exclusions []string) ([]string, error) {
+	m, err := scope.NewMatcher(exclusions)
+	if err != nil {
+		return nil, err
+	}
+	_, excluded := m.Filter(HostsOf(spec))
+	return excluded, nil
+}
@@ -0,0 +1,259 @@
+package apispec
+
+import (
+	"demo/db/mysqldb"
+	"demo/db/redisdb"
+	"demo/models"
+	"demo/scope"
+	"demo/target"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Upload 上传表单中的文件与生成请求参数
+type Upload struct {
+	Format   string
+	FileName string
+	Data     []byte
+	Options  Options
+}
+
+// ReadUpload 读取 multipart 表单：format、file、variables（JSON 对象或每行 key=value）、requiredOnly，
+// 以及 HAR 的过滤条件 filterHosts、filterMimeTypes（逗号或换行分隔）
+// requireFile 为 false 时允许不传文件（只修改参数）
+func ReadUpload(c *gin.Context, requireFile bool) (*Upload, error) {
+	u := &Upload{}
+	vars, err := ParseVariables(c.PostForm("variables"))
+	if err != nil {
+		return nil, err
+	}
+	u.Options = Options{
+		Variables:    vars,
+		RequiredOnly: parseBool(c.PostForm("requiredOnly")),
+		Filters: Filters{
+			Hosts:     ParseList(c.PostForm("filterHosts")),
+			MimeTypes: ParseList(c.PostForm("filterMimeTypes")),
+		},
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		if requireFile {
+			return nil, errors.New("missing file")
+		}
+		return u, nil
+	}
+	if fh.Size > MaxSize {
+		return nil, errors.New("file too large")
+	}
+	if u.Format, err = NormalizeFormat(c.PostForm("format")); err != nil {
+		return nil, err
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if u.Data, err = io.ReadAll(io.LimitReader(f, MaxSize)); err != nil {
+		return nil, err
+	}
+	u.FileName = fh.Filename
+	return u, nil
+}
+
+func parseBool(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// Preview - 解析上传的文件，返回可生成的请求数与涉及的地址，不创建任务
+// POST /api/apispec/preview（multipart：format、file、variables、requiredOnly、filterHosts、filterMimeTypes）
+func Preview() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u, err := ReadUpload(c, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		s, err := Parse(u.Format, u.FileName, u.Data, u.Options)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "parse file failed: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, s)
+	}
+}
+
+// Get - 查看任务的 API 输入（不含文件内容）
+// GET /api/apispec/get?taskId=
+func Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		taskId := c.Query("taskId")
+		if taskId == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing taskId"})
+			return
+		}
+		spec, err := Load(taskId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if spec == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "api spec not found"})
+			return
+		}
+		c.JSON(http.StatusOK, view(spec))
+	}
+}
+
+func view(spec *models.APISpec) gin.H {
+	opts := OptionsOf(spec)
+	hosts := HostsOf(spec)
+	if hosts == nil {
+		hosts = []string{}
+	}
+	return gin.H{
+		"taskId":       spec.TaskID,
+		"format":       spec.Format,
+		"fileName":     spec.FileName,
+		"size":         spec.Size,
+		"variables":    opts.Variables,
+		"requiredOnly": spec.RequiredOnly,
+		"filters":      opts.Filters,
+		"requestCount": spec.RequestCount,
+		"hosts":        hosts,
+		"creator":      spec.Creator,
+		"createdAt":    spec.CreatedAt,
+		"updatedAt":    spec.UpdatedAt,
+	}
+}
+
+// Update - 重新上传文件或修改生成请求的参数，下次执行时生效；任务的目标同步为新的请求地址
+// POST /api/apispec/update（multipart：taskId、可选 format + file、variables、requiredOnly、filterHosts、filterMimeTypes）
+func Update() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		taskId := c.PostForm("taskId")
+		if taskId == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing taskId"})
+			return
+		}
+		var t models.Task
+		if err := mysqldb.DB.First(&t, "id = ?", taskId).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+			return
+		}
+		if t.Status == "running" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "task is running"})
+			return
+		}
+		old, err := Load(taskId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if old == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "api spec not found"})
+			return
+		}
+		u, err := ReadUpload(c, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if u.Data == nil {
+			u.Format, u.FileName, u.Data = old.Format, old.FileName, []byte(old.Content)
+		}
+		s, err := Parse(u.Format, u.FileName, u.Data, u.Options)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "parse file failed: " + err.Error()})
+			return
+		}
+		if s.RequestCount == 0 || len(s.Hosts) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "no requests in file"})
+			return
+		}
+
+		spec := NewModel(u.FileName, u.Data, u.Options, s, old.Creator)
+		spec.ID, spec.TaskID, spec.CreatedAt, spec.UpdatedAt = old.ID, old.TaskID, old.CreatedAt, time.Now()
+		// 排除列表无法作用于单个请求，新的请求地址不能命中任务的排除列表
+		exclusions, err := scope.DecodeList(t.Exclusions)
+		if err != nil {
+			log.Printf("[apispec.Update] decode exclusions failed task=%s err=%v", taskId, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid exclusions: " + err.Error()})
+			return
+		}
+		excluded, err := ExcludedHosts(spec, exclusions)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(excluded) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "api spec hosts excluded", "hosts": excluded})
+			return
+		}
+		if err := replace(spec); err != nil {
+			log.Printf("[apispec.Update] save spec failed task=%s err=%v", taskId, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "保存成功", "spec": view(spec), "requests": s.Requests})
+	}
+}
+
+// replace 保存 API 输入，并把任务的目标替换为请求涉及的地址（MySQL 与 Redis）
+func replace(spec *models.APISpec) error {
+	hosts := HostsOf(spec)
+	tx := mysqldb.DB.Begin()
+	err := tx.Model(&models.APISpec{}).Where("id = ?", spec.ID).Updates(map[string]interface{}{
+		"format": spec.Format, "file_name": spec.FileName, "content": spec.Content, "size": spec.Size,
+		"variables": nullable(spec.Variables), "required_only": spec.RequiredOnly, "filters": nullable(spec.Filters),
+		"request_count": spec.RequestCount, "hosts": nullable(spec.Hosts),
+	}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Where("task_id = ?", spec.TaskID).Delete(&models.Target{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	now := time.Now()
+	targets := make([]models.Target, 0, len(hosts))
+	for _, h := range hosts {
+		targets = append(targets, models.Target{TaskID: spec.TaskID, Target: h, CreatedAt: now, UpdatedAt: now})
+	}
+	if len(targets) > 0 {
+		if err := tx.CreateInBatches(&targets, 100).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	key := target.GetTaskTargetsKey(spec.TaskID)
+	pipe := redisdb.Client.TxPipeline()
+	pipe.Del(redisdb.Ctx, key)
+	for _, h := range hosts {
+		pipe.RPush(redisdb.Ctx, key, h)
+	}
+	_, err = pipe.Exec(redisdb.Ctx)
+	return err
+}
+
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
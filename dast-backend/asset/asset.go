@@ -0,0 +1,184 @@
+package asset
+
+import (
+	"demo/db/mysqldb"
+	"demo/finding"
+	"demo/models"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// paginate 统计总数并分页查询，结果写入 out
+func paginate(c *gin.Context, db *gorm.DB, order string, out interface{}) (int64, int, int, bool) {
+	page, pageSize := finding.ParsePage(c)
+	// 新 Session 使带条件的查询可以安全地先 Count 再 Find
+	db = db.Session(&gorm.Session{})
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		log.Printf("[asset] db count failed path=%s err=%v", c.FullPath(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return 0, 0, 0, false
+	}
+	if err := db.Order(order).Offset((page - 1) * pageSize).Limit(pageSize).Find(out).Error; err != nil {
+		log.Printf("[asset] db query failed path=%s err=%v", c.FullPath(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return 0, 0, 0, false
+	}
+	return total, page, pageSize, true
+}
+
+// Hosts - 主机列表，附带当前开放端口数
+// GET /api/asset/hosts?keyword=&page=&pageSize=
+func Hosts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := mysqldb.DB.Model(&models.AssetHost{})
+		if kw := c.Query("keyword"); kw != "" {
+			db = db.Where("host LIKE ?", "%"+kw+"%")
+		}
+		hosts := []models.AssetHost{}
+		total, page, pageSize, ok := paginate(c, db, "last_seen_at desc, id desc", &hosts)
+		if !ok {
+			return
+		}
+
+		names := make([]string, 0, len(hosts))
+		for _, h := range hosts {
+			names = append(names, h.Host)
+		}
+		var rows []struct {
+			Host  string
+			Count int64
+		}
+		if len(names) > 0 {
+			if err := mysqldb.DB.Model(&models.AssetPort{}).
+				Select("host, COUNT(*) AS count").
+				Where("host IN ? AND status = ?", names, "open").
+				Group("host").Scan(&rows).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		openPorts := map[string]int64{}
+		for _, r := range rows {
+			openPorts[r.Host] = r.Count
+		}
+
+		resp := make([]gin.H, 0, len(hosts))
+		for _, h := range hosts {
+			resp = append(resp, gin.H{
+				"host":        h.Host,
+				"openPorts":   openPorts[h.Host],
+				"firstSeenAt": h.FirstSeenAt.Format("2006-01-02 15:04:05"),
+				"lastSeenAt":  h.LastSeenAt.Format("2006-01-02 15:04:05"),
+				"lastTaskId":  h.LastTaskID,
+				"lastRunId":   h.LastRunID,
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{"total": total, "page": page, "pageSize": pageSize, "hosts": resp})
+	}
+}
+
+// Ports - 端口列表
+// GET /api/asset/ports?host=&port=&status=open&page=&pageSize=
+func Ports() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := mysqldb.DB.Model(&models.AssetPort{})
+		if host := c.Query("host"); host != "" {
+			db = db.Where("host = ?", host)
+		}
+		if s := c.Query("port"); s != "" {
+			port, err := strconv.Atoi(s)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid port"})
+				return
+			}
+			db = db.Where("port = ?", port)
+		}
+		if status := c.Query("status"); status != "" {
+			db = db.Where("status = ?", status)
+		}
+		ports := []models.AssetPort{}
+		total, page, pageSize, ok := paginate(c, db, "host asc, port asc", &ports)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"total": total, "page": page, "pageSize": pageSize, "ports": ports})
+	}
+}
+
+// Services - 服务列表，expiringDays 用于查找证书即将过期的 HTTPS 服务，product 按指纹识别出的产品名过滤
+// GET /api/asset/services?host=&scheme=https&product=Apache%20Tomcat&expiringDays=30&page=&pageSize=
+func Services() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := mysqldb.DB.Model(&models.AssetService{})
+		if host := c.Query("host"); host != "" {
+			db = db.Where("host = ?", host)
+		}
+		if scheme := c.Query("scheme"); scheme != "" {
+			db = db.Where("scheme = ?", scheme)
+		}
+		if product := c.Query("product"); product != "" {
+			db = db.Where("products LIKE ?", "%"+product+"%")
+		}
+		order := "host asc, port asc"
+		if s := c.Query("expiringDays"); s != "" {
+			days, err := strconv.Atoi(s)
+			if err != nil || days < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid expiringDays"})
+				return
+			}
+			db = db.Where("tls_not_after IS NOT NULL AND tls_not_after <= ?", time.Now().AddDate(0, 0, days))
+			order = "tls_not_after asc"
+		}
+		services := []models.AssetService{}
+		total, page, pageSize, ok := paginate(c, db, order, &services)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"total": total, "page": page, "pageSize": pageSize, "services": services})
+	}
+}
+
+// Changes - 暴露面变化记录（新主机、端口开放/关闭、服务变化、指纹变化）
+// GET /api/asset/changes?host=&kind=port_opened&runId=&start=&end=&page=&pageSize=
+func Changes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := mysqldb.DB.Model(&models.AssetChange{})
+		if host := c.Query("host"); host != "" {
+			db = db.Where("host = ?", host)
+		}
+		if kind := c.Query("kind"); kind != "" {
+			db = db.Where("kind = ?", kind)
+		}
+		if runId := c.Query("runId"); runId != "" {
+			db = db.Where("run_id = ?", runId)
+		}
+		if s := c.Query("start"); s != "" {
+			t, err := finding.ParseTime(s)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start: " + err.Error()})
+				return
+			}
+			db = db.Where("created_at >= ?", *t)
+		}
+		if s := c.Query("end"); s != "" {
+			t, err := finding.ParseTime(s)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end: " + err.Error()})
+				return
+			}
+			db = db.Where("created_at <= ?", *t)
+		}
+		changes := []models.AssetChange{}
+		total, page, pageSize, ok := paginate(c, db, "created_at desc, id desc", &changes)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"total": total, "page": page, "pageSize": pageSize, "changes": changes})
+	}
+}
@@ -0,0 +1,298 @@
+/**
+ * 资产清单：把端口扫描与 HTTP 测活的结果合并到 asset_hosts / asset_ports / asset_services，
+ * 并把暴露面的变化（新主机、端口开放/关闭、服务变化）记录到 asset_changes
+ */
+package asset
+
+import (
+	"demo/db/mysqldb"
+	"demo/fingerprint"
+	"demo/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 变化类型
+const (
+	KindHostDiscovered  = "host_discovered"
+	KindPortOpened      = "port_opened"
+	KindPortClosed      = "port_closed"
+	KindServiceChanged  = "service_changed"
+	KindProductsChanged = "products_changed"
+)
+
+// Observation 一次扫描中确认开放的 host:port 及测活得到的服务信息
+type Observation struct {
+	Host        string
+	Port        int
+	Probed      bool   // 测活阶段有响应（HTTP 响应或 TCP 可连），为 false 时只记录端口
+	Scheme      string // http / https，非 HTTP 服务为空
+	URL         string
+	StatusCode  int
+	Title       string
+	Server      string
+	TLSSubject  string
+	TLSNotAfter *time.Time
+}
+
+// Scan 一次执行的资产观测结果
+type Scan struct {
+	TaskID string
+	RunID  string
+	Open   []Observation
+	// 本次做过端口扫描的主机及其覆盖的端口；这些主机上此前开放、
+	// 本次在覆盖范围内却未发现的端口记为 closed。Ports 为空时不判断关闭
+	PortScanned []string
+	Ports       map[int]bool
+}
+
+// Record 把一次扫描的结果合并进资产清单
+// 整个合并在一个事务中完成，行的新建与状态翻转以数据库结果为准，
+// 并发的执行观测到同一主机/端口时只有一方记录变化
+func Record(s Scan) error {
+	now := time.Now()
+	var changes []models.AssetChange
+	change := func(host string, port int, kind, detail string) {
+		changes = append(changes, models.AssetChange{
+			Host: host, Port: port, Kind: kind, Detail: detail, TaskID: s.TaskID, RunID: s.RunID,
+		})
+	}
+
+	// 按 host、port 顺序加锁，避免并发事务交叉加锁导致死锁
+	observations := append([]Observation(nil), s.Open...)
+	sort.SliceStable(observations, func(i, j int) bool {
+		if observations[i].Host != observations[j].Host {
+			return observations[i].Host < observations[j].Host
+		}
+		return observations[i].Port < observations[j].Port
+	})
+
+	err := mysqldb.DB.Transaction(func(tx *gorm.DB) error {
+		open := map[string]map[int]bool{}
+		for _, o := range observations {
+			if o.Host == "" || o.Port <= 0 {
+				continue
+			}
+			if open[o.Host] == nil {
+				open[o.Host] = map[int]bool{}
+				created, err := upsertHost(tx, o.Host, s, now)
+				if err != nil {
+					return err
+				}
+				if created {
+					change(o.Host, 0, KindHostDiscovered, "")
+				}
+			}
+			if open[o.Host][o.Port] {
+				continue
+			}
+			open[o.Host][o.Port] = true
+
+			opened, err := upsertPort(tx, o, s, now)
+			if err != nil {
+				return err
+			}
+			if opened {
+				change(o.Host, o.Port, KindPortOpened, "")
+			}
+			if o.Probed {
+				detail, err := upsertService(tx, o, s, now)
+				if err != nil {
+					return err
+				}
+				if detail != "" {
+					change(o.Host, o.Port, KindServiceChanged, detail)
+				}
+			}
+		}
+
+		if len(s.Ports) > 0 {
+			for _, host := range s.PortScanned {
+				var ports []models.AssetPort
+				if err := tx.Where("host = ? AND status = ?", host, "open").Order("port").Find(&ports).Error; err != nil {
+					return err
+				}
+				for _, p := range ports {
+					if open[host][p.Port] || !s.Ports[p.Port] {
+						continue
+					}
+					// 只关闭仍为 open 的端口，并发执行已关闭时不重复记录
+					res := tx.Model(&models.AssetPort{}).Where("id = ? AND status = ?", p.ID, "open").
+						Updates(map[string]interface{}{"status": "closed", "closed_at": now})
+					if res.Error != nil {
+						return res.Error
+					}
+					if res.RowsAffected > 0 {
+						change(host, p.Port, KindPortClosed, "")
+					}
+				}
+			}
+		}
+
+		if len(changes) > 0 {
+			return tx.CreateInBatches(&changes, 100).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		log.Printf("[asset.Record] exposure changed task=%s run=%s changes=%d", s.TaskID, s.RunID, len(changes))
+	}
+	return nil
+}
+
+// upsertHost 返回主机是否为首次发现；主机已存在时插入被忽略，只更新最近一次发现的信息
+func upsertHost(tx *gorm.DB, host string, s Scan, now time.Time) (bool, error) {
+	h := models.AssetHost{Host: host, FirstSeenAt: now, LastSeenAt: now, LastTaskID: s.TaskID, LastRunID: s.RunID}
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&h)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected > 0 {
+		return true, nil
+	}
+	return false, tx.Model(&models.AssetHost{}).Where("host = ?", host).Updates(map[string]interface{}{
+		"last_seen_at": now, "last_task_id": s.TaskID, "last_run_id": s.RunID,
+	}).Error
+}
+
+// upsertPort 返回端口是否为新开放（首次发现或此前已关闭）
+func upsertPort(tx *gorm.DB, o Observation, s Scan, now time.Time) (bool, error) {
+	p := models.AssetPort{
+		Host: o.Host, Port: o.Port, Protocol: "tcp", Status: "open",
+		FirstSeenAt: now, LastSeenAt: now, FirstRunID: s.RunID, LastRunID: s.RunID,
+	}
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&p)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected > 0 {
+		return true, nil
+	}
+	// 从 closed 翻转为 open 的一方记录重新开放
+	res = tx.Model(&models.AssetPort{}).Where("host = ? AND port = ? AND status <> ?", o.Host, o.Port, "open").
+		Updates(map[string]interface{}{"status": "open", "closed_at": nil})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	reopened := res.RowsAffected > 0
+	return reopened, tx.Model(&models.AssetPort{}).Where("host = ? AND port = ?", o.Host, o.Port).Updates(map[string]interface{}{
+		"last_seen_at": now, "last_run_id": s.RunID,
+	}).Error
+}
+
+// upsertService 更新服务信息，scheme 或证书主体发生变化时返回变化描述
+func upsertService(tx *gorm.DB, o Observation, s Scan, now time.Time) (string, error) {
+	svc := models.AssetService{
+		Host: o.Host, Port: o.Port, Protocol: "tcp", Scheme: o.Scheme, URL: o.URL,
+		StatusCode: o.StatusCode, Title: o.Title, Server: o.Server,
+		TLSSubject: o.TLSSubject, TLSNotAfter: o.TLSNotAfter,
+		FirstSeenAt: now, LastSeenAt: now, LastRunID: s.RunID,
+	}
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&svc)
+	if res.Error != nil {
+		return "", res.Error
+	}
+	if res.RowsAffected > 0 {
+		return "", nil
+	}
+
+	// 锁住已有记录再比较，避免并发执行基于同一旧值各记一次变化
+	var old models.AssetService
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&old, "host = ? AND port = ?", o.Host, o.Port).Error; err != nil {
+		return "", err
+	}
+	detail := ""
+	if old.Scheme != o.Scheme {
+		detail = fmt.Sprintf("scheme %q -> %q", old.Scheme, o.Scheme)
+	} else if old.TLSSubject != "" && o.TLSSubject != "" && old.TLSSubject != o.TLSSubject {
+		detail = fmt.Sprintf("tls subject %q -> %q", old.TLSSubject, o.TLSSubject)
+	}
+	return detail, tx.Model(&old).Updates(map[string]interface{}{
+		"scheme": o.Scheme, "url": o.URL, "status_code": o.StatusCode, "title": o.Title, "server": o.Server,
+		"tls_subject": o.TLSSubject, "tls_not_after": o.TLSNotAfter,
+		"last_seen_at": now, "last_run_id": s.RunID,
+	}).Error
+}
+
+// RecordProducts 把指纹识别结果写入已有服务的 products 字段，产品或版本变化时记录 products_changed
+// key 为 host:port 形式的服务；资产清单中不存在的服务（例如未带端口的 URL 目标）会被跳过
+func RecordProducts(taskId, runId string, services map[string][]fingerprint.Product) error {
+	var changes []models.AssetChange
+	for key, products := range services {
+		host, port, ok := splitService(key)
+		if !ok {
+			continue
+		}
+		var svc models.AssetService
+		err := mysqldb.DB.First(&svc, "host = ? AND port = ?", host, port).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		var value interface{}
+		if len(products) > 0 {
+			data, err := json.Marshal(products)
+			if err != nil {
+				return err
+			}
+			value = string(data)
+		}
+		before := productNames(svc.Products)
+		after := strings.Join(fingerprint.Names(products), ", ")
+		if err := mysqldb.DB.Model(&svc).Update("products", value).Error; err != nil {
+			return err
+		}
+		// 首次识别（之前没有记录）不算变化，避免首次扫描产生大量变化记录
+		if svc.Products != "" && before != after {
+			changes = append(changes, models.AssetChange{
+				Host: host, Port: port, Kind: KindProductsChanged,
+				Detail: fmt.Sprintf("products [%s] -> [%s]", before, after),
+				TaskID: taskId, RunID: runId,
+			})
+		}
+	}
+	if len(changes) > 0 {
+		if err := mysqldb.DB.CreateInBatches(&changes, 100).Error; err != nil {
+			return err
+		}
+		log.Printf("[asset.RecordProducts] products changed task=%s run=%s changes=%d", taskId, runId, len(changes))
+	}
+	return nil
+}
+
+func productNames(raw string) string {
+	var products []fingerprint.Product
+	if raw != "" {
+		_ = json.Unmarshal([]byte(raw), &products)
+	}
+	return strings.Join(fingerprint.Names(products), ", ")
+}
+
+func splitService(key string) (string, int, bool) {
+	host, portStr, err := net.SplitHostPort(key)
+	if err != nil {
+		return "", 0, false
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, false
+	}
+	return host, port, true
+}
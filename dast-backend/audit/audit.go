@@ -0,0 +1,362 @@
+/**
+ * 审计日志：记录修改状态的 API 调用（操作人、来源 IP、动作、对象、请求摘要）与扫描生命周期变化，写入 task_logs
+ * 超过保留期（环境变量 DAST_AUDIT_RETENTION_DAYS，默认 180 天）的记录定期清理
+ */
+package audit
+
+import (
+	"bytes"
+	"demo/db/mysqldb"
+	"demo/finding"
+	"demo/models"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActorSystem 扫描生命周期等系统动作的操作人
+const ActorSystem = "system"
+
+const (
+	// 请求体最多读取的字节数，超过时不生成请求摘要
+	maxBody = 64 << 10
+	// 请求摘要最大长度
+	maxSummary = 2000
+	// 默认保留天数
+	defaultRetentionDays = 180
+	// 清理间隔与每批删除条数
+	cleanupInterval = time.Hour
+	cleanupBatch    = 5000
+	// 脱敏后的占位符
+	masked = "******"
+)
+
+// 使用 GET 但会修改状态的接口
+var mutatingGets = map[string]bool{
+	"/api/task/start":  true,
+	"/api/task/stop":   true,
+	"/api/task/delete": true,
+}
+
+// 请求摘要中需要脱敏的字段（字段名小写后包含即脱敏）
+var sensitiveKeys = []string{"password", "token", "secret", "key", "cookie", "authorization"}
+
+// 这些接口的 config 字段包含第三方凭据或扫描认证凭据，整体脱敏
+var credentialPaths = []string{"/api/issue/", "/api/notify/", "/api/auth/"}
+
+// 对象 ID 依次从这些参数中取（query 优先，其次 JSON / 表单请求体）
+var objectFields = []string{"taskId", "runId", "id", "ids", "username", "templateId", "name"}
+
+// objectKey 处理函数可通过 SetObject 指定对象 ID（如新建任务生成的 taskId）
+const objectKey = "audit.objectId"
+
+// SetObject 在处理函数中记录本次操作的对象 ID，覆盖从请求参数推断的结果
+func SetObject(c *gin.Context, id string) {
+	c.Set(objectKey, id)
+}
+
+// Init 启动过期审计日志的清理协程
+func Init() {
+	days := retentionDays()
+	if days <= 0 {
+		log.Printf("[audit.Init] retention disabled, audit logs are kept forever")
+		return
+	}
+	go func() {
+		for {
+			cleanup(days)
+			time.Sleep(cleanupInterval)
+		}
+	}()
+}
+
+func retentionDays() int {
+	if v := os.Getenv("DAST_AUDIT_RETENTION_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+		log.Printf("[audit] invalid DAST_AUDIT_RETENTION_DAYS=%q, use default %d", v, defaultRetentionDays)
+	}
+	return defaultRetentionDays
+}
+
+// cleanup 分批删除超过保留期的记录，避免长事务锁表
+func cleanup(days int) {
+	before := time.Now().AddDate(0, 0, -days)
+	var total int64
+	for {
+		res := mysqldb.DB.Where("created_at < ?", before).Limit(cleanupBatch).Delete(&models.TaskLog{})
+		if res.Error != nil {
+			log.Printf("[audit.cleanup] db delete failed err=%v", res.Error)
+			return
+		}
+		total += res.RowsAffected
+		if res.RowsAffected < cleanupBatch {
+			break
+		}
+	}
+	if total > 0 {
+		log.Printf("[audit.cleanup] deleted expired audit logs rows=%d before=%s", total, before.Format("2006-01-02 15:04:05"))
+	}
+}
+
+// Record 写入一条审计日志
+func Record(l *models.TaskLog) {
+	if err := mysqldb.DB.Create(l).Error; err != nil {
+		log.Printf("[audit.Record] db create failed action=%s actor=%s task=%s err=%v", l.Action, l.Actor, l.TaskID, err)
+	}
+}
+
+// Lifecycle 记录扫描生命周期变化（queued / running / resumed / finished / error / stopped / interrupted）
+func Lifecycle(taskId, runId, status, message string) {
+	Record(&models.TaskLog{
+		TaskID:     taskId,
+		Action:     "scan." + status,
+		Actor:      ActorSystem,
+		ObjectType: "run",
+		ObjectID:   runId,
+		Message:    message,
+	})
+}
+
+// Middleware 记录 /api 下修改状态的请求（非 GET 请求与 mutatingGets），在处理函数执行后写入，包含响应状态码
+// 操作人取鉴权中间件设置的 username；登录接口取请求中的用户名
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		method := c.Request.Method
+		if !strings.HasPrefix(path, "/api/") || method == http.MethodOptions || method == http.MethodHead ||
+			(method == http.MethodGet && !mutatingGets[path]) {
+			c.Next()
+			return
+		}
+		params := readParams(c.Request)
+		c.Next()
+
+		l := &models.TaskLog{
+			TaskID:     first(c.Query("taskId"), str(params["taskId"])),
+			Action:     strings.ReplaceAll(strings.TrimPrefix(path, "/api/"), "/", "."),
+			Actor:      c.GetString("username"),
+			IP:         c.ClientIP(),
+			ObjectType: strings.SplitN(strings.TrimPrefix(path, "/api/"), "/", 2)[0],
+			Method:     method,
+			Path:       path,
+			Status:     c.Writer.Status(),
+		}
+		if l.Actor == "" {
+			l.Actor = str(params["username"])
+		}
+		if id, ok := c.Get(objectKey); ok {
+			l.ObjectID, _ = id.(string)
+		}
+		for _, k := range objectFields {
+			if l.ObjectID != "" {
+				break
+			}
+			l.ObjectID = first(c.Query(k), str(params[k]))
+		}
+		if l.TaskID == "" && l.ObjectType == "task" {
+			l.TaskID = l.ObjectID
+		}
+		l.Message = summarize(path, c.Request.URL.Query(), params)
+		Record(l)
+	}
+}
+
+// readParams 读取 JSON / 表单请求体（读取后还原，不影响处理函数），其它类型或过大的请求体返回 nil
+func readParams(r *http.Request) map[string]interface{} {
+	if r.Body == nil {
+		return nil
+	}
+	ct := r.Header.Get("Content-Type")
+	isJSON := strings.HasPrefix(ct, "application/json")
+	isForm := strings.HasPrefix(ct, "application/x-www-form-urlencoded")
+	if !isJSON && !isForm {
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBody+1))
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), r.Body))
+	if err != nil || len(data) > maxBody {
+		return nil
+	}
+	params := map[string]interface{}{}
+	if isJSON {
+		if json.Unmarshal(data, &params) != nil {
+			return nil
+		}
+		return params
+	}
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return nil
+	}
+	for k, v := range values {
+		if len(v) == 1 {
+			params[k] = v[0]
+		} else {
+			params[k] = v
+		}
+	}
+	return params
+}
+
+// summarize 生成脱敏后的请求摘要：query 与请求体
+func summarize(path string, query url.Values, params map[string]interface{}) string {
+	out := map[string]interface{}{}
+	for k, v := range query {
+		if len(v) == 1 {
+			out[k] = v[0]
+		} else {
+			out[k] = v
+		}
+	}
+	for k, v := range params {
+		out[k] = v
+	}
+	if len(out) == 0 {
+		return ""
+	}
+	credentials := false
+	for _, p := range credentialPaths {
+		credentials = credentials || strings.HasPrefix(path, p)
+	}
+	redact(out, credentials)
+	data, err := json.Marshal(out)
+	if err != nil {
+		return ""
+	}
+	if len(data) > maxSummary {
+		return string(data[:maxSummary]) + "...(truncated)"
+	}
+	return string(data)
+}
+
+// redact 递归替换敏感字段的值
+func redact(v interface{}, credentials bool) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			if sensitive(k) || (credentials && k == "config") {
+				t[k] = masked
+				continue
+			}
+			redact(val, credentials)
+		}
+	case []interface{}:
+		for _, val := range t {
+			redact(val, credentials)
+		}
+	}
+}
+
+func sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// str 把请求参数转为字符串，数组取逗号拼接
+func str(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case []interface{}:
+		parts := make([]string, 0, len(t))
+		for _, x := range t {
+			if s := str(x); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, ",")
+	case []string:
+		return strings.Join(t, ",")
+	}
+	return ""
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// List - 查询审计日志
+// GET /api/audit/list?actor=&action=task.&taskId=&objectType=&objectId=&ip=&failed=1&start=&end=&page=&pageSize=
+// action 以 "." 结尾时按前缀匹配（如 task. 匹配 task.create / task.start ...）
+func List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := mysqldb.DB.Model(&models.TaskLog{})
+		for param, column := range map[string]string{
+			"actor": "actor", "taskId": "task_id", "objectType": "object_type", "objectId": "object_id", "ip": "ip",
+		} {
+			if v := c.Query(param); v != "" {
+				db = db.Where(column+" = ?", v)
+			}
+		}
+		if v := c.Query("action"); strings.HasSuffix(v, ".") {
+			db = db.Where("action LIKE ?", strings.ReplaceAll(v, "_", `\_`)+"%")
+		} else if v != "" {
+			db = db.Where("action = ?", v)
+		}
+		if c.Query("failed") == "1" {
+			db = db.Where("status >= ?", http.StatusBadRequest)
+		}
+		if s := c.Query("start"); s != "" {
+			t, err := finding.ParseTime(s)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start: " + err.Error()})
+				return
+			}
+			db = db.Where("created_at >= ?", *t)
+		}
+		if s := c.Query("end"); s != "" {
+			t, err := finding.ParseTime(s)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end: " + err.Error()})
+				return
+			}
+			db = db.Where("created_at <= ?", *t)
+		}
+		page, pageSize := finding.ParsePage(c)
+
+		var total int64
+		if err := db.Count(&total).Error; err != nil {
+			log.Printf("[audit.List] db count failed err=%v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		logs := []models.TaskLog{}
+		if err := db.Order("id desc").
+			Offset((page - 1) * pageSize).
+			Limit(pageSize).
+			Find(&logs).Error; err != nil {
+			log.Printf("[audit.List] db query failed err=%v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"total":         total,
+			"page":          page,
+			"pageSize":      pageSize,
+			"retentionDays": retentionDays(),
+			"logs":          logs,
+		})
+	}
+}
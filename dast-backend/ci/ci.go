@@ -0,0 +1,378 @@
+/**
+ * CI 集成：创建并启动任务后阻塞等待扫描结束，按严重等级阈值给出 pass / fail 结论
+ */
+package ci
+
+import (
+	"context"
+	"demo/db/mysqldb"
+	"demo/db/redisdb"
+	"demo/finding"
+	"demo/models"
+	"demo/scanner"
+	"demo/task"
+	"demo/taskrun"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// 默认阻塞等待时间与上限（秒）
+	defaultTimeout = 30 * 60
+	maxTimeout     = 2 * 60 * 60
+	// 等待期间轮询执行状态的间隔
+	pollInterval = 2 * time.Second
+	// 流式模式下阻塞读取事件的超时，超时发送 ping 行保持连接
+	streamBlock = 15 * time.Second
+	// 结论中最多列出的阻断漏洞条数
+	maxBlocking = 50
+	// 默认阈值：出现 high 及以上漏洞即失败
+	defaultFailOn = "high"
+)
+
+// 严重等级由低到高
+var severityRank = map[string]int{
+	"info":     1,
+	"low":      2,
+	"medium":   3,
+	"high":     4,
+	"critical": 5,
+}
+
+// 结论
+const (
+	VerdictPass    = "pass"    // 执行完成且没有达到阈值的漏洞
+	VerdictFail    = "fail"    // 存在达到阈值的漏洞
+	VerdictError   = "error"   // 执行失败/被停止/中断，结果不可信
+	VerdictPending = "pending" // 等待超时，扫描仍在进行
+)
+
+// Scan - 创建任务并启动扫描，阻塞到执行结束后返回结论
+// POST /api/ci/scan {"targets":["https://example.com"],"profile":"critical-only","failOn":"high","timeout":1800,"stream":false}
+// stream=true 时以 NDJSON 逐行推送进度事件，最后一行为 {"event":"verdict",...}
+func Scan() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			TaskName   string          `json:"taskName"`
+			Targets    []string        `json:"targets"`
+			Profile    string          `json:"profile"`
+			Config     json.RawMessage `json:"config"`
+			Exclusions []string        `json:"exclusions"`
+			FailOn     string          `json:"failOn"`
+			Timeout    int             `json:"timeout"` // 秒
+			Stream     bool            `json:"stream"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+			return
+		}
+		failOn, err := parseFailOn(req.FailOn)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		timeout, err := parseTimeout(req.Timeout, defaultTimeout)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if strings.TrimSpace(req.TaskName) == "" {
+			req.TaskName = "ci-" + time.Now().Format("20060102-150405")
+		}
+
+		created, err := task.CreateTask(req.TaskName, req.Targets, req.Profile, req.Config, req.Exclusions, c.GetString("username"))
+		if errors.Is(err, task.ErrInvalidTask) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		t := created.Task
+		run, err := task.StartTask(t.ID, "ci")
+		if err != nil {
+			log.Printf("[ci.Scan] start task failed task=%s err=%v", t.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "start task failed: " + err.Error(), "taskId": t.ID})
+			return
+		}
+		log.Printf("[ci.Scan] scan started task=%s run=%s user=%s failOn=%s", t.ID, run.ID, c.GetString("username"), failOn)
+
+		if req.Stream {
+			stream(c, run, failOn, timeout)
+			return
+		}
+		respond(c, run.ID, failOn, timeout)
+	}
+}
+
+// Result - 查询（并可选阻塞等待）某次执行的结论，CI 连接中断后可用 runId 重新等待
+// GET /api/ci/result?runId=&failOn=high&wait=600
+func Result() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		runId := c.Query("runId")
+		if runId == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing runId"})
+			return
+		}
+		failOn, err := parseFailOn(c.Query("failOn"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		wait := 0
+		if s := c.Query("wait"); s != "" {
+			if wait, err = strconv.Atoi(s); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wait"})
+				return
+			}
+		}
+		timeout, err := parseTimeout(wait, 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		respond(c, runId, failOn, timeout)
+	}
+}
+
+// respond 阻塞等待执行结束（最多 timeout）后返回结论；仍在扫描时返回 202
+func respond(c *gin.Context, runId, failOn string, timeout time.Duration) {
+	run, err := waitRun(c.Request.Context(), runId, timeout)
+	if err != nil {
+		if c.Request.Context().Err() != nil {
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "run not found"})
+		return
+	}
+	v, err := verdict(run, failOn)
+	if err != nil {
+		log.Printf("[ci] build verdict failed run=%s err=%v", run.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	status := http.StatusOK
+	if v["verdict"] == VerdictPending {
+		status = http.StatusAccepted
+	}
+	c.JSON(status, v)
+}
+
+// stream 以 NDJSON 推送执行的进度事件，执行结束（或超时）后输出结论
+func stream(c *gin.Context, run *models.TaskRun, failOn string, timeout time.Duration) {
+	ctx := c.Request.Context()
+	deadline := time.Now().Add(timeout)
+	streamKey := taskrun.EventsKey(run.TaskID, run.ID)
+	lastId := "0"
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	w := c.Writer
+	enc := json.NewEncoder(w)
+
+	for time.Now().Before(deadline) {
+		block := streamBlock
+		if left := time.Until(deadline); left < block {
+			block = left
+		}
+		streams, err := redisdb.Client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{streamKey, lastId},
+			Count:   100,
+			Block:   block,
+		}).Result()
+		if ctx.Err() != nil {
+			return
+		}
+		if err == redis.Nil {
+			_ = enc.Encode(gin.H{"event": "ping"})
+			w.Flush()
+			continue
+		}
+		if err != nil {
+			log.Printf("[ci.stream] redis xread failed task=%s run=%s err=%v", run.TaskID, run.ID, err)
+			break
+		}
+
+		finished := false
+		for _, s := range streams {
+			for _, msg := range s.Messages {
+				lastId = msg.ID
+				event, _ := msg.Values["event"].(string)
+				data, _ := msg.Values["data"].(string)
+				_ = enc.Encode(gin.H{"event": event, "data": json.RawMessage(data)})
+				if event == scanner.EventStatus && terminalEvent(data) {
+					finished = true
+				}
+			}
+		}
+		w.Flush()
+		if finished {
+			break
+		}
+	}
+
+	// 状态事件先于 MySQL 更新写入，稍等执行记录落库
+	cur, err := waitRun(ctx, run.ID, time.Until(deadline))
+	if err != nil {
+		return
+	}
+	v, err := verdict(cur, failOn)
+	if err != nil {
+		v = gin.H{"error": err.Error()}
+	}
+	v["event"] = "verdict"
+	_ = enc.Encode(v)
+	w.Flush()
+}
+
+// waitRun 轮询执行记录直到不再 running、超时或客户端断开，返回最新的执行记录
+func waitRun(ctx context.Context, runId string, timeout time.Duration) (*models.TaskRun, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		var run models.TaskRun
+		if err := mysqldb.DB.First(&run, "id = ?", runId).Error; err != nil {
+			return nil, err
+		}
+		if run.Status != "running" || !time.Now().Before(deadline) {
+			return &run, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// verdict 根据执行状态与达到阈值的漏洞数量给出结论
+func verdict(run *models.TaskRun, failOn string) (gin.H, error) {
+	counts, err := taskrun.SeverityCounts([]string{run.ID})
+	if err != nil {
+		return nil, err
+	}
+	severities := counts[run.ID]
+	if severities == nil {
+		severities = map[string]int64{}
+	}
+	var total, blockingCount int64
+	for sev, n := range severities {
+		total += n
+		if severityRank[sev] >= severityRank[failOn] {
+			blockingCount += n
+		}
+	}
+
+	resp := gin.H{
+		"taskId":     run.TaskID,
+		"runId":      run.ID,
+		"status":     run.Status,
+		"failOn":     failOn,
+		"findings":   total,
+		"severities": severities,
+		"blocking":   blockingCount,
+	}
+	if run.StartedAt != nil && run.FinishedAt != nil {
+		resp["durationSeconds"] = int64(run.FinishedAt.Sub(*run.StartedAt).Seconds())
+	}
+
+	switch {
+	case run.Status == "running":
+		resp["verdict"] = VerdictPending
+	case blockingCount > 0:
+		// 即便执行没有正常完成，已发现的阻断漏洞也足以判定失败
+		resp["verdict"] = VerdictFail
+	case run.Status != "finished":
+		resp["verdict"] = VerdictError
+		resp["message"] = run.Message
+	default:
+		resp["verdict"] = VerdictPass
+	}
+	resp["passed"] = resp["verdict"] == VerdictPass
+
+	if blockingCount > 0 {
+		list, err := blockingFindings(run.ID, failOn)
+		if err != nil {
+			return nil, err
+		}
+		resp["blockingFindings"] = list
+	}
+	return resp, nil
+}
+
+// blockingFindings 列出达到阈值且未标记为误报/接受风险的漏洞（按严重等级降序，最多 maxBlocking 条）
+func blockingFindings(runId, failOn string) ([]gin.H, error) {
+	levels := []string{}
+	for sev, rank := range severityRank {
+		if rank >= severityRank[failOn] {
+			levels = append(levels, sev)
+		}
+	}
+	var findings []models.Finding
+	if err := mysqldb.DB.Omit("details").
+		Where("run_id = ? AND severity IN ? AND state NOT IN ?", runId, levels, finding.IgnoredStates).
+		Order("id asc").Find(&findings).Error; err != nil {
+		return nil, err
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRank[findings[i].Severity] > severityRank[findings[j].Severity]
+	})
+	if len(findings) > maxBlocking {
+		findings = findings[:maxBlocking]
+	}
+	list := make([]gin.H, 0, len(findings))
+	for _, f := range findings {
+		list = append(list, gin.H{
+			"templateId":  f.TemplateID,
+			"severity":    f.Severity,
+			"title":       f.Title,
+			"matchedAt":   f.MatchedAt,
+			"matcherName": f.MatcherName,
+		})
+	}
+	return list, nil
+}
+
+func parseFailOn(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return defaultFailOn, nil
+	}
+	if _, ok := severityRank[s]; !ok {
+		return "", fmt.Errorf("invalid failOn %q (info/low/medium/high/critical)", s)
+	}
+	return s, nil
+}
+
+func parseTimeout(seconds, def int) (time.Duration, error) {
+	if seconds == 0 {
+		seconds = def
+	}
+	if seconds < 0 || seconds > maxTimeout {
+		return 0, fmt.Errorf("timeout must be between 0 and %d seconds", maxTimeout)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// terminalEvent 判断 status 事件是否表示执行已结束
+func terminalEvent(data string) bool {
+	var ev struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(data), &ev); err != nil {
+		return false
+	}
+	return ev.Status != "" && ev.Status != "running"
+}
@@ -67,15 +67,35 @@ func ensureSchema() error {
 		`CREATE TABLE IF NOT EXISTS findings (
 			id BIGINT AUTO_INCREMENT PRIMARY KEY,
 			task_id VARCHAR(64) NOT NULL,
+			run_id VARCHAR(64),
 			target VARCHAR(512) NOT NULL,
 			template_id VARCHAR(128),
 			severity VARCHAR(32),
 			title VARCHAR(512),
+			type VARCHAR(32),
+			matcher_name VARCHAR(128),
+			matched_at VARCHAR(1024),
+			host VARCHAR(255),
+			port INT,
+			ip VARCHAR(64),
+			extracted_results JSON,
+			metadata JSON,
 			details JSON,
 			raw_ref VARCHAR(1024),
+			issues JSON NULL,
+			fingerprint VARCHAR(40) NULL,
+			state VARCHAR(32) NOT NULL DEFAULT 'open',
+			assignee VARCHAR(64) NULL,
+			suppression_id BIGINT NULL,
 			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 			INDEX idx_task_id (task_id),
-			INDEX idx_template_id (template_id)
+			INDEX idx_run_id (run_id),
+			INDEX idx_template_id (template_id),
+			INDEX idx_severity (severity),
+			INDEX idx_host (host),
+			INDEX idx_fingerprint (fingerprint),
+			INDEX idx_state (state),
+			INDEX idx_created_at (created_at)
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
 
 		// task_logs 表（审计 / 操作日志）
@@ -84,9 +104,340 @@ func ensureSchema() error {
 			task_id VARCHAR(64),
 			action VARCHAR(64) NOT NULL,
 			actor VARCHAR(128),
+			ip VARCHAR(64) NULL,
+			object_type VARCHAR(32) NULL,
+			object_id VARCHAR(128) NULL,
+			method VARCHAR(8) NULL,
+			path VARCHAR(255) NULL,
+			status INT NULL,
+			message TEXT,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_task_action (task_id, action),
+			INDEX idx_actor (actor),
+			INDEX idx_created_at (created_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		// scan_profiles 表（用户自定义的扫描配置，内置配置不入库）
+		`CREATE TABLE IF NOT EXISTS scan_profiles (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			name VARCHAR(64) NOT NULL,
+			description VARCHAR(512),
+			config JSON NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			UNIQUE INDEX idx_name (name)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		// task_runs 表（每次启动任务对应一次执行记录）
+		`CREATE TABLE IF NOT EXISTS task_runs (
+			id VARCHAR(64) NOT NULL PRIMARY KEY,
+			task_id VARCHAR(64) NOT NULL,
+			triggered_by VARCHAR(32) NOT NULL,
+			status VARCHAR(32) NOT NULL,
+			message TEXT,
+			targets JSON NULL,
+			config JSON NULL,
+			template_revision BIGINT NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			started_at DATETIME NULL,
+			finished_at DATETIME NULL,
+			INDEX idx_task_id (task_id),
+			INDEX idx_created_at (created_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		// task_schedules 表（每个任务一条 cron 调度配置）
+		`CREATE TABLE IF NOT EXISTS task_schedules (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			task_id VARCHAR(64) NOT NULL,
+			cron VARCHAR(128) NOT NULL,
+			timezone VARCHAR(64) NOT NULL,
+			window_start VARCHAR(8),
+			window_end VARCHAR(8),
+			enabled TINYINT(1) NOT NULL DEFAULT 1,
+			next_run_at DATETIME NULL,
+			last_run_at DATETIME NULL,
+			last_run_id VARCHAR(64),
+			last_result VARCHAR(512),
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			UNIQUE INDEX idx_task_id (task_id),
+			INDEX idx_next_run_at (next_run_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		// users 表（平台账号，密码为 bcrypt 哈希）
+		`CREATE TABLE IF NOT EXISTS users (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			username VARCHAR(64) NOT NULL,
+			password_hash VARCHAR(255) NOT NULL,
+			role VARCHAR(16) NOT NULL,
+			disabled TINYINT(1) NOT NULL DEFAULT 0,
+			last_login_at DATETIME NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			UNIQUE INDEX idx_username (username)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		// api_tokens 表（CI 等使用的长期 token，只保存哈希）
+		`CREATE TABLE IF NOT EXISTS api_tokens (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			name VARCHAR(128) NOT NULL,
+			username VARCHAR(64) NOT NULL,
+			token_hash VARCHAR(64) NOT NULL,
+			prefix VARCHAR(16) NOT NULL,
+			scopes VARCHAR(128) NOT NULL,
+			expires_at DATETIME NULL,
+			last_used_at DATETIME NULL,
+			last_used_ip VARCHAR(64) NULL,
+			revoked_at DATETIME NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_username (username),
+			UNIQUE INDEX idx_token_hash (token_hash)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		// asset_hosts 表（资产清单：发现过开放端口的主机）
+		`CREATE TABLE IF NOT EXISTS asset_hosts (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			host VARCHAR(255) NOT NULL,
+			first_seen_at DATETIME NOT NULL,
+			last_seen_at DATETIME NOT NULL,
+			last_task_id VARCHAR(64) NULL,
+			last_run_id VARCHAR(64) NULL,
+			UNIQUE INDEX idx_host (host),
+			INDEX idx_last_seen_at (last_seen_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		// asset_ports 表（主机开放端口，status 为 open / closed）
+		`CREATE TABLE IF NOT EXISTS asset_ports (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			host VARCHAR(255) NOT NULL,
+			port INT NOT NULL,
+			protocol VARCHAR(8) NOT NULL,
+			status VARCHAR(16) NOT NULL,
+			first_seen_at DATETIME NOT NULL,
+			last_seen_at DATETIME NOT NULL,
+			first_run_id VARCHAR(64) NULL,
+			last_run_id VARCHAR(64) NULL,
+			closed_at DATETIME NULL,
+			UNIQUE INDEX idx_host_port (host, port),
+			INDEX idx_status (status)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		// asset_services 表（端口上的服务：HTTP 状态、标题、Server 头、TLS 证书、指纹）
+		`CREATE TABLE IF NOT EXISTS asset_services (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			host VARCHAR(255) NOT NULL,
+			port INT NOT NULL,
+			protocol VARCHAR(8) NOT NULL,
+			scheme VARCHAR(8) NULL,
+			url VARCHAR(1024) NULL,
+			status_code INT NULL,
+			title VARCHAR(512) NULL,
+			server VARCHAR(255) NULL,
+			tls_subject VARCHAR(512) NULL,
+			tls_not_after DATETIME NULL,
+			products JSON NULL,
+			first_seen_at DATETIME NOT NULL,
+			last_seen_at DATETIME NOT NULL,
+			last_run_id VARCHAR(64) NULL,
+			UNIQUE INDEX idx_host_port (host, port),
+			INDEX idx_tls_not_after (tls_not_after)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		// asset_changes 表（暴露面变化记录）
+		`CREATE TABLE IF NOT EXISTS asset_changes (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			host VARCHAR(255) NULL,
+			port INT NULL,
+			kind VARCHAR(32) NOT NULL,
+			detail VARCHAR(512) NULL,
+			task_id VARCHAR(64) NULL,
+			run_id VARCHAR(64) NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_host (host),
+			INDEX idx_kind (kind),
+			INDEX idx_run_id (run_id),
+			INDEX idx_created_at (created_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		// templates 表（./poc 下 nuclei 模板的索引）
+		`CREATE TABLE IF NOT EXISTS templates (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			template_id VARCHAR(255) NOT NULL,
+			path VARCHAR(1024) NOT NULL,
+			name VARCHAR(512) NULL,
+			author VARCHAR(512) NULL,
+			severity VARCHAR(16) NULL,
+			tags VARCHAR(1024) NULL,
+			cve VARCHAR(512) NULL,
+			protocol VARCHAR(32) NULL,
+			source VARCHAR(16) NOT NULL,
+			enabled TINYINT(1) NOT NULL DEFAULT 1,
+			verified TINYINT(1) NOT NULL DEFAULT 0,
+			version INT NOT NULL,
+			sha256 VARCHAR(64) NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			UNIQUE INDEX idx_template_id (template_id),
+			INDEX idx_severity (severity),
+			INDEX idx_source (source)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		// template_versions 表（模板变更记录，自增 ID 即模板库修订号）
+		`CREATE TABLE IF NOT EXISTS template_versions (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			template_id VARCHAR(255) NOT NULL,
+			version INT NOT NULL,
+			action VARCHAR(16) NOT NULL,
+			path VARCHAR(1024) NULL,
+			sha256 VARCHAR(64) NULL,
+			content MEDIUMTEXT NULL,
+			verified TINYINT(1) NOT NULL DEFAULT 0,
+			creator VARCHAR(64) NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_template_id (template_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		// reports 表（执行报告，文件保存在报告目录）
+		`CREATE TABLE IF NOT EXISTS reports (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			task_id VARCHAR(64) NULL,
+			run_id VARCHAR(64) NOT NULL,
+			format VARCHAR(16) NOT NULL,
+			status VARCHAR(16) NOT NULL,
 			message TEXT,
+			path VARCHAR(1024) NULL,
+			size BIGINT NOT NULL DEFAULT 0,
+			findings BIGINT NOT NULL DEFAULT 0,
+			creator VARCHAR(64) NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			finished_at DATETIME NULL,
+			INDEX idx_task_id (task_id),
+			INDEX idx_run_id (run_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		// issue_trackers 表（任务的缺陷跟踪配置，config 为加密后的凭据与项目配置）
+		`CREATE TABLE IF NOT EXISTS issue_trackers (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			task_id VARCHAR(64) NOT NULL,
+			name VARCHAR(128) NOT NULL,
+			type VARCHAR(16) NOT NULL,
+			config TEXT,
+			min_severity VARCHAR(16) NULL,
+			allow_list JSON NULL,
+			deny_list JSON NULL,
+			auto_close TINYINT(1) NOT NULL DEFAULT 1,
+			enabled TINYINT(1) NOT NULL DEFAULT 1,
+			creator VARCHAR(64) NULL,
+			last_sync_at DATETIME NULL,
+			last_error TEXT,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			INDEX idx_task_id (task_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		// scan_auths 表（任务的扫描认证配置，config 为加密后的凭据与登录流程）
+		`CREATE TABLE IF NOT EXISTS scan_auths (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			task_id VARCHAR(64) NOT NULL,
+			name VARCHAR(128) NOT NULL,
+			type VARCHAR(16) NOT NULL,
+			domains JSON NULL,
+			domains_regex JSON NULL,
+			config TEXT,
+			enabled TINYINT(1) NOT NULL DEFAULT 1,
+			creator VARCHAR(64) NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			INDEX idx_task_id (task_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		// api_specs 表（API 扫描任务上传的接口文档 / 流量导出，content 为原始文件内容）
+		`CREATE TABLE IF NOT EXISTS api_specs (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			task_id VARCHAR(64) NOT NULL,
+			format VARCHAR(16) NOT NULL,
+			file_name VARCHAR(255) NULL,
+			content LONGTEXT,
+			size BIGINT NOT NULL DEFAULT 0,
+			variables JSON NULL,
+			required_only TINYINT(1) NOT NULL DEFAULT 0,
+			filters JSON NULL,
+			request_count BIGINT NOT NULL DEFAULT 0,
+			hosts JSON NULL,
+			creator VARCHAR(64) NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			UNIQUE KEY uk_task_id (task_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		// notify_channels 表（通知渠道，config 为加密后的地址与凭据）
+		`CREATE TABLE IF NOT EXISTS notify_channels (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			name VARCHAR(128) NOT NULL,
+			type VARCHAR(16) NOT NULL,
+			config TEXT,
+			events VARCHAR(255) NULL,
+			min_severity VARCHAR(16) NULL,
+			task_id VARCHAR(64) NULL,
+			template TEXT,
+			enabled TINYINT(1) NOT NULL DEFAULT 1,
+			creator VARCHAR(64) NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			INDEX idx_task_id (task_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		// notify_deliveries 表（通知投递记录）
+		`CREATE TABLE IF NOT EXISTS notify_deliveries (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			channel_id BIGINT NOT NULL,
+			event VARCHAR(32) NOT NULL,
+			task_id VARCHAR(64) NULL,
+			run_id VARCHAR(64) NULL,
+			status VARCHAR(16) NOT NULL,
+			attempts INT NOT NULL DEFAULT 0,
+			last_error TEXT,
+			payload MEDIUMTEXT,
+			next_retry_at DATETIME NULL,
 			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			INDEX idx_task_action (task_id, action)
+			delivered_at DATETIME NULL,
+			INDEX idx_channel_id (channel_id),
+			INDEX idx_task_id (task_id),
+			INDEX idx_status (status),
+			INDEX idx_next_retry_at (next_retry_at),
+			INDEX idx_created_at (created_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		// finding_activities 表（漏洞处置记录：状态变更、指派、评论）
+		`CREATE TABLE IF NOT EXISTS finding_activities (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			task_id VARCHAR(64) NOT NULL,
+			fingerprint VARCHAR(40) NOT NULL,
+			finding_id BIGINT NULL,
+			action VARCHAR(16) NOT NULL,
+			actor VARCHAR(64) NULL,
+			from_value VARCHAR(64) NULL,
+			to_value VARCHAR(64) NULL,
+			comment TEXT,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_task_id (task_id),
+			INDEX idx_fingerprint (fingerprint)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		// suppressions 表（屏蔽规则：模板 + 主机通配符，命中的漏洞自动标记为误报 / 接受风险）
+		`CREATE TABLE IF NOT EXISTS suppressions (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			template_id VARCHAR(128) NOT NULL,
+			host_pattern VARCHAR(255) NULL,
+			task_id VARCHAR(64) NULL,
+			state VARCHAR(32) NOT NULL,
+			reason TEXT,
+			expires_at DATETIME NULL,
+			creator VARCHAR(64) NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			INDEX idx_template_id (template_id),
+			INDEX idx_task_id (task_id)
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
 	}
 
@@ -40,7 +40,12 @@ func Init(user string, pass string, host string, name string) {
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
 	// 自动迁移创建/修改表结构（生产中用 migrations 管理）
-	if err := DB.AutoMigrate(&models.Task{}, &models.Target{}, &models.Finding{}, &models.TaskLog{}); err != nil {
+	if err := DB.AutoMigrate(&models.Task{}, &models.Target{}, &models.Finding{}, &models.TaskLog{}, &models.ScanProfile{},
+		&models.TaskRun{}, &models.TaskSchedule{}, &models.User{}, &models.APIToken{},
+		&models.AssetHost{}, &models.AssetPort{}, &models.AssetService{}, &models.AssetChange{},
+		&models.Template{}, &models.TemplateVersion{}, &models.Report{}, &models.IssueTracker{},
+		&models.ScanAuth{}, &models.APISpec{}, &models.NotifyChannel{}, &models.NotifyDelivery{},
+		&models.FindingActivity{}, &models.Suppression{}); err != nil {
 		log.Fatalf("auto migrate failed: %v", err)
 	}
 }
@@ -0,0 +1,287 @@
+package finding
+
+import (
+	"demo/db/mysqldb"
+	"demo/models"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"gorm.io/gorm"
+)
+
+// 防止 request/response 过大撑爆 details 列
+const maxEvidenceSize = 10240
+
+// FromResultEvent 把 nuclei 命中的 ResultEvent 归一化成一条 models.Finding
+// details 列保存完整事件 JSON，便于后续按原始格式回放给前端
+func FromResultEvent(taskId, runId string, ev *output.ResultEvent) (*models.Finding, error) {
+	if len(ev.Request) > maxEvidenceSize {
+		ev.Request = ev.Request[:maxEvidenceSize]
+	}
+	if len(ev.Response) > maxEvidenceSize {
+		ev.Response = ev.Response[:maxEvidenceSize]
+	}
+	details, err := json.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+
+	matchedAt := ev.Matched
+	if matchedAt == "" {
+		matchedAt = ev.URL
+	}
+	host, port := hostPort(ev)
+
+	f := &models.Finding{
+		TaskID:      taskId,
+		RunID:       runId,
+		Target:      firstNonEmpty(ev.URL, ev.Host, matchedAt),
+		TemplateID:  ev.TemplateID,
+		Severity:    ev.Info.SeverityHolder.Severity.String(),
+		Title:       firstNonEmpty(ev.Info.Name, ev.TemplateID),
+		Type:        ev.Type,
+		MatcherName: ev.MatcherName,
+		MatchedAt:   matchedAt,
+		Host:        host,
+		Port:        port,
+		IP:          ev.IP,
+		Details:     string(details),
+		Fingerprint: Fingerprint(taskId, ev.TemplateID, matchedAt, ev.MatcherName),
+		State:       StateOpen,
+		CreatedAt:   ev.Timestamp,
+	}
+	if len(ev.ExtractedResults) > 0 {
+		if b, err := json.Marshal(ev.ExtractedResults); err == nil {
+			f.ExtractedResults = string(b)
+		}
+	}
+	if len(ev.Metadata) > 0 {
+		if b, err := json.Marshal(ev.Metadata); err == nil {
+			f.Metadata = string(b)
+		}
+	}
+	return f, nil
+}
+
+// Save 把命中结果写入 MySQL findings 表，归属于 runId 对应的执行
+// 处置状态沿用同一漏洞之前的记录，并按屏蔽规则标记
+func Save(taskId, runId string, ev *output.ResultEvent) (*models.Finding, error) {
+	f, err := FromResultEvent(taskId, runId, ev)
+	if err != nil {
+		return nil, err
+	}
+	triage(f)
+	if err := mysqldb.DB.Create(f).Error; err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// hostPort 优先使用事件自带的 host/port，缺失时从 matched-at / url 中解析
+func hostPort(ev *output.ResultEvent) (string, int) {
+	// http 模板的 host 字段可能是完整 URL 或 host:port，统一只保留主机名
+	host, portStr := splitHostPort(ev.Host)
+	if ev.Port != "" {
+		portStr = ev.Port
+	}
+	if host == "" || portStr == "" {
+		h, p := splitHostPort(firstNonEmpty(ev.Matched, ev.URL))
+		if host == "" {
+			host = h
+		}
+		if portStr == "" {
+			portStr = p
+		}
+	}
+
+	port, _ := strconv.Atoi(portStr)
+	return host, port
+}
+
+// splitHostPort 支持 URL、host:port、host 三种写法，URL 未带端口时按 scheme 推断
+func splitHostPort(raw string) (string, string) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", ""
+	}
+	if strings.Contains(raw, "://") {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return "", ""
+		}
+		port := u.Port()
+		if port == "" {
+			switch u.Scheme {
+			case "http":
+				port = "80"
+			case "https":
+				port = "443"
+			}
+		}
+		return u.Hostname(), port
+	}
+	if h, p, err := net.SplitHostPort(raw); err == nil {
+		return h, p
+	}
+	return raw, ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Query 是 findings 的通用过滤条件，List 与 target.Result 共用
+type Query struct {
+	TaskID     string
+	RunID      string
+	Severities []string
+	TemplateID string
+	Host       string
+	States     []string
+	Assignee   string
+	Start      *time.Time
+	End        *time.Time
+}
+
+// Apply 把过滤条件拼到 gorm 查询上
+func (q Query) Apply(db *gorm.DB) *gorm.DB {
+	if q.TaskID != "" {
+		db = db.Where("task_id = ?", q.TaskID)
+	}
+	if q.RunID != "" {
+		db = db.Where("run_id = ?", q.RunID)
+	}
+	if len(q.Severities) > 0 {
+		db = db.Where("severity IN ?", q.Severities)
+	}
+	if q.TemplateID != "" {
+		db = db.Where("template_id = ?", q.TemplateID)
+	}
+	if q.Host != "" {
+		db = db.Where("host = ?", q.Host)
+	}
+	if len(q.States) > 0 {
+		db = db.Where("state IN ?", q.States)
+	}
+	if q.Assignee != "" {
+		db = db.Where("assignee = ?", q.Assignee)
+	}
+	if q.Start != nil {
+		db = db.Where("created_at >= ?", *q.Start)
+	}
+	if q.End != nil {
+		db = db.Where("created_at <= ?", *q.End)
+	}
+	return db
+}
+
+// ParseTime 支持 "2006-01-02 15:04:05"、"2006-01-02" 和 RFC3339
+func ParseTime(s string) (*time.Time, error) {
+	layouts := []string{"2006-01-02 15:04:05", "2006-01-02", time.RFC3339}
+	var lastErr error
+	for _, layout := range layouts {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err == nil {
+			return &t, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// ParsePage 解析 page/pageSize 参数，非法值回退到默认值
+func ParsePage(c *gin.Context) (int, int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
+
+// List - 跨任务查询 findings
+// GET /api/finding/list?taskId=&runId=&severity=high,critical&templateId=&host=&state=open,confirmed&assignee=&start=&end=&page=&pageSize=
+func List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := Query{
+			TaskID:     c.Query("taskId"),
+			RunID:      c.Query("runId"),
+			TemplateID: c.Query("templateId"),
+			Host:       c.Query("host"),
+			Assignee:   c.Query("assignee"),
+		}
+		if s := c.Query("severity"); s != "" {
+			for _, sev := range strings.Split(s, ",") {
+				if sev = strings.ToLower(strings.TrimSpace(sev)); sev != "" {
+					q.Severities = append(q.Severities, sev)
+				}
+			}
+		}
+		if s := c.Query("state"); s != "" {
+			for _, state := range strings.Split(s, ",") {
+				if state = strings.ToLower(strings.TrimSpace(state)); state != "" {
+					q.States = append(q.States, state)
+				}
+			}
+		}
+		if s := c.Query("start"); s != "" {
+			t, err := ParseTime(s)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start: " + err.Error()})
+				return
+			}
+			q.Start = t
+		}
+		if s := c.Query("end"); s != "" {
+			t, err := ParseTime(s)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end: " + err.Error()})
+				return
+			}
+			q.End = t
+		}
+		page, pageSize := ParsePage(c)
+
+		var total int64
+		if err := q.Apply(mysqldb.DB.Model(&models.Finding{})).Count(&total).Error; err != nil {
+			log.Printf("[finding.List] db count failed err=%v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		findings := []models.Finding{}
+		if err := q.Apply(mysqldb.DB.Model(&models.Finding{})).
+			Order("created_at desc, id desc").
+			Offset((page - 1) * pageSize).
+			Limit(pageSize).
+			Find(&findings).Error; err != nil {
+			log.Printf("[finding.List] db query failed err=%v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"total":    total,
+			"page":     page,
+			"pageSize": pageSize,
+			"count":    len(findings),
+			"findings": findings,
+		})
+	}
+}
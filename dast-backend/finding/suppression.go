@@ -0,0 +1,198 @@
+package finding
+
+import (
+	"demo/db/mysqldb"
+	"demo/models"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 屏蔽规则缓存，每条命中结果都会匹配一次
+const suppressionCacheTTL = 30 * time.Second
+
+var suppressions struct {
+	sync.Mutex
+	rules   []models.Suppression
+	expires time.Time
+}
+
+func loadSuppressions() []models.Suppression {
+	suppressions.Lock()
+	defer suppressions.Unlock()
+	if time.Now().After(suppressions.expires) {
+		var rules []models.Suppression
+		if err := mysqldb.DB.Where("expires_at IS NULL OR expires_at > ?", time.Now()).Order("id asc").Find(&rules).Error; err != nil {
+			log.Printf("[finding.loadSuppressions] db query failed err=%v", err)
+		}
+		suppressions.rules = rules
+		suppressions.expires = time.Now().Add(suppressionCacheTTL)
+	}
+	return suppressions.rules
+}
+
+func invalidateSuppressions() {
+	suppressions.Lock()
+	suppressions.expires = time.Time{}
+	suppressions.Unlock()
+}
+
+// matchSuppression 返回第一条匹配漏洞的有效屏蔽规则
+func matchSuppression(f *models.Finding) *models.Suppression {
+	now := time.Now()
+	rules := loadSuppressions()
+	for i := range rules {
+		s := &rules[i]
+		if s.ExpiresAt != nil && !s.ExpiresAt.After(now) {
+			continue
+		}
+		if s.TemplateID != f.TemplateID || (s.TaskID != "" && s.TaskID != f.TaskID) {
+			continue
+		}
+		if s.HostPattern != "" {
+			if ok, _ := path.Match(strings.ToLower(s.HostPattern), strings.ToLower(f.Host)); !ok {
+				continue
+			}
+		}
+		return s
+	}
+	return nil
+}
+
+// ListSuppressions - 屏蔽规则列表
+// GET /api/finding/suppressions?taskId=&templateId=
+func ListSuppressions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := mysqldb.DB.Model(&models.Suppression{})
+		if v := c.Query("taskId"); v != "" {
+			db = db.Where("task_id = ? OR task_id = '' OR task_id IS NULL", v)
+		}
+		if v := c.Query("templateId"); v != "" {
+			db = db.Where("template_id = ?", v)
+		}
+		rules := []models.Suppression{}
+		if err := db.Order("id desc").Find(&rules).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"suppressions": rules})
+	}
+}
+
+// SaveSuppression - 新建或更新屏蔽规则（带 id 时更新），只影响之后执行中命中的漏洞
+// POST /api/finding/suppression/save
+//
+//	{"templateId":"tech-detect","hostPattern":"*.test.example.com","taskId":"","state":"false_positive",
+//	 "reason":"测试环境","expiresAt":"2026-12-31"}
+func SaveSuppression() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			ID          uint64 `json:"id"`
+			TemplateID  string `json:"templateId"`
+			HostPattern string `json:"hostPattern"`
+			TaskID      string `json:"taskId"`
+			State       string `json:"state"`
+			Reason      string `json:"reason"`
+			ExpiresAt   string `json:"expiresAt"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+			return
+		}
+		req.TemplateID = strings.TrimSpace(req.TemplateID)
+		req.HostPattern = strings.TrimSpace(req.HostPattern)
+		req.TaskID = strings.TrimSpace(req.TaskID)
+		req.State = strings.ToLower(strings.TrimSpace(req.State))
+		if req.TemplateID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing templateId"})
+			return
+		}
+		if req.State == "" {
+			req.State = StateFalsePositive
+		}
+		if !Ignored(req.State) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid state (false_positive/accepted_risk)"})
+			return
+		}
+		if _, err := path.Match(req.HostPattern, ""); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid hostPattern: " + err.Error()})
+			return
+		}
+		var expiresAt *time.Time
+		if req.ExpiresAt != "" {
+			t, err := ParseTime(req.ExpiresAt)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid expiresAt: " + err.Error()})
+				return
+			}
+			expiresAt = t
+		}
+		if req.TaskID != "" {
+			if err := mysqldb.DB.Select("id").First(&models.Task{}, "id = ?", req.TaskID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+				return
+			}
+		}
+
+		s := models.Suppression{Creator: c.GetString("username")}
+		if req.ID != 0 {
+			if err := mysqldb.DB.First(&s, req.ID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "suppression not found"})
+				return
+			}
+		}
+		s.TemplateID = req.TemplateID
+		s.HostPattern = req.HostPattern
+		s.TaskID = req.TaskID
+		s.State = req.State
+		s.Reason = strings.TrimSpace(req.Reason)
+		s.ExpiresAt = expiresAt
+
+		var err error
+		if s.ID == 0 {
+			err = mysqldb.DB.Create(&s).Error
+		} else {
+			err = mysqldb.DB.Model(&s).Updates(map[string]interface{}{
+				"template_id": s.TemplateID, "host_pattern": s.HostPattern, "task_id": s.TaskID,
+				"state": s.State, "reason": s.Reason, "expires_at": s.ExpiresAt,
+			}).Error
+		}
+		if err != nil {
+			log.Printf("[finding.SaveSuppression] db save failed template=%s err=%v", s.TemplateID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db save suppression failed: " + err.Error()})
+			return
+		}
+		invalidateSuppressions()
+		c.JSON(http.StatusOK, gin.H{"message": "保存成功", "suppression": s})
+	}
+}
+
+// DeleteSuppression - 删除屏蔽规则，之后执行中命中的漏洞恢复为 open
+// POST /api/finding/suppression/delete {"id":1}
+func DeleteSuppression() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			ID uint64 `json:"id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.ID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
+			return
+		}
+		res := mysqldb.DB.Delete(&models.Suppression{}, req.ID)
+		if res.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": res.Error.Error()})
+			return
+		}
+		if res.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "suppression not found"})
+			return
+		}
+		invalidateSuppressions()
+		c.JSON(http.StatusOK, gin.H{"message": "删除成功", "id": req.ID})
+	}
+}
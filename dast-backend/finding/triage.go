@@ -0,0 +1,244 @@
+package finding
+
+import (
+	"crypto/sha1"
+	"demo/db/mysqldb"
+	"demo/models"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 漏洞处置状态
+const (
+	StateOpen          = "open"
+	StateConfirmed     = "confirmed"
+	StateFalsePositive = "false_positive"
+	StateAcceptedRisk  = "accepted_risk"
+	StateFixed         = "fixed"
+)
+
+// States 所有处置状态
+var States = []string{StateOpen, StateConfirmed, StateFalsePositive, StateAcceptedRisk, StateFixed}
+
+// 处置记录类型
+const (
+	ActionState   = "state"
+	ActionAssign  = "assign"
+	ActionComment = "comment"
+)
+
+// 系统自动变更状态时记录的操作人
+const systemActor = "system"
+
+func validState(state string) bool {
+	for _, s := range States {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// IgnoredStates 误报与接受风险：不再提交 issue、不推送新漏洞通知、不计入统计与 CI 阻断
+var IgnoredStates = []string{StateFalsePositive, StateAcceptedRisk}
+
+// Ignored 误报与接受风险的漏洞不再提交 issue、不推送新漏洞通知
+func Ignored(state string) bool {
+	return state == StateFalsePositive || state == StateAcceptedRisk
+}
+
+// Fingerprint 同一任务内同一漏洞的标识，与 taskrun.FindingKey 使用相同的字段
+func Fingerprint(taskId, templateId, matchedAt, matcherName string) string {
+	sum := sha1.Sum([]byte(taskId + "|" + templateId + "|" + matchedAt + "|" + matcherName))
+	return hex.EncodeToString(sum[:])
+}
+
+// Init 为早期没有指纹的 findings 补齐指纹（与 Fingerprint 的算法一致）
+func Init() {
+	res := mysqldb.DB.Exec(`UPDATE findings
+		SET fingerprint = SHA1(CONCAT(task_id, '|', IFNULL(template_id, ''), '|', IFNULL(matched_at, ''), '|', IFNULL(matcher_name, '')))
+		WHERE fingerprint IS NULL OR fingerprint = ''`)
+	if res.Error != nil {
+		log.Printf("[finding.Init] backfill fingerprint failed err=%v", res.Error)
+	} else if res.RowsAffected > 0 {
+		log.Printf("[finding.Init] backfilled fingerprint rows=%d", res.RowsAffected)
+	}
+}
+
+// triage 新保存的漏洞沿用同一指纹上一次的处置状态与指派人，再按屏蔽规则标记：
+//   - 已修复的漏洞再次出现时重新打开；
+//   - 由屏蔽规则标记的状态不沿用，规则删除或过期后漏洞恢复为 open。
+func triage(f *models.Finding) {
+	f.State = StateOpen
+	var prev models.Finding
+	err := mysqldb.DB.Select("id", "state", "assignee", "suppression_id").
+		Where("task_id = ? AND fingerprint = ? AND run_id != ?", f.TaskID, f.Fingerprint, f.RunID).
+		Order("id desc").First(&prev).Error
+	reopened := false
+	if err == nil {
+		f.Assignee = prev.Assignee
+		switch {
+		case prev.State == StateFixed:
+			reopened = true
+		case prev.SuppressionID == nil && prev.State != "":
+			f.State = prev.State
+		}
+	}
+	if f.State == StateOpen {
+		if s := matchSuppression(f); s != nil {
+			f.State = s.State
+			f.SuppressionID = &s.ID
+			reopened = false
+		}
+	}
+	if reopened {
+		record(mysqldb.DB, &models.FindingActivity{
+			TaskID: f.TaskID, Fingerprint: f.Fingerprint, FindingID: prev.ID, Action: ActionState,
+			Actor: systemActor, From: StateFixed, To: StateOpen, Comment: "漏洞再次出现",
+		})
+	}
+}
+
+// MarkFixed 执行正常结束后，把上一次执行中有、本次不再复现且仍为 open / confirmed 的漏洞标记为已修复
+func MarkFixed(taskId string, resolved []models.Finding) {
+	for _, f := range resolved {
+		fp := Fingerprint(taskId, f.TemplateID, f.MatchedAt, f.MatcherName)
+		err := mysqldb.DB.Transaction(func(tx *gorm.DB) error {
+			res := tx.Model(&models.Finding{}).
+				Where("task_id = ? AND fingerprint = ? AND state IN ?", taskId, fp, []string{StateOpen, StateConfirmed}).
+				Update("state", StateFixed)
+			if res.Error != nil || res.RowsAffected == 0 {
+				return res.Error
+			}
+			return record(tx, &models.FindingActivity{
+				TaskID: taskId, Fingerprint: fp, FindingID: f.ID, Action: ActionState,
+				Actor: systemActor, From: f.State, To: StateFixed, Comment: "最近一次执行中未再复现",
+			})
+		})
+		if err != nil {
+			log.Printf("[finding.MarkFixed] update state failed task=%s finding=%d err=%v", taskId, f.ID, err)
+		}
+	}
+}
+
+func record(db *gorm.DB, a *models.FindingActivity) error {
+	err := db.Create(a).Error
+	if err != nil {
+		log.Printf("[finding] db create activity failed task=%s fingerprint=%s action=%s err=%v", a.TaskID, a.Fingerprint, a.Action, err)
+	}
+	return err
+}
+
+// Triage - 批量处置漏洞：修改状态、指派人，可附带评论；同一指纹在各次执行中的记录一起更新
+// POST /api/finding/triage {"ids":[1,2],"state":"false_positive","assignee":"alice","comment":"测试环境"}
+func Triage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			IDs      []uint64 `json:"ids"`
+			State    string   `json:"state"`
+			Assignee *string  `json:"assignee"`
+			Comment  string   `json:"comment"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || len(req.IDs) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing ids"})
+			return
+		}
+		req.State = strings.ToLower(strings.TrimSpace(req.State))
+		if req.State != "" && !validState(req.State) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid state (" + strings.Join(States, "/") + ")"})
+			return
+		}
+		req.Comment = strings.TrimSpace(req.Comment)
+		if req.State == "" && req.Assignee == nil && req.Comment == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to change"})
+			return
+		}
+
+		var findings []models.Finding
+		if err := mysqldb.DB.Select("id", "task_id", "fingerprint", "state", "assignee").
+			Where("id IN ?", req.IDs).Find(&findings).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if len(findings) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "finding not found"})
+			return
+		}
+
+		actor := c.GetString("username")
+		done := map[string]bool{}
+		updated := 0
+		err := mysqldb.DB.Transaction(func(tx *gorm.DB) error {
+			for _, f := range findings {
+				if done[f.Fingerprint] {
+					continue
+				}
+				done[f.Fingerprint] = true
+				scope := tx.Model(&models.Finding{}).Where("task_id = ? AND fingerprint = ?", f.TaskID, f.Fingerprint)
+				if req.State != "" && req.State != f.State {
+					// 人工处置后不再视为屏蔽规则的结果
+					if err := scope.Session(&gorm.Session{}).Updates(map[string]interface{}{"state": req.State, "suppression_id": nil}).Error; err != nil {
+						return err
+					}
+					if err := record(tx, &models.FindingActivity{TaskID: f.TaskID, Fingerprint: f.Fingerprint, FindingID: f.ID,
+						Action: ActionState, Actor: actor, From: f.State, To: req.State}); err != nil {
+						return err
+					}
+				}
+				if req.Assignee != nil && strings.TrimSpace(*req.Assignee) != f.Assignee {
+					assignee := strings.TrimSpace(*req.Assignee)
+					if err := scope.Session(&gorm.Session{}).Update("assignee", assignee).Error; err != nil {
+						return err
+					}
+					if err := record(tx, &models.FindingActivity{TaskID: f.TaskID, Fingerprint: f.Fingerprint, FindingID: f.ID,
+						Action: ActionAssign, Actor: actor, From: f.Assignee, To: assignee}); err != nil {
+						return err
+					}
+				}
+				if req.Comment != "" {
+					if err := record(tx, &models.FindingActivity{TaskID: f.TaskID, Fingerprint: f.Fingerprint, FindingID: f.ID,
+						Action: ActionComment, Actor: actor, Comment: req.Comment}); err != nil {
+						return err
+					}
+				}
+				updated++
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("[finding.Triage] db update failed ids=%v err=%v", req.IDs, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db update failed: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "保存成功", "updated": updated})
+	}
+}
+
+// History - 漏洞的处置记录（状态变更、指派与评论），包含同一指纹在之前执行中的记录
+// GET /api/finding/history?id=
+func History() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Query("id")
+		if id == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
+			return
+		}
+		var f models.Finding
+		if err := mysqldb.DB.Omit("details").First(&f, "id = ?", id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "finding not found"})
+			return
+		}
+		activities := []models.FindingActivity{}
+		if err := mysqldb.DB.Where("task_id = ? AND fingerprint = ?", f.TaskID, f.Fingerprint).
+			Order("id asc").Find(&activities).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"finding": f, "activities": activities})
+	}
+}
@@ -0,0 +1,310 @@
+/**
+ * 指纹识别：基于本地规则库（兼容 ehole 的 finger.json 格式），按响应头、正文关键字、标题与 favicon hash 识别产品及版本
+ */
+package fingerprint
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spaolacci/murmur3"
+)
+
+// 内置规则库；设置环境变量 DAST_FINGER_FILE 时改为加载该文件
+//
+//go:embed finger.json
+var builtinRules []byte
+
+// 匹配方式
+const (
+	MethodKeyword     = "keyword"     // keyword 全部出现在 location 中
+	MethodRegula      = "regula"      // keyword 中的正则全部匹配 location
+	MethodFaviconHash = "faviconhash" // favicon 的 mmh3 hash 等于 keyword 中任意一个
+)
+
+// 匹配位置
+const (
+	LocationBody   = "body"
+	LocationHeader = "header"
+	LocationTitle  = "title"
+)
+
+// Rule 一条指纹规则。cms/method/location/keyword 与 ehole 相同，version 与 tags 为扩展字段：
+//   - version: 从响应头与正文中提取版本号的正则（取第一个分组）
+//   - tags:    对应的 nuclei 模板 tag，用于按指纹缩小模板范围；为空时使用小写的产品名
+type Rule struct {
+	CMS      string   `json:"cms"`
+	Method   string   `json:"method"`
+	Location string   `json:"location"`
+	Keyword  []string `json:"keyword"`
+	Version  string   `json:"version,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+
+	patterns []*regexp.Regexp
+	version  *regexp.Regexp
+}
+
+// Page 一个 HTTP 服务的响应，由扫描器抓取后交给 Match
+type Page struct {
+	URL         string
+	Header      http.Header
+	Body        []byte
+	Title       string
+	FaviconHash string // 没有 favicon 时为空
+}
+
+// Product 识别出的产品
+type Product struct {
+	Name    string   `json:"name"`
+	Version string   `json:"version,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+var (
+	mu    sync.RWMutex
+	rules []*Rule
+)
+
+// Init 加载规则库，失败时回退到内置规则
+func Init() {
+	data, source := builtinRules, "builtin"
+	if path := os.Getenv("DAST_FINGER_FILE"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("[fingerprint.Init] read rule file failed path=%s err=%v; fallback to builtin", path, err)
+		} else {
+			data, source = b, path
+		}
+	}
+	list, err := Parse(data)
+	if err != nil && source != "builtin" {
+		log.Printf("[fingerprint.Init] parse rule file failed path=%s err=%v; fallback to builtin", source, err)
+		list, err = Parse(builtinRules)
+		source = "builtin"
+	}
+	if err != nil {
+		log.Printf("[fingerprint.Init] parse builtin rules failed err=%v", err)
+		return
+	}
+	mu.Lock()
+	rules = list
+	mu.Unlock()
+	log.Printf("[fingerprint.Init] loaded %d rules from %s", len(list), source)
+}
+
+// Parse 解析 finger.json：{"fingerprint":[{"cms":"...","method":"keyword","location":"body","keyword":["..."]}]}
+func Parse(data []byte) ([]*Rule, error) {
+	var doc struct {
+		Fingerprint []*Rule `json:"fingerprint"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	list := make([]*Rule, 0, len(doc.Fingerprint))
+	for i, r := range doc.Fingerprint {
+		if err := r.compile(); err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", i, r.CMS, err)
+		}
+		list = append(list, r)
+	}
+	return list, nil
+}
+
+func (r *Rule) compile() error {
+	if r.CMS == "" || len(r.Keyword) == 0 {
+		return fmt.Errorf("missing cms or keyword")
+	}
+	switch r.Location {
+	case LocationBody, LocationHeader, LocationTitle:
+	default:
+		return fmt.Errorf("invalid location %q", r.Location)
+	}
+	switch r.Method {
+	case MethodKeyword, MethodFaviconHash:
+	case MethodRegula:
+		for _, k := range r.Keyword {
+			re, err := regexp.Compile(k)
+			if err != nil {
+				return err
+			}
+			r.patterns = append(r.patterns, re)
+		}
+	default:
+		return fmt.Errorf("invalid method %q", r.Method)
+	}
+	if r.Version != "" {
+		re, err := regexp.Compile(r.Version)
+		if err != nil {
+			return fmt.Errorf("invalid version regexp: %w", err)
+		}
+		r.version = re
+	}
+	if len(r.Tags) == 0 {
+		r.Tags = []string{strings.ToLower(strings.ReplaceAll(r.CMS, " ", "-"))}
+	}
+	return nil
+}
+
+// Match 用已加载的规则识别页面，同一产品的多条规则只记一次，按产品名排序
+func Match(p *Page) []Product {
+	mu.RLock()
+	list := rules
+	mu.RUnlock()
+
+	header := dumpHeader(p.Header)
+	found := map[string]*Product{}
+	for _, r := range list {
+		if !r.match(p, header) {
+			continue
+		}
+		prod := found[r.CMS]
+		if prod == nil {
+			prod = &Product{Name: r.CMS}
+			found[r.CMS] = prod
+		}
+		if prod.Version == "" && r.version != nil {
+			prod.Version = r.extractVersion(header, p.Body)
+		}
+		prod.Tags = mergeTags(prod.Tags, r.Tags)
+	}
+
+	out := make([]Product, 0, len(found))
+	for _, prod := range found {
+		out = append(out, *prod)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (r *Rule) match(p *Page, header string) bool {
+	if r.Method == MethodFaviconHash {
+		if p.FaviconHash == "" {
+			return false
+		}
+		for _, k := range r.Keyword {
+			if k == p.FaviconHash {
+				return true
+			}
+		}
+		return false
+	}
+
+	var text string
+	switch r.Location {
+	case LocationHeader:
+		text = header
+	case LocationTitle:
+		text = p.Title
+	default:
+		text = string(p.Body)
+	}
+	if r.Method == MethodRegula {
+		for _, re := range r.patterns {
+			if !re.MatchString(text) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, k := range r.Keyword {
+		if !strings.Contains(text, k) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Rule) extractVersion(header string, body []byte) string {
+	if m := r.version.FindStringSubmatch(header); len(m) > 1 {
+		return m[1]
+	}
+	if m := r.version.FindSubmatch(body); len(m) > 1 {
+		return string(m[1])
+	}
+	return ""
+}
+
+// dumpHeader 把响应头拼成 "Name: value" 多行文本，header 规则在其上匹配
+func dumpHeader(h http.Header) string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		for _, v := range h[k] {
+			b.WriteString(k)
+			b.WriteString(": ")
+			b.WriteString(v)
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func mergeTags(dst, src []string) []string {
+	for _, t := range src {
+		dup := false
+		for _, d := range dst {
+			if d == t {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			dst = append(dst, t)
+		}
+	}
+	return dst
+}
+
+// FaviconHash 计算 favicon 的 hash（与 Shodan / FOFA 的 icon_hash 相同：按 76 字符换行的 base64 后取 mmh3）
+func FaviconHash(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for i := 0; i < len(encoded); i += 76 {
+		end := i + 76
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+		b.WriteString(encoded[i:end])
+		b.WriteString("\n")
+	}
+	return strconv.Itoa(int(int32(murmur3.Sum32([]byte(b.String())))))
+}
+
+// Tags 汇总多个服务识别出的 nuclei tag（去重、排序）
+func Tags(products map[string][]Product) []string {
+	var tags []string
+	for _, list := range products {
+		for _, p := range list {
+			tags = mergeTags(tags, p.Tags)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Names 产品名（带版本）列表，用于日志与变化记录
+func Names(products []Product) []string {
+	out := make([]string, 0, len(products))
+	for _, p := range products {
+		if p.Version != "" {
+			out = append(out, p.Name+" "+p.Version)
+		} else {
+			out = append(out, p.Name)
+		}
+	}
+	return out
+}
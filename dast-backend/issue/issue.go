@@ -0,0 +1,250 @@
+/**
+ * 缺陷跟踪：按任务配置 GitHub / GitLab / Gitea / Jira / Linear，扫描结束后把新漏洞提交为 issue，
+ * 不再复现的漏洞自动关闭对应 issue，issue 链接写回 findings.issues
+ */
+package issue
+
+import (
+	"demo/db/mysqldb"
+	"demo/models"
+	"demo/secret"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// view 返回给前端的 tracker，凭据已被 mask
+func view(t *models.IssueTracker) gin.H {
+	h := gin.H{
+		"id":          t.ID,
+		"taskId":      t.TaskID,
+		"name":        t.Name,
+		"type":        t.Type,
+		"minSeverity": t.MinSeverity,
+		"autoClose":   t.AutoClose,
+		"enabled":     t.Enabled,
+		"creator":     t.Creator,
+		"lastSyncAt":  t.LastSyncAt,
+		"lastError":   t.LastError,
+		"createdAt":   t.CreatedAt,
+		"updatedAt":   t.UpdatedAt,
+	}
+	if t.AllowList != "" {
+		h["allowList"] = json.RawMessage(t.AllowList)
+	}
+	if t.DenyList != "" {
+		h["denyList"] = json.RawMessage(t.DenyList)
+	}
+	if config, err := decryptConfig(t); err != nil {
+		h["configError"] = err.Error()
+	} else {
+		h["config"] = secret.Mask(config, secretKeys...)
+	}
+	return h
+}
+
+// List - 任务的缺陷跟踪配置
+// GET /api/issue/trackers?taskId=
+func List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		taskId := c.Query("taskId")
+		if taskId == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing taskId"})
+			return
+		}
+		var trackers []models.IssueTracker
+		if err := mysqldb.DB.Where("task_id = ?", taskId).Order("id asc").Find(&trackers).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		list := make([]gin.H, 0, len(trackers))
+		for i := range trackers {
+			list = append(list, view(&trackers[i]))
+		}
+		c.JSON(http.StatusOK, gin.H{"trackers": list})
+	}
+}
+
+// Save - 新建或更新缺陷跟踪配置（带 id 时更新）
+// POST /api/issue/tracker/save
+//
+//	{"taskId":"...","name":"repo","type":"github","config":{"username":"bot","owner":"acme","token":"...","project-name":"web"},
+//	 "minSeverity":"high","allowList":{"tags":["cve"]},"denyList":{"severity":["info"]},"autoClose":true,"enabled":true}
+//
+// config 的字段与 nuclei report-config 中对应 tracker 一节相同；更新时凭据传 "******" 或省略表示不修改
+func Save() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			ID          uint64                 `json:"id"`
+			TaskID      string                 `json:"taskId"`
+			Name        string                 `json:"name"`
+			Type        string                 `json:"type"`
+			Config      map[string]interface{} `json:"config"`
+			MinSeverity string                 `json:"minSeverity"`
+			AllowList   json.RawMessage        `json:"allowList"`
+			DenyList    json.RawMessage        `json:"denyList"`
+			AutoClose   *bool                  `json:"autoClose"`
+			Enabled     *bool                  `json:"enabled"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+			return
+		}
+		req.Type = strings.ToLower(strings.TrimSpace(req.Type))
+		req.MinSeverity = strings.ToLower(strings.TrimSpace(req.MinSeverity))
+		if req.MinSeverity != "" && rank(req.MinSeverity) == len(severityOrder) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid minSeverity (critical/high/medium/low/info)"})
+			return
+		}
+
+		var t models.IssueTracker
+		if req.ID != 0 {
+			if err := mysqldb.DB.First(&t, req.ID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "tracker not found"})
+				return
+			}
+			if req.Type != "" && req.Type != t.Type {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "type cannot be changed"})
+				return
+			}
+		} else {
+			if req.TaskID == "" || req.Type == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "missing taskId or type"})
+				return
+			}
+			if err := mysqldb.DB.Select("id").First(&models.Task{}, "id = ?", req.TaskID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+				return
+			}
+			t = models.IssueTracker{TaskID: req.TaskID, Type: req.Type, AutoClose: true, Enabled: true, Creator: c.GetString("username")}
+		}
+
+		if name := strings.TrimSpace(req.Name); name != "" {
+			t.Name = name
+		} else if t.Name == "" {
+			t.Name = t.Type
+		}
+		if req.AutoClose != nil {
+			t.AutoClose = *req.AutoClose
+		}
+		if req.Enabled != nil {
+			t.Enabled = *req.Enabled
+		}
+		t.MinSeverity = req.MinSeverity
+
+		// 过滤条件：未传时保持不变，传 null 清空
+		for _, f := range []struct {
+			raw  json.RawMessage
+			dst  *string
+			name string
+		}{{req.AllowList, &t.AllowList, "allowList"}, {req.DenyList, &t.DenyList, "denyList"}} {
+			if f.raw == nil {
+				continue
+			}
+			raw := strings.TrimSpace(string(f.raw))
+			if raw == "null" || raw == "{}" {
+				*f.dst = ""
+				continue
+			}
+			if _, err := decodeFilter(raw); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + f.name + ": " + err.Error()})
+				return
+			}
+			*f.dst = raw
+		}
+
+		if req.Config != nil || t.ID == 0 {
+			if t.ID != 0 {
+				old, err := decryptConfig(&t)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "decrypt config failed: " + err.Error()})
+					return
+				}
+				secret.Merge(req.Config, old, secretKeys...)
+			}
+			enc, err := encryptConfig(t.Type, req.Config)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			t.Config = enc
+		}
+
+		var err error
+		if t.ID == 0 {
+			err = mysqldb.DB.Create(&t).Error
+		} else {
+			err = mysqldb.DB.Model(&t).Updates(map[string]interface{}{
+				"name": t.Name, "config": t.Config, "min_severity": t.MinSeverity,
+				"allow_list": nullable(t.AllowList), "deny_list": nullable(t.DenyList),
+				"auto_close": t.AutoClose, "enabled": t.Enabled,
+			}).Error
+		}
+		if err != nil {
+			log.Printf("[issue.Save] db save tracker failed task=%s err=%v", t.TaskID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db save tracker failed: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "保存成功", "tracker": view(&t)})
+	}
+}
+
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// Delete - 删除缺陷跟踪配置（已提交的 issue 保持不变）
+// POST /api/issue/tracker/delete {"id":1}
+func Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			ID uint64 `json:"id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.ID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
+			return
+		}
+		res := mysqldb.DB.Delete(&models.IssueTracker{}, req.ID)
+		if res.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": res.Error.Error()})
+			return
+		}
+		if res.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tracker not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "删除成功", "id": req.ID})
+	}
+}
+
+// SyncRun - 手动同步一次执行的 issue（如修改配置后补提交），已提交的不会重复提交
+// POST /api/issue/sync {"runId":"..."}
+func SyncRun() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			RunID string `json:"runId"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.RunID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing runId"})
+			return
+		}
+		res, err := Sync(req.RunID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "run not found"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"result": res})
+	}
+}
@@ -0,0 +1,318 @@
+package issue
+
+import (
+	"context"
+	"demo/db/mysqldb"
+	"demo/db/redisdb"
+	"demo/finding"
+	"demo/models"
+	"demo/taskrun"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/projectdiscovery/nuclei/v3/pkg/reporting"
+)
+
+const (
+	// 待同步 issue 的执行 ID 队列：扫描正常结束后写入，由 API 进程后台消费
+	queueKey = "issue:sync:queue"
+	// 每个 tracker 记录的错误条数上限
+	maxErrors = 5
+)
+
+// 后台队列与手动同步互斥，避免同一执行被并发同步而重复提交
+var syncMu sync.Mutex
+
+// issue 状态
+const (
+	StatusOpen   = "open"
+	StatusClosed = "closed"
+)
+
+// Link 漏洞关联的 issue，保存在 findings.issues 中（key 为 tracker ID）
+type Link struct {
+	TrackerID uint64 `json:"trackerId"`
+	Type      string `json:"type"`
+	IssueID   string `json:"issueId,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Status    string `json:"status"`
+	CreatedAt string `json:"createdAt"`
+	ClosedAt  string `json:"closedAt,omitempty"`
+}
+
+func parseLinks(raw string) map[string]*Link {
+	links := map[string]*Link{}
+	if raw != "" {
+		_ = json.Unmarshal([]byte(raw), &links)
+	}
+	return links
+}
+
+func saveLinks(findingId uint64, links map[string]*Link) error {
+	data, err := json.Marshal(links)
+	if err != nil {
+		return err
+	}
+	return mysqldb.DB.Model(&models.Finding{}).Where("id = ?", findingId).Update("issues", string(data)).Error
+}
+
+// Init 启动后台同步协程
+func Init() {
+	go loop(context.Background())
+}
+
+// Enqueue 执行正常结束后调用，把执行放入同步队列
+func Enqueue(runId string) {
+	if err := redisdb.Client.RPush(redisdb.Ctx, queueKey, runId).Err(); err != nil {
+		log.Printf("[issue.Enqueue] push failed run=%s err=%v", runId, err)
+	}
+}
+
+func loop(ctx context.Context) {
+	for {
+		res, err := redisdb.Client.BLPop(ctx, 5*time.Second, queueKey).Result()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		if len(res) < 2 {
+			continue
+		}
+		if _, err := Sync(res[1]); err != nil {
+			log.Printf("[issue] sync failed run=%s err=%v", res[1], err)
+		}
+	}
+}
+
+// SyncResult 一次同步的结果
+type SyncResult struct {
+	Created int      `json:"created"`
+	Closed  int      `json:"closed"`
+	Failed  int      `json:"failed"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// 严重等级由高到低
+var severityOrder = []string{"critical", "high", "medium", "low", "info"}
+
+func rank(sev string) int {
+	for i, s := range severityOrder {
+		if s == sev {
+			return i
+		}
+	}
+	return len(severityOrder)
+}
+
+// qualifies 漏洞等级是否达到 tracker 的阈值，未设置阈值时全部提交
+func qualifies(sev, min string) bool {
+	return min == "" || rank(sev) <= rank(min)
+}
+
+// loadFindings 读取一次执行的 findings（不含 details），同一漏洞只保留第一条
+func loadFindings(runId string) ([]models.Finding, error) {
+	var findings []models.Finding
+	if err := mysqldb.DB.Omit("details").Where("run_id = ?", runId).Order("id asc").Find(&findings).Error; err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	uniq := findings[:0]
+	for _, f := range findings {
+		k := taskrun.FindingKey(f)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		uniq = append(uniq, f)
+	}
+	return uniq, nil
+}
+
+// loadEvent 读取 finding 保存的完整 nuclei 事件，用于生成 issue 内容
+func loadEvent(findingId uint64) (*output.ResultEvent, error) {
+	var f models.Finding
+	if err := mysqldb.DB.Select("id", "details").First(&f, findingId).Error; err != nil {
+		return nil, err
+	}
+	var ev output.ResultEvent
+	if err := json.Unmarshal([]byte(f.Details), &ev); err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+
+// Sync 按任务的 tracker 配置处理一次正常结束的执行：
+//   - 与上一次完成的执行相比仍存在的漏洞沿用已有 issue；
+//   - 尚未关联 issue 且满足阈值与 allow/deny 过滤的漏洞提交新 issue；
+//   - 上一次执行中有、本次不再复现的漏洞关闭其 issue（tracker 开启 autoClose 时）。
+//
+// 重复同步同一执行不会重复提交
+func Sync(runId string) (*SyncResult, error) {
+	syncMu.Lock()
+	defer syncMu.Unlock()
+
+	var run models.TaskRun
+	if err := mysqldb.DB.Omit("targets", "config").First(&run, "id = ?", runId).Error; err != nil {
+		return nil, err
+	}
+	if run.Status != "finished" {
+		return nil, errors.New("run is not finished")
+	}
+	var trackers []models.IssueTracker
+	if err := mysqldb.DB.Where("task_id = ? AND enabled = ?", run.TaskID, true).Order("id asc").Find(&trackers).Error; err != nil {
+		return nil, err
+	}
+	res := &SyncResult{}
+	if len(trackers) == 0 {
+		return res, nil
+	}
+
+	clients := map[uint64]reporting.Client{}
+	trackerErrs := map[uint64][]string{}
+	fail := func(t *models.IssueTracker, err error) {
+		res.Failed++
+		msg := t.Name + ": " + err.Error()
+		res.Errors = append(res.Errors, msg)
+		// 只保留前几条错误，避免 tracker 不可用时 last_error 过长
+		if len(trackerErrs[t.ID]) < maxErrors {
+			trackerErrs[t.ID] = append(trackerErrs[t.ID], err.Error())
+		}
+	}
+	byID := map[uint64]*models.IssueTracker{}
+	for i := range trackers {
+		t := &trackers[i]
+		byID[t.ID] = t
+		c, err := newClient(t)
+		if err != nil {
+			log.Printf("[issue.Sync] create tracker client failed tracker=%d type=%s err=%v", t.ID, t.Type, err)
+			fail(t, err)
+			continue
+		}
+		defer c.Close()
+		clients[t.ID] = c
+	}
+
+	head, err := loadFindings(run.ID)
+	if err != nil {
+		return nil, err
+	}
+	var base []models.Finding
+	if prev, err := taskrun.Previous(&run); err == nil {
+		if base, err = loadFindings(prev.ID); err != nil {
+			return nil, err
+		}
+	}
+	baseByKey := make(map[string]*models.Finding, len(base))
+	for i := range base {
+		baseByKey[taskrun.FindingKey(base[i])] = &base[i]
+	}
+
+	now := time.Now().Format("2006-01-02 15:04:05")
+	inHead := make(map[string]bool, len(head))
+	for i := range head {
+		f := &head[i]
+		key := taskrun.FindingKey(*f)
+		inHead[key] = true
+
+		links := parseLinks(f.Issues)
+		changed := false
+		// 仍存在的漏洞沿用上一次执行中仍打开的 issue
+		if b := baseByKey[key]; b != nil {
+			for id, l := range parseLinks(b.Issues) {
+				if l.Status == StatusOpen && links[id] == nil {
+					links[id] = l
+					changed = true
+				}
+			}
+		}
+
+		var ev *output.ResultEvent
+		for _, t := range trackers {
+			c := clients[t.ID]
+			id := strconv.FormatUint(t.ID, 10)
+			if c == nil || links[id] != nil || finding.Ignored(f.State) || !qualifies(f.Severity, t.MinSeverity) {
+				continue
+			}
+			if ev == nil {
+				if ev, err = loadEvent(f.ID); err != nil {
+					log.Printf("[issue.Sync] load finding event failed finding=%d err=%v", f.ID, err)
+					break
+				}
+			}
+			ev.IssueTrackers = nil
+			if err := c.CreateIssue(ev); err != nil {
+				log.Printf("[issue.Sync] create issue failed tracker=%d finding=%d err=%v", t.ID, f.ID, err)
+				fail(&t, err)
+				continue
+			}
+			meta, ok := ev.IssueTrackers[t.Type]
+			if !ok {
+				// 被 allow/deny 过滤
+				continue
+			}
+			links[id] = &Link{TrackerID: t.ID, Type: t.Type, IssueID: meta.IssueID, URL: meta.IssueURL, Status: StatusOpen, CreatedAt: now}
+			changed = true
+			res.Created++
+		}
+		if changed {
+			if err := saveLinks(f.ID, links); err != nil {
+				log.Printf("[issue.Sync] save issue links failed finding=%d err=%v", f.ID, err)
+			}
+		}
+	}
+
+	// 不再复现的漏洞：关闭其仍打开的 issue
+	for i := range base {
+		b := &base[i]
+		if inHead[taskrun.FindingKey(*b)] {
+			continue
+		}
+		links := parseLinks(b.Issues)
+		changed := false
+		for _, l := range links {
+			t := byID[l.TrackerID]
+			if l.Status != StatusOpen || t == nil || !t.AutoClose || clients[t.ID] == nil {
+				continue
+			}
+			ev, err := loadEvent(b.ID)
+			if err != nil {
+				log.Printf("[issue.Sync] load finding event failed finding=%d err=%v", b.ID, err)
+				continue
+			}
+			ev.IssueTrackers = map[string]output.IssueTrackerMetadata{t.Type: {IssueID: l.IssueID, IssueURL: l.URL}}
+			if err := clients[t.ID].CloseIssue(ev); err != nil {
+				log.Printf("[issue.Sync] close issue failed tracker=%d finding=%d issue=%s err=%v", t.ID, b.ID, l.IssueID, err)
+				fail(t, err)
+				continue
+			}
+			l.Status = StatusClosed
+			l.ClosedAt = now
+			changed = true
+			res.Closed++
+		}
+		if changed {
+			if err := saveLinks(b.ID, links); err != nil {
+				log.Printf("[issue.Sync] save issue links failed finding=%d err=%v", b.ID, err)
+			}
+		}
+	}
+
+	syncedAt := time.Now()
+	for _, t := range trackers {
+		_ = mysqldb.DB.Model(&models.IssueTracker{}).Where("id = ?", t.ID).UpdateColumns(map[string]interface{}{
+			"last_sync_at": syncedAt,
+			"last_error":   strings.Join(trackerErrs[t.ID], "; "),
+		}).Error
+	}
+	log.Printf("[issue.Sync] run synced task=%s run=%s trackers=%d created=%d closed=%d failed=%d",
+		run.TaskID, run.ID, len(trackers), res.Created, res.Closed, res.Failed)
+	return res, nil
+}
@@ -0,0 +1,127 @@
+package issue
+
+import (
+	"bytes"
+	"demo/models"
+	"demo/secret"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/reporting"
+	"github.com/projectdiscovery/nuclei/v3/pkg/reporting/trackers/filters"
+	"github.com/projectdiscovery/nuclei/v3/pkg/reporting/trackers/gitea"
+	"github.com/projectdiscovery/nuclei/v3/pkg/reporting/trackers/github"
+	"github.com/projectdiscovery/nuclei/v3/pkg/reporting/trackers/gitlab"
+	"github.com/projectdiscovery/nuclei/v3/pkg/reporting/trackers/jira"
+	"github.com/projectdiscovery/nuclei/v3/pkg/reporting/trackers/linear"
+	yamlutil "github.com/projectdiscovery/nuclei/v3/pkg/utils/yaml"
+)
+
+// 支持的缺陷跟踪类型，与 nuclei report-config 中的名称一致
+const (
+	TypeGitHub = "github"
+	TypeGitLab = "gitlab"
+	TypeGitea  = "gitea"
+	TypeJira   = "jira"
+	TypeLinear = "linear"
+)
+
+// 配置中的凭据字段，查询接口中以 secret.Masked 代替；保存时传回该值表示沿用原值
+var secretKeys = []string{"token", "personal-access-token", "api-key", "password"}
+
+// decodeOptions 把 tracker 配置（字段与 nuclei report-config 中对应 tracker 一节相同，JSON 或 YAML）
+// 解析为 reporting.Options 并按 nuclei 的规则校验必填项
+func decodeOptions(typ string, config []byte) (*reporting.Options, error) {
+	opts := &reporting.Options{}
+	var target interface{}
+	switch typ {
+	case TypeGitHub:
+		opts.GitHub = &github.Options{}
+		target = opts.GitHub
+	case TypeGitLab:
+		opts.GitLab = &gitlab.Options{}
+		target = opts.GitLab
+	case TypeGitea:
+		opts.Gitea = &gitea.Options{}
+		target = opts.Gitea
+	case TypeJira:
+		opts.Jira = &jira.Options{}
+		target = opts.Jira
+	case TypeLinear:
+		opts.Linear = &linear.Options{}
+		target = opts.Linear
+	default:
+		return nil, fmt.Errorf("unsupported tracker type %q (github/gitlab/gitea/jira/linear)", typ)
+	}
+	if err := yamlutil.DecodeAndValidate(bytes.NewReader(config), target); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// decodeFilter 解析 allow/deny 过滤条件 {"severity":["high"],"tags":["cve"]}，为空时返回 nil
+func decodeFilter(raw string) (*filters.Filter, error) {
+	if raw == "" || raw == "null" {
+		return nil, nil
+	}
+	f := &filters.Filter{}
+	if err := yamlutil.DecodeAndValidate(strings.NewReader(raw), f); err != nil {
+		return nil, err
+	}
+	if len(f.Severities) == 0 && f.Tags.IsEmpty() {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// newClient 根据保存的配置创建只包含这一个 tracker 的 nuclei reporting 客户端
+// 去重由平台按 finding 上记录的 issue 完成，不使用 nuclei 的本地去重库
+func newClient(t *models.IssueTracker) (reporting.Client, error) {
+	config, err := secret.Decrypt(t.Config)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := decodeOptions(t.Type, config)
+	if err != nil {
+		return nil, err
+	}
+	if opts.AllowList, err = decodeFilter(t.AllowList); err != nil {
+		return nil, fmt.Errorf("invalid allowList: %w", err)
+	}
+	if opts.DenyList, err = decodeFilter(t.DenyList); err != nil {
+		return nil, fmt.Errorf("invalid denyList: %w", err)
+	}
+	return reporting.New(opts, "", true)
+}
+
+// decryptConfig 解密保存的配置为 map，供查询与合并更新使用
+func decryptConfig(t *models.IssueTracker) (map[string]interface{}, error) {
+	plain, err := secret.Decrypt(t.Config)
+	if err != nil {
+		return nil, err
+	}
+	config := map[string]interface{}{}
+	if len(plain) > 0 {
+		if err := json.Unmarshal(plain, &config); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// encryptConfig 校验并加密配置
+func encryptConfig(typ string, config map[string]interface{}) (string, error) {
+	if len(config) == 0 {
+		return "", errors.New("missing config")
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	if _, err := decodeOptions(typ, data); err != nil {
+		return "", fmt.Errorf("invalid config: %w", err)
+	}
+	return secret.Encrypt(data)
+}
@@ -2,30 +2,252 @@ package log
 
 import (
 	"demo/db/redisdb"
+	"demo/finding"
+	"demo/taskrun"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// 下载与过滤时每次从 Redis 读取的条数
+const readBatch = 1000
+
+// filter 日志过滤条件：最低级别与阶段
+type filter struct {
+	minRank int
+	stages  map[string]bool
+}
+
+func (f *filter) empty() bool {
+	return f.minRank <= 0 && len(f.stages) == 0
+}
+
+func (f *filter) match(e *taskrun.LogEntry) bool {
+	if taskrun.LogLevelRank(e.Level) < f.minRank {
+		return false
+	}
+	return len(f.stages) == 0 || f.stages[e.Stage]
+}
+
+// parseFilter 解析 level（最低级别，如 warn 返回 warn 与 error）与 stage（逗号分隔）
+func parseFilter(c *gin.Context) (*filter, error) {
+	f := &filter{}
+	if v := strings.ToLower(strings.TrimSpace(c.Query("level"))); v != "" {
+		f.minRank = taskrun.LogLevelRank(v)
+		if f.minRank < 0 {
+			return nil, fmt.Errorf("invalid level (%s)", strings.Join(taskrun.LogLevels, "/"))
+		}
+	}
+	if v := c.Query("stage"); v != "" {
+		f.stages = map[string]bool{}
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				f.stages[s] = true
+			}
+		}
+	}
+	return f, nil
+}
+
+// logKey 默认读取最近一次执行的日志；可通过 runId 指定历史执行
+func logKey(c *gin.Context) (taskId, runId, key string, ok bool) {
+	taskId = c.Query("taskId")
+	if taskId == "" {
+		c.JSON(400, gin.H{"error": "missing taskId"})
+		return "", "", "", false
+	}
+	runId = c.Query("runId")
+	if runId == "" {
+		runId = taskrun.Latest(taskId)
+	}
+	key = "task:" + taskId + ":log"
+	if runId != "" {
+		key = taskrun.LogKey(taskId, runId)
+	}
+	return taskId, runId, key, true
+}
+
+// parseEntry 解析一条日志；早期执行的日志是命中结果的原始 JSON，作为 info 级别的消息返回
+func parseEntry(raw string) taskrun.LogEntry {
+	var e taskrun.LogEntry
+	if err := json.Unmarshal([]byte(raw), &e); err != nil || e.Level == "" {
+		return taskrun.LogEntry{Level: taskrun.LogInfo, Message: raw}
+	}
+	return e
+}
+
+// scan 按顺序读取整个日志 list，对每条日志调用 fn，fn 返回 false 时停止
+func scan(key string, fn func(e *taskrun.LogEntry) bool) error {
+	for start := int64(0); ; start += readBatch {
+		items, err := redisdb.Client.LRange(redisdb.Ctx, key, start, start+readBatch-1).Result()
+		if err != nil {
+			return err
+		}
+		for _, raw := range items {
+			e := parseEntry(raw)
+			if !fn(&e) {
+				return nil
+			}
+		}
+		if len(items) < readBatch {
+			return nil
+		}
+	}
+}
+
+// GetLog - 分页查询执行日志
+// GET /api/log?taskId=&runId=&level=warn&stage=nuclei,httpprobe&order=desc&page=&pageSize=
+// level 为最低级别（debug/info/warn/error）；默认按时间正序，order=desc 时最新的在前
 func GetLog() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		taskId := c.Query("taskId")
-		if taskId == "" {
-			c.JSON(400, gin.H{"error": "missing taskId"})
+		taskId, runId, key, ok := logKey(c)
+		if !ok {
 			return
 		}
+		f, err := parseFilter(c)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		page, pageSize := finding.ParsePage(c)
+		desc := c.Query("order") == "desc"
+
+		logs := []taskrun.LogEntry{}
+		var total int64
+		if f.empty() {
+			// 无过滤条件时直接按下标读取
+			total, err = redisdb.Client.LLen(redisdb.Ctx, key).Result()
+			if err != nil {
+				c.JSON(500, gin.H{"error": err.Error()})
+				return
+			}
+			start, stop := int64((page-1)*pageSize), int64(page*pageSize-1)
+			if desc {
+				start, stop = -stop-1, -start-1
+			}
+			items, err := redisdb.Client.LRange(redisdb.Ctx, key, start, stop).Result()
+			if err != nil {
+				c.JSON(500, gin.H{"error": err.Error()})
+				return
+			}
+			for _, raw := range items {
+				logs = append(logs, parseEntry(raw))
+			}
+			if desc {
+				logs = reverse(logs)
+			}
+		} else {
+			var matched []taskrun.LogEntry
+			err = scan(key, func(e *taskrun.LogEntry) bool {
+				if f.match(e) {
+					matched = append(matched, *e)
+				}
+				return true
+			})
+			if err != nil {
+				c.JSON(500, gin.H{"error": err.Error()})
+				return
+			}
+			total = int64(len(matched))
+			if desc {
+				matched = reverse(matched)
+			}
+			if from := (page - 1) * pageSize; from < len(matched) {
+				logs = matched[from:min(from+pageSize, len(matched))]
+			}
+		}
+
+		c.JSON(200, gin.H{
+			"taskId":   taskId,
+			"runId":    runId,
+			"total":    total,
+			"page":     page,
+			"pageSize": pageSize,
+			"logs":     logs,
+		})
+	}
+}
 
-		logKey := "task:" + taskId + ":log"
+func reverse(logs []taskrun.LogEntry) []taskrun.LogEntry {
+	for i, j := 0, len(logs)-1; i < j; i, j = i+1, j-1 {
+		logs[i], logs[j] = logs[j], logs[i]
+	}
+	return logs
+}
 
-		// 读取最近 N 条日志（例如最后 100 条）
-		logs, err := redisdb.Client.LRange(redisdb.Ctx, logKey, -100, -1).Result()
+// Download - 下载完整执行日志，可按级别与阶段过滤
+// GET /api/log/download?taskId=&runId=&level=&stage=&format=text|json
+// text 每行一条日志；json 为 NDJSON，每行一个 LogEntry
+func Download() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		taskId, runId, key, ok := logKey(c)
+		if !ok {
+			return
+		}
+		f, err := parseFilter(c)
 		if err != nil {
-			c.JSON(500, gin.H{"error": err.Error()})
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		format := c.DefaultQuery("format", "text")
+		if format != "text" && format != "json" {
+			c.JSON(400, gin.H{"error": "invalid format (text/json)"})
 			return
 		}
 
-		c.JSON(200, gin.H{
-			"taskId": taskId,
-			"logs":   logs,
+		name := "task-" + taskId
+		if runId != "" {
+			name += "-run-" + runId
+		}
+		if format == "json" {
+			c.Header("Content-Type", "application/x-ndjson; charset=utf-8")
+			name += ".ndjson"
+		} else {
+			c.Header("Content-Type", "text/plain; charset=utf-8")
+			name += ".log"
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+		c.Status(http.StatusOK)
+
+		enc := json.NewEncoder(c.Writer)
+		err = scan(key, func(e *taskrun.LogEntry) bool {
+			if !f.match(e) {
+				return true
+			}
+			var werr error
+			if format == "json" {
+				werr = enc.Encode(e)
+			} else {
+				_, werr = fmt.Fprintln(c.Writer, formatLine(e))
+			}
+			return werr == nil
 		})
+		if err != nil {
+			// 响应头已发送，只能在末尾注明读取失败
+			fmt.Fprintf(c.Writer, "read log failed: %v\n", err)
+		}
+	}
+}
+
+// formatLine 文本格式：时间 级别 [阶段] 消息 key=value...
+func formatLine(e *taskrun.LogEntry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %-5s", e.Time, strings.ToUpper(e.Level))
+	if e.Stage != "" {
+		fmt.Fprintf(&b, " [%s]", e.Stage)
+	}
+	b.WriteString(" " + e.Message)
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, e.Fields[k])
 	}
+	return strings.TrimSpace(b.String())
 }
@@ -1,12 +1,32 @@
 package main
 
 import (
+	"context"
+	"demo/apispec"
+	"demo/asset"
+	"demo/audit"
+	"demo/ci"
 	"demo/db/mysqldb"
 	"demo/db/redisdb"
+	"demo/finding"
+	"demo/fingerprint"
+	"demo/issue"
 	"demo/log"
+	"demo/notify"
+	"demo/profile"
+	"demo/report"
+	"demo/scanauth"
+	"demo/schedule"
+	"demo/secret"
 	"demo/target"
 	"demo/task"
+	"demo/taskrun"
+	"demo/template"
 	"demo/user"
+	"demo/worker"
+	"flag"
+	"fmt"
+	"os"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -14,11 +34,54 @@ import (
 )
 
 func main() {
+	// 运行模式：
+	//   all    API + 内置 worker（单机部署，默认）
+	//   api    仅 API，扫描作业交给独立 worker 进程
+	//   worker 仅 worker，从 Redis 队列领取扫描作业
+	mode := flag.String("mode", "all", "run mode: all | api | worker")
+	concurrency := flag.Int("concurrency", 2, "max concurrent scan jobs per worker")
+	// 启动时发现中断的扫描（仍为 running 但无人执行）如何处理：resume 断点续扫 / interrupt 标记为 interrupted
+	recoverMode := flag.String("recover", task.RecoverResume, "orphaned running tasks on startup: resume | interrupt")
+	flag.Parse()
+	if *mode != "all" && *mode != "api" && *mode != "worker" {
+		fmt.Fprintf(os.Stderr, "unknown mode: %s\n", *mode)
+		os.Exit(2)
+	}
+	if *recoverMode != task.RecoverResume && *recoverMode != task.RecoverInterrupt {
+		fmt.Fprintf(os.Stderr, "unknown recover mode: %s\n", *recoverMode)
+		os.Exit(2)
+	}
+
 	redisdb.Init("127.0.0.1:6379", "", 0)
 	mysqldb.Init("root", "123456", "127.0.0.1", "dast")
 	mysqldb.DB = mysqldb.DB.Debug()
+	worker.Init()
+	fingerprint.Init()
+	template.LoadSigner()
+
+	if *mode == "worker" {
+		secret.InitWorker()
+		worker.New(*concurrency).Run(context.Background())
+		return
+	}
+
+	user.Init()
+	audit.Init()
+	finding.Init()
+	// 密钥需在本地 worker 启动前加载，否则作业先解密凭据时不会生成密钥文件
+	secret.Init()
+	task.Recover(*recoverMode)
+	// 本地 worker 在崩溃恢复之后启动，避免领取中的作业被当作孤儿
+	if *mode == "all" {
+		go worker.New(*concurrency).Run(context.Background())
+	}
 	task.Init()
 	target.Init()
+	schedule.Init()
+	template.Init()
+	report.Init()
+	issue.Init()
+	notify.Init()
 
 	router := gin.Default()
 
@@ -31,6 +94,8 @@ func main() {
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
+	// 审计：记录修改状态的请求
+	router.Use(audit.Middleware())
 
 	router.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -48,27 +113,176 @@ func main() {
 		//全局鉴权中间件
 		v1.Use(user.AuthMiddleware())
 
+		// 路由权限：viewer 只读，operator 可管理任务/目标/配置，admin 额外管理用户
+		viewer := user.Require(user.RoleViewer)
+		operator := user.Require(user.RoleOperator)
+		admin := user.Require(user.RoleAdmin)
+
+		// 当前用户
+		v1.GET("/user/me", user.Me())
+		v1.POST("/user/password", user.SessionOnly(), user.ChangePassword())
+
+		// API token（只能通过登录 session 管理）
+		tokens := v1.Group("/token", user.SessionOnly())
+		{
+			tokens.GET("/list", user.ListTokens())
+			tokens.POST("/create", user.CreateToken())
+			tokens.POST("/revoke", user.RevokeToken())
+		}
+
+		// 用户管理
+		users := v1.Group("/user", admin)
+		{
+			users.GET("/list", user.List())
+			users.POST("/create", user.Create())
+			users.POST("/update", user.Update())
+			users.POST("/delete", user.Delete())
+		}
+
 		// 任务管理
 		tasks := v1.Group("/task")
 		{
-			tasks.POST("/create", task.Create())
-			tasks.GET("/list", task.List())
-			tasks.GET("/start", task.Start())
-			tasks.GET("/stop", task.Stop())
-			tasks.GET("/delete", task.Delete())
+			tasks.POST("/create", operator, task.Create())
+			tasks.POST("/create-api", operator, task.CreateAPI())
+			tasks.GET("/list", viewer, task.List())
+			tasks.GET("/start", operator, task.Start())
+			tasks.GET("/stop", operator, task.Stop())
+			tasks.GET("/delete", operator, task.Delete())
+			tasks.GET("/progress", viewer, task.Progress())
 		}
 
 		// 目标管理
 		targets := v1.Group("/target")
 		{
-			targets.GET("/list", target.List())
-			targets.POST("/add", target.Add())
-			targets.POST("/delete", target.Delete())
-			targets.GET("/result", target.Result())
+			targets.GET("/list", viewer, target.List())
+			targets.POST("/add", operator, target.Add())
+			targets.POST("/upload", operator, target.Upload())
+			targets.GET("/exclusions", viewer, target.Exclusions())
+			targets.POST("/exclusions", operator, target.SetExclusions())
+			targets.POST("/delete", operator, target.Delete())
+			targets.GET("/result", viewer, target.Result())
+		}
+
+		// API 扫描输入
+		apispecs := v1.Group("/apispec")
+		{
+			apispecs.POST("/preview", operator, apispec.Preview())
+			apispecs.GET("/get", viewer, apispec.Get())
+			apispecs.POST("/update", operator, apispec.Update())
+		}
+
+		// 模板管理
+		templates := v1.Group("/template")
+		{
+			templates.GET("/list", viewer, template.List())
+			templates.GET("/view", viewer, template.View())
+			templates.GET("/versions", viewer, template.Versions())
+			templates.POST("/upload", admin, template.Upload())
+			templates.POST("/enable", admin, template.Enable())
+			templates.POST("/delete", admin, template.Delete())
+			templates.POST("/sync", admin, template.SyncNow())
+		}
+
+		// 报告
+		reports := v1.Group("/report")
+		{
+			reports.POST("/create", viewer, report.Create())
+			reports.GET("/list", viewer, report.List())
+			reports.GET("/get", viewer, report.Get())
+			reports.GET("/download", viewer, report.Download())
+			reports.POST("/delete", operator, report.Delete())
+		}
+
+		// 缺陷跟踪
+		issues := v1.Group("/issue")
+		{
+			issues.GET("/trackers", viewer, issue.List())
+			issues.POST("/tracker/save", operator, issue.Save())
+			issues.POST("/tracker/delete", operator, issue.Delete())
+			issues.POST("/sync", operator, issue.SyncRun())
 		}
 
-		// 日志管理
-		v1.GET("/log", log.GetLog())
+		// 扫描认证
+		auths := v1.Group("/auth")
+		{
+			auths.GET("/list", operator, scanauth.List())
+			auths.POST("/save", operator, scanauth.Save())
+			auths.POST("/delete", operator, scanauth.Delete())
+		}
+
+		// 通知
+		notifies := v1.Group("/notify")
+		{
+			notifies.GET("/channels", viewer, notify.ListChannels())
+			notifies.POST("/channel/save", operator, notify.SaveChannel())
+			notifies.POST("/channel/delete", operator, notify.DeleteChannel())
+			notifies.POST("/channel/test", operator, notify.TestChannel())
+			notifies.GET("/deliveries", viewer, notify.ListDeliveries())
+			notifies.POST("/retry", operator, notify.RetryDelivery())
+		}
+
+		// 扫描配置
+		profiles := v1.Group("/profile")
+		{
+			profiles.GET("/list", viewer, profile.List())
+			profiles.POST("/save", operator, profile.Save())
+			profiles.POST("/delete", operator, profile.Delete())
+		}
+
+		// 执行历史
+		runs := v1.Group("/run")
+		{
+			runs.GET("/list", viewer, taskrun.List())
+			runs.GET("/get", viewer, taskrun.Get())
+			runs.GET("/compare", viewer, taskrun.Compare())
+			runs.GET("/diff", viewer, taskrun.Diff())
+			runs.POST("/delete", operator, taskrun.Delete())
+		}
+
+		// 定时调度
+		schedules := v1.Group("/schedule")
+		{
+			schedules.GET("/list", viewer, schedule.List())
+			schedules.POST("/save", operator, schedule.Save())
+			schedules.POST("/delete", operator, schedule.Delete())
+		}
+
+		// CI 集成：创建任务并阻塞等待扫描结论
+		cis := v1.Group("/ci")
+		{
+			cis.POST("/scan", operator, ci.Scan())
+			cis.GET("/result", viewer, ci.Result())
+		}
+
+		// 资产清单
+		assets := v1.Group("/asset", viewer)
+		{
+			assets.GET("/hosts", asset.Hosts())
+			assets.GET("/ports", asset.Ports())
+			assets.GET("/services", asset.Services())
+			assets.GET("/changes", asset.Changes())
+		}
+
+		// 漏洞结果与处置
+		findings := v1.Group("/finding")
+		{
+			findings.GET("/list", viewer, finding.List())
+			findings.GET("/history", viewer, finding.History())
+			findings.POST("/triage", operator, finding.Triage())
+			findings.GET("/suppressions", viewer, finding.ListSuppressions())
+			findings.POST("/suppression/save", operator, finding.SaveSuppression())
+			findings.POST("/suppression/delete", operator, finding.DeleteSuppression())
+		}
+
+		// 审计日志
+		v1.GET("/audit/list", admin, audit.List())
+
+		// 扫描 worker
+		v1.GET("/worker/list", viewer, worker.List())
+
+		// 执行日志
+		v1.GET("/log", viewer, log.GetLog())
+		v1.GET("/log/download", viewer, log.Download())
 	}
 
 	router.Run(":5003") // 在 5003 端口监听并启动服务
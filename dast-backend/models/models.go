@@ -7,7 +7,9 @@ type Task struct {
 	Name       string     `gorm:"size:255;not null" json:"taskName"`
 	Status     string     `gorm:"size:32;not null" json:"status"`
 	Config     string     `gorm:"type:json;default:null" json:"config,omitempty"`
-	Creator    string     `gorm:"size:64;default:null" json:"creator,omitempty"`
+	Creator    string     `gorm:"size:64;default:null;index" json:"creator,omitempty"`
+	Exclusions string     `gorm:"type:json;default:null" json:"exclusions,omitempty"` // 排除列表（JSON 数组），端口扫描前过滤
+	Type       string     `gorm:"size:16;default:null" json:"type,omitempty"`         // 空为主机/URL 任务，api 为 API 扫描任务（见 APISpec）
 	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"createdAt"`
 	UpdatedAt  time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
 	StartedAt  *time.Time `json:"startedAt,omitempty"`
@@ -23,22 +25,328 @@ type Target struct {
 }
 
 type Finding struct {
+	ID               uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID           string    `gorm:"size:64;index" json:"taskId"`
+	RunID            string    `gorm:"size:64;index" json:"runId"`
+	Target           string    `gorm:"size:512" json:"target"`
+	TemplateID       string    `gorm:"size:128;index" json:"templateId"`
+	Severity         string    `gorm:"size:32;index" json:"severity"`
+	Title            string    `gorm:"size:512" json:"title"`
+	Type             string    `gorm:"size:32" json:"type,omitempty"`
+	MatcherName      string    `gorm:"size:128" json:"matcherName,omitempty"`
+	MatchedAt        string    `gorm:"size:1024" json:"matchedAt"`
+	Host             string    `gorm:"size:255;index" json:"host"`
+	Port             int       `json:"port,omitempty"`
+	IP               string    `gorm:"size:64" json:"ip,omitempty"`
+	ExtractedResults string    `gorm:"type:json;default:null" json:"extractedResults,omitempty"`
+	Metadata         string    `gorm:"type:json;default:null" json:"metadata,omitempty"`
+	Details          string    `gorm:"type:json" json:"details,omitempty"`
+	RawRef           string    `gorm:"size:1024" json:"rawRef,omitempty"`
+	Issues           string    `gorm:"type:json;default:null" json:"issues,omitempty"` // 关联的缺陷跟踪 issue，key 为 tracker ID
+	Fingerprint      string    `gorm:"size:40;index" json:"fingerprint"`               // 同一任务内标识同一漏洞，跨执行沿用处置状态
+	State            string    `gorm:"size:32;index;default:open" json:"state"`        // open, confirmed, false_positive, accepted_risk, fixed
+	Assignee         string    `gorm:"size:64" json:"assignee,omitempty"`
+	SuppressionID    *uint64   `json:"suppressionId,omitempty"` // 命中的屏蔽规则
+	CreatedAt        time.Time `gorm:"autoCreateTime;index" json:"createdAt"`
+}
+
+// FindingActivity 漏洞的处置记录：状态变更、指派与评论，按指纹跨执行保留
+type FindingActivity struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID      string    `gorm:"size:64;not null;index" json:"taskId"`
+	Fingerprint string    `gorm:"size:40;not null;index" json:"fingerprint"`
+	FindingID   uint64    `json:"findingId"`
+	Action      string    `gorm:"size:16;not null" json:"action"` // state, assign, comment
+	Actor       string    `gorm:"size:64" json:"actor,omitempty"`
+	From        string    `gorm:"column:from_value;size:64" json:"from,omitempty"`
+	To          string    `gorm:"column:to_value;size:64" json:"to,omitempty"`
+	Comment     string    `gorm:"type:text" json:"comment,omitempty"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// Suppression 屏蔽规则：之后执行中命中的漏洞（模板 + 主机匹配）自动标记为误报或接受风险
+type Suppression struct {
+	ID          uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	TemplateID  string     `gorm:"size:128;not null;index" json:"templateId"`
+	HostPattern string     `gorm:"size:255" json:"hostPattern,omitempty"` // 主机通配符，如 *.example.com；为空匹配所有主机
+	TaskID      string     `gorm:"size:64;index" json:"taskId,omitempty"` // 为空表示所有任务
+	State       string     `gorm:"size:32;not null" json:"state"`         // false_positive, accepted_risk
+	Reason      string     `gorm:"type:text" json:"reason,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"` // 过期后不再生效
+	Creator     string     `gorm:"size:64" json:"creator,omitempty"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// TaskLog 审计日志：修改状态的 API 调用与扫描生命周期变化
+type TaskLog struct {
 	ID         uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
 	TaskID     string    `gorm:"size:64;index" json:"taskId"`
-	Target     string    `gorm:"size:512" json:"target"`
-	TemplateID string    `gorm:"size:128" json:"templateId"`
-	Severity   string    `gorm:"size:32" json:"severity"`
-	Title      string    `gorm:"size:512" json:"title"`
-	Details    string    `gorm:"type:json" json:"details,omitempty"`
-	RawRef     string    `gorm:"size:1024" json:"rawRef,omitempty"`
-	CreatedAt  time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	Action     string    `gorm:"size:64" json:"action"`
+	Actor      string    `gorm:"size:128;index" json:"actor,omitempty"`
+	IP         string    `gorm:"size:64" json:"ip,omitempty"`
+	ObjectType string    `gorm:"size:32" json:"objectType,omitempty"` // task, target, template, user, run ...
+	ObjectID   string    `gorm:"size:128" json:"objectId,omitempty"`
+	Method     string    `gorm:"size:8" json:"method,omitempty"`
+	Path       string    `gorm:"size:255" json:"path,omitempty"`
+	Status     int       `json:"status,omitempty"`                   // HTTP 状态码
+	Message    string    `gorm:"type:text" json:"message,omitempty"` // 请求摘要（凭据已脱敏）或状态说明
+	CreatedAt  time.Time `gorm:"autoCreateTime;index" json:"createdAt"`
 }
 
-type TaskLog struct {
+type ScanProfile struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string    `gorm:"size:64;uniqueIndex;not null" json:"name"`
+	Description string    `gorm:"size:512" json:"description,omitempty"`
+	Config      string    `gorm:"type:json;not null" json:"config"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+type TaskRun struct {
+	ID          string `gorm:"primaryKey;size:64" json:"runId"`
+	TaskID      string `gorm:"size:64;index" json:"taskId"`
+	TriggeredBy string `gorm:"size:32;not null" json:"triggeredBy"` // manual, schedule, ci
+	Status      string `gorm:"size:32;not null" json:"status"`
+	Message     string `gorm:"type:text" json:"message,omitempty"`
+	Targets     string `gorm:"type:json;default:null" json:"targets,omitempty"` // 本次执行的目标快照
+	Config      string `gorm:"type:json;default:null" json:"config,omitempty"`  // 本次执行的 profile 快照
+	// 开始 nuclei 阶段时的模板库修订号（template_versions 的最大 ID），据此还原每个模板使用的版本
+	TemplateRevision uint64     `gorm:"default:0" json:"templateRevision,omitempty"`
+	CreatedAt        time.Time  `gorm:"autoCreateTime;index" json:"createdAt"`
+	StartedAt        *time.Time `json:"startedAt,omitempty"`
+	FinishedAt       *time.Time `json:"finishedAt,omitempty"`
+}
+
+type TaskSchedule struct {
+	ID          uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID      string     `gorm:"size:64;uniqueIndex;not null" json:"taskId"`
+	Cron        string     `gorm:"size:128;not null" json:"cron"`
+	Timezone    string     `gorm:"size:64;not null" json:"timezone"`
+	WindowStart string     `gorm:"size:8" json:"windowStart,omitempty"` // 允许扫描的时间窗口 HH:MM，为空表示不限制
+	WindowEnd   string     `gorm:"size:8" json:"windowEnd,omitempty"`
+	Enabled     bool       `gorm:"not null" json:"enabled"`
+	NextRunAt   *time.Time `gorm:"index" json:"nextRunAt,omitempty"`
+	LastRunAt   *time.Time `json:"lastRunAt,omitempty"`
+	LastRunID   string     `gorm:"size:64" json:"lastRunId,omitempty"`
+	LastResult  string     `gorm:"size:512" json:"lastResult,omitempty"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+type User struct {
+	ID           uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Username     string     `gorm:"size:64;uniqueIndex;not null" json:"username"`
+	PasswordHash string     `gorm:"size:255;not null" json:"-"`
+	Role         string     `gorm:"size:16;not null" json:"role"` // admin, operator, viewer
+	Disabled     bool       `gorm:"not null;default:false" json:"disabled"`
+	LastLoginAt  *time.Time `json:"lastLoginAt,omitempty"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// APIToken 长期有效的 API token（CI 等非交互调用），只保存 SHA-256 哈希
+type APIToken struct {
+	ID         uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name       string     `gorm:"size:128;not null" json:"name"`
+	Username   string     `gorm:"size:64;index;not null" json:"username"`
+	TokenHash  string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	Prefix     string     `gorm:"size:16;not null" json:"prefix"`  // token 前几位，便于在列表中辨认
+	Scopes     string     `gorm:"size:128;not null" json:"scopes"` // 逗号分隔：read, scan
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	LastUsedIP string     `gorm:"size:64" json:"lastUsedIp,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// AssetHost 资产清单：扫描中发现过开放端口的主机
+type AssetHost struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	Host        string    `gorm:"size:255;uniqueIndex;not null" json:"host"`
+	FirstSeenAt time.Time `json:"firstSeenAt"`
+	LastSeenAt  time.Time `gorm:"index" json:"lastSeenAt"`
+	LastTaskID  string    `gorm:"size:64" json:"lastTaskId"`
+	LastRunID   string    `gorm:"size:64" json:"lastRunId"`
+}
+
+// AssetPort 主机上的开放端口；后续扫描覆盖该端口却未发现时标记为 closed
+type AssetPort struct {
+	ID          uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Host        string     `gorm:"size:255;not null;uniqueIndex:idx_host_port" json:"host"`
+	Port        int        `gorm:"not null;uniqueIndex:idx_host_port" json:"port"`
+	Protocol    string     `gorm:"size:8;not null" json:"protocol"`      // tcp
+	Status      string     `gorm:"size:16;not null;index" json:"status"` // open, closed
+	FirstSeenAt time.Time  `json:"firstSeenAt"`
+	LastSeenAt  time.Time  `json:"lastSeenAt"`
+	FirstRunID  string     `gorm:"size:64" json:"firstRunId"`
+	LastRunID   string     `gorm:"size:64" json:"lastRunId"`
+	ClosedAt    *time.Time `json:"closedAt,omitempty"`
+}
+
+// AssetService 开放端口上识别到的服务（HTTP 测活阶段的结果）
+type AssetService struct {
+	ID          uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Host        string     `gorm:"size:255;not null;uniqueIndex:idx_host_port" json:"host"`
+	Port        int        `gorm:"not null;uniqueIndex:idx_host_port" json:"port"`
+	Protocol    string     `gorm:"size:8;not null" json:"protocol"` // tcp
+	Scheme      string     `gorm:"size:8" json:"scheme,omitempty"`  // http, https；非 HTTP 服务为空
+	URL         string     `gorm:"size:1024" json:"url,omitempty"`
+	StatusCode  int        `json:"statusCode,omitempty"`
+	Title       string     `gorm:"size:512" json:"title,omitempty"`
+	Server      string     `gorm:"size:255" json:"server,omitempty"`
+	TLSSubject  string     `gorm:"size:512" json:"tlsSubject,omitempty"`
+	TLSNotAfter *time.Time `gorm:"index" json:"tlsNotAfter,omitempty"`
+	Products    string     `gorm:"type:json;default:null" json:"products,omitempty"` // 指纹识别出的产品与版本（JSON 数组）
+	FirstSeenAt time.Time  `json:"firstSeenAt"`
+	LastSeenAt  time.Time  `json:"lastSeenAt"`
+	LastRunID   string     `gorm:"size:64" json:"lastRunId"`
+}
+
+// AssetChange 资产暴露面变化：新主机、端口开放/关闭、服务变化
+type AssetChange struct {
 	ID        uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
-	TaskID    string    `gorm:"size:64;index" json:"taskId"`
-	Action    string    `gorm:"size:64" json:"action"`
-	Actor     string    `gorm:"size:128" json:"actor,omitempty"`
-	Message   string    `gorm:"type:text" json:"message,omitempty"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	Host      string    `gorm:"size:255;index" json:"host"`
+	Port      int       `json:"port,omitempty"`
+	Kind      string    `gorm:"size:32;not null;index" json:"kind"` // host_discovered, port_opened, port_closed, service_changed, products_changed
+	Detail    string    `gorm:"size:512" json:"detail,omitempty"`
+	TaskID    string    `gorm:"size:64" json:"taskId"`
+	RunID     string    `gorm:"size:64;index" json:"runId"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"createdAt"`
+}
+
+// Template ./poc 下 nuclei 模板的索引，启动时与磁盘同步，上传/启用/禁用/删除通过 API 维护
+type Template struct {
+	ID         uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	TemplateID string    `gorm:"size:255;not null;uniqueIndex" json:"templateId"`
+	Path       string    `gorm:"size:1024;not null" json:"path"` // 相对 ./poc 的路径
+	Name       string    `gorm:"size:512" json:"name"`
+	Author     string    `gorm:"size:512" json:"author,omitempty"`
+	Severity   string    `gorm:"size:16;index" json:"severity"`
+	Tags       string    `gorm:"size:1024" json:"tags,omitempty"` // 逗号分隔
+	CVE        string    `gorm:"column:cve;size:512" json:"cve,omitempty"`
+	Protocol   string    `gorm:"size:32" json:"protocol,omitempty"`
+	Source     string    `gorm:"size:16;not null;index" json:"source"` // builtin：磁盘上的模板库，custom：通过 API 上传或修改
+	Enabled    bool      `gorm:"not null;default:true" json:"enabled"`
+	Verified   bool      `json:"verified"` // 签名已验证
+	Version    int       `gorm:"not null" json:"version"`
+	SHA256     string    `gorm:"column:sha256;size:64" json:"sha256"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// TemplateVersion 模板的变更记录，只追加；自增 ID 同时作为模板库的修订号
+type TemplateVersion struct {
+	ID         uint64    `gorm:"primaryKey;autoIncrement" json:"revision"`
+	TemplateID string    `gorm:"size:255;not null;index" json:"templateId"`
+	Version    int       `gorm:"not null" json:"version"`
+	Action     string    `gorm:"size:16;not null" json:"action"` // sync, upload, enable, disable, delete, remove
+	Path       string    `gorm:"size:1024" json:"path"`
+	SHA256     string    `gorm:"column:sha256;size:64" json:"sha256"`
+	Content    string    `gorm:"type:mediumtext" json:"-"` // 只保存上传的版本，磁盘同步的版本以 sha256 对照当前文件
+	Verified   bool      `json:"verified"`
+	Creator    string    `gorm:"size:64" json:"creator,omitempty"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// Report 执行报告（HTML / Markdown / SARIF / CSV），文件保存在报告目录中供之后下载
+type Report struct {
+	ID         uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID     string     `gorm:"size:64;index" json:"taskId"`
+	RunID      string     `gorm:"size:64;not null;index" json:"runId"`
+	Format     string     `gorm:"size:16;not null" json:"format"` // html, md, sarif, csv
+	Status     string     `gorm:"size:16;not null" json:"status"` // pending, running, done, error
+	Message    string     `gorm:"type:text" json:"message,omitempty"`
+	Path       string     `gorm:"size:1024" json:"-"`
+	Size       int64      `json:"size"`
+	Findings   int64      `json:"findings"`
+	Creator    string     `gorm:"size:64" json:"creator,omitempty"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// IssueTracker 任务的缺陷跟踪配置：达到阈值的新漏洞自动提交 issue，之后的执行不再复现时关闭
+type IssueTracker struct {
+	ID          uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID      string     `gorm:"size:64;not null;index" json:"taskId"`
+	Name        string     `gorm:"size:128;not null" json:"name"`
+	Type        string     `gorm:"size:16;not null" json:"type"` // github, gitlab, gitea, jira, linear
+	Config      string     `gorm:"type:text" json:"-"`           // 加密后的 tracker 配置（含 token 等凭据）
+	MinSeverity string     `gorm:"size:16" json:"minSeverity,omitempty"`
+	AllowList   string     `gorm:"type:json;default:null" json:"allowList,omitempty"` // {"severity":[...],"tags":[...]}
+	DenyList    string     `gorm:"type:json;default:null" json:"denyList,omitempty"`
+	AutoClose   bool       `gorm:"not null" json:"autoClose"`
+	Enabled     bool       `gorm:"not null" json:"enabled"`
+	Creator     string     `gorm:"size:64" json:"creator,omitempty"`
+	LastSyncAt  *time.Time `json:"lastSyncAt,omitempty"`
+	LastError   string     `gorm:"type:text" json:"lastError,omitempty"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// ScanAuth 任务的扫描认证配置：静态凭据（header / cookie / bearer / basic / query）或动态登录流程，
+// 只对 domains / domainsRegex 匹配的目标生效
+type ScanAuth struct {
+	ID           uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID       string    `gorm:"size:64;not null;index" json:"taskId"`
+	Name         string    `gorm:"size:128;not null" json:"name"`
+	Type         string    `gorm:"size:16;not null" json:"type"`                         // header, cookie, bearer, basic, query, dynamic
+	Domains      string    `gorm:"type:json;default:null" json:"domains,omitempty"`      // ["app.example.com","api.example.com:8443"]
+	DomainsRegex string    `gorm:"type:json;default:null" json:"domainsRegex,omitempty"` // [".*\\.example\\.com"]
+	Config       string    `gorm:"type:text" json:"-"`                                   // 加密后的凭据与登录流程配置
+	Enabled      bool      `gorm:"not null" json:"enabled"`
+	Creator      string    `gorm:"size:64" json:"creator,omitempty"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// APISpec API 扫描任务的输入：OpenAPI / Swagger 文档或 Burp 等流量导出，
+// 扫描时由 nuclei 的 input formats 解析为请求，再对这些请求执行 DAST（fuzzing）模板
+type APISpec struct {
+	ID           uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID       string    `gorm:"size:64;not null;uniqueIndex" json:"taskId"`
+	Format       string    `gorm:"size:16;not null" json:"format"` // openapi, swagger, graphql, postman, har, burp, jsonl, yaml
+	FileName     string    `gorm:"size:255" json:"fileName"`
+	Content      string    `gorm:"type:longtext" json:"-"`
+	Size         int64     `json:"size"`
+	Variables    string    `gorm:"type:json;default:null" json:"variables,omitempty"` // 生成请求时使用的参数值 {"name":"value"}
+	RequiredOnly bool      `gorm:"not null" json:"requiredOnly"`                      // 只使用必填参数生成请求
+	Filters      string    `gorm:"type:json;default:null" json:"filters,omitempty"`   // HAR 文件的过滤条件 {"hosts":[],"mimeTypes":[]}
+	RequestCount int64     `json:"requestCount"`
+	Hosts        string    `gorm:"type:json;default:null" json:"hosts,omitempty"` // 请求涉及的地址，同时作为任务的目标
+	Creator      string    `gorm:"size:64" json:"creator,omitempty"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// NotifyChannel 通知渠道：任务开始/完成/失败/停止与发现高危漏洞时推送消息
+type NotifyChannel struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string    `gorm:"size:128;not null" json:"name"`
+	Type        string    `gorm:"size:16;not null" json:"type"`          // webhook, email, dingtalk, feishu, wecom, slack
+	Config      string    `gorm:"type:text" json:"-"`                    // 加密后的渠道配置（地址、密钥、SMTP 账号等）
+	Events      string    `gorm:"size:255" json:"events"`                // 订阅的事件，逗号分隔
+	MinSeverity string    `gorm:"size:16" json:"minSeverity,omitempty"`  // finding.new 事件的等级阈值
+	TaskID      string    `gorm:"size:64;index" json:"taskId,omitempty"` // 为空表示所有任务
+	Template    string    `gorm:"type:text" json:"template,omitempty"`   // 消息模板（text/template），为空时使用默认模板
+	Enabled     bool      `gorm:"not null" json:"enabled"`
+	Creator     string    `gorm:"size:64" json:"creator,omitempty"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// NotifyDelivery 通知投递记录，失败时按退避间隔重试
+type NotifyDelivery struct {
+	ID          uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	ChannelID   uint64     `gorm:"not null;index" json:"channelId"`
+	Event       string     `gorm:"size:32;not null" json:"event"`
+	TaskID      string     `gorm:"size:64;index" json:"taskId,omitempty"`
+	RunID       string     `gorm:"size:64" json:"runId,omitempty"`
+	Status      string     `gorm:"size:16;not null;index" json:"status"` // pending, sending, success, failed
+	Attempts    int        `gorm:"not null" json:"attempts"`
+	LastError   string     `gorm:"type:text" json:"lastError,omitempty"`
+	Payload     string     `gorm:"type:mediumtext" json:"-"` // 事件内容，重试时重新渲染
+	NextRetryAt *time.Time `gorm:"index" json:"nextRetryAt,omitempty"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime;index" json:"createdAt"`
+	DeliveredAt *time.Time `json:"deliveredAt,omitempty"`
 }
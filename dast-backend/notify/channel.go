@@ -0,0 +1,323 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 渠道类型
+const (
+	TypeWebhook  = "webhook"
+	TypeEmail    = "email"
+	TypeDingTalk = "dingtalk"
+	TypeFeishu   = "feishu"
+	TypeWeCom    = "wecom"
+	TypeSlack    = "slack"
+)
+
+// 各类型配置中的凭据字段，查询接口中 mask；机器人地址本身带有 token，也视为凭据
+var secretKeys = map[string][]string{
+	TypeWebhook:  {"secret"},
+	TypeEmail:    {"password"},
+	TypeDingTalk: {"url", "secret"},
+	TypeFeishu:   {"url", "secret"},
+	TypeWeCom:    {"url"},
+	TypeSlack:    {"url"},
+}
+
+// 单次投递的超时
+const sendTimeout = 15 * time.Second
+
+var httpClient = &http.Client{Timeout: sendTimeout}
+
+// channelConfig 渠道配置，不同类型使用其中的部分字段
+type channelConfig struct {
+	// webhook / 机器人
+	URL     string            `json:"url,omitempty"`
+	Secret  string            `json:"secret,omitempty"`  // webhook 的签名密钥；钉钉、飞书机器人的加签密钥
+	Headers map[string]string `json:"headers,omitempty"` // webhook 额外请求头
+
+	// email
+	Host     string   `json:"host,omitempty"`
+	Port     int      `json:"port,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+	TLS      string   `json:"tls,omitempty"` // starttls（默认）、ssl、none
+}
+
+func (c *channelConfig) validate(typ string) error {
+	switch typ {
+	case TypeWebhook, TypeDingTalk, TypeFeishu, TypeWeCom, TypeSlack:
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("invalid url")
+		}
+	case TypeEmail:
+		if c.Host == "" || c.From == "" || len(c.To) == 0 {
+			return errors.New("host, from and to are required")
+		}
+		if _, err := mail.ParseAddress(c.From); err != nil {
+			return fmt.Errorf("invalid from: %w", err)
+		}
+		for _, to := range c.To {
+			if _, err := mail.ParseAddress(to); err != nil {
+				return fmt.Errorf("invalid to %q: %w", to, err)
+			}
+		}
+		switch c.TLS {
+		case "", "starttls", "ssl", "none":
+		default:
+			return errors.New("invalid tls (starttls/ssl/none)")
+		}
+	default:
+		return fmt.Errorf("unsupported channel type %q (webhook/email/dingtalk/feishu/wecom/slack)", typ)
+	}
+	return nil
+}
+
+// send 按渠道类型投递一条消息；deliveryId 仅用于 webhook 请求头
+func send(ctx context.Context, typ string, cfg *channelConfig, e *Event, text string, deliveryId uint64) error {
+	switch typ {
+	case TypeWebhook:
+		return sendWebhook(ctx, cfg, e, text, deliveryId)
+	case TypeEmail:
+		return sendEmail(cfg, e, text)
+	case TypeDingTalk:
+		return sendDingTalk(ctx, cfg, e, text)
+	case TypeFeishu:
+		return sendFeishu(ctx, cfg, text)
+	case TypeWeCom:
+		body := map[string]interface{}{"msgtype": "markdown", "markdown": map[string]string{"content": text}}
+		return postChat(ctx, cfg.URL, body)
+	case TypeSlack:
+		return postChat(ctx, cfg.URL, map[string]string{"text": text})
+	}
+	return fmt.Errorf("unsupported channel type %q", typ)
+}
+
+// postJSON 发送 JSON 请求，非 2xx 视为失败；返回响应体（最多 64KB）
+func postJSON(ctx context.Context, target string, body []byte, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("User-Agent", "dast-notify/1.0")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return data, fmt.Errorf("http %d: %s", resp.StatusCode, truncate(string(data), 200))
+	}
+	return data, nil
+}
+
+// postChat 发送机器人消息；钉钉/企业微信返回 errcode，飞书返回 code，非 0 视为失败
+func postChat(ctx context.Context, target string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := postJSON(ctx, target, data, nil)
+	if err != nil {
+		return err
+	}
+	var r struct {
+		ErrCode *int   `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+		Code    *int   `json:"code"`
+		Msg     string `json:"msg"`
+	}
+	if json.Unmarshal(resp, &r) != nil {
+		return nil
+	}
+	if r.ErrCode != nil && *r.ErrCode != 0 {
+		return fmt.Errorf("errcode %d: %s", *r.ErrCode, r.ErrMsg)
+	}
+	if r.Code != nil && *r.Code != 0 {
+		return fmt.Errorf("code %d: %s", *r.Code, r.Msg)
+	}
+	return nil
+}
+
+// sendWebhook 通用 webhook：POST 事件 JSON（附带渲染后的 title / text）
+// 配置 secret 时带签名头 X-DAST-Signature: sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))
+func sendWebhook(ctx context.Context, cfg *channelConfig, e *Event, text string, deliveryId uint64) error {
+	body, err := json.Marshal(struct {
+		*Event
+		Title string `json:"title"`
+		Text  string `json:"text"`
+	}{e, e.Title(), text})
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	headers := map[string]string{
+		"X-DAST-Event":     e.Event,
+		"X-DAST-Delivery":  strconv.FormatUint(deliveryId, 10),
+		"X-DAST-Timestamp": ts,
+	}
+	if cfg.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(cfg.Secret))
+		mac.Write([]byte(ts + "."))
+		mac.Write(body)
+		headers["X-DAST-Signature"] = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	for k, v := range cfg.Headers {
+		headers[k] = v
+	}
+	_, err = postJSON(ctx, cfg.URL, body, headers)
+	return err
+}
+
+// sendDingTalk 钉钉机器人 markdown 消息；配置加签密钥时在地址上附加 timestamp 与 sign
+func sendDingTalk(ctx context.Context, cfg *channelConfig, e *Event, text string) error {
+	target := cfg.URL
+	if cfg.Secret != "" {
+		ts := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		mac := hmac.New(sha256.New, []byte(cfg.Secret))
+		mac.Write([]byte(ts + "\n" + cfg.Secret))
+		sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + "timestamp=" + ts + "&sign=" + url.QueryEscape(sign)
+	}
+	// 钉钉 markdown 中单个换行不生效
+	body := map[string]interface{}{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"title": e.Title(), "text": strings.ReplaceAll(text, "\n", "  \n")},
+	}
+	return postChat(ctx, target, body)
+}
+
+// sendFeishu 飞书 / Lark 机器人文本消息；配置加签密钥时在消息体中附加 timestamp 与 sign
+func sendFeishu(ctx context.Context, cfg *channelConfig, text string) error {
+	body := map[string]interface{}{
+		"msg_type": "text",
+		"content":  map[string]string{"text": text},
+	}
+	if cfg.Secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(ts+"\n"+cfg.Secret))
+		body["timestamp"] = ts
+		body["sign"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+	return postChat(ctx, cfg.URL, body)
+}
+
+// sendEmail 通过 SMTP 发送纯文本邮件；tls 为 ssl 时直接建立 TLS 连接，starttls 时在服务器支持时升级
+func sendEmail(cfg *channelConfig, e *Event, text string) error {
+	port := cfg.Port
+	if port == 0 {
+		port = 25
+		if cfg.TLS == "ssl" {
+			port = 465
+		}
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: sendTimeout}
+	if cfg.TLS == "ssl" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(2 * sendTimeout))
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if cfg.TLS != "ssl" && cfg.TLS != "none" {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		}
+	}
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return err
+		}
+	}
+	from, _ := mail.ParseAddress(cfg.From)
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	var to []string
+	for _, t := range cfg.To {
+		a, _ := mail.ParseAddress(t)
+		if err := client.Rcpt(a.Address); err != nil {
+			return err
+		}
+		to = append(to, a.String())
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	var msg bytes.Buffer
+	msg.WriteString("From: " + from.String() + "\r\n")
+	msg.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	msg.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", "[DAST] "+e.Title()) + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	encoded := base64.StdEncoding.EncodeToString([]byte(text))
+	for len(encoded) > 76 {
+		msg.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	msg.WriteString(encoded + "\r\n")
+	if _, err := w.Write(msg.Bytes()); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,286 @@
+package notify
+
+import (
+	"context"
+	"demo/db/mysqldb"
+	"demo/db/redisdb"
+	"demo/models"
+	"demo/secret"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 投递状态
+const (
+	StatusPending = "pending"
+	StatusSending = "sending"
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+const (
+	// 最多尝试次数，超过后标记为 failed
+	maxAttempts = 5
+	// 首次重试间隔，之后每次翻倍
+	retryBase = 30 * time.Second
+	// 重试扫描间隔
+	retryInterval = 10 * time.Second
+	// 投递中的记录超过该时间未完成视为进程中断，重新投递
+	sendingLease = 2 * time.Minute
+)
+
+// Init 启动事件消费与失败重试协程
+func Init() {
+	ctx := context.Background()
+	go loop(ctx)
+	go retryLoop(ctx)
+}
+
+func loop(ctx context.Context) {
+	for {
+		res, err := redisdb.Client.BLPop(ctx, 5*time.Second, queueKey).Result()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		if len(res) < 2 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal([]byte(res[1]), &e); err != nil {
+			log.Printf("[notify] invalid event err=%v", err)
+			continue
+		}
+		dispatch(&e)
+	}
+}
+
+func retryLoop(ctx context.Context) {
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		var ids []uint64
+		if err := mysqldb.DB.Model(&models.NotifyDelivery{}).
+			Where("status IN ? AND next_retry_at <= ?", []string{StatusPending, StatusSending}, time.Now()).
+			Order("id asc").Limit(100).
+			Pluck("id", &ids).Error; err != nil {
+			log.Printf("[notify.retryLoop] db query failed err=%v", err)
+			continue
+		}
+		for _, id := range ids {
+			attempt(id)
+		}
+	}
+}
+
+// subscribed 渠道是否订阅了事件
+func subscribed(ch *models.NotifyChannel, event string) bool {
+	for _, ev := range strings.Split(ch.Events, ",") {
+		if strings.TrimSpace(ev) == event {
+			return true
+		}
+	}
+	return false
+}
+
+// matches 渠道是否需要接收该事件
+func matches(ch *models.NotifyChannel, e *Event) bool {
+	if !subscribed(ch, e.Event) {
+		return false
+	}
+	if ch.TaskID != "" && ch.TaskID != e.TaskID {
+		return false
+	}
+	if e.Event == EventNewFinding && e.Finding != nil && !qualifies(e.Finding.Severity, ch.MinSeverity) {
+		return false
+	}
+	return true
+}
+
+// dispatch 为匹配的每个渠道创建投递记录并立即投递
+func dispatch(e *Event) {
+	if e.TaskID != "" && e.TaskName == "" {
+		var task models.Task
+		if err := mysqldb.DB.Select("id", "name").First(&task, "id = ?", e.TaskID).Error; err == nil {
+			e.TaskName = task.Name
+		}
+	}
+	if e.RunID != "" && e.TriggeredBy == "" {
+		var run models.TaskRun
+		if err := mysqldb.DB.Select("id", "triggered_by").First(&run, "id = ?", e.RunID).Error; err == nil {
+			e.TriggeredBy = run.TriggeredBy
+		}
+	}
+
+	var channels []models.NotifyChannel
+	if err := mysqldb.DB.Where("enabled = ?", true).Find(&channels).Error; err != nil {
+		log.Printf("[notify.dispatch] db query channels failed event=%s err=%v", e.Event, err)
+		return
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	for i := range channels {
+		ch := &channels[i]
+		if !matches(ch, e) {
+			continue
+		}
+		d := models.NotifyDelivery{
+			ChannelID: ch.ID,
+			Event:     e.Event,
+			TaskID:    e.TaskID,
+			RunID:     e.RunID,
+			Status:    StatusPending,
+			Payload:   string(payload),
+		}
+		if err := mysqldb.DB.Create(&d).Error; err != nil {
+			log.Printf("[notify.dispatch] db create delivery failed channel=%d event=%s err=%v", ch.ID, e.Event, err)
+			continue
+		}
+		go attempt(d.ID)
+	}
+}
+
+// claim 把投递记录原子地改为 sending，多个 API 进程之间只有一个能拿到；next_retry_at 兼作投递租约
+func claim(id uint64) bool {
+	now := time.Now()
+	res := mysqldb.DB.Model(&models.NotifyDelivery{}).
+		Where("id = ? AND status IN ? AND (next_retry_at IS NULL OR next_retry_at <= ?)", id, []string{StatusPending, StatusSending}, now).
+		Updates(map[string]interface{}{"status": StatusSending, "next_retry_at": now.Add(sendingLease)})
+	return res.Error == nil && res.RowsAffected == 1
+}
+
+// attempt 投递一次，失败时按指数退避安排下一次重试
+func attempt(id uint64) {
+	if !claim(id) {
+		return
+	}
+	var d models.NotifyDelivery
+	if err := mysqldb.DB.First(&d, id).Error; err != nil {
+		return
+	}
+
+	err := deliver(&d)
+	d.Attempts++
+	now := time.Now()
+	updates := map[string]interface{}{"attempts": d.Attempts}
+	switch {
+	case err == nil:
+		updates["status"] = StatusSuccess
+		updates["last_error"] = ""
+		updates["next_retry_at"] = nil
+		updates["delivered_at"] = now
+	case d.Attempts >= maxAttempts || errors.Is(err, errPermanent):
+		updates["status"] = StatusFailed
+		updates["last_error"] = err.Error()
+		updates["next_retry_at"] = nil
+	default:
+		updates["status"] = StatusPending
+		updates["last_error"] = err.Error()
+		updates["next_retry_at"] = now.Add(retryBase << (d.Attempts - 1))
+	}
+	if err != nil {
+		log.Printf("[notify] deliver failed delivery=%d channel=%d event=%s attempts=%d err=%v", d.ID, d.ChannelID, d.Event, d.Attempts, err)
+	}
+	if err := mysqldb.DB.Model(&models.NotifyDelivery{}).Where("id = ?", d.ID).Updates(updates).Error; err != nil {
+		log.Printf("[notify] db update delivery failed delivery=%d err=%v", d.ID, err)
+	}
+}
+
+// errPermanent 重试也不会成功的错误（渠道已删除、配置无法解密等）
+var errPermanent = errors.New("permanent failure")
+
+func deliver(d *models.NotifyDelivery) error {
+	var ch models.NotifyChannel
+	if err := mysqldb.DB.First(&ch, d.ChannelID).Error; err != nil {
+		return errors.Join(errPermanent, errors.New("channel not found"))
+	}
+	var e Event
+	if err := json.Unmarshal([]byte(d.Payload), &e); err != nil {
+		return errors.Join(errPermanent, err)
+	}
+	return Send(&ch, &e, d.ID)
+}
+
+// Send 按渠道的配置与模板发送一条事件
+func Send(ch *models.NotifyChannel, e *Event, deliveryId uint64) error {
+	plain, err := secret.Decrypt(ch.Config)
+	if err != nil {
+		return errors.Join(errPermanent, err)
+	}
+	var cfg channelConfig
+	if err := json.Unmarshal(plain, &cfg); err != nil {
+		return errors.Join(errPermanent, err)
+	}
+	tmpl, err := parseTemplate(ch.Template)
+	if err != nil {
+		return errors.Join(errPermanent, err)
+	}
+	text, err := render(tmpl, e)
+	if err != nil {
+		return errors.Join(errPermanent, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	return send(ctx, ch.Type, &cfg, e, text, deliveryId)
+}
+
+// Retry 把失败的投递重新放回队列
+func Retry(id uint64) (bool, error) {
+	res := mysqldb.DB.Model(&models.NotifyDelivery{}).
+		Where("id = ? AND status = ?", id, StatusFailed).
+		Updates(map[string]interface{}{"status": StatusPending, "attempts": 0, "next_retry_at": nil})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false, res.Error
+	}
+	go attempt(id)
+	return true, nil
+}
+
+// 订阅 finding.new 的最低等级缓存，扫描进程每个命中都会查询
+const wantCacheTTL = 30 * time.Second
+
+var want struct {
+	sync.Mutex
+	rank    int // 渠道阈值中最低的等级；-1 表示没有渠道订阅
+	expires time.Time
+}
+
+// WantFinding 是否有启用的渠道订阅了该等级的新漏洞
+func WantFinding(sev string) bool {
+	want.Lock()
+	defer want.Unlock()
+	if time.Now().After(want.expires) {
+		want.rank = -1
+		var channels []models.NotifyChannel
+		if err := mysqldb.DB.Select("id", "events", "min_severity").Where("enabled = ?", true).Find(&channels).Error; err != nil {
+			log.Printf("[notify.WantFinding] db query channels failed err=%v", err)
+		}
+		for i := range channels {
+			if !subscribed(&channels[i], EventNewFinding) {
+				continue
+			}
+			r := len(severityOrder)
+			if channels[i].MinSeverity != "" {
+				r = rank(channels[i].MinSeverity)
+			}
+			if r > want.rank {
+				want.rank = r
+			}
+		}
+		want.expires = time.Now().Add(wantCacheTTL)
+	}
+	return rank(sev) <= want.rank
+}
@@ -0,0 +1,185 @@
+package notify
+
+import (
+	"bytes"
+	"demo/db/redisdb"
+	"demo/models"
+	"encoding/json"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// 事件类型
+const (
+	EventStarted    = "task.started"
+	EventFinished   = "task.finished"
+	EventFailed     = "task.failed"
+	EventStopped    = "task.stopped"
+	EventNewFinding = "finding.new"
+	EventTest       = "test"
+)
+
+// Events 可订阅的事件
+var Events = []string{EventStarted, EventFinished, EventFailed, EventStopped, EventNewFinding}
+
+const (
+	// 事件队列：扫描进程写入，API 进程消费后按渠道投递
+	queueKey = "notify:queue"
+	queueMax = 1000
+)
+
+// FindingInfo 新漏洞事件中的漏洞信息
+type FindingInfo struct {
+	TemplateID string `json:"templateId"`
+	Name       string `json:"name"`
+	Severity   string `json:"severity"`
+	MatchedAt  string `json:"matchedAt"`
+	Host       string `json:"host,omitempty"`
+}
+
+// SeverityCount 某个等级的漏洞数
+type SeverityCount struct {
+	Severity string `json:"severity"`
+	Count    int64  `json:"count"`
+}
+
+// 严重等级由高到低
+var severityOrder = []string{"critical", "high", "medium", "low", "info", "unknown"}
+
+func rank(sev string) int {
+	for i, s := range severityOrder {
+		if s == sev {
+			return i
+		}
+	}
+	return len(severityOrder)
+}
+
+// qualifies 漏洞等级是否达到阈值，未设置阈值时全部通知
+func qualifies(sev, min string) bool {
+	return min == "" || rank(sev) <= rank(min)
+}
+
+// SortCounts 把 severity -> 数量按等级由高到低排列，省略数量为 0 的等级
+func SortCounts(counts map[string]int64) []SeverityCount {
+	out := make([]SeverityCount, 0, len(counts))
+	for _, sev := range severityOrder {
+		if n := counts[sev]; n > 0 {
+			out = append(out, SeverityCount{Severity: sev, Count: n})
+		}
+	}
+	return out
+}
+
+// Event 通知事件，同时作为消息模板的数据
+type Event struct {
+	Event       string           `json:"event"`
+	TaskID      string           `json:"taskId,omitempty"`
+	TaskName    string           `json:"taskName,omitempty"`
+	RunID       string           `json:"runId,omitempty"`
+	TriggeredBy string           `json:"triggeredBy,omitempty"`
+	Status      string           `json:"status,omitempty"`
+	Message     string           `json:"message,omitempty"`
+	Time        string           `json:"time"`
+	Finding     *FindingInfo     `json:"finding,omitempty"`
+	Counts      []SeverityCount  `json:"counts,omitempty"`    // task.finished：本次执行各等级漏洞数
+	BaseRunID   string           `json:"baseRunId,omitempty"` // task.finished：对比的上一次执行
+	Summary     map[string]int   `json:"summary,omitempty"`   // task.finished：new / resolved / persistent 数量
+	New         []models.Finding `json:"new,omitempty"`
+	Resolved    []models.Finding `json:"resolved,omitempty"`
+}
+
+// Title 事件标题，用于邮件主题与消息标题
+func (e *Event) Title() string {
+	switch e.Event {
+	case EventStarted:
+		return "扫描开始：" + e.taskLabel()
+	case EventFinished:
+		return "扫描完成：" + e.taskLabel()
+	case EventFailed:
+		return "扫描失败：" + e.taskLabel()
+	case EventStopped:
+		return "扫描已停止：" + e.taskLabel()
+	case EventNewFinding:
+		if e.Finding != nil {
+			return "发现 " + strings.ToUpper(e.Finding.Severity) + " 漏洞：" + e.Finding.Name
+		}
+		return "发现新漏洞：" + e.taskLabel()
+	case EventTest:
+		return "测试通知"
+	}
+	return e.Event
+}
+
+func (e *Event) taskLabel() string {
+	if e.TaskName != "" {
+		return e.TaskName
+	}
+	return e.TaskID
+}
+
+// StatusEvent 任务状态对应的事件，running 以外的未知状态返回空
+func StatusEvent(status string) string {
+	switch status {
+	case "running":
+		return EventStarted
+	case "finished":
+		return EventFinished
+	case "error":
+		return EventFailed
+	case "stopped":
+		return EventStopped
+	}
+	return ""
+}
+
+// Publish 把事件写入通知队列，队列只保留最近 queueMax 条
+func Publish(e *Event) {
+	if e.Time == "" {
+		e.Time = time.Now().Format("2006-01-02 15:04:05")
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	pipe := redisdb.Client.TxPipeline()
+	pipe.RPush(redisdb.Ctx, queueKey, data)
+	pipe.LTrim(redisdb.Ctx, queueKey, -queueMax, -1)
+	if _, err := pipe.Exec(redisdb.Ctx); err != nil {
+		log.Printf("[notify.Publish] push event failed event=%s task=%s run=%s err=%v", e.Event, e.TaskID, e.RunID, err)
+	}
+}
+
+// defaultTemplate 默认消息内容，同时适用于纯文本与 Markdown
+const defaultTemplate = `【{{.Title}}】
+{{if .TaskID}}
+任务：{{.TaskName}}（{{.TaskID}}）{{end}}{{if .RunID}}
+执行：{{.RunID}}{{if .TriggeredBy}}（{{.TriggeredBy}}）{{end}}{{end}}{{if .Finding}}
+漏洞：[{{.Finding.Severity}}] {{.Finding.Name}}
+模板：{{.Finding.TemplateID}}
+位置：{{.Finding.MatchedAt}}{{end}}{{if .Counts}}
+结果：{{range $i, $c := .Counts}}{{if $i}}，{{end}}{{$c.Severity}} {{$c.Count}}{{end}}{{end}}{{if .Summary}}
+对比上次：新增 {{index .Summary "new"}}，已修复 {{index .Summary "resolved"}}，仍存在 {{index .Summary "persistent"}}{{end}}{{if .Message}}
+信息：{{.Message}}{{end}}
+时间：{{.Time}}`
+
+var defaultTmpl = template.Must(template.New("default").Parse(defaultTemplate))
+
+// parseTemplate 解析渠道的自定义模板，为空时返回默认模板
+func parseTemplate(text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		return defaultTmpl, nil
+	}
+	return template.New("channel").Option("missingkey=zero").Parse(text)
+}
+
+// render 按模板渲染消息内容
+func render(tmpl *template.Template, e *Event) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, e); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
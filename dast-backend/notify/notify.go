@@ -0,0 +1,351 @@
+/**
+ * 通知：任务开始 / 完成 / 失败 / 停止以及发现新漏洞时，按渠道推送 webhook、邮件、钉钉、飞书、企业微信、Slack 消息
+ * 扫描进程把事件写入 Redis 队列，API 进程消费后为每个匹配的渠道生成投递记录，失败时指数退避重试
+ */
+package notify
+
+import (
+	"demo/db/mysqldb"
+	"demo/finding"
+	"demo/models"
+	"demo/secret"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 新建渠道未指定事件时默认订阅的事件
+var defaultEvents = []string{EventFinished, EventFailed}
+
+// decryptConfig 解密保存的渠道配置为 map，供查询与合并更新使用
+func decryptConfig(ch *models.NotifyChannel) (map[string]interface{}, error) {
+	plain, err := secret.Decrypt(ch.Config)
+	if err != nil {
+		return nil, err
+	}
+	config := map[string]interface{}{}
+	if len(plain) > 0 {
+		if err := json.Unmarshal(plain, &config); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// encryptConfig 校验并加密渠道配置
+func encryptConfig(typ string, config map[string]interface{}) (string, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	var cfg channelConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return "", err
+	}
+	if err := cfg.validate(typ); err != nil {
+		return "", err
+	}
+	return secret.Encrypt(data)
+}
+
+// view 返回给前端的渠道，凭据已被 mask
+func view(ch *models.NotifyChannel) gin.H {
+	events := []string{}
+	for _, ev := range strings.Split(ch.Events, ",") {
+		if ev = strings.TrimSpace(ev); ev != "" {
+			events = append(events, ev)
+		}
+	}
+	h := gin.H{
+		"id":          ch.ID,
+		"name":        ch.Name,
+		"type":        ch.Type,
+		"events":      events,
+		"minSeverity": ch.MinSeverity,
+		"taskId":      ch.TaskID,
+		"template":    ch.Template,
+		"enabled":     ch.Enabled,
+		"creator":     ch.Creator,
+		"createdAt":   ch.CreatedAt,
+		"updatedAt":   ch.UpdatedAt,
+	}
+	if config, err := decryptConfig(ch); err != nil {
+		h["configError"] = err.Error()
+	} else {
+		h["config"] = secret.Mask(config, secretKeys[ch.Type]...)
+	}
+	return h
+}
+
+// ListChannels - 通知渠道列表
+// GET /api/notify/channels
+func ListChannels() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var channels []models.NotifyChannel
+		if err := mysqldb.DB.Order("id asc").Find(&channels).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		list := make([]gin.H, 0, len(channels))
+		for i := range channels {
+			list = append(list, view(&channels[i]))
+		}
+		c.JSON(http.StatusOK, gin.H{"channels": list, "events": Events, "defaultTemplate": defaultTemplate})
+	}
+}
+
+// SaveChannel - 新建或更新通知渠道（带 id 时更新）
+// POST /api/notify/channel/save
+//
+//	{"name":"ops","type":"dingtalk","config":{"url":"https://oapi.dingtalk.com/robot/send?access_token=...","secret":"SEC..."},
+//	 "events":["task.finished","finding.new"],"minSeverity":"high","taskId":"","template":"","enabled":true}
+//
+// config 按类型：webhook {url,secret,headers}；email {host,port,username,password,from,to,tls}；
+// dingtalk / feishu {url,secret}；wecom / slack {url}。更新时凭据传 "******" 或省略表示不修改
+func SaveChannel() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			ID          uint64                 `json:"id"`
+			Name        string                 `json:"name"`
+			Type        string                 `json:"type"`
+			Config      map[string]interface{} `json:"config"`
+			Events      []string               `json:"events"`
+			MinSeverity string                 `json:"minSeverity"`
+			TaskID      *string                `json:"taskId"`
+			Template    *string                `json:"template"`
+			Enabled     *bool                  `json:"enabled"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+			return
+		}
+		req.Type = strings.ToLower(strings.TrimSpace(req.Type))
+		req.MinSeverity = strings.ToLower(strings.TrimSpace(req.MinSeverity))
+		if req.MinSeverity != "" && rank(req.MinSeverity) >= len(severityOrder)-1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid minSeverity (critical/high/medium/low/info)"})
+			return
+		}
+
+		var ch models.NotifyChannel
+		if req.ID != 0 {
+			if err := mysqldb.DB.First(&ch, req.ID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "channel not found"})
+				return
+			}
+			if req.Type != "" && req.Type != ch.Type {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "type cannot be changed"})
+				return
+			}
+		} else {
+			if _, ok := secretKeys[req.Type]; !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid type (webhook/email/dingtalk/feishu/wecom/slack)"})
+				return
+			}
+			ch = models.NotifyChannel{Type: req.Type, Enabled: true, Events: strings.Join(defaultEvents, ","), Creator: c.GetString("username")}
+		}
+
+		if name := strings.TrimSpace(req.Name); name != "" {
+			ch.Name = name
+		} else if ch.Name == "" {
+			ch.Name = ch.Type
+		}
+		if req.Events != nil {
+			events := make([]string, 0, len(req.Events))
+			for _, ev := range req.Events {
+				ev = strings.TrimSpace(ev)
+				valid := false
+				for _, e := range Events {
+					valid = valid || e == ev
+				}
+				if !valid {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event " + ev + " (" + strings.Join(Events, "/") + ")"})
+					return
+				}
+				events = append(events, ev)
+			}
+			ch.Events = strings.Join(events, ",")
+		}
+		ch.MinSeverity = req.MinSeverity
+		if req.TaskID != nil {
+			ch.TaskID = strings.TrimSpace(*req.TaskID)
+			if ch.TaskID != "" {
+				if err := mysqldb.DB.Select("id").First(&models.Task{}, "id = ?", ch.TaskID).Error; err != nil {
+					c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+					return
+				}
+			}
+		}
+		if req.Template != nil {
+			if _, err := parseTemplate(*req.Template); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template: " + err.Error()})
+				return
+			}
+			ch.Template = *req.Template
+		}
+		if req.Enabled != nil {
+			ch.Enabled = *req.Enabled
+		}
+
+		if req.Config != nil || ch.ID == 0 {
+			if ch.ID != 0 {
+				old, err := decryptConfig(&ch)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "decrypt config failed: " + err.Error()})
+					return
+				}
+				secret.Merge(req.Config, old, secretKeys[ch.Type]...)
+			}
+			enc, err := encryptConfig(ch.Type, req.Config)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid config: " + err.Error()})
+				return
+			}
+			ch.Config = enc
+		}
+
+		var err error
+		if ch.ID == 0 {
+			err = mysqldb.DB.Create(&ch).Error
+		} else {
+			err = mysqldb.DB.Model(&ch).Updates(map[string]interface{}{
+				"name": ch.Name, "config": ch.Config, "events": ch.Events, "min_severity": ch.MinSeverity,
+				"task_id": ch.TaskID, "template": ch.Template, "enabled": ch.Enabled,
+			}).Error
+		}
+		if err != nil {
+			log.Printf("[notify.SaveChannel] db save channel failed name=%s err=%v", ch.Name, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db save channel failed: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "保存成功", "channel": view(&ch)})
+	}
+}
+
+// DeleteChannel - 删除通知渠道及其投递记录
+// POST /api/notify/channel/delete {"id":1}
+func DeleteChannel() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			ID uint64 `json:"id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.ID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
+			return
+		}
+		res := mysqldb.DB.Delete(&models.NotifyChannel{}, req.ID)
+		if res.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": res.Error.Error()})
+			return
+		}
+		if res.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "channel not found"})
+			return
+		}
+		if err := mysqldb.DB.Where("channel_id = ?", req.ID).Delete(&models.NotifyDelivery{}).Error; err != nil {
+			log.Printf("[notify.DeleteChannel] db delete deliveries failed channel=%d err=%v", req.ID, err)
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "删除成功", "id": req.ID})
+	}
+}
+
+// TestChannel - 立即发送一条测试消息（不记录投递、不重试），返回发送结果
+// POST /api/notify/channel/test {"id":1}
+func TestChannel() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			ID uint64 `json:"id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.ID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
+			return
+		}
+		var ch models.NotifyChannel
+		if err := mysqldb.DB.First(&ch, req.ID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "channel not found"})
+			return
+		}
+		e := &Event{
+			Event:       EventTest,
+			TriggeredBy: c.GetString("username"),
+			Message:     "这是一条来自 DAST 平台的测试通知（渠道：" + ch.Name + "）",
+			Time:        time.Now().Format("2006-01-02 15:04:05"),
+		}
+		if err := Send(&ch, e, 0); err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "send failed: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "发送成功"})
+	}
+}
+
+// ListDeliveries - 投递记录
+// GET /api/notify/deliveries?channelId=&status=&taskId=&event=&page=&pageSize=
+func ListDeliveries() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := mysqldb.DB.Model(&models.NotifyDelivery{})
+		if v := c.Query("channelId"); v != "" {
+			db = db.Where("channel_id = ?", v)
+		}
+		if v := c.Query("status"); v != "" {
+			db = db.Where("status = ?", v)
+		}
+		if v := c.Query("taskId"); v != "" {
+			db = db.Where("task_id = ?", v)
+		}
+		if v := c.Query("event"); v != "" {
+			db = db.Where("event = ?", v)
+		}
+		page, pageSize := finding.ParsePage(c)
+
+		var total int64
+		if err := db.Count(&total).Error; err != nil {
+			log.Printf("[notify.ListDeliveries] db count failed err=%v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		deliveries := []models.NotifyDelivery{}
+		if err := db.Omit("payload").
+			Order("id desc").
+			Offset((page - 1) * pageSize).
+			Limit(pageSize).
+			Find(&deliveries).Error; err != nil {
+			log.Printf("[notify.ListDeliveries] db query failed err=%v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"total":      total,
+			"page":       page,
+			"pageSize":   pageSize,
+			"deliveries": deliveries,
+		})
+	}
+}
+
+// RetryDelivery - 重新投递失败的记录
+// POST /api/notify/retry {"id":1}
+func RetryDelivery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			ID uint64 `json:"id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.ID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
+			return
+		}
+		ok, err := Retry(req.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "failed delivery not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "已重新投递", "id": req.ID})
+	}
+}
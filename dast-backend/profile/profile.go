@@ -0,0 +1,168 @@
+package profile
+
+import (
+	"demo/db/mysqldb"
+	"demo/models"
+	"demo/scanner"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Get 按名称查找 profile：先查内置，再查 MySQL scan_profiles 表
+// name 为空时返回默认 profile
+func Get(name string) (*scanner.Profile, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return scanner.DefaultProfile(), nil
+	}
+	if p, ok := scanner.BuiltinProfile(name); ok {
+		return p, nil
+	}
+
+	var m models.ScanProfile
+	if err := mysqldb.DB.First(&m, "name = ?", name).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("profile not found: " + name)
+		}
+		return nil, err
+	}
+	p, err := scanner.ParseProfile(m.Config)
+	if err != nil {
+		return nil, err
+	}
+	p.Name = m.Name
+	return p, nil
+}
+
+// Resolve 供创建任务时使用：config 非空时以它为自定义 profile，否则按名称查找
+// 返回值可直接序列化写入 models.Task.Config
+func Resolve(name string, config json.RawMessage) (*scanner.Profile, error) {
+	if len(config) == 0 || string(config) == "null" {
+		return Get(name)
+	}
+	p, err := scanner.ParseProfile(string(config))
+	if err != nil {
+		return nil, errors.New("invalid config: " + err.Error())
+	}
+	if name != "" && p.Name == "custom" {
+		p.Name = name
+	}
+	return p, nil
+}
+
+// List - 列出内置 + 自定义 profile
+func List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		profiles := scanner.BuiltinProfiles()
+		resp := make([]gin.H, 0, len(profiles))
+		for _, p := range profiles {
+			resp = append(resp, gin.H{"name": p.Name, "builtin": true, "config": p})
+		}
+
+		var dbProfiles []models.ScanProfile
+		if err := mysqldb.DB.Order("name asc").Find(&dbProfiles).Error; err != nil {
+			log.Printf("[profile.List] db query failed err=%v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for _, m := range dbProfiles {
+			p, err := scanner.ParseProfile(m.Config)
+			if err != nil {
+				log.Printf("[profile.List] invalid profile name=%s err=%v", m.Name, err)
+				continue
+			}
+			p.Name = m.Name
+			resp = append(resp, gin.H{"name": m.Name, "builtin": false, "config": p, "updatedAt": m.UpdatedAt})
+		}
+
+		c.JSON(http.StatusOK, gin.H{"profiles": resp})
+	}
+}
+
+// Save - 新建或覆盖一个自定义 profile（按 name upsert，内置名称不可覆盖）
+func Save() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var p scanner.Profile
+		if err := c.ShouldBindJSON(&p); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+			return
+		}
+		p.Name = strings.TrimSpace(p.Name)
+		if p.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
+			return
+		}
+		if _, ok := scanner.BuiltinProfile(p.Name); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot overwrite builtin profile"})
+			return
+		}
+		p.Normalize()
+		if err := p.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid profile: " + err.Error()})
+			return
+		}
+
+		data, err := json.Marshal(p)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		var m models.ScanProfile
+		err = mysqldb.DB.First(&m, "name = ?", p.Name).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			m = models.ScanProfile{Name: p.Name, Description: p.Description, Config: string(data)}
+			err = mysqldb.DB.Create(&m).Error
+		case err == nil:
+			err = mysqldb.DB.Model(&m).Updates(map[string]interface{}{
+				"description": p.Description,
+				"config":      string(data),
+			}).Error
+		}
+		if err != nil {
+			log.Printf("[profile.Save] db save failed name=%s err=%v", p.Name, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db save profile failed: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "保存成功",
+			"profile": p,
+		})
+	}
+}
+
+// Delete - 删除自定义 profile（已创建任务中保存的是配置快照，不受影响）
+func Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
+			return
+		}
+		if _, ok := scanner.BuiltinProfile(req.Name); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot delete builtin profile"})
+			return
+		}
+
+		res := mysqldb.DB.Where("name = ?", req.Name).Delete(&models.ScanProfile{})
+		if res.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": res.Error.Error()})
+			return
+		}
+		if res.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "profile not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "删除成功", "name": req.Name})
+	}
+}
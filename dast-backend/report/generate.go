@@ -0,0 +1,292 @@
+package report
+
+import (
+	"demo/db/mysqldb"
+	"demo/models"
+	"demo/scanner"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// 严重等级由高到低
+var severityOrder = []string{"critical", "high", "medium", "low", "info", "unknown"}
+
+// 每批读取的 findings 数量，报告按批流式写出，避免大结果集全部载入内存
+const batchSize = 200
+
+// SeverityCount 某个严重等级的数量
+type SeverityCount struct {
+	Severity string
+	Count    int64
+	Percent  float64 // 占全部 findings 的百分比，用于 HTML 条形图
+}
+
+// HostSummary 单个主机的 findings 统计
+type HostSummary struct {
+	Host       string
+	Anchor     string
+	Total      int64
+	Severities []SeverityCount
+	Worst      string
+}
+
+// Summary 报告的执行摘要
+type Summary struct {
+	Task        models.Task
+	Run         models.TaskRun
+	Profile     string
+	Targets     int
+	Duration    string
+	GeneratedAt string
+	Total       int64
+	Risk        string // 出现过的最高严重等级，没有 findings 时为 none
+	Severities  []SeverityCount
+	Hosts       []HostSummary
+}
+
+// Finding 报告中的一条 finding，Event 为保存的完整 nuclei 事件（含 request/response）
+type Finding struct {
+	models.Finding
+	Event *output.ResultEvent
+}
+
+// renderer 各格式的报告写出器：begin 写入摘要，host 开始一个主机分组，finding 写一条结果，close 收尾并落盘
+type renderer interface {
+	begin(s *Summary) error
+	host(h *HostSummary) error
+	finding(f *Finding) error
+	close() error
+}
+
+func newRenderer(format, path string) (renderer, error) {
+	switch format {
+	case FormatSARIF:
+		return newSARIFRenderer(path)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatHTML:
+		return newHTMLRenderer(f), nil
+	case FormatMarkdown:
+		return newMarkdownRenderer(f), nil
+	case FormatCSV:
+		return newCSVRenderer(f), nil
+	}
+	f.Close()
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// generate 按报告格式写出文件，返回写入的 findings 数量
+func generate(r *models.Report) (int64, error) {
+	s, err := loadSummary(r.RunID)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0o755); err != nil {
+		return 0, err
+	}
+	w, err := newRenderer(r.Format, r.Path)
+	if err != nil {
+		return 0, err
+	}
+	if err := w.begin(s); err != nil {
+		w.close()
+		return 0, err
+	}
+
+	hosts := make(map[string]*HostSummary, len(s.Hosts))
+	for i := range s.Hosts {
+		hosts[s.Hosts[i].Host] = &s.Hosts[i]
+	}
+	var count int64
+	current := "\x00"
+	err = eachFinding(r.RunID, func(f *models.Finding) error {
+		if f.Host != current {
+			current = f.Host
+			h := hosts[f.Host]
+			if h == nil {
+				h = &HostSummary{Host: f.Host, Anchor: anchor(f.Host)}
+			}
+			if err := w.host(h); err != nil {
+				return err
+			}
+		}
+		item := &Finding{Finding: *f}
+		if f.Details != "" {
+			var ev output.ResultEvent
+			if json.Unmarshal([]byte(f.Details), &ev) == nil {
+				item.Event = &ev
+			}
+		}
+		if err := w.finding(item); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		w.close()
+		return count, err
+	}
+	return count, w.close()
+}
+
+// findingPage 按主机、严重等级读取一页 findings，排序以 id 收尾构成全序，offset 分页才不会漏读或重读
+var findingPage = func(runId string, offset, limit int) ([]models.Finding, error) {
+	var page []models.Finding
+	err := mysqldb.DB.Where("run_id = ?", runId).
+		Order("host asc").Order(severityOrderBy()).Order("id asc").
+		Offset(offset).Limit(limit).Find(&page).Error
+	return page, err
+}
+
+// eachFinding 分批遍历执行的 findings
+// 不能用 FindInBatches：它以 id > 上一批最后的 id 取下一批，只适用于按主键排序的查询
+func eachFinding(runId string, fn func(f *models.Finding) error) error {
+	for offset := 0; ; offset += batchSize {
+		page, err := findingPage(runId, offset, batchSize)
+		if err != nil {
+			return err
+		}
+		for i := range page {
+			if err := fn(&page[i]); err != nil {
+				return err
+			}
+		}
+		if len(page) < batchSize {
+			return nil
+		}
+	}
+}
+
+// loadSummary 统计执行的摘要信息：各等级数量与各主机的分布
+func loadSummary(runId string) (*Summary, error) {
+	s := &Summary{GeneratedAt: time.Now().Format("2006-01-02 15:04:05"), Risk: "none"}
+	if err := mysqldb.DB.First(&s.Run, "id = ?", runId).Error; err != nil {
+		return nil, err
+	}
+	_ = mysqldb.DB.First(&s.Task, "id = ?", s.Run.TaskID).Error
+	if s.Run.Targets != "" {
+		var targets []string
+		if json.Unmarshal([]byte(s.Run.Targets), &targets) == nil {
+			s.Targets = len(targets)
+		}
+	}
+	s.Profile = scanner.DefaultProfileName
+	if p, err := scanner.ParseProfile(s.Run.Config); err == nil {
+		s.Profile = p.Name
+	}
+	if s.Run.StartedAt != nil && s.Run.FinishedAt != nil {
+		s.Duration = s.Run.FinishedAt.Sub(*s.Run.StartedAt).Round(time.Second).String()
+	}
+
+	var rows []struct {
+		Host     string
+		Severity string
+		Count    int64
+	}
+	if err := mysqldb.DB.Model(&models.Finding{}).Select("host, severity, COUNT(*) AS count").
+		Where("run_id = ?", runId).Group("host, severity").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	total := map[string]int64{}
+	byHost := map[string]map[string]int64{}
+	for _, row := range rows {
+		total[row.Severity] += row.Count
+		s.Total += row.Count
+		if byHost[row.Host] == nil {
+			byHost[row.Host] = map[string]int64{}
+		}
+		byHost[row.Host][row.Severity] += row.Count
+	}
+	s.Severities = severityCounts(total, s.Total)
+	for _, sc := range s.Severities {
+		if sc.Count > 0 {
+			s.Risk = sc.Severity
+			break
+		}
+	}
+
+	for host, counts := range byHost {
+		h := HostSummary{Host: host, Anchor: anchor(host), Worst: "none"}
+		for _, n := range counts {
+			h.Total += n
+		}
+		h.Severities = severityCounts(counts, h.Total)
+		for _, sc := range h.Severities {
+			if sc.Count > 0 {
+				h.Worst = sc.Severity
+				break
+			}
+		}
+		s.Hosts = append(s.Hosts, h)
+	}
+	// 按最高严重等级、数量排序，风险最高的主机排在前面
+	sort.Slice(s.Hosts, func(i, j int) bool {
+		a, b := s.Hosts[i], s.Hosts[j]
+		if rank(a.Worst) != rank(b.Worst) {
+			return rank(a.Worst) < rank(b.Worst)
+		}
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		return a.Host < b.Host
+	})
+	return s, nil
+}
+
+func severityCounts(counts map[string]int64, total int64) []SeverityCount {
+	out := make([]SeverityCount, 0, len(severityOrder))
+	for _, sev := range severityOrder {
+		n := counts[sev]
+		if sev == "unknown" && n == 0 {
+			continue
+		}
+		sc := SeverityCount{Severity: sev, Count: n}
+		if total > 0 {
+			sc.Percent = float64(n) * 100 / float64(total)
+		}
+		out = append(out, sc)
+	}
+	return out
+}
+
+func rank(sev string) int {
+	for i, s := range severityOrder {
+		if s == sev {
+			return i
+		}
+	}
+	return len(severityOrder)
+}
+
+// severityOrderBy 按严重等级由高到低排序的 ORDER BY 子句
+func severityOrderBy() string {
+	return "FIELD(severity, 'critical', 'high', 'medium', 'low', 'info') = 0, FIELD(severity, 'critical', 'high', 'medium', 'low', 'info')"
+}
+
+// anchor 主机分组在 HTML / Markdown 中的锚点
+func anchor(host string) string {
+	if host == "" {
+		return "host-unknown"
+	}
+	b := []byte("host-")
+	for _, ch := range []byte(host) {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9', ch == '-':
+			b = append(b, ch)
+		default:
+			b = append(b, '-')
+		}
+	}
+	return string(b)
+}
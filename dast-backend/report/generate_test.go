@@ -0,0 +1,96 @@
+package report
+
+import (
+	"demo/models"
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// 超过一批的 findings 分布在多个主机上，id 与主机顺序交错，分页后不能漏读或重读
+func TestEachFindingPagesAcrossHosts(t *testing.T) {
+	hosts := []string{"c.example.com", "a.example.com", "b.example.com"}
+	var rows []models.Finding
+	for i := 1; i <= 2*batchSize+50; i++ {
+		rows = append(rows, models.Finding{
+			ID:       uint64(i),
+			RunID:    "run",
+			Host:     hosts[i%len(hosts)],
+			Severity: severityOrder[i%5],
+		})
+	}
+	sorted := append([]models.Finding(nil), rows...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Host != b.Host {
+			return a.Host < b.Host
+		}
+		if rank(a.Severity) != rank(b.Severity) {
+			return rank(a.Severity) < rank(b.Severity)
+		}
+		return a.ID < b.ID
+	})
+
+	original := findingPage
+	defer func() { findingPage = original }()
+	var pages int
+	findingPage = func(runId string, offset, limit int) ([]models.Finding, error) {
+		pages++
+		if offset >= len(sorted) {
+			return nil, nil
+		}
+		end := offset + limit
+		if end > len(sorted) {
+			end = len(sorted)
+		}
+		return append([]models.Finding(nil), sorted[offset:end]...), nil
+	}
+
+	var got []models.Finding
+	if err := eachFinding("run", func(f *models.Finding) error {
+		got = append(got, *f)
+		return nil
+	}); err != nil {
+		t.Fatalf("eachFinding: %v", err)
+	}
+	if len(got) != len(rows) {
+		t.Fatalf("got %d findings, want %d", len(got), len(rows))
+	}
+	seen := make(map[uint64]bool, len(got))
+	for i, f := range got {
+		if seen[f.ID] {
+			t.Fatalf("finding %d returned twice", f.ID)
+		}
+		seen[f.ID] = true
+		if f.ID != sorted[i].ID {
+			t.Fatalf("finding %d at position %d, want %d", f.ID, i, sorted[i].ID)
+		}
+	}
+	if pages != 3 {
+		t.Fatalf("got %d pages, want 3", pages)
+	}
+}
+
+func TestEachFindingStopsOnError(t *testing.T) {
+	original := findingPage
+	defer func() { findingPage = original }()
+	findingPage = func(runId string, offset, limit int) ([]models.Finding, error) {
+		page := make([]models.Finding, limit)
+		for i := range page {
+			page[i].ID = uint64(offset + i + 1)
+		}
+		return page, nil
+	}
+
+	var count int
+	err := eachFinding("run", func(f *models.Finding) error {
+		count++
+		if count == batchSize+1 {
+			return fmt.Errorf("write failed")
+		}
+		return nil
+	})
+	if err == nil || count != batchSize+1 {
+		t.Fatalf("got err %v after %d findings, want error after %d", err, count, batchSize+1)
+	}
+}
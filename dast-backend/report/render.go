@@ -0,0 +1,331 @@
+package report
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/model"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/projectdiscovery/nuclei/v3/pkg/reporting/exporters/markdown/util"
+	"github.com/projectdiscovery/nuclei/v3/pkg/reporting/format"
+)
+
+// event 返回 finding 对应的 nuclei 事件；details 缺失或无法解析时用表中字段拼出一个最小事件
+func event(f *Finding) *output.ResultEvent {
+	if f.Event != nil {
+		return f.Event
+	}
+	ev := &output.ResultEvent{
+		TemplateID:  f.TemplateID,
+		Info:        model.Info{Name: f.Title},
+		MatcherName: f.MatcherName,
+		Type:        f.Type,
+		Host:        f.Host,
+		Matched:     f.MatchedAt,
+		IP:          f.IP,
+		Timestamp:   f.CreatedAt,
+	}
+	if f.Port > 0 {
+		ev.Port = strconv.Itoa(f.Port)
+	}
+	_ = ev.Info.SeverityHolder.UnmarshalJSON([]byte(strconv.Quote(f.Severity)))
+	_ = json.Unmarshal([]byte(f.ExtractedResults), &ev.ExtractedResults)
+	return ev
+}
+
+func references(ev *output.ResultEvent) []string {
+	if ev.Info.Reference == nil {
+		return nil
+	}
+	return ev.Info.Reference.ToSlice()
+}
+
+// ---------------- HTML ----------------
+
+var htmlTmpl = template.Must(template.New("report").Funcs(template.FuncMap{
+	"refs": references,
+	"pct":  func(v float64) string { return strconv.FormatFloat(v, 'f', 1, 64) },
+}).Parse(`
+{{define "begin"}}<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>扫描报告 - {{.Task.Name}} - {{.Run.ID}}</title>
+<style>
+body{font-family:-apple-system,"Segoe UI",Roboto,"PingFang SC","Microsoft YaHei",sans-serif;margin:0;padding:24px 40px;color:#1f2328;background:#f6f8fa}
+h1{margin:0 0 4px}h2{margin-top:32px;border-bottom:1px solid #d0d7de;padding-bottom:6px}
+.muted{color:#656d76}.card{background:#fff;border:1px solid #d0d7de;border-radius:6px;padding:16px 20px;margin:12px 0}
+table{border-collapse:collapse;width:100%}td,th{text-align:left;padding:6px 10px;border-bottom:1px solid #eaeef2;vertical-align:top}
+.sev{display:inline-block;min-width:64px;text-align:center;padding:2px 8px;border-radius:12px;color:#fff;font-size:12px;font-weight:600;text-transform:uppercase}
+.critical{background:#8b0000}.high{background:#d1242f}.medium{background:#d4a72c}.low{background:#1a7f37}.info{background:#0969da}.unknown,.none{background:#6e7781}
+.bar{height:14px;border-radius:3px;display:inline-block;vertical-align:middle}.track{width:320px;background:#eaeef2;border-radius:3px;display:inline-block}
+pre{background:#f6f8fa;border:1px solid #d0d7de;border-radius:6px;padding:10px;overflow:auto;max-height:420px;white-space:pre-wrap;word-break:break-all;font-size:12px}
+details summary{cursor:pointer;font-weight:600;margin:8px 0}
+</style>
+</head>
+<body>
+<h1>扫描报告</h1>
+<div class="muted">任务 {{.Task.Name}}（{{.Task.ID}}） · 执行 {{.Run.ID}} · 生成于 {{.GeneratedAt}}</div>
+
+<h2>执行摘要</h2>
+<div class="card">
+<table>
+<tr><th>风险等级</th><td><span class="sev {{.Risk}}">{{.Risk}}</span></td></tr>
+<tr><th>漏洞总数</th><td>{{.Total}}</td></tr>
+<tr><th>受影响主机</th><td>{{len .Hosts}}</td></tr>
+<tr><th>扫描目标数</th><td>{{.Targets}}</td></tr>
+<tr><th>扫描配置</th><td>{{.Profile}}</td></tr>
+<tr><th>触发方式</th><td>{{.Run.TriggeredBy}}</td></tr>
+<tr><th>执行状态</th><td>{{.Run.Status}}</td></tr>
+{{if .Run.StartedAt}}<tr><th>开始时间</th><td>{{.Run.StartedAt.Format "2006-01-02 15:04:05"}}</td></tr>{{end}}
+{{if .Run.FinishedAt}}<tr><th>结束时间</th><td>{{.Run.FinishedAt.Format "2006-01-02 15:04:05"}}</td></tr>{{end}}
+{{if .Duration}}<tr><th>耗时</th><td>{{.Duration}}</td></tr>{{end}}
+</table>
+</div>
+
+<h2>严重等级分布</h2>
+<div class="card">
+<table>
+{{range .Severities}}<tr><td style="width:100px"><span class="sev {{.Severity}}">{{.Severity}}</span></td><td style="width:60px">{{.Count}}</td><td><span class="track"><span class="bar {{.Severity}}" style="width:{{pct .Percent}}%"></span></span> <span class="muted">{{pct .Percent}}%</span></td></tr>
+{{end}}</table>
+</div>
+
+{{if .Hosts}}<h2>主机概览</h2>
+<div class="card">
+<table>
+<tr><th>主机</th><th>最高等级</th><th>总数</th>{{range (index .Hosts 0).Severities}}<th>{{.Severity}}</th>{{end}}</tr>
+{{range .Hosts}}<tr><td><a href="#{{.Anchor}}">{{.Host}}</a></td><td><span class="sev {{.Worst}}">{{.Worst}}</span></td><td>{{.Total}}</td>{{range .Severities}}<td>{{.Count}}</td>{{end}}</tr>
+{{end}}</table>
+</div>
+
+<h2>漏洞详情</h2>
+{{else}}<p class="muted">本次执行没有发现漏洞。</p>
+{{end}}{{end}}
+
+{{define "host"}}<h3 id="{{.Anchor}}">{{if .Host}}{{.Host}}{{else}}(未知主机){{end}} <span class="muted">{{.Total}} 个</span></h3>
+{{end}}
+
+{{define "finding"}}{{$ev := .Event}}<div class="card">
+<div><span class="sev {{.Severity}}">{{.Severity}}</span> <strong>{{.Title}}</strong> <span class="muted">{{.TemplateID}}</span></div>
+<table>
+<tr><th style="width:120px">命中位置</th><td>{{.MatchedAt}}</td></tr>
+{{if .MatcherName}}<tr><th>匹配器</th><td>{{.MatcherName}}</td></tr>{{end}}
+{{if .Type}}<tr><th>协议</th><td>{{.Type}}</td></tr>{{end}}
+{{if .IP}}<tr><th>IP</th><td>{{.IP}}</td></tr>{{end}}
+<tr><th>发现时间</th><td>{{.CreatedAt.Format "2006-01-02 15:04:05"}}</td></tr>
+{{if $ev.Info.Description}}<tr><th>描述</th><td>{{$ev.Info.Description}}</td></tr>{{end}}
+{{if $ev.Info.Remediation}}<tr><th>修复建议</th><td>{{$ev.Info.Remediation}}</td></tr>{{end}}
+{{with refs $ev}}<tr><th>参考链接</th><td>{{range .}}<div><a href="{{.}}" rel="noreferrer">{{.}}</a></div>{{end}}</td></tr>{{end}}
+{{if $ev.ExtractedResults}}<tr><th>提取结果</th><td>{{range $ev.ExtractedResults}}<div><code>{{.}}</code></div>{{end}}</td></tr>{{end}}
+</table>
+{{if $ev.Request}}<details><summary>请求</summary><pre>{{$ev.Request}}</pre></details>{{end}}
+{{if $ev.Response}}<details><summary>响应</summary><pre>{{$ev.Response}}</pre></details>{{end}}
+{{if $ev.CURLCommand}}<details><summary>curl</summary><pre>{{$ev.CURLCommand}}</pre></details>{{end}}
+</div>
+{{end}}
+
+{{define "end"}}<p class="muted">由 DevSecOps 平台生成</p>
+</body>
+</html>
+{{end}}`))
+
+// htmlRenderer 生成单文件 HTML 报告，样式内联，离线可直接打开
+type htmlRenderer struct {
+	f *os.File
+	w *bufio.Writer
+}
+
+func newHTMLRenderer(f *os.File) *htmlRenderer {
+	return &htmlRenderer{f: f, w: bufio.NewWriter(f)}
+}
+
+func (r *htmlRenderer) begin(s *Summary) error {
+	return htmlTmpl.ExecuteTemplate(r.w, "begin", s)
+}
+
+func (r *htmlRenderer) host(h *HostSummary) error {
+	return htmlTmpl.ExecuteTemplate(r.w, "host", h)
+}
+
+func (r *htmlRenderer) finding(f *Finding) error {
+	return htmlTmpl.ExecuteTemplate(r.w, "finding", &Finding{Finding: f.Finding, Event: event(f)})
+}
+
+func (r *htmlRenderer) close() error {
+	err := htmlTmpl.ExecuteTemplate(r.w, "end", nil)
+	if ferr := r.w.Flush(); err == nil {
+		err = ferr
+	}
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ---------------- Markdown ----------------
+
+// markdownRenderer 生成 Markdown 报告，每条 finding 的详情沿用 nuclei markdown 导出的格式
+type markdownRenderer struct {
+	f *os.File
+	w *bufio.Writer
+}
+
+func newMarkdownRenderer(f *os.File) *markdownRenderer {
+	return &markdownRenderer{f: f, w: bufio.NewWriter(f)}
+}
+
+func (r *markdownRenderer) begin(s *Summary) error {
+	w := r.w
+	fmt.Fprintf(w, "# 扫描报告：%s\n\n", mdEscape(s.Task.Name))
+	fmt.Fprintf(w, "- 任务：%s\n- 执行：%s\n- 生成时间：%s\n\n", s.Task.ID, s.Run.ID, s.GeneratedAt)
+
+	fmt.Fprintf(w, "## 执行摘要\n\n")
+	rows := [][]string{
+		{"风险等级", strings.ToUpper(s.Risk)},
+		{"漏洞总数", strconv.FormatInt(s.Total, 10)},
+		{"受影响主机", strconv.Itoa(len(s.Hosts))},
+		{"扫描目标数", strconv.Itoa(s.Targets)},
+		{"扫描配置", s.Profile},
+		{"触发方式", s.Run.TriggeredBy},
+		{"执行状态", s.Run.Status},
+	}
+	if s.Run.StartedAt != nil {
+		rows = append(rows, []string{"开始时间", s.Run.StartedAt.Format("2006-01-02 15:04:05")})
+	}
+	if s.Run.FinishedAt != nil {
+		rows = append(rows, []string{"结束时间", s.Run.FinishedAt.Format("2006-01-02 15:04:05")})
+	}
+	if s.Duration != "" {
+		rows = append(rows, []string{"耗时", s.Duration})
+	}
+	writeMarkdownTable(w, []string{"项目", "值"}, rows)
+
+	fmt.Fprintf(w, "## 严重等级分布\n\n")
+	rows = rows[:0]
+	for _, sc := range s.Severities {
+		rows = append(rows, []string{sc.Severity, strconv.FormatInt(sc.Count, 10), strconv.FormatFloat(sc.Percent, 'f', 1, 64) + "%"})
+	}
+	writeMarkdownTable(w, []string{"等级", "数量", "占比"}, rows)
+
+	if len(s.Hosts) == 0 {
+		fmt.Fprintf(w, "本次执行没有发现漏洞。\n")
+		return nil
+	}
+	fmt.Fprintf(w, "## 主机概览\n\n")
+	headers := []string{"主机", "最高等级", "总数"}
+	for _, sc := range s.Hosts[0].Severities {
+		headers = append(headers, sc.Severity)
+	}
+	rows = rows[:0]
+	for _, h := range s.Hosts {
+		row := []string{fmt.Sprintf("[%s](#%s)", mdEscape(h.Host), h.Anchor), h.Worst, strconv.FormatInt(h.Total, 10)}
+		for _, sc := range h.Severities {
+			row = append(row, strconv.FormatInt(sc.Count, 10))
+		}
+		rows = append(rows, row)
+	}
+	writeMarkdownTable(w, headers, rows)
+	fmt.Fprintf(w, "## 漏洞详情\n\n")
+	return nil
+}
+
+func (r *markdownRenderer) host(h *HostSummary) error {
+	name := h.Host
+	if name == "" {
+		name = "(未知主机)"
+	}
+	_, err := fmt.Fprintf(r.w, "<a id=\"%s\"></a>\n\n### %s\n\n", h.Anchor, mdEscape(name))
+	return err
+}
+
+func (r *markdownRenderer) finding(f *Finding) error {
+	fmt.Fprintf(r.w, "#### [%s] %s\n\n", strings.ToUpper(f.Severity), mdEscape(f.Title))
+	r.w.WriteString(format.CreateReportDescription(event(f), util.MarkdownFormatter{}, false))
+	_, err := r.w.WriteString("\n\n---\n\n")
+	return err
+}
+
+func (r *markdownRenderer) close() error {
+	err := r.w.Flush()
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func writeMarkdownTable(w *bufio.Writer, headers []string, rows [][]string) {
+	w.WriteString(util.CreateTableHeader(headers...))
+	for _, row := range rows {
+		w.WriteString(util.CreateTableRow(row...))
+	}
+	w.WriteString("\n")
+}
+
+var mdReplacer = strings.NewReplacer("|", "\\|", "\n", " ", "\r", "", "<", "&lt;", ">", "&gt;", "[", "\\[", "]", "\\]")
+
+// mdEscape 转义表格与标题中的 Markdown 特殊字符
+func mdEscape(s string) string {
+	return mdReplacer.Replace(s)
+}
+
+// ---------------- CSV ----------------
+
+var csvHeader = []string{"severity", "templateId", "title", "host", "port", "matchedAt", "matcherName", "type", "ip", "extractedResults", "createdAt"}
+
+// csvRenderer 每条 finding 一行，不含请求与响应
+type csvRenderer struct {
+	f *os.File
+	w *csv.Writer
+}
+
+func newCSVRenderer(f *os.File) *csvRenderer {
+	// 写入 BOM，Excel 打开时按 UTF-8 识别中文
+	f.WriteString("\xef\xbb\xbf")
+	return &csvRenderer{f: f, w: csv.NewWriter(f)}
+}
+
+func (r *csvRenderer) begin(*Summary) error {
+	return r.w.Write(csvHeader)
+}
+
+func (r *csvRenderer) host(*HostSummary) error { return nil }
+
+func (r *csvRenderer) finding(f *Finding) error {
+	var extracted []string
+	_ = json.Unmarshal([]byte(f.ExtractedResults), &extracted)
+	port := ""
+	if f.Port > 0 {
+		port = strconv.Itoa(f.Port)
+	}
+	row := []string{
+		f.Severity, f.TemplateID, f.Title, f.Host, port, f.MatchedAt, f.MatcherName, f.Type, f.IP,
+		strings.Join(extracted, "; "), f.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	for i := range row {
+		row[i] = csvCell(row[i])
+	}
+	return r.w.Write(row)
+}
+
+func (r *csvRenderer) close() error {
+	r.w.Flush()
+	err := r.w.Error()
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// csvCell 以 = + - @ 开头的单元格加 ' 前缀，避免表格软件当作公式执行
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,323 @@
+/**
+ * 执行报告：把一次执行的 findings 渲染成 HTML / Markdown / SARIF / CSV 文件，保存后供下载
+ * findings 较多时放入 Redis 队列由后台协程生成
+ */
+package report
+
+import (
+	"context"
+	"demo/db/mysqldb"
+	"demo/db/redisdb"
+	"demo/models"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 报告格式
+const (
+	FormatHTML     = "html"
+	FormatMarkdown = "md"
+	FormatSARIF    = "sarif"
+	FormatCSV      = "csv"
+)
+
+// 报告状态
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusError   = "error"
+)
+
+const (
+	// 待生成的报告 ID 队列
+	queueKey = "report:queue"
+	// findings 不超过该数量时在请求中直接生成
+	syncLimit = 500
+)
+
+var extensions = map[string]string{
+	FormatHTML:     ".html",
+	FormatMarkdown: ".md",
+	FormatSARIF:    ".sarif",
+	FormatCSV:      ".csv",
+}
+
+var contentTypes = map[string]string{
+	FormatHTML:     "text/html; charset=utf-8",
+	FormatMarkdown: "text/markdown; charset=utf-8",
+	FormatSARIF:    "application/sarif+json",
+	FormatCSV:      "text/csv; charset=utf-8",
+}
+
+// dir 报告文件目录，可通过环境变量 DAST_REPORT_DIR 修改
+func dir() string {
+	if d := os.Getenv("DAST_REPORT_DIR"); d != "" {
+		return d
+	}
+	return "./reports"
+}
+
+// Init 把上次进程退出时仍在生成的报告标记为失败，并启动后台生成协程
+func Init() {
+	res := mysqldb.DB.Model(&models.Report{}).Where("status = ?", StatusRunning).
+		Updates(map[string]interface{}{"status": StatusError, "message": "interrupted by restart", "finished_at": time.Now()})
+	if res.Error != nil {
+		log.Printf("[report.Init] mark interrupted reports failed err=%v", res.Error)
+	} else if res.RowsAffected > 0 {
+		log.Printf("[report.Init] interrupted reports marked error count=%d", res.RowsAffected)
+	}
+	go loop(context.Background())
+}
+
+func loop(ctx context.Context) {
+	for {
+		res, err := redisdb.Client.BLPop(ctx, 5*time.Second, queueKey).Result()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		if len(res) < 2 {
+			continue
+		}
+		id, err := strconv.ParseUint(res[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		run(id)
+	}
+}
+
+// run 生成一份报告并更新状态；只处理仍为 pending 的报告，避免重复生成
+func run(id uint64) {
+	res := mysqldb.DB.Model(&models.Report{}).Where("id = ? AND status = ?", id, StatusPending).Update("status", StatusRunning)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return
+	}
+	var r models.Report
+	if err := mysqldb.DB.First(&r, id).Error; err != nil {
+		return
+	}
+
+	start := time.Now()
+	count, err := generate(&r)
+	now := time.Now()
+	updates := map[string]interface{}{"finished_at": now, "findings": count}
+	if err != nil {
+		log.Printf("[report] generate report failed id=%d run=%s format=%s err=%v", r.ID, r.RunID, r.Format, err)
+		updates["status"] = StatusError
+		updates["message"] = err.Error()
+		_ = os.Remove(r.Path)
+	} else {
+		updates["status"] = StatusDone
+		if fi, err := os.Stat(r.Path); err == nil {
+			updates["size"] = fi.Size()
+		}
+		log.Printf("[report] report generated id=%d run=%s format=%s findings=%d cost=%s", r.ID, r.RunID, r.Format, count, now.Sub(start))
+	}
+	res = mysqldb.DB.Model(&models.Report{}).Where("id = ?", r.ID).Updates(updates)
+	if res.Error != nil {
+		log.Printf("[report] update report status failed id=%d err=%v", r.ID, res.Error)
+	} else if res.RowsAffected == 0 {
+		// 生成期间执行或任务已被删除，文件不再有记录引用
+		_ = os.Remove(r.Path)
+		_ = os.Remove(filepath.Dir(r.Path))
+	}
+}
+
+// Create - 为一次执行生成报告
+// POST /api/report/create {"runId":"...","format":"html"}
+// findings 较少时直接生成并返回 200，否则返回 202，可通过 /api/report/get 查询状态
+func Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			RunID  string `json:"runId"`
+			Format string `json:"format"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.RunID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing runId"})
+			return
+		}
+		format := strings.ToLower(strings.TrimSpace(req.Format))
+		if format == "" {
+			format = FormatHTML
+		}
+		if format == "markdown" {
+			format = FormatMarkdown
+		}
+		ext, ok := extensions[format]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format (html/md/sarif/csv)"})
+			return
+		}
+
+		var taskRun models.TaskRun
+		if err := mysqldb.DB.Omit("targets", "config").First(&taskRun, "id = ?", req.RunID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "run not found"})
+			return
+		}
+		if taskRun.Status == "running" {
+			c.JSON(http.StatusConflict, gin.H{"error": "run is still in progress"})
+			return
+		}
+		var count int64
+		if err := mysqldb.DB.Model(&models.Finding{}).Where("run_id = ?", taskRun.ID).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		r := models.Report{
+			TaskID:   taskRun.TaskID,
+			RunID:    taskRun.ID,
+			Format:   format,
+			Status:   StatusPending,
+			Findings: count,
+			Creator:  c.GetString("username"),
+		}
+		if err := mysqldb.DB.Create(&r).Error; err != nil {
+			log.Printf("[report.Create] db create report failed run=%s err=%v", taskRun.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		r.Path = filepath.Join(dir(), taskRun.ID, fmt.Sprintf("report-%d%s", r.ID, ext))
+		if err := mysqldb.DB.Model(&r).Update("path", r.Path).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if count <= syncLimit {
+			run(r.ID)
+			_ = mysqldb.DB.First(&r, r.ID).Error
+			c.JSON(http.StatusOK, gin.H{"report": r})
+			return
+		}
+		if err := redisdb.Client.RPush(redisdb.Ctx, queueKey, r.ID).Err(); err != nil {
+			log.Printf("[report.Create] enqueue report failed id=%d err=%v", r.ID, err)
+			_ = mysqldb.DB.Model(&r).Updates(map[string]interface{}{"status": StatusError, "message": "enqueue failed: " + err.Error()}).Error
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "enqueue report failed: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"message": "报告生成中", "report": r})
+	}
+}
+
+// List - 报告列表
+// GET /api/report/list?runId=&taskId=
+func List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := mysqldb.DB.Model(&models.Report{})
+		if runId := c.Query("runId"); runId != "" {
+			db = db.Where("run_id = ?", runId)
+		}
+		if taskId := c.Query("taskId"); taskId != "" {
+			db = db.Where("task_id = ?", taskId)
+		}
+		list := []models.Report{}
+		if err := db.Order("id desc").Limit(200).Find(&list).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"reports": list})
+	}
+}
+
+// Get - 查询报告状态
+// GET /api/report/get?id=
+func Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		r, ok := load(c, c.Query("id"))
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"report": r})
+	}
+}
+
+// Download - 下载报告文件；HTML 报告带 inline=1 时直接在浏览器中打开
+// GET /api/report/download?id=&inline=1
+func Download() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		r, ok := load(c, c.Query("id"))
+		if !ok {
+			return
+		}
+		if r.Status != StatusDone {
+			c.JSON(http.StatusConflict, gin.H{"error": "report is " + r.Status, "report": r})
+			return
+		}
+		if _, err := os.Stat(r.Path); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "report file missing"})
+			return
+		}
+		c.Header("Content-Type", contentTypes[r.Format])
+		if c.Query("inline") == "1" && r.Format == FormatHTML {
+			c.File(r.Path)
+			return
+		}
+		c.FileAttachment(r.Path, fmt.Sprintf("dast-report-%s%s", r.RunID, extensions[r.Format]))
+	}
+}
+
+// Delete - 删除报告及其文件
+// POST /api/report/delete {"id":1}
+func Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			ID uint64 `json:"id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.ID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
+			return
+		}
+		var r models.Report
+		if err := mysqldb.DB.First(&r, req.ID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+			return
+		}
+		if r.Status == StatusRunning {
+			c.JSON(http.StatusConflict, gin.H{"error": "report is being generated"})
+			return
+		}
+		if r.Path != "" {
+			if err := os.Remove(r.Path); err != nil && !os.IsNotExist(err) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "remove report file failed: " + err.Error()})
+				return
+			}
+		}
+		if err := mysqldb.DB.Delete(&r).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "删除成功", "id": r.ID})
+	}
+}
+
+func load(c *gin.Context, raw string) (*models.Report, bool) {
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	var r models.Report
+	if err := mysqldb.DB.First(&r, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return nil, false
+	}
+	return &r, true
+}
@@ -0,0 +1,194 @@
+package report
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/catalog/config"
+)
+
+// SARIF 2.1.0 报告：results 按批流式写出，rules（每个模板一条）在结尾写入 tool.driver
+// nuclei 自带的 sarif exporter 会把全部结果放在内存中，且规则序号在第二个模板起会错位，这里单独实现
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifRule struct {
+	ID               string                 `json:"id"`
+	Name             string                 `json:"name,omitempty"`
+	ShortDescription sarifMessage           `json:"shortDescription"`
+	FullDescription  *sarifMessage          `json:"fullDescription,omitempty"`
+	Help             *sarifMessage          `json:"help,omitempty"`
+	HelpURI          string                 `json:"helpUri,omitempty"`
+	Properties       map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+	} `json:"physicalLocation"`
+	Message *sarifMessage `json:"message,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID              string                 `json:"ruleId"`
+	RuleIndex           int                    `json:"ruleIndex"`
+	Level               string                 `json:"level"`
+	Message             sarifMessage           `json:"message"`
+	Locations           []sarifLocation        `json:"locations"`
+	PartialFingerprints map[string]string      `json:"partialFingerprints,omitempty"`
+	Properties          map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifRenderer struct {
+	f       *os.File
+	w       *bufio.Writer
+	enc     *json.Encoder
+	summary *Summary
+	rules   []sarifRule
+	index   map[string]int
+	count   int
+}
+
+func newSARIFRenderer(path string) (*sarifRenderer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &sarifRenderer{f: f, w: w, enc: enc, index: map[string]int{}}, nil
+}
+
+func (r *sarifRenderer) begin(s *Summary) error {
+	r.summary = s
+	_, err := r.w.WriteString(`{"$schema":"` + sarifSchema + `","version":"2.1.0","runs":[{"results":[`)
+	return err
+}
+
+func (r *sarifRenderer) host(*HostSummary) error { return nil }
+
+func (r *sarifRenderer) finding(f *Finding) error {
+	ev := event(f)
+	idx, ok := r.index[f.TemplateID]
+	if !ok {
+		idx = len(r.rules)
+		r.index[f.TemplateID] = idx
+		rule := sarifRule{
+			ID:               f.TemplateID,
+			Name:             ev.Info.Name,
+			ShortDescription: sarifMessage{Text: f.Title},
+			Properties: map[string]interface{}{
+				"tags": append([]string{"security"}, ev.Info.Tags.ToSlice()...),
+				// GitHub code scanning 按该值划分严重等级
+				"security-severity": securitySeverity(f.Severity),
+			},
+		}
+		if ev.Info.Description != "" {
+			rule.FullDescription = &sarifMessage{Text: ev.Info.Description}
+		}
+		if ev.Info.Remediation != "" {
+			rule.Help = &sarifMessage{Text: ev.Info.Remediation}
+		}
+		if refs := references(ev); len(refs) > 0 {
+			rule.HelpURI = refs[0]
+		}
+		r.rules = append(r.rules, rule)
+	}
+
+	loc := sarifLocation{}
+	loc.PhysicalLocation.ArtifactLocation.URI = firstNonEmpty(f.MatchedAt, f.Target, f.Host)
+	res := sarifResult{
+		RuleID:    f.TemplateID,
+		RuleIndex: idx,
+		Level:     sarifLevel(f.Severity),
+		Message:   sarifMessage{Text: f.Title + " (" + f.TemplateID + ") found on " + firstNonEmpty(f.Host, f.Target)},
+		Locations: []sarifLocation{loc},
+		PartialFingerprints: map[string]string{
+			"findingKey/v1": f.TemplateID + "|" + f.MatcherName + "|" + f.MatchedAt,
+		},
+		Properties: map[string]interface{}{"severity": f.Severity},
+	}
+	if f.MatcherName != "" {
+		res.Properties["matcherName"] = f.MatcherName
+	}
+	if len(ev.ExtractedResults) > 0 {
+		res.Properties["extractedResults"] = ev.ExtractedResults
+	}
+	if r.count > 0 {
+		r.w.WriteString(",")
+	}
+	r.count++
+	return r.enc.Encode(res)
+}
+
+func (r *sarifRenderer) close() error {
+	driver := map[string]interface{}{
+		"name":            "Nuclei",
+		"organization":    "ProjectDiscovery",
+		"semanticVersion": strings.TrimPrefix(config.Version, "v"),
+		"informationUri":  "https://github.com/projectdiscovery/nuclei",
+		"rules":           r.rules,
+	}
+	if r.rules == nil {
+		driver["rules"] = []sarifRule{}
+	}
+	r.w.WriteString(`],"tool":{"driver":`)
+	err := r.enc.Encode(driver)
+	if err == nil && r.summary != nil {
+		r.w.WriteString(`},"automationDetails":`)
+		err = r.enc.Encode(map[string]string{"id": r.summary.Task.ID + "/" + r.summary.Run.ID})
+		r.w.WriteString("}]}\n")
+	}
+	if ferr := r.w.Flush(); err == nil {
+		err = ferr
+	}
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// sarifLevel 严重等级映射为 SARIF level
+func sarifLevel(sev string) string {
+	switch sev {
+	case "critical", "high":
+		return "error"
+	case "medium":
+		return "warning"
+	case "low", "info":
+		return "note"
+	}
+	return "none"
+}
+
+func securitySeverity(sev string) string {
+	switch sev {
+	case "critical":
+		return "9.5"
+	case "high":
+		return "8.0"
+	case "medium":
+		return "5.0"
+	case "low":
+		return "2.0"
+	}
+	return "0.0"
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
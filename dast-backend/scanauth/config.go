@@ -0,0 +1,259 @@
+package scanauth
+
+import (
+	"demo/secret"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/authprovider/authx"
+)
+
+// 认证类型
+const (
+	TypeHeader  = "header"
+	TypeCookie  = "cookie"
+	TypeBearer  = "bearer"
+	TypeBasic   = "basic"
+	TypeQuery   = "query"
+	TypeDynamic = "dynamic"
+)
+
+// 平台类型与 nuclei secrets 文件中 type 的对应关系
+var authxTypes = map[string]authx.AuthType{
+	TypeHeader: authx.HeadersAuth,
+	TypeCookie: authx.CookiesAuth,
+	TypeBearer: authx.BearerTokenAuth,
+	TypeBasic:  authx.BasicAuth,
+	TypeQuery:  authx.QueryAuth,
+}
+
+type kv struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type cookie struct {
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value,omitempty"`
+	Raw   string `json:"raw,omitempty"` // Set-Cookie 格式，如 "session=abc; Path=/"
+}
+
+// credential 一组凭据，字段与 nuclei secrets 文件中的 static 一节相同
+// 动态登录流程中可以使用 {{name}} 引用登录模板提取的值
+type credential struct {
+	Type     string   `json:"type,omitempty"` // 仅用于动态流程的 secrets
+	Headers  []kv     `json:"headers,omitempty"`
+	Cookies  []cookie `json:"cookies,omitempty"`
+	Params   []kv     `json:"params,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	Token    string   `json:"token,omitempty"`
+}
+
+// Config 加密保存的认证配置
+// 静态类型只使用 credential 中对应的字段；dynamic 先执行登录模板（template 为模板库中的相对路径，
+// 或 templateContent 直接给出模板内容），variables 传给模板，提取的值填入 secrets 中的 {{name}}
+type Config struct {
+	credential
+	Template        string       `json:"template,omitempty"`
+	TemplateContent string       `json:"templateContent,omitempty"`
+	Input           string       `json:"input,omitempty"` // 登录模板的目标，默认取模板中的地址
+	Variables       []kv         `json:"variables,omitempty"`
+	Secrets         []credential `json:"secrets,omitempty"`
+}
+
+// placeholder 引用登录模板提取值的字段不是凭据本身，不需要隐藏
+func placeholder(v string) bool {
+	return strings.Contains(v, "{{")
+}
+
+func maskValue(v *string) {
+	if *v != "" && !placeholder(*v) {
+		*v = secret.Masked
+	}
+}
+
+func (c *credential) mask() {
+	for i := range c.Headers {
+		maskValue(&c.Headers[i].Value)
+	}
+	for i := range c.Cookies {
+		maskValue(&c.Cookies[i].Value)
+		maskValue(&c.Cookies[i].Raw)
+	}
+	for i := range c.Params {
+		maskValue(&c.Params[i].Value)
+	}
+	maskValue(&c.Password)
+	maskValue(&c.Token)
+}
+
+// Masked 返回凭据被替换为 secret.Masked 的副本，用于接口返回；登录变量的值全部隐藏
+func (c Config) Masked() Config {
+	c.credential = c.credential.clone()
+	c.credential.mask()
+	c.Variables = append([]kv(nil), c.Variables...)
+	for i := range c.Variables {
+		maskValue(&c.Variables[i].Value)
+	}
+	secrets := make([]credential, len(c.Secrets))
+	for i, s := range c.Secrets {
+		secrets[i] = s.clone()
+		secrets[i].mask()
+	}
+	c.Secrets = secrets
+	return c
+}
+
+func (c credential) clone() credential {
+	c.Headers = append([]kv(nil), c.Headers...)
+	c.Cookies = append([]cookie(nil), c.Cookies...)
+	c.Params = append([]kv(nil), c.Params...)
+	return c
+}
+
+// restore 字段为 secret.Masked 时沿用原值
+func restore(v *string, old string) {
+	if *v == secret.Masked {
+		*v = old
+	}
+}
+
+// mergeKV 按 key 找到原来的同名项，沿用被隐藏的值
+func mergeKV(items, old []kv) {
+	for i := range items {
+		for _, o := range old {
+			if strings.EqualFold(o.Key, items[i].Key) {
+				restore(&items[i].Value, o.Value)
+				break
+			}
+		}
+	}
+}
+
+func (c *credential) merge(old *credential) {
+	mergeKV(c.Headers, old.Headers)
+	mergeKV(c.Params, old.Params)
+	for i := range c.Cookies {
+		for j, o := range old.Cookies {
+			if (c.Cookies[i].Key != "" && strings.EqualFold(o.Key, c.Cookies[i].Key)) || (c.Cookies[i].Key == "" && i == j) {
+				restore(&c.Cookies[i].Value, o.Value)
+				restore(&c.Cookies[i].Raw, o.Raw)
+				break
+			}
+		}
+	}
+	restore(&c.Password, old.Password)
+	restore(&c.Token, old.Token)
+}
+
+// Merge 更新配置时，传回 secret.Masked 的字段沿用 old 中的原值
+func (c *Config) Merge(old *Config) {
+	c.credential.merge(&old.credential)
+	mergeKV(c.Variables, old.Variables)
+	for i := range c.Secrets {
+		if i < len(old.Secrets) {
+			c.Secrets[i].merge(&old.Secrets[i])
+		}
+	}
+}
+
+// secret 转为 nuclei 的 Secret
+func (c *credential) secret(typ authx.AuthType, domains, domainsRegex []string) *authx.Secret {
+	s := &authx.Secret{
+		Type:         string(typ),
+		Domains:      domains,
+		DomainsRegex: domainsRegex,
+		Username:     c.Username,
+		Password:     c.Password,
+		Token:        c.Token,
+	}
+	for _, h := range c.Headers {
+		s.Headers = append(s.Headers, authx.KV{Key: h.Key, Value: h.Value})
+	}
+	for _, ck := range c.Cookies {
+		s.Cookies = append(s.Cookies, authx.Cookie{Key: ck.Key, Value: ck.Value, Raw: ck.Raw})
+	}
+	for _, p := range c.Params {
+		s.Params = append(s.Params, authx.KV{Key: p.Key, Value: p.Value})
+	}
+	return s
+}
+
+// staticSecret 静态类型的凭据，按 nuclei 的规则校验
+func (c *Config) staticSecret(typ string, domains, domainsRegex []string) (*authx.Secret, error) {
+	s := c.credential.secret(authxTypes[typ], domains, domainsRegex)
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// dynamic 动态登录流程，templatePath 为登录模板的实际路径
+func (c *Config) dynamic(domains, domainsRegex []string, templatePath string) (*authx.Dynamic, error) {
+	if len(c.Secrets) == 0 {
+		return nil, errors.New("dynamic auth requires secrets")
+	}
+	d := &authx.Dynamic{
+		TemplatePath: templatePath,
+		Input:        c.Input,
+	}
+	for _, v := range c.Variables {
+		d.Variables = append(d.Variables, authx.KV{Key: v.Key, Value: v.Value})
+	}
+	for i := range c.Secrets {
+		s := &c.Secrets[i]
+		typ, ok := authxTypes[strings.ToLower(s.Type)]
+		if !ok {
+			return nil, fmt.Errorf("secrets[%d]: invalid type %q (header/cookie/bearer/basic/query)", i, s.Type)
+		}
+		d.Secrets = append(d.Secrets, s.secret(typ, domains, domainsRegex))
+	}
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Validate 校验认证类型、生效范围与配置；dynamic 的 template 必须位于模板目录内
+func Validate(typ string, domains, domainsRegex []string, c *Config, templateDir string) error {
+	if len(domains) == 0 && len(domainsRegex) == 0 {
+		return errors.New("domains or domainsRegex is required")
+	}
+	for _, r := range domainsRegex {
+		if _, err := regexp.Compile(r); err != nil {
+			return fmt.Errorf("invalid domainsRegex %q: %v", r, err)
+		}
+	}
+	if typ != TypeDynamic {
+		if _, ok := authxTypes[typ]; !ok {
+			return fmt.Errorf("invalid type %q (header/cookie/bearer/basic/query/dynamic)", typ)
+		}
+		_, err := c.staticSecret(typ, domains, domainsRegex)
+		return err
+	}
+	switch {
+	case c.Template == "" && c.TemplateContent == "":
+		return errors.New("dynamic auth requires template or templateContent")
+	case c.Template != "" && c.TemplateContent != "":
+		return errors.New("template and templateContent are mutually exclusive")
+	case c.Template != "":
+		if _, err := libraryPath(templateDir, c.Template); err != nil {
+			return err
+		}
+	}
+	_, err := c.dynamic(domains, domainsRegex, "login.yaml")
+	return err
+}
+
+// libraryPath 模板库中的登录模板路径，不允许跳出模板目录
+func libraryPath(dir, rel string) (string, error) {
+	p := filepath.Join(dir, filepath.Clean("/"+rel))
+	if ext := filepath.Ext(p); ext != ".yaml" && ext != ".yml" {
+		return "", fmt.Errorf("invalid template %q: must be a .yaml file", rel)
+	}
+	return filepath.Abs(p)
+}
@@ -0,0 +1,131 @@
+package scanauth
+
+import (
+	"demo/secret"
+	"encoding/base64"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// 短于该长度的凭据值不做全文替换，避免误伤普通内容
+const minRedactLen = 4
+
+// Redactor 从命中结果与执行日志中去掉凭据：
+//   - 已知的凭据值（静态凭据、登录变量）全文替换；
+//   - 动态登录得到的值事先未知，按名称替换：配置的请求头整行、cookie / query 参数的 name=value。
+type Redactor struct {
+	values   []string
+	patterns []*regexp.Regexp
+}
+
+func newRedactor() *Redactor {
+	return &Redactor{}
+}
+
+func (r *Redactor) addValue(v string) {
+	if len(v) < minRedactLen || placeholder(v) {
+		return
+	}
+	r.values = append(r.values, v)
+}
+
+// addHeader 替换 "Name: value" 中的值（原始请求中的整行，curl 命令中引号内的部分）
+func (r *Redactor) addHeader(name string) {
+	if name == "" || placeholder(name) {
+		return
+	}
+	r.patterns = append(r.patterns, regexp.MustCompile(`(?im)(^|[\s'"])(`+regexp.QuoteMeta(name)+`:[ \t]*)[^\r\n'"]*`))
+}
+
+// addParam 替换 cookie 与 query 参数中的 "name=value"
+func (r *Redactor) addParam(name string) {
+	if name == "" || placeholder(name) {
+		return
+	}
+	r.patterns = append(r.patterns, regexp.MustCompile(`(^|[^\w.-])(`+regexp.QuoteMeta(name)+`=)[^;&\s"',#]+`))
+}
+
+func (r *Redactor) addCredential(typ string, c *credential) {
+	switch typ {
+	case TypeBearer, TypeBasic:
+		r.addHeader("Authorization")
+	}
+	for _, h := range c.Headers {
+		r.addHeader(h.Key)
+		r.addValue(h.Value)
+	}
+	for _, ck := range c.Cookies {
+		name, value := ck.Key, ck.Value
+		if ck.Raw != "" {
+			pair := strings.SplitN(strings.SplitN(strings.TrimPrefix(ck.Raw, "Set-Cookie: "), ";", 2)[0], "=", 2)
+			name = strings.TrimSpace(pair[0])
+			if len(pair) == 2 {
+				value = strings.TrimSpace(pair[1])
+			}
+		}
+		r.addParam(name)
+		r.addValue(value)
+	}
+	for _, p := range c.Params {
+		r.addParam(p.Key)
+		r.addValue(p.Value)
+	}
+	r.addValue(c.Password)
+	r.addValue(c.Token)
+	if typ == TypeBasic && c.Username != "" && c.Password != "" {
+		r.addValue(base64.StdEncoding.EncodeToString([]byte(c.Username + ":" + c.Password)))
+	}
+}
+
+func (r *Redactor) add(typ string, c *Config) {
+	if typ != TypeDynamic {
+		r.addCredential(typ, &c.credential)
+		return
+	}
+	for _, v := range c.Variables {
+		r.addValue(v.Value)
+	}
+	for i := range c.Secrets {
+		r.addCredential(strings.ToLower(c.Secrets[i].Type), &c.Secrets[i])
+	}
+}
+
+// finish 长的值优先替换，避免较短的值先替换后长值匹配不上
+func (r *Redactor) finish() {
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) })
+}
+
+// String 返回去掉凭据后的内容
+func (r *Redactor) String(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, v := range r.values {
+		s = strings.ReplaceAll(s, v, secret.Masked)
+	}
+	for _, p := range r.patterns {
+		s = p.ReplaceAllString(s, "${1}${2}"+secret.Masked)
+	}
+	return s
+}
+
+// Event 去掉命中结果中请求、响应、命中地址等字段里的凭据
+func (r *Redactor) Event(ev *output.ResultEvent) {
+	if r == nil || ev == nil {
+		return
+	}
+	for _, f := range []*string{&ev.Host, &ev.URL, &ev.Path, &ev.Matched, &ev.Request, &ev.Response, &ev.CURLCommand, &ev.Error} {
+		*f = r.String(*f)
+	}
+	for i := range ev.ExtractedResults {
+		ev.ExtractedResults[i] = r.String(ev.ExtractedResults[i])
+	}
+	for k, v := range ev.Metadata {
+		if s, ok := v.(string); ok {
+			ev.Metadata[k] = r.String(s)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+/**
+ * 认证扫描：按任务配置静态凭据（header / cookie / bearer / basic / query）或动态登录流程（nuclei authx dynamic secrets），
+ * 凭据加密保存；扫描时生成临时 secrets 文件交给 nuclei，只对配置的域名生效，结果与日志中的凭据被替换
+ */
+package scanauth
+
+import (
+	"demo/db/mysqldb"
+	"demo/models"
+	"demo/secret"
+	"demo/template"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projectdiscovery/nuclei/v3/pkg/authprovider/authx"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// decodeConfig 解密保存的配置
+func decodeConfig(a *models.ScanAuth) (*Config, error) {
+	plain, err := secret.Decrypt(a.Config)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if len(plain) > 0 {
+		if err := json.Unmarshal(plain, c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func decodeList(raw string) []string {
+	var list []string
+	if raw != "" {
+		_ = json.Unmarshal([]byte(raw), &list)
+	}
+	return list
+}
+
+func encodeList(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(list)
+	return string(data)
+}
+
+// view 返回给前端的认证配置，凭据已被 mask
+func view(a *models.ScanAuth) gin.H {
+	h := gin.H{
+		"id":           a.ID,
+		"taskId":       a.TaskID,
+		"name":         a.Name,
+		"type":         a.Type,
+		"domains":      decodeList(a.Domains),
+		"domainsRegex": decodeList(a.DomainsRegex),
+		"enabled":      a.Enabled,
+		"creator":      a.Creator,
+		"createdAt":    a.CreatedAt,
+		"updatedAt":    a.UpdatedAt,
+	}
+	if c, err := decodeConfig(a); err != nil {
+		h["configError"] = err.Error()
+	} else {
+		h["config"] = c.Masked()
+	}
+	return h
+}
+
+// List - 任务的认证配置
+// GET /api/auth/list?taskId=
+func List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		taskId := c.Query("taskId")
+		if taskId == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing taskId"})
+			return
+		}
+		var auths []models.ScanAuth
+		if err := mysqldb.DB.Where("task_id = ?", taskId).Order("id asc").Find(&auths).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		list := make([]gin.H, 0, len(auths))
+		for i := range auths {
+			list = append(list, view(&auths[i]))
+		}
+		c.JSON(http.StatusOK, gin.H{"auths": list})
+	}
+}
+
+// Save - 新建或更新认证配置（带 id 时更新）
+// POST /api/auth/save
+//
+//	{"taskId":"...","name":"api key","type":"header","domains":["app.example.com"],
+//	 "config":{"headers":[{"key":"X-API-Key","value":"..."}]},"enabled":true}
+//	{"taskId":"...","name":"login","type":"dynamic","domainsRegex":[".*\\.example\\.com"],
+//	 "config":{"template":"auth/login.yaml","variables":[{"key":"username","value":"admin"},{"key":"password","value":"..."}],
+//	           "secrets":[{"type":"cookie","cookies":[{"raw":"{{session}}"}]}]}}
+//
+// 更新时凭据传 "******" 表示不修改；config 未传时只修改名称、范围与启用状态
+func Save() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			ID           uint64   `json:"id"`
+			TaskID       string   `json:"taskId"`
+			Name         string   `json:"name"`
+			Type         string   `json:"type"`
+			Domains      []string `json:"domains"`
+			DomainsRegex []string `json:"domainsRegex"`
+			Config       *Config  `json:"config"`
+			Enabled      *bool    `json:"enabled"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+			return
+		}
+		req.Type = strings.ToLower(strings.TrimSpace(req.Type))
+
+		var a models.ScanAuth
+		if req.ID != 0 {
+			if err := mysqldb.DB.First(&a, req.ID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "auth not found"})
+				return
+			}
+			if req.Type != "" && req.Type != a.Type {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "type cannot be changed"})
+				return
+			}
+		} else {
+			if req.TaskID == "" || req.Type == "" || req.Config == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "missing taskId, type or config"})
+				return
+			}
+			if err := mysqldb.DB.Select("id").First(&models.Task{}, "id = ?", req.TaskID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+				return
+			}
+			a = models.ScanAuth{TaskID: req.TaskID, Type: req.Type, Enabled: true, Creator: c.GetString("username")}
+		}
+
+		if name := strings.TrimSpace(req.Name); name != "" {
+			a.Name = name
+		} else if a.Name == "" {
+			a.Name = a.Type
+		}
+		if req.Enabled != nil {
+			a.Enabled = *req.Enabled
+		}
+		if req.Domains != nil {
+			a.Domains = encodeList(trimList(req.Domains))
+		}
+		if req.DomainsRegex != nil {
+			a.DomainsRegex = encodeList(trimList(req.DomainsRegex))
+		}
+
+		config := req.Config
+		if config == nil {
+			old, err := decodeConfig(&a)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "decrypt config failed: " + err.Error()})
+				return
+			}
+			config = old
+		} else if a.ID != 0 {
+			old, err := decodeConfig(&a)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "decrypt config failed: " + err.Error()})
+				return
+			}
+			config.Merge(old)
+		}
+		if err := Validate(a.Type, decodeList(a.Domains), decodeList(a.DomainsRegex), config, template.Dir); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid auth config: " + err.Error()})
+			return
+		}
+		data, err := json.Marshal(config)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if a.Config, err = secret.Encrypt(data); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "encrypt config failed: " + err.Error()})
+			return
+		}
+
+		if a.ID == 0 {
+			err = mysqldb.DB.Create(&a).Error
+		} else {
+			err = mysqldb.DB.Model(&a).Updates(map[string]interface{}{
+				"name": a.Name, "domains": nullable(a.Domains), "domains_regex": nullable(a.DomainsRegex),
+				"config": a.Config, "enabled": a.Enabled,
+			}).Error
+		}
+		if err != nil {
+			log.Printf("[scanauth.Save] db save auth failed task=%s err=%v", a.TaskID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db save auth failed: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "保存成功", "auth": view(&a)})
+	}
+}
+
+func trimList(list []string) []string {
+	out := make([]string, 0, len(list))
+	for _, s := range list {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// Delete - 删除认证配置
+// POST /api/auth/delete {"id":1}
+func Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			ID uint64 `json:"id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.ID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
+			return
+		}
+		res := mysqldb.DB.Delete(&models.ScanAuth{}, req.ID)
+		if res.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": res.Error.Error()})
+			return
+		}
+		if res.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "auth not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "删除成功", "id": req.ID})
+	}
+}
+
+// Prepared 一次扫描使用的认证配置：临时 secrets 文件、登录模板与凭据替换规则
+type Prepared struct {
+	Dir      string // 临时目录，扫描结束后由 Cleanup 删除
+	File     string // 交给 nuclei LoadSecretsFromFile 的 secrets 文件
+	Count    int
+	Dynamic  int
+	Redactor *Redactor
+
+	// 登录模板的路径：执行登录时的结果不作为漏洞保存
+	loginTemplates map[string]bool
+}
+
+// Prepare 读取任务启用的认证配置并生成 nuclei secrets 文件；没有配置时返回 nil
+func Prepare(taskId string) (*Prepared, error) {
+	var auths []models.ScanAuth
+	if err := mysqldb.DB.Where("task_id = ? AND enabled = ?", taskId, true).Order("id asc").Find(&auths).Error; err != nil {
+		return nil, err
+	}
+	if len(auths) == 0 {
+		return nil, nil
+	}
+	dir, err := os.MkdirTemp("", "dast-auth-*")
+	if err != nil {
+		return nil, err
+	}
+	p := &Prepared{Dir: dir, Redactor: newRedactor(), loginTemplates: map[string]bool{}}
+	if err := p.build(taskId, auths); err != nil {
+		p.Cleanup()
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Prepared) build(taskId string, auths []models.ScanAuth) error {
+	file := &authx.Authx{
+		ID:   "dast-task-" + taskId,
+		Info: authx.AuthFileInfo{Name: "task " + taskId},
+	}
+	for i := range auths {
+		a := &auths[i]
+		c, err := decodeConfig(a)
+		if err != nil {
+			return fmt.Errorf("auth %q: %w", a.Name, err)
+		}
+		domains, domainsRegex := decodeList(a.Domains), decodeList(a.DomainsRegex)
+		if err := Validate(a.Type, domains, domainsRegex, c, template.Dir); err != nil {
+			return fmt.Errorf("auth %q: %w", a.Name, err)
+		}
+		p.Redactor.add(a.Type, c)
+
+		if a.Type != TypeDynamic {
+			s, err := c.staticSecret(a.Type, domains, domainsRegex)
+			if err != nil {
+				return fmt.Errorf("auth %q: %w", a.Name, err)
+			}
+			file.Secrets = append(file.Secrets, *s)
+			continue
+		}
+		path, err := p.loginTemplate(a.ID, c)
+		if err != nil {
+			return fmt.Errorf("auth %q: %w", a.Name, err)
+		}
+		d, err := c.dynamic(domains, domainsRegex, path)
+		if err != nil {
+			return fmt.Errorf("auth %q: %w", a.Name, err)
+		}
+		file.Dynamic = append(file.Dynamic, *d)
+		p.Dynamic++
+	}
+	p.Count = len(auths)
+	p.Redactor.finish()
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	p.File = filepath.Join(p.Dir, "secrets.json")
+	return os.WriteFile(p.File, data, 0o600)
+}
+
+// loginTemplate 返回登录模板的绝对路径：模板库中的模板，或把 templateContent 写入临时目录
+func (p *Prepared) loginTemplate(id uint64, c *Config) (string, error) {
+	var path string
+	if c.TemplateContent != "" {
+		path = filepath.Join(p.Dir, fmt.Sprintf("login-%d.yaml", id))
+		if err := os.WriteFile(path, []byte(c.TemplateContent), 0o600); err != nil {
+			return "", err
+		}
+	} else {
+		var err error
+		if path, err = libraryPath(template.Dir, c.Template); err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("login template %s not found", c.Template)
+		}
+	}
+	p.loginTemplates[path] = true
+	return path, nil
+}
+
+// IsLogin 命中结果是否来自登录模板
+func (p *Prepared) IsLogin(ev *output.ResultEvent) bool {
+	if p == nil || ev == nil || ev.TemplatePath == "" {
+		return false
+	}
+	path, err := filepath.Abs(ev.TemplatePath)
+	return err == nil && p.loginTemplates[path]
+}
+
+// Redact 替换命中结果中的凭据
+func (p *Prepared) Redact(ev *output.ResultEvent) {
+	if p == nil {
+		return
+	}
+	p.Redactor.Event(ev)
+}
+
+// Cleanup 删除临时 secrets 文件与登录模板
+func (p *Prepared) Cleanup() {
+	if p == nil || p.Dir == "" {
+		return
+	}
+	if err := os.RemoveAll(p.Dir); err != nil {
+		log.Printf("[scanauth] remove temp dir failed dir=%s err=%v", p.Dir, err)
+	}
+}
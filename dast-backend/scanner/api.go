@@ -0,0 +1,83 @@
+/**
+ * API 扫描：输入为上传的接口文档或流量导出，不做端口扫描、测活与指纹识别
+ */
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"demo/apispec"
+	"demo/models"
+
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+)
+
+// runAPI API 扫描任务的执行流程：nuclei 以 DAST 模式对文件生成的请求执行 fuzzing 模板，状态更新与 Run 相同
+// 排除列表无法作用于文件中的单个请求，请求地址命中排除列表（或排除列表无法解析）时不执行扫描
+func runAPI(ctx context.Context, taskId, runId, infoKey string, spec *models.APISpec, exclusions []string, profile *Profile, cp *checkpoint, rep *reporter) {
+	excluded, err := apispec.ExcludedHosts(spec, exclusions)
+	if err != nil {
+		setStatus(taskId, runId, infoKey, "error", fmt.Sprintf("invalid exclusions: %v", err))
+		return
+	}
+	if len(excluded) > 0 {
+		setStatus(taskId, runId, infoKey, "error", fmt.Sprintf("api spec hosts excluded: %s", strings.Join(excluded, ", ")))
+		return
+	}
+
+	setStage(rep, infoKey, StageNuclei)
+	if err := APIScan(ctx, taskId, runId, spec, profile, cp, rep); err != nil {
+		if errors.Is(err, context.Canceled) || ctx.Err() == context.Canceled {
+			setStopped(ctx, taskId, runId, infoKey)
+		} else {
+			setStatus(taskId, runId, infoKey, "error", fmt.Sprintf("nuclei error: %v", err))
+		}
+		return
+	}
+	setStatus(taskId, runId, infoKey, "finished", "")
+}
+
+// APIScan 把保存的文件交给 nuclei 的 input formats（LoadTargetsWithHttpData）生成请求，只加载 DAST 模板；
+// 参数取值通过 WithVars 传入，requiredOnly 只使用必填参数
+func APIScan(ctx context.Context, taskId, runId string, spec *models.APISpec, profile *Profile, cp *checkpoint, rep *reporter) error {
+	dir, err := os.MkdirTemp("", "dast-apispec-*")
+	if err != nil {
+		return fmt.Errorf("[+]create input dir failed: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	path, err := apispec.WriteFile(dir, spec.Format, spec.FileName, []byte(spec.Content))
+	if err != nil {
+		return fmt.Errorf("[+]write input file failed: %w", err)
+	}
+
+	opts := apispec.OptionsOf(spec)
+	rep.log.Info("api scan start", map[string]interface{}{
+		"format":       spec.Format,
+		"file":         spec.FileName,
+		"requests":     spec.RequestCount,
+		"requiredOnly": opts.RequiredOnly,
+		"variables":    len(opts.Variables),
+		"filters":      opts.Filters,
+	})
+	input := &nucleiInput{
+		options: []nuclei.NucleiSDKOptions{
+			nuclei.DASTMode(),
+			nuclei.WithInputFormatOptions(nuclei.InputFormatOptions{
+				RequiredOnly:    opts.RequiredOnly,
+				FilterHosts:     opts.Filters.Hosts,
+				FilterMimeTypes: opts.Filters.MimeTypes,
+			}),
+		},
+		load: func(engine *nuclei.NucleiEngine) error {
+			return engine.LoadTargetsWithHttpData(path, spec.Format)
+		},
+	}
+	if vars := opts.Vars(); len(vars) > 0 {
+		input.options = append(input.options, nuclei.WithVars(vars))
+	}
+	return runNuclei(ctx, taskId, runId, profile, cp, rep, input)
+}
@@ -0,0 +1,115 @@
+/**
+ * 扫描断点：记录每个阶段的输出，进程重启或 worker 宕机后从最后完成的阶段继续
+ */
+package scanner
+
+import (
+	"encoding/json"
+	"log"
+
+	"demo/db/redisdb"
+	"demo/taskrun"
+)
+
+// checkpoint 字段：
+//   - stage       最后完成的阶段（StagePortScan / StageHttpProbe / StageFingerprint）
+//   - portscan    端口扫描后得到的 host:port 列表
+//   - httpprobe   测活后交给 nuclei 的目标列表
+//   - fingerprint 指纹识别结果（URL -> 产品列表）
+//   - nuclei      nuclei resume 配置（SaveResumeConfig 的内容）；按指纹拆分的各轮扫描为 nuclei:{pass}，
+//     完成的轮次记录 nuclei:{pass}:done
+const (
+	checkpointStage  = "stage"
+	checkpointNuclei = "nuclei"
+)
+
+type checkpoint struct {
+	taskId string
+	runId  string
+	values map[string]string
+}
+
+// loadCheckpoint 读取本次执行已有的断点；全新执行返回空断点
+func loadCheckpoint(taskId, runId string) *checkpoint {
+	cp := &checkpoint{taskId: taskId, runId: runId, values: map[string]string{}}
+	if runId == "" {
+		return cp
+	}
+	values, err := redisdb.Client.HGetAll(redisdb.Ctx, taskrun.CheckpointKey(taskId, runId)).Result()
+	if err != nil {
+		log.Printf("[scanner] load checkpoint failed task=%s run=%s err=%v", taskId, runId, err)
+		return cp
+	}
+	cp.values = values
+	return cp
+}
+
+// Stage 最后完成的阶段，空串表示没有断点
+func (cp *checkpoint) Stage() string {
+	return cp.values[checkpointStage]
+}
+
+// Targets 读取某阶段保存的目标列表
+func (cp *checkpoint) Targets(stage string) ([]string, bool) {
+	var targets []string
+	if !cp.Load(stage, &targets) {
+		return nil, false
+	}
+	return targets, true
+}
+
+// Load 读取某阶段保存的输出，没有断点或解析失败时返回 false
+func (cp *checkpoint) Load(stage string, v interface{}) bool {
+	raw, ok := cp.values[stage]
+	if !ok {
+		return false
+	}
+	return json.Unmarshal([]byte(raw), v) == nil
+}
+
+// Done 记录阶段完成及其输出
+func (cp *checkpoint) Done(stage string, output interface{}) {
+	data, _ := json.Marshal(output)
+	cp.values[stage] = string(data)
+	cp.values[checkpointStage] = stage
+	cp.save(stage, string(data), checkpointStage, stage)
+}
+
+// nucleiKey 某一轮 nuclei 扫描的断点字段，pass 为空时即 nuclei
+func nucleiKey(pass string) string {
+	if pass == "" {
+		return checkpointNuclei
+	}
+	return checkpointNuclei + ":" + pass
+}
+
+// NucleiResume 某一轮 nuclei 扫描上次中断时保存的 resume 配置
+func (cp *checkpoint) NucleiResume(pass string) string {
+	return cp.values[nucleiKey(pass)]
+}
+
+// SetNucleiResume 保存某一轮 nuclei 扫描的 resume 配置
+func (cp *checkpoint) SetNucleiResume(pass, data string) {
+	cp.values[nucleiKey(pass)] = data
+	cp.save(nucleiKey(pass), data)
+}
+
+// NucleiDone 某一轮 nuclei 扫描是否已经完成
+func (cp *checkpoint) NucleiDone(pass string) bool {
+	return cp.values[nucleiKey(pass)+":done"] != ""
+}
+
+// SetNucleiDone 记录某一轮 nuclei 扫描完成，续扫时跳过
+func (cp *checkpoint) SetNucleiDone(pass string) {
+	cp.values[nucleiKey(pass)+":done"] = "1"
+	cp.save(nucleiKey(pass)+":done", "1")
+}
+
+func (cp *checkpoint) save(values ...interface{}) {
+	if cp.runId == "" {
+		return
+	}
+	if err := redisdb.Client.HSet(redisdb.Ctx, taskrun.CheckpointKey(cp.taskId, cp.runId), values...).Err(); err != nil {
+		log.Printf("[scanner] save checkpoint failed task=%s run=%s err=%v", cp.taskId, cp.runId, err)
+	}
+}
@@ -0,0 +1,230 @@
+package scanner
+
+import (
+	"context"
+	"crypto/tls"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"demo/fingerprint"
+)
+
+const (
+	// 指纹识别时最多读取的正文与 favicon 大小
+	maxFingerprintBody = 1 << 20
+	maxFaviconSize     = 256 * 1024
+)
+
+var iconRe = regexp.MustCompile(`(?is)<link[^>]+rel=["']?(?:shortcut )?icon["']?[^>]*>`)
+var hrefRe = regexp.MustCompile(`(?is)href=["']?([^"'\s>]+)`)
+
+// Fingerprint 对测活得到的 HTTP(S) URL 做指纹识别：抓取首页与 favicon 后交给规则库匹配
+// 并发数与请求超时取自 profile，每识别完一个 URL 通过 rep 推送进度
+// 返回：map[URL]识别出的产品；未识别出产品的 URL 对应空列表，抓取失败的 URL 不在结果中
+func Fingerprint(ctx context.Context, urls []string, profile *Profile, rep *reporter) (map[string][]fingerprint.Product, error) {
+	results := make(map[string][]fingerprint.Product)
+	if len(urls) == 0 {
+		return results, nil
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+		Timeout: time.Duration(profile.ProbeTimeout) * time.Second,
+	}
+
+	var (
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+		done       int
+		identified int
+	)
+	sem := make(chan struct{}, profile.ProbeWorkers)
+	progress := func(final bool) {
+		mu.Lock()
+		data := map[string]interface{}{
+			"total":      len(urls),
+			"done":       done,
+			"identified": identified,
+		}
+		mu.Unlock()
+		rep.EmitThrottled(EventFingerprint, data, final)
+	}
+	progress(true)
+
+	for _, u := range urls {
+		wg.Add(1)
+		go func(target string) {
+			defer wg.Done()
+			defer func() {
+				mu.Lock()
+				done++
+				mu.Unlock()
+				progress(false)
+			}()
+
+			select {
+			case <-ctx.Done():
+				return
+			case sem <- struct{}{}:
+			}
+			defer func() { <-sem }()
+
+			page, err := fetchPage(ctx, client, target)
+			if err != nil {
+				if ctx.Err() == nil {
+					rep.log.Warn("fetch page failed", map[string]interface{}{"url": target, "error": err.Error()})
+				}
+				return
+			}
+			products := fingerprint.Match(page)
+			mu.Lock()
+			results[target] = products
+			if len(products) > 0 {
+				identified++
+			}
+			mu.Unlock()
+			if len(products) == 0 {
+				return
+			}
+			rep.Emit(EventFingerprint, map[string]interface{}{
+				"url":      target,
+				"products": products,
+			})
+		}(u)
+	}
+
+	wg.Wait()
+	progress(true)
+	if ctx.Err() != nil {
+		return results, ctx.Err()
+	}
+	rep.log.Info("fingerprint finished", map[string]interface{}{"urls": len(urls), "identified": identified})
+	return results, nil
+}
+
+// fetchPage 抓取首页（跟随跳转）及其 favicon
+func fetchPage(ctx context.Context, client *http.Client, target string) (*fingerprint.Page, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFingerprintBody))
+	if err != nil {
+		return nil, err
+	}
+
+	page := &fingerprint.Page{URL: target, Header: resp.Header, Body: body}
+	if m := titleRe.FindSubmatch(body); m != nil {
+		page.Title = strings.TrimSpace(html.UnescapeString(string(m[1])))
+	}
+	if icon := faviconURL(resp.Request.URL, body); icon != "" {
+		page.FaviconHash = fetchFavicon(ctx, client, icon)
+	}
+	return page, nil
+}
+
+// faviconURL 优先取页面中 <link rel="icon"> 的地址，否则使用 /favicon.ico
+func faviconURL(base *url.URL, body []byte) string {
+	if link := iconRe.Find(body); link != nil {
+		if m := hrefRe.FindSubmatch(link); m != nil {
+			if ref, err := url.Parse(html.UnescapeString(string(m[1]))); err == nil {
+				if strings.HasPrefix(ref.Scheme, "data") {
+					return ""
+				}
+				return base.ResolveReference(ref).String()
+			}
+		}
+	}
+	return base.ResolveReference(&url.URL{Path: "/favicon.ico"}).String()
+}
+
+func fetchFavicon(ctx context.Context, client *http.Client, iconURL string) string {
+	req, err := http.NewRequestWithContext(ctx, "GET", iconURL, nil)
+	if err != nil {
+		return ""
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFaviconSize))
+	if err != nil || len(data) == 0 {
+		return ""
+	}
+	return fingerprint.FaviconHash(data)
+}
+
+// nucleiPass 一轮 nuclei 扫描的目标与 profile，name 用于区分各轮的断点，tags 为按指纹追加的模板 tag
+type nucleiPass struct {
+	name    string
+	targets []string
+	profile *Profile
+	tags    []string
+}
+
+// nucleiPasses 开启 NarrowByFingerprint 时按指纹拆分 nuclei 扫描：识别出产品的 URL 只追加各自识别出的 tag，
+// tag 相同的 URL 合并为一轮；非 HTTP 目标与未识别出产品的 URL 保持原有模板范围，避免漏扫
+func nucleiPasses(targets []string, profile *Profile, products map[string][]fingerprint.Product) []nucleiPass {
+	if !profile.NarrowByFingerprint {
+		return []nucleiPass{{targets: targets, profile: profile}}
+	}
+	var base []string
+	var keys []string
+	groups := map[string]*nucleiPass{}
+	for _, t := range targets {
+		tags := fingerprint.Tags(map[string][]fingerprint.Product{t: products[t]})
+		if len(tags) == 0 {
+			base = append(base, t)
+			continue
+		}
+		key := strings.Join(tags, ",")
+		g := groups[key]
+		if g == nil {
+			narrowed := *profile
+			narrowed.Tags = append(append([]string{}, profile.Tags...), tags...)
+			g = &nucleiPass{name: "fingerprint:" + key, profile: &narrowed, tags: tags}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+		g.targets = append(g.targets, t)
+	}
+
+	var passes []nucleiPass
+	if len(base) > 0 {
+		passes = append(passes, nucleiPass{targets: base, profile: profile})
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		passes = append(passes, *groups[key])
+	}
+	return passes
+}
+
+// httpURLs 从 nuclei 目标中取出 HTTP(S) URL
+func httpURLs(targets []string) []string {
+	var out []string
+	for _, t := range targets {
+		if strings.HasPrefix(t, "http://") || strings.HasPrefix(t, "https://") {
+			out = append(out, t)
+		}
+	}
+	return out
+}
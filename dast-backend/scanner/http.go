@@ -4,27 +4,47 @@ import (
 	"bufio"
 	"context"
 	"crypto/tls"
-	"fmt"
+	"html"
 	"io"
 	"net"
 	"net/http"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
+// 提取标题时最多读取的响应体大小
+const maxTitleBody = 64 * 1024
+
+var titleRe = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// ProbeResult 单个 host:port 的测活结果，除 URL 外的字段写入资产清单
+type ProbeResult struct {
+	URL         string // 交给 nuclei 的目标：http(s)://host:port，或仅 TCP 可连时的 host:port
+	Alive       bool   // HTTP 状态码 2xx/3xx 或非 HTTP 但 TCP 可连
+	Scheme      string
+	StatusCode  int
+	Title       string
+	Server      string
+	TLSSubject  string
+	TLSNotAfter *time.Time
+}
+
 // HttpAliveProbe 对给定的目标做 HTTP/HTTPS 测活：
 // 输入可以是 host、host:port、url：
 //   - 如果本身带 http:// 或 https://，会以该 URL 为主进行探测
 //   - 否则会按 host:port 猜测 http/https（带端口会先探测端口是否支持 http/https）
 //
-// 返回：map[原始输入(规范化后的 host:port)]存活URL
-func HttpAliveProbe(ctx context.Context, targets []string) (map[string]string, error) {
-	fmt.Println("[+]HttpAliveProbe start")
-	aliveMap := make(map[string]string)
+// 并发数与请求超时取自 profile，每探测完一个目标通过 rep 推送进度
+// 返回：map[原始输入(规范化后的 host:port)]测活结果；HTTP 有响应但状态码不满足时也会返回（Alive 为 false）
+func HttpAliveProbe(ctx context.Context, targets []string, profile *Profile, rep *reporter) (map[string]*ProbeResult, error) {
+	aliveMap := make(map[string]*ProbeResult)
 	if len(targets) == 0 {
 		return aliveMap, nil
 	}
+	rep.log.Info("http probe start", map[string]interface{}{"targets": len(targets)})
 
 	// HTTP client 用于对带 scheme 的候选发 GET 验证
 	transport := &http.Transport{
@@ -34,14 +54,27 @@ func HttpAliveProbe(ctx context.Context, targets []string) (map[string]string, e
 	}
 	client := &http.Client{
 		Transport: transport,
-		Timeout:   5 * time.Second,
+		Timeout:   time.Duration(profile.ProbeTimeout) * time.Second,
 	}
 
 	var (
-		mu sync.Mutex
-		wg sync.WaitGroup
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		done  int
+		alive int
 	)
-	sem := make(chan struct{}, 50) // 并发限制
+	sem := make(chan struct{}, profile.ProbeWorkers) // 并发限制
+	progress := func(final bool) {
+		mu.Lock()
+		data := map[string]interface{}{
+			"total": len(targets),
+			"done":  done,
+			"alive": alive,
+		}
+		mu.Unlock()
+		rep.EmitThrottled(EventHttpProbe, data, final)
+	}
+	progress(true)
 
 	for _, raw := range targets {
 		raw = strings.TrimSpace(raw)
@@ -52,6 +85,12 @@ func HttpAliveProbe(ctx context.Context, targets []string) (map[string]string, e
 		wg.Add(1)
 		go func(target string) {
 			defer wg.Done()
+			defer func() {
+				mu.Lock()
+				done++
+				mu.Unlock()
+				progress(false)
+			}()
 
 			// respect context & concurrency slot
 			select {
@@ -69,8 +108,21 @@ func HttpAliveProbe(ctx context.Context, targets []string) (map[string]string, e
 
 			// 构造候选：现在由 buildURLCandidates 做端口探测并返回合适候选
 			candidates := buildURLCandidates(ctx, target)
-			fmt.Println("[+]target:", candidates)
+			rep.log.Debug("probe candidates", map[string]interface{}{"target": target, "candidates": candidates})
 
+			var responded *ProbeResult
+			var lastErr error
+			defer func() {
+				// 所有候选都不满足存活条件，但有 HTTP 响应：只记录到资产清单
+				if responded != nil && !responded.Alive {
+					mu.Lock()
+					aliveMap[key] = responded
+					mu.Unlock()
+					rep.log.Info("target responded but not alive", map[string]interface{}{"target": target, "url": responded.URL, "status": responded.StatusCode})
+				} else if responded == nil && lastErr != nil && ctx.Err() == nil {
+					rep.log.Warn("target unreachable", map[string]interface{}{"target": target, "error": lastErr.Error()})
+				}
+			}()
 			for _, cand := range candidates {
 				if ctx.Err() != nil {
 					return
@@ -84,14 +136,17 @@ func HttpAliveProbe(ctx context.Context, targets []string) (map[string]string, e
 					}
 					resp, err := client.Do(req)
 					if err != nil {
+						lastErr = err
 						continue
 					}
-					io.Copy(io.Discard, resp.Body)
+					responded = probeResult(cand, resp)
 					resp.Body.Close()
 
 					if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+						responded.Alive = true
 						mu.Lock()
-						aliveMap[key] = cand
+						aliveMap[key] = responded
+						alive++
 						mu.Unlock()
 						return
 					}
@@ -105,12 +160,14 @@ func HttpAliveProbe(ctx context.Context, targets []string) (map[string]string, e
 				conn, err := dialer.DialContext(connCtx, "tcp", cand)
 				cancel()
 				if err != nil {
+					lastErr = err
 					continue
 				}
 				conn.Close()
 				// TCP 可连，认为存活（非 HTTP/HTTPS），返回 ip:port
 				mu.Lock()
-				aliveMap[key] = cand
+				aliveMap[key] = &ProbeResult{URL: cand, Alive: true}
+				alive++
 				mu.Unlock()
 				return
 			}
@@ -118,12 +175,47 @@ func HttpAliveProbe(ctx context.Context, targets []string) (map[string]string, e
 	}
 
 	wg.Wait()
+	progress(true)
 	if ctx.Err() != nil {
 		return aliveMap, ctx.Err()
 	}
+	rep.log.Info("http probe finished", map[string]interface{}{"targets": len(targets), "alive": alive})
 	return aliveMap, nil
 }
 
+// probeResult 从 HTTP 响应中提取状态码、标题、Server 头与 TLS 证书信息
+func probeResult(rawURL string, resp *http.Response) *ProbeResult {
+	r := &ProbeResult{
+		URL:        rawURL,
+		Scheme:     strings.SplitN(rawURL, "://", 2)[0],
+		StatusCode: resp.StatusCode,
+		Server:     resp.Header.Get("Server"),
+	}
+	if body, err := io.ReadAll(io.LimitReader(resp.Body, maxTitleBody)); err == nil {
+		if m := titleRe.FindSubmatch(body); m != nil {
+			r.Title = truncate(strings.TrimSpace(html.UnescapeString(string(m[1]))), 255)
+		}
+	}
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		cert := resp.TLS.PeerCertificates[0]
+		r.TLSSubject = truncate(cert.Subject.String(), 512)
+		notAfter := cert.NotAfter
+		r.TLSNotAfter = &notAfter
+	}
+	return r
+}
+
+// truncate 按字节截断，不切断多字节字符
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
 // buildURLCandidates 根据 target 构造一组可能的 URL
 // 保持外部签名不变，但内部实现会先探测端口协议类型：
 // - 如果 target 带 scheme，则直接返回该 URL
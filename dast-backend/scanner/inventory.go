@@ -0,0 +1,84 @@
+package scanner
+
+import (
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+
+	"demo/asset"
+	"demo/fingerprint"
+)
+
+// recordAssets 把端口扫描与测活结果写入资产清单，失败只记录日志，不影响扫描
+//   - portScanned: 本次做过端口扫描的主机
+//   - explicit:    用户直接给出的 host:port（未经端口扫描，只有测活有响应才算开放）
+//   - hostPorts:   进入测活阶段的全部 host:port
+func recordAssets(taskId, runId string, portScanned, explicit, hostPorts []string, probe map[string]*ProbeResult, profile *Profile) {
+	given := map[string]bool{}
+	for _, hp := range explicit {
+		given[hp] = true
+	}
+
+	s := asset.Scan{TaskID: taskId, RunID: runId, PortScanned: portScanned}
+	if len(portScanned) > 0 {
+		ports, err := scannedPorts(profile)
+		if err != nil {
+			log.Printf("[scanner] parse scanned ports failed task=%s run=%s err=%v", taskId, runId, err)
+		}
+		s.Ports = ports
+	}
+
+	for _, hp := range hostPorts {
+		host, portStr, err := net.SplitHostPort(hp)
+		if err != nil {
+			continue
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			continue
+		}
+		r := probe[hp]
+		if r == nil && given[hp] {
+			continue
+		}
+		o := asset.Observation{Host: host, Port: port}
+		if r != nil {
+			o.Probed = true
+			o.Scheme = r.Scheme
+			o.URL = r.URL
+			o.StatusCode = r.StatusCode
+			o.Title = r.Title
+			o.Server = r.Server
+			o.TLSSubject = r.TLSSubject
+			o.TLSNotAfter = r.TLSNotAfter
+		}
+		s.Open = append(s.Open, o)
+	}
+
+	if err := asset.Record(s); err != nil {
+		log.Printf("[scanner] record assets failed task=%s run=%s err=%v", taskId, runId, err)
+	}
+}
+
+// recordProducts 把指纹识别结果（URL -> 产品）按 host:port 写入资产清单，失败只记录日志
+func recordProducts(taskId, runId string, products map[string][]fingerprint.Product) {
+	services := make(map[string][]fingerprint.Product, len(products))
+	for raw, list := range products {
+		u, err := url.Parse(raw)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		port := u.Port()
+		if port == "" {
+			port = "80"
+			if u.Scheme == "https" {
+				port = "443"
+			}
+		}
+		services[net.JoinHostPort(u.Hostname(), port)] = list
+	}
+	if err := asset.RecordProducts(taskId, runId, services); err != nil {
+		log.Printf("[scanner] record products failed task=%s run=%s err=%v", taskId, runId, err)
+	}
+}
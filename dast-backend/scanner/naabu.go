@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	naaburesult "github.com/projectdiscovery/naabu/v2/pkg/result"
@@ -15,44 +16,48 @@ import (
 
 // PortScan 使用 naabu 对给定 host 列表做端口扫描，返回 host:port 列表。
 // 注意：这里假设传入的 hosts 都是不带端口的，例如：1.2.3.4 / example.com
-func PortScan(ctx context.Context, hosts []string) ([]string, error) {
-	fmt.Println("[+]naabu start")
+// 端口范围、速率、并发与超时取自 profile；发现开放端口时通过 rep 推送进度
+func PortScan(ctx context.Context, hosts []string, profile *Profile, rep *reporter) ([]string, error) {
 	if len(hosts) == 0 {
 		return nil, nil
 	}
+	rep.log.Info("port scan start", map[string]interface{}{"hosts": len(hosts), "ports": profile.Ports, "topPorts": profile.TopPorts})
 
 	openTargets := make([]string, 0)
+	openHosts := map[string]bool{}
+	var mu sync.Mutex
+	progress := func(final bool) {
+		mu.Lock()
+		data := map[string]interface{}{
+			"hosts":     len(hosts),
+			"openHosts": len(openHosts),
+			"openPorts": len(openTargets),
+		}
+		mu.Unlock()
+		rep.EmitThrottled(EventPortScan, data, final)
+	}
+	progress(true)
 
-	options := &naaburunner.Options{
-		// 这些参数可以按实际情况调整
-		Rate:     1000, // 扫描速率
-		TopPorts: "1000",
-		Timeout:  5 * time.Second, // 单个探测的超时
-		Threads:  25,              // 并发线程数
-
-		Silent: true,  // 不往 stdout 打日志
-		JSON:   false, // 不直接输出 JSON，我们用回调拿结果
-
-		// 只要端口扫描，不做额外 Host 探测
-		WithHostDiscovery: false,
-		SkipHostDiscovery: true,
-
-		// 每个 host扫描结果回调（naabu v2 提供的 HostResult）
-		OnResult: func(hr *naaburesult.HostResult) {
-			if hr == nil {
-				return
-			}
-			host := hr.Host
-			if host == "" {
-				host = hr.IP
-			}
-			if host == "" {
-				return
-			}
-			for _, p := range hr.Ports {
-				openTargets = append(openTargets, fmt.Sprintf("%s:%d", host, p.Port))
-			}
-		},
+	options := portOptions(profile)
+	// 每个 host扫描结果回调（naabu v2 提供的 HostResult）
+	options.OnResult = func(hr *naaburesult.HostResult) {
+		if hr == nil {
+			return
+		}
+		host := hr.Host
+		if host == "" {
+			host = hr.IP
+		}
+		if host == "" {
+			return
+		}
+		mu.Lock()
+		openHosts[host] = true
+		for _, p := range hr.Ports {
+			openTargets = append(openTargets, fmt.Sprintf("%s:%d", host, p.Port))
+		}
+		mu.Unlock()
+		progress(false)
 	}
 
 	r, err := naaburunner.NewRunner(options)
@@ -61,18 +66,67 @@ func PortScan(ctx context.Context, hosts []string) ([]string, error) {
 	}
 	defer r.Close()
 
-	// 把 host 加到 runner 里
+	// 把 host 加到 runner 里（域名在这里解析，解析失败的 host 不会被扫描）
 	for _, h := range hosts {
 		h = strings.TrimSpace(h)
 		if h == "" {
 			continue
 		}
-		_ = r.AddTarget(h)
+		if err := r.AddTarget(h); err != nil {
+			rep.log.Warn("add port scan target failed", map[string]interface{}{"host": h, "error": err.Error()})
+		}
 	}
 
 	// 开始端口扫描
 	if err := r.RunEnumeration(ctx); err != nil {
 		return nil, err
 	}
+	progress(true)
+
+	mu.Lock()
+	for _, h := range hosts {
+		if !openHosts[h] {
+			rep.log.Debug("no open ports", map[string]interface{}{"host": h})
+		}
+	}
+	rep.log.Info("port scan finished", map[string]interface{}{"openHosts": len(openHosts), "openPorts": len(openTargets)})
+	mu.Unlock()
 	return openTargets, nil
 }
+
+// portOptions 由 profile 生成 naabu 参数（不含回调）
+func portOptions(profile *Profile) *naaburunner.Options {
+	options := &naaburunner.Options{
+		Rate:         profile.PortRate, // 扫描速率
+		Ports:        profile.Ports,
+		ExcludePorts: profile.ExcludePorts,
+		Timeout:      time.Duration(profile.PortTimeout) * time.Millisecond, // 单个探测的超时
+		Threads:      profile.PortThreads,                                   // 并发线程数
+
+		Silent: true,  // 不往 stdout 打日志
+		JSON:   false, // 不直接输出 JSON，我们用回调拿结果
+
+		// 只要端口扫描，不做额外 Host 探测
+		WithHostDiscovery: false,
+		SkipHostDiscovery: true,
+	}
+
+	// 指定了端口列表时不再叠加 top ports（naabu 会取并集）
+	if options.Ports == "" {
+		options.TopPorts = profile.TopPorts
+	}
+	return options
+}
+
+// scannedPorts 返回 profile 端口扫描覆盖的端口集合，资产清单据此判断端口是否关闭
+func scannedPorts(profile *Profile) (map[int]bool, error) {
+	ports, err := naaburunner.ParsePorts(portOptions(profile))
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[int]bool, len(ports))
+	for _, p := range ports {
+		set[p.Port] = true
+	}
+	return set, nil
+}
@@ -0,0 +1,86 @@
+package scanner
+
+import (
+	"demo/db/mysqldb"
+	"demo/models"
+	"demo/notify"
+	"demo/taskrun"
+	"sync"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// findingNotifier 扫描过程中命中上一次完成的执行中没有的漏洞时，立即推送 finding.new 事件
+type findingNotifier struct {
+	taskId, runId string
+
+	once sync.Once
+	base map[string]bool // 上一次完成的执行中的漏洞，首次需要时加载
+
+	mu   sync.Mutex
+	sent map[string]bool
+}
+
+func newFindingNotifier(taskId, runId string) *findingNotifier {
+	return &findingNotifier{taskId: taskId, runId: runId, sent: map[string]bool{}}
+}
+
+func (n *findingNotifier) loadBase() {
+	n.base = map[string]bool{}
+	var run models.TaskRun
+	if err := mysqldb.DB.Omit("targets", "config").First(&run, "id = ?", n.runId).Error; err != nil {
+		return
+	}
+	prev, err := taskrun.Previous(&run)
+	if err != nil {
+		return
+	}
+	var rows []models.Finding
+	if err := mysqldb.DB.Select("template_id", "matched_at", "matcher_name").
+		Where("run_id = ?", prev.ID).Find(&rows).Error; err != nil {
+		return
+	}
+	for _, f := range rows {
+		n.base[taskrun.FindingKey(f)] = true
+	}
+}
+
+// Notify 在有渠道订阅该等级的新漏洞时，对比上一次执行并推送
+func (n *findingNotifier) Notify(ev *output.ResultEvent) {
+	sev := ev.Info.SeverityHolder.Severity.String()
+	if !notify.WantFinding(sev) {
+		return
+	}
+	matchedAt := ev.Matched
+	if matchedAt == "" {
+		matchedAt = ev.URL
+	}
+	key := taskrun.FindingKey(models.Finding{TemplateID: ev.TemplateID, MatchedAt: matchedAt, MatcherName: ev.MatcherName})
+
+	n.once.Do(n.loadBase)
+	n.mu.Lock()
+	if n.base[key] || n.sent[key] {
+		n.mu.Unlock()
+		return
+	}
+	n.sent[key] = true
+	n.mu.Unlock()
+
+	name := ev.Info.Name
+	if name == "" {
+		name = ev.TemplateID
+	}
+	notify.Publish(&notify.Event{
+		Event:  notify.EventNewFinding,
+		TaskID: n.taskId,
+		RunID:  n.runId,
+		Status: "running",
+		Finding: &notify.FindingInfo{
+			TemplateID: ev.TemplateID,
+			Name:       name,
+			Severity:   sev,
+			MatchedAt:  matchedAt,
+			Host:       ev.Host,
+		},
+	})
+}
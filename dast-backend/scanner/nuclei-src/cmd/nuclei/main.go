@@ -269,6 +269,8 @@ on extensive configurability, massive extensibility and ease of use.`)
 		flagSet.BoolVarP(&options.SkipFormatValidation, "skip-format-validation", "sfv", false, "skip format validation (like missing vars) when parsing input file"),
 		flagSet.BoolVarP(&options.VarsTextTemplating, "vars-text-templating", "vtt", false, "enable text templating for vars in input file (only for yaml input mode)"),
 		flagSet.StringSliceVarP(&options.VarsFilePaths, "var-file-paths", "vfp", nil, "list of yaml file contained vars to inject into yaml input", goflags.CommaSeparatedStringSliceOptions),
+		flagSet.StringSliceVarP(&options.FormatFilterHosts, "format-filter-host", "ffh", nil, "hosts to keep from input file, supports wildcards like *.example.com (only for har input mode)", goflags.CommaSeparatedStringSliceOptions),
+		flagSet.StringSliceVarP(&options.FormatFilterMimeTypes, "format-filter-mime", "ffm", nil, "response mime types to keep from input file, like application/json (only for har input mode)", goflags.CommaSeparatedStringSliceOptions),
 	)
 
 	flagSet.CreateGroup("templates", "Templates",
@@ -499,6 +499,30 @@ func LoadSecretsFromFile(files []string, prefetch bool) NucleiSDKOptions {
 	}
 }
 
+// InputFormatOptions contains options used when generating requests from
+// http input formats (openapi, swagger etc) loaded with LoadTargetsWithHttpData
+type InputFormatOptions struct {
+	// RequiredOnly only uses required fields when generating requests
+	RequiredOnly bool
+	// SkipFormatValidation skips requests with missing parameters instead of failing
+	SkipFormatValidation bool
+	// FilterHosts only keeps requests to the given hosts (har)
+	FilterHosts []string
+	// FilterMimeTypes only keeps requests with the given response mime types (har)
+	FilterMimeTypes []string
+}
+
+// WithInputFormatOptions sets options for http input formats
+func WithInputFormatOptions(opts InputFormatOptions) NucleiSDKOptions {
+	return func(e *NucleiEngine) error {
+		e.opts.FormatUseRequiredOnly = opts.RequiredOnly
+		e.opts.SkipFormatValidation = opts.SkipFormatValidation
+		e.opts.FormatFilterHosts = opts.FilterHosts
+		e.opts.FormatFilterMimeTypes = opts.FilterMimeTypes
+		return nil
+	}
+}
+
 // DASTMode only run DAST templates
 func DASTMode() NucleiSDKOptions {
 	return func(e *NucleiEngine) error {
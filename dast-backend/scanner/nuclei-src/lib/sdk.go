@@ -4,8 +4,10 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"io"
 	"os"
+	"path/filepath"
 	"sync"
 
 	"github.com/projectdiscovery/gologger"
@@ -30,6 +32,8 @@ import (
 	"github.com/projectdiscovery/ratelimit"
 	"github.com/projectdiscovery/retryablehttp-go"
 	"github.com/projectdiscovery/utils/errkit"
+	fileutil "github.com/projectdiscovery/utils/file"
+	permissionutil "github.com/projectdiscovery/utils/permission"
 	"github.com/rs/xid"
 )
 
@@ -155,7 +159,7 @@ func (e *NucleiEngine) LoadTargetsFromReader(reader io.Reader, probeNonHttp bool
 }
 
 // LoadTargetsWithHttpData loads targets that contain http data from file it currently supports
-// multiple formats like burp xml,openapi,swagger,proxify json
+// multiple formats like burp xml,openapi,swagger,postman,har,graphql,proxify json
 // Note: this is mutually exclusive with LoadTargets and LoadTargetsFromReader
 func (e *NucleiEngine) LoadTargetsWithHttpData(filePath string, filemode string) error {
 	e.opts.TargetsFilePath = filePath
@@ -175,6 +179,26 @@ func (e *NucleiEngine) GetExecuterOptions() *protocols.ExecutorOptions {
 	return e.executerOpts
 }
 
+// SaveResumeConfig writes the current scan progression to path so that
+// an interrupted scan can be continued later using WithResumeFile
+func (e *NucleiEngine) SaveResumeConfig(path string) error {
+	if e.executerOpts == nil || e.executerOpts.ResumeCfg == nil {
+		return errkit.New("resume config not initialized")
+	}
+	if dir := filepath.Dir(path); !fileutil.FolderExists(dir) {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return err
+		}
+	}
+	resumeCfgClone := e.executerOpts.ResumeCfg.Clone()
+	resumeCfgClone.ResumeFrom = resumeCfgClone.Current
+	data, err := json.MarshalIndent(resumeCfgClone, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, permissionutil.ConfigFilePermission)
+}
+
 // ParseTemplate parses a template from given data
 // template verification status can be accessed from template.Verified
 func (e *NucleiEngine) ParseTemplate(data []byte) (*templates.Template, error) {
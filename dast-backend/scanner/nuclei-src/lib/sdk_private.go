@@ -2,6 +2,7 @@ package nuclei
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -179,6 +180,19 @@ func (e *NucleiEngine) init(ctx context.Context) error {
 		e.tmpDir = tmpDir
 	}
 
+	// load the scan progression saved by a previous (interrupted) scan
+	resumeCfg := types.NewResumeCfg()
+	if e.opts.ShouldLoadResume() {
+		data, err := os.ReadFile(e.opts.Resume)
+		if err != nil {
+			return errors.Wrap(err, "could not read resume file")
+		}
+		if err := json.Unmarshal(data, &resumeCfg); err != nil {
+			return errors.Wrap(err, "could not parse resume file")
+		}
+		resumeCfg.Compile()
+	}
+
 	e.executerOpts = &protocols.ExecutorOptions{
 		Output:             e.customWriter,
 		Options:            e.opts,
@@ -188,7 +202,7 @@ func (e *NucleiEngine) init(ctx context.Context) error {
 		RateLimiter:        e.rateLimiter,
 		Interactsh:         e.interactshClient,
 		Colorizer:          aurora.NewAurora(true),
-		ResumeCfg:          types.NewResumeCfg(),
+		ResumeCfg:          resumeCfg,
 		Browser:            e.browserInstance,
 		Parser:             e.parser,
 		InputHelper:        input.NewHelper(),
@@ -199,7 +213,8 @@ func (e *NucleiEngine) init(ctx context.Context) error {
 		e.executerOpts.HostErrorsCache = e.hostErrCache
 	}
 	if len(e.opts.SecretsFile) > 0 {
-		authTmplStore, err := runner.GetAuthTmplStore(e.opts, e.catalog, e.executerOpts)
+		// Clone options so GetAuthTmplStore can modify them without affecting the template filters of the engine
+		authTmplStore, err := runner.GetAuthTmplStore(e.opts.Copy(), e.catalog, e.executerOpts)
 		if err != nil {
 			return errors.Wrap(err, "failed to load dynamic auth templates")
 		}
@@ -8,6 +8,10 @@ import (
 // dataformats is a list of dataformats
 var dataformats map[string]DataFormat
 
+// decodeOrder is the order in which dataformats are
+// checked when decoding, i.e. the registration order
+var decodeOrder []string
+
 const (
 	// DefaultKey is the key i.e used when given
 	// data is not of k-v type
@@ -18,6 +22,8 @@ func init() {
 	dataformats = make(map[string]DataFormat)
 
 	// register the default data formats
+	// graphql bodies are also json objects so it must be checked first
+	RegisterDataFormat(NewGraphQL())
 	RegisterDataFormat(NewJSON())
 	RegisterDataFormat(NewXML())
 	RegisterDataFormat(NewRaw())
@@ -36,6 +42,8 @@ const (
 	FormDataFormat = "form"
 	// MultiPartFormDataFormat is the name of the MultiPartForm data format
 	MultiPartFormDataFormat = "multipart/form-data"
+	// GraphQLDataFormat is the name of the GraphQL data format
+	GraphQLDataFormat = "graphql"
 )
 
 // Get returns the dataformat by name
@@ -45,6 +53,9 @@ func Get(name string) DataFormat {
 
 // RegisterEncoder registers an encoder
 func RegisterDataFormat(dataformat DataFormat) {
+	if _, ok := dataformats[dataformat.Name()]; !ok {
+		decodeOrder = append(decodeOrder, dataformat.Name())
+	}
 	dataformats[dataformat.Name()] = dataformat
 }
 
@@ -70,7 +81,8 @@ type Decoded struct {
 
 // Decode decodes the data from a format
 func Decode(data string) (*Decoded, error) {
-	for _, dataformat := range dataformats {
+	for _, name := range decodeOrder {
+		dataformat := dataformats[name]
 		if dataformat.IsType(data) {
 			decoded, err := dataformat.Decode(data)
 			if err != nil {
@@ -0,0 +1,464 @@
+package dataformat
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	graphqlutil "github.com/projectdiscovery/nuclei/v3/pkg/utils/graphql"
+	mapsutil "github.com/projectdiscovery/utils/maps"
+)
+
+// GraphQL is a GraphQL request body encoder
+//
+// The body is a JSON object with a query and optional variables.
+// Variables are exposed as $name keys (nested values joined by ~,
+// like $input~email) and inline arguments of the query as
+// field~argument keys (like user~id), using aliases when present.
+// Keys of arguments in fragments are prefixed with the fragment name.
+type GraphQL struct{}
+
+var (
+	_ DataFormat = &GraphQL{}
+)
+
+const (
+	graphqlBodyKey  = "#_graphql_body"
+	graphqlQueryKey = "#_graphql_query"
+)
+
+// NewGraphQL returns a new GraphQL encoder
+func NewGraphQL() *GraphQL {
+	return &GraphQL{}
+}
+
+// IsType returns true if the data is a GraphQL request body
+func (g *GraphQL) IsType(data string) bool {
+	if !strings.HasPrefix(data, "{") || !strings.HasSuffix(data, "}") || !strings.Contains(data, `"query"`) {
+		return false
+	}
+	body, err := decodeGraphQLBody(data)
+	if err != nil {
+		return false
+	}
+	_, err = graphqlArguments(body.query)
+	return err == nil
+}
+
+// Encode encodes the data into a GraphQL request body
+func (g *GraphQL) Encode(data KV) (string, error) {
+	raw, _ := data.Get(graphqlBodyKey).(string)
+	query, _ := data.Get(graphqlQueryKey).(string)
+	if raw == "" {
+		return "", fmt.Errorf("graphql: missing original body")
+	}
+	values := make(map[string]any)
+	data.Iterate(func(key string, value any) bool {
+		values[key] = value
+		return true
+	})
+
+	body, err := decodeGraphQLBody(raw)
+	if err != nil {
+		return "", err
+	}
+	args, err := graphqlArguments(query)
+	if err != nil {
+		return "", err
+	}
+
+	var rebuilt strings.Builder
+	last := 0
+	for _, arg := range args {
+		value, ok := values[arg.key]
+		if !ok {
+			continue
+		}
+		rebuilt.WriteString(query[last:arg.token.Start])
+		rebuilt.WriteString(arg.literal(fmt.Sprint(value)))
+		last = arg.token.End
+	}
+	rebuilt.WriteString(query[last:])
+
+	encodedQuery, err := jsoniter.Marshal(rebuilt.String())
+	if err != nil {
+		return "", err
+	}
+	body.fields["query"] = encodedQuery
+	if body.variables != nil {
+		variables, _ := rebuildVariables(body.variables, "$", values)
+		encodedVariables, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(variables)
+		if err != nil {
+			return "", err
+		}
+		body.fields["variables"] = encodedVariables
+	}
+	encoded, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(body.fields)
+	return string(encoded), err
+}
+
+// Decode decodes the data from a GraphQL request body
+func (g *GraphQL) Decode(data string) (KV, error) {
+	body, err := decodeGraphQLBody(data)
+	if err != nil {
+		return KV{}, err
+	}
+	args, err := graphqlArguments(body.query)
+	if err != nil {
+		return KV{}, err
+	}
+
+	decoded := mapsutil.NewOrderedMap[string, any]()
+	decoded.Set(graphqlBodyKey, data)
+	decoded.Set(graphqlQueryKey, body.query)
+	flattenVariables(body.variables, "$", &decoded)
+	for _, arg := range args {
+		decoded.Set(arg.key, arg.token.StringValue())
+	}
+	return KVOrderedMap(&decoded), nil
+}
+
+// Name returns the name of the encoder
+func (g *GraphQL) Name() string {
+	return GraphQLDataFormat
+}
+
+// graphqlBody is a decoded GraphQL request body, fields
+// holds all the fields to keep the unknown ones when encoding
+type graphqlBody struct {
+	fields    map[string]jsoniter.RawMessage
+	query     string
+	variables map[string]any
+}
+
+func decodeGraphQLBody(data string) (*graphqlBody, error) {
+	body := &graphqlBody{}
+	if err := jsoniter.Unmarshal([]byte(data), &body.fields); err != nil {
+		return nil, err
+	}
+	query, ok := body.fields["query"]
+	if !ok {
+		return nil, fmt.Errorf("graphql: missing query")
+	}
+	if err := jsoniter.Unmarshal(query, &body.query); err != nil || strings.TrimSpace(body.query) == "" {
+		return nil, fmt.Errorf("graphql: invalid query")
+	}
+	if variables, ok := body.fields["variables"]; ok && string(variables) != "null" {
+		if err := jsoniter.Unmarshal(variables, &body.variables); err != nil {
+			return nil, fmt.Errorf("graphql: invalid variables: %w", err)
+		}
+	}
+	return body, nil
+}
+
+// flattenVariables adds the leaf values of the variables with ~ joined keys
+func flattenVariables(value any, key string, decoded *mapsutil.OrderedMap[string, any]) {
+	switch v := value.(type) {
+	case map[string]any:
+		prefix := key
+		if key != "$" {
+			prefix += "~"
+		}
+		for _, k := range mapsutil.GetSortedKeys(v) {
+			flattenVariables(v[k], prefix+k, decoded)
+		}
+	case []any:
+		for i, item := range v {
+			flattenVariables(item, key+"~"+strconv.Itoa(i), decoded)
+		}
+	default:
+		decoded.Set(key, value)
+	}
+}
+
+// rebuildVariables replaces the leaf values of the variables with the
+// current values, leaves whose key was deleted are removed
+func rebuildVariables(value any, key string, values map[string]any) (any, bool) {
+	switch v := value.(type) {
+	case map[string]any:
+		prefix := key
+		if key != "$" {
+			prefix += "~"
+		}
+		rebuilt := make(map[string]any, len(v))
+		for k, item := range v {
+			if item, ok := rebuildVariables(item, prefix+k, values); ok {
+				rebuilt[k] = item
+			}
+		}
+		return rebuilt, true
+	case []any:
+		rebuilt := make([]any, 0, len(v))
+		for i, item := range v {
+			if item, ok := rebuildVariables(item, key+"~"+strconv.Itoa(i), values); ok {
+				rebuilt = append(rebuilt, item)
+			}
+		}
+		return rebuilt, true
+	default:
+		current, ok := values[key]
+		return current, ok
+	}
+}
+
+// graphqlArgument is an inline argument literal of the query
+type graphqlArgument struct {
+	key   string
+	token graphqlutil.Token
+}
+
+var (
+	graphqlIntPattern   = regexp.MustCompile(`^-?(0|[1-9][0-9]*)$`)
+	graphqlFloatPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+	graphqlNamePattern  = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+)
+
+// literal returns the literal for the value, the literal keeps its
+// kind when the value is valid for it and is quoted as a string otherwise
+func (a *graphqlArgument) literal(value string) string {
+	if value == a.token.StringValue() {
+		return a.token.Value
+	}
+	switch a.token.Kind {
+	case graphqlutil.Int:
+		if graphqlIntPattern.MatchString(value) {
+			return value
+		}
+	case graphqlutil.Float:
+		if graphqlFloatPattern.MatchString(value) {
+			return value
+		}
+	case graphqlutil.Name:
+		// booleans, null and enum values
+		if graphqlNamePattern.MatchString(value) {
+			return value
+		}
+	}
+	return graphqlutil.Quote(value)
+}
+
+// graphqlArguments returns the inline argument literals of the query
+// in document order, directive arguments and default values are skipped
+func graphqlArguments(query string) ([]*graphqlArgument, error) {
+	p, err := graphqlutil.NewParser(query)
+	if err != nil {
+		return nil, err
+	}
+	d := &graphqlDocument{p: p, seen: make(map[string]int)}
+	if p.Is(graphqlutil.EOF, "") {
+		return nil, fmt.Errorf("graphql: empty document")
+	}
+	for !p.Is(graphqlutil.EOF, "") {
+		if err := d.definition(); err != nil {
+			return nil, err
+		}
+	}
+	return d.args, nil
+}
+
+type graphqlDocument struct {
+	p    *graphqlutil.Parser
+	args []*graphqlArgument
+	seen map[string]int
+}
+
+func (d *graphqlDocument) definition() error {
+	p := d.p
+	if p.Is(graphqlutil.Punct, "{") {
+		return d.selectionSet("")
+	}
+	keyword, err := p.Expect(graphqlutil.Name, "")
+	if err != nil {
+		return err
+	}
+	switch keyword.Value {
+	case "query", "mutation", "subscription":
+		p.Skip(graphqlutil.Name, "")
+		if p.Skip(graphqlutil.Punct, "(") {
+			for !p.Skip(graphqlutil.Punct, ")") {
+				if err := d.variableDefinition(); err != nil {
+					return err
+				}
+			}
+		}
+		if err := p.SkipDirectives(); err != nil {
+			return err
+		}
+		return d.selectionSet("")
+	case "fragment":
+		name, err := p.Expect(graphqlutil.Name, "")
+		if err != nil {
+			return err
+		}
+		if _, err := p.Expect(graphqlutil.Name, "on"); err != nil {
+			return err
+		}
+		if _, err := p.Expect(graphqlutil.Name, ""); err != nil {
+			return err
+		}
+		if err := p.SkipDirectives(); err != nil {
+			return err
+		}
+		return d.selectionSet(name.Value)
+	}
+	return fmt.Errorf("graphql: unexpected %q at %d", keyword.Value, keyword.Start)
+}
+
+// variableDefinition skips $name: Type = default @directives
+func (d *graphqlDocument) variableDefinition() error {
+	p := d.p
+	if _, err := p.Expect(graphqlutil.Punct, "$"); err != nil {
+		return err
+	}
+	if _, err := p.Expect(graphqlutil.Name, ""); err != nil {
+		return err
+	}
+	if _, err := p.Expect(graphqlutil.Punct, ":"); err != nil {
+		return err
+	}
+	if err := d.skipType(); err != nil {
+		return err
+	}
+	if p.Skip(graphqlutil.Punct, "=") {
+		if err := p.SkipValue(); err != nil {
+			return err
+		}
+	}
+	return p.SkipDirectives()
+}
+
+func (d *graphqlDocument) skipType() error {
+	p := d.p
+	if p.Skip(graphqlutil.Punct, "[") {
+		if err := d.skipType(); err != nil {
+			return err
+		}
+		if _, err := p.Expect(graphqlutil.Punct, "]"); err != nil {
+			return err
+		}
+	} else if _, err := p.Expect(graphqlutil.Name, ""); err != nil {
+		return err
+	}
+	p.Skip(graphqlutil.Punct, "!")
+	return nil
+}
+
+func (d *graphqlDocument) selectionSet(path string) error {
+	p := d.p
+	if _, err := p.Expect(graphqlutil.Punct, "{"); err != nil {
+		return err
+	}
+	for !p.Skip(graphqlutil.Punct, "}") {
+		if p.Skip(graphqlutil.Punct, "...") {
+			// fragment spread or inline fragment
+			if p.Skip(graphqlutil.Name, "on") {
+				if _, err := p.Expect(graphqlutil.Name, ""); err != nil {
+					return err
+				}
+			} else if !p.Is(graphqlutil.Punct, "{") && !p.Is(graphqlutil.Punct, "@") {
+				if _, err := p.Expect(graphqlutil.Name, ""); err != nil {
+					return err
+				}
+				if err := p.SkipDirectives(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := p.SkipDirectives(); err != nil {
+				return err
+			}
+			if err := d.selectionSet(path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, err := p.Expect(graphqlutil.Name, "")
+		if err != nil {
+			return err
+		}
+		if p.Skip(graphqlutil.Punct, ":") {
+			// alias: name
+			if _, err := p.Expect(graphqlutil.Name, ""); err != nil {
+				return err
+			}
+		}
+		fieldPath := joinGraphQLPath(path, name.Value)
+		if p.Skip(graphqlutil.Punct, "(") {
+			for !p.Skip(graphqlutil.Punct, ")") {
+				arg, err := p.Expect(graphqlutil.Name, "")
+				if err != nil {
+					return err
+				}
+				if _, err := p.Expect(graphqlutil.Punct, ":"); err != nil {
+					return err
+				}
+				if err := d.value(joinGraphQLPath(fieldPath, arg.Value)); err != nil {
+					return err
+				}
+			}
+		}
+		if err := p.SkipDirectives(); err != nil {
+			return err
+		}
+		if p.Is(graphqlutil.Punct, "{") {
+			if err := d.selectionSet(fieldPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// value records the scalar literals of an argument value
+func (d *graphqlDocument) value(key string) error {
+	p := d.p
+	switch {
+	case p.Is(graphqlutil.Punct, "$"):
+		// variables are fuzzed through the variables object
+		return p.SkipValue()
+	case p.Skip(graphqlutil.Punct, "["):
+		for i := 0; !p.Skip(graphqlutil.Punct, "]"); i++ {
+			if err := d.value(key + "~" + strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case p.Skip(graphqlutil.Punct, "{"):
+		for !p.Skip(graphqlutil.Punct, "}") {
+			name, err := p.Expect(graphqlutil.Name, "")
+			if err != nil {
+				return err
+			}
+			if _, err := p.Expect(graphqlutil.Punct, ":"); err != nil {
+				return err
+			}
+			if err := d.value(key + "~" + name.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	t := p.Peek()
+	if err := p.SkipValue(); err != nil {
+		return err
+	}
+	// the same path can appear in several operations
+	if n := d.seen[key]; n > 0 {
+		d.seen[key]++
+		key = fmt.Sprintf("%s#%d", key, n+1)
+	} else {
+		d.seen[key] = 1
+	}
+	d.args = append(d.args, &graphqlArgument{key: key, token: t})
+	return nil
+}
+
+func joinGraphQLPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "~" + name
+}
@@ -0,0 +1,75 @@
+package dataformat
+
+import (
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataformatDecodeEncode_GraphQL(t *testing.T) {
+	obj := `{"operationName":"GetUser","query":"query GetUser($id: ID!) { user(id: $id) { name latest: posts(first: 10, filter: {status: PUBLISHED, tags: [\"go\"]}) @include(if: true) { title } } }","variables":{"id":"1","input":{"email":"a@example.com","roles":["admin"]}}}`
+
+	decoded, err := Decode(obj)
+	require.Nil(t, err, "could not decode graphql body")
+	require.NotNil(t, decoded)
+	require.Equal(t, GraphQLDataFormat, decoded.DataFormat, "unexpected data format")
+
+	var keys []string
+	decoded.Data.Iterate(func(key string, value any) bool {
+		keys = append(keys, key)
+		return true
+	})
+	require.Equal(t, []string{
+		"#_graphql_body",
+		"#_graphql_query",
+		"$id",
+		"$input~email",
+		"$input~roles~0",
+		"user~latest~first",
+		"user~latest~filter~status",
+		"user~latest~filter~tags~0",
+	}, keys, "unexpected keys")
+	require.Equal(t, "10", decoded.Data.Get("user~latest~first"))
+	require.Equal(t, "go", decoded.Data.Get("user~latest~filter~tags~0"))
+
+	// unchanged data is encoded back as is
+	encoded, err := Encode(decoded.Data, decoded.DataFormat)
+	require.Nil(t, err, "could not encode graphql body")
+	require.JSONEq(t, obj, encoded)
+
+	decoded.Data.Set("$id", "1'")
+	decoded.Data.Set("$input~email", "a@example.com<script>")
+	decoded.Data.Set("user~latest~first", "10 OR 1=1")
+	decoded.Data.Set("user~latest~filter~status", "DRAFT")
+	decoded.Data.Set("user~latest~filter~tags~0", `go"}`)
+	decoded.Data.Delete("$input~roles~0")
+
+	encoded, err = Encode(decoded.Data, decoded.DataFormat)
+	require.Nil(t, err, "could not encode graphql body")
+
+	var body struct {
+		OperationName string                 `json:"operationName"`
+		Query         string                 `json:"query"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+	require.Nil(t, jsoniter.Unmarshal([]byte(encoded), &body))
+	require.Equal(t, "GetUser", body.OperationName)
+	require.Equal(t, `query GetUser($id: ID!) { user(id: $id) { name latest: posts(first: "10 OR 1=1", filter: {status: DRAFT, tags: ["go\"}"]}) @include(if: true) { title } } }`, body.Query)
+	require.Equal(t, map[string]interface{}{
+		"id":    "1'",
+		"input": map[string]interface{}{"email": "a@example.com<script>", "roles": []interface{}{}},
+	}, body.Variables)
+}
+
+func TestDataformatGraphQLIsType(t *testing.T) {
+	graphql := NewGraphQL()
+
+	require.True(t, graphql.IsType(`{"query":"{ users { id } }"}`))
+	require.False(t, graphql.IsType(`{"query":"select * from users"}`), "invalid graphql query")
+	require.False(t, graphql.IsType(`{"name":"query"}`), "missing query")
+
+	decoded, err := Decode(`{"foo":"bar"}`)
+	require.Nil(t, err)
+	require.Equal(t, JSONDataFormat, decoded.DataFormat, "json body should not be decoded as graphql")
+}
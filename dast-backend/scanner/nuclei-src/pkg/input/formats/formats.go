@@ -35,6 +35,14 @@ type InputFormatOptions struct {
 	VarsTextTemplating bool
 	// VarsFilePaths is the path to the file containing variables
 	VarsFilePaths []string
+	// FilterHosts only keeps requests to the given hosts,
+	// wildcards like *.example.com are supported
+	// Only available for HAR format
+	FilterHosts []string
+	// FilterMimeTypes only keeps requests whose response
+	// has one of the given mime types (like application/json)
+	// Only available for HAR format
+	FilterMimeTypes []string
 }
 
 // Format is an interface implemented by all input formats
@@ -0,0 +1,318 @@
+package graphql
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v3/pkg/input/formats"
+	"github.com/projectdiscovery/nuclei/v3/pkg/input/types"
+	"github.com/projectdiscovery/nuclei/v3/pkg/utils/json"
+	urlutil "github.com/projectdiscovery/utils/url"
+)
+
+// EndpointVariable is the variable containing the url of the GraphQL endpoint
+const EndpointVariable = "endpoint"
+
+const (
+	// maxInputDepth limits nested input objects in generated variables
+	maxInputDepth = 3
+	// maxSelectionDepth limits nested objects in generated selection sets
+	maxSelectionDepth = 2
+)
+
+// GraphQLFormat is a GraphQL schema (SDL or introspection result) parser
+type GraphQLFormat struct {
+	opts formats.InputFormatOptions
+}
+
+// New creates a new GraphQL schema parser
+func New() *GraphQLFormat {
+	return &GraphQLFormat{}
+}
+
+var _ formats.Format = &GraphQLFormat{}
+
+// Name returns the name of the format
+func (j *GraphQLFormat) Name() string {
+	return "graphql"
+}
+
+func (j *GraphQLFormat) SetOptions(options formats.InputFormatOptions) {
+	j.opts = options
+}
+
+// Parse parses the input and calls the provided callback
+// function for each RawRequest it discovers.
+//
+// A request is generated for each field of the query and mutation
+// types with the arguments passed as typed placeholder variables,
+// the requests are sent to the url of the endpoint variable.
+func (j *GraphQLFormat) Parse(input io.Reader, resultsCb formats.ParseReqRespCallback, filePath string) error {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return errors.Wrap(err, "could not read graphql schema")
+	}
+	var s *schema
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("{")) && bytes.Contains(trimmed, []byte(`"__schema"`)) {
+		s, err = parseIntrospection(trimmed)
+	} else {
+		s, err = parseSDL(string(data))
+	}
+	if err != nil {
+		return err
+	}
+
+	endpoint, ok := j.opts.Variables[EndpointVariable].(string)
+	if !ok || endpoint == "" {
+		return fmt.Errorf("graphql: missing %s variable, specify the graphql url using -var %s=https://example.com/graphql", EndpointVariable, EndpointVariable)
+	}
+	if _, err := urlutil.ParseAbsoluteURL(endpoint, false); err != nil {
+		return errors.Wrap(err, "graphql: invalid endpoint")
+	}
+
+	g := &generator{schema: s, opts: j.opts}
+	var count int
+	for _, operation := range []struct{ name, root string }{{"query", s.query}, {"mutation", s.mutation}} {
+		root, ok := s.types[operation.root]
+		if operation.root == "" || !ok {
+			continue
+		}
+		for _, f := range root.fields {
+			if strings.HasPrefix(f.name, "__") {
+				continue
+			}
+			rr, err := buildRequest(endpoint, g.operation(operation.name, f))
+			if err != nil {
+				gologger.Warning().Msgf("graphql: Could not build request for %s %s: %s\n", operation.name, f.name, err)
+				continue
+			}
+			count++
+			resultsCb(rr)
+		}
+	}
+	if count == 0 {
+		return errors.New("no queries or mutations found in graphql schema")
+	}
+	return nil
+}
+
+// requestBody is the json body of a GraphQL request
+type requestBody struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+func buildRequest(endpoint string, body *requestBody) (*types.RequestResponse, error) {
+	bin, err := json.Marshal(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not encode body")
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(bin))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	dumped, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not dump request")
+	}
+	rr, err := types.ParseRawRequestWithURL(string(dumped), req.URL.String())
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse raw request")
+	}
+	return rr, nil
+}
+
+// generator generates operations and placeholder values from a schema
+type generator struct {
+	schema *schema
+	opts   formats.InputFormatOptions
+}
+
+// operation generates an operation for a root field, arguments are
+// passed as variables, optional ones are skipped with RequiredOnly
+func (g *generator) operation(operation string, f *field) *requestBody {
+	body := &requestBody{OperationName: f.name}
+	var definitions, arguments []string
+	for _, arg := range f.args {
+		required := arg.typ.nonNull() && !arg.hasDefault
+		if g.opts.RequiredOnly && !required {
+			continue
+		}
+		if body.Variables == nil {
+			body.Variables = make(map[string]interface{})
+		}
+		definitions = append(definitions, "$"+arg.name+": "+arg.typ.String())
+		arguments = append(arguments, arg.name+": $"+arg.name)
+		if value, ok := g.opts.Variables[arg.name]; ok {
+			body.Variables[arg.name] = g.convert(value, arg.typ)
+		} else {
+			body.Variables[arg.name] = g.value(arg.typ, 0)
+		}
+	}
+
+	var query strings.Builder
+	query.WriteString(operation + " " + f.name)
+	if len(definitions) > 0 {
+		query.WriteString("(" + strings.Join(definitions, ", ") + ")")
+	}
+	query.WriteString(" { " + f.name)
+	if len(arguments) > 0 {
+		query.WriteString("(" + strings.Join(arguments, ", ") + ")")
+	}
+	if selection := g.selection(f.typ, 0); selection != "" {
+		query.WriteString(" " + selection)
+	}
+	query.WriteString(" }")
+	body.Query = query.String()
+	return body
+}
+
+// selection returns the selection set for the type, scalar fields and
+// nested objects up to maxSelectionDepth are selected, fields with
+// required arguments are skipped
+func (g *generator) selection(t *typeRef, depth int) string {
+	def := g.schema.types[t.named()]
+	if def == nil || def.kind == kindScalar || def.kind == kindEnum {
+		return ""
+	}
+	if def.kind == kindUnion {
+		return "{ __typename }"
+	}
+
+	var fields []string
+	for _, f := range def.fields {
+		if strings.HasPrefix(f.name, "__") || hasRequiredArgs(f) {
+			continue
+		}
+		fieldDef := g.schema.types[f.typ.named()]
+		if fieldDef == nil || fieldDef.kind == kindScalar || fieldDef.kind == kindEnum {
+			fields = append(fields, f.name)
+			continue
+		}
+		if depth+1 >= maxSelectionDepth {
+			continue
+		}
+		if sub := g.selection(f.typ, depth+1); sub != "" {
+			fields = append(fields, f.name+" "+sub)
+		}
+	}
+	if len(fields) == 0 {
+		return "{ __typename }"
+	}
+	return "{ " + strings.Join(fields, " ") + " }"
+}
+
+func hasRequiredArgs(f *field) bool {
+	for _, arg := range f.args {
+		if arg.typ.nonNull() && !arg.hasDefault {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns a typed placeholder value for the type
+func (g *generator) value(t *typeRef, depth int) interface{} {
+	switch t.Kind {
+	case kindNonNull:
+		return g.value(t.OfType, depth)
+	case kindList:
+		return []interface{}{g.value(t.OfType, depth)}
+	}
+
+	def := g.schema.types[t.Name]
+	if def == nil {
+		return scalarValue(t.Name)
+	}
+	switch def.kind {
+	case kindEnum:
+		if len(def.enumValues) > 0 {
+			return def.enumValues[0]
+		}
+		return ""
+	case kindInputObject:
+		object := make(map[string]interface{})
+		if depth >= maxInputDepth {
+			// recursive inputs
+			return object
+		}
+		for _, f := range def.inputFields {
+			if g.opts.RequiredOnly && (!f.typ.nonNull() || f.hasDefault) {
+				continue
+			}
+			object[f.name] = g.value(f.typ, depth+1)
+		}
+		return object
+	}
+	return scalarValue(t.Name)
+}
+
+// convert converts a user variable to the type of the argument
+func (g *generator) convert(value interface{}, t *typeRef) interface{} {
+	str, ok := value.(string)
+	if !ok {
+		return value
+	}
+	switch t.named() {
+	case "Int":
+		if v, err := strconv.ParseInt(str, 10, 64); err == nil {
+			return v
+		}
+	case "Float":
+		if v, err := strconv.ParseFloat(str, 64); err == nil {
+			return v
+		}
+	case "Boolean":
+		if v, err := strconv.ParseBool(str); err == nil {
+			return v
+		}
+	}
+	return str
+}
+
+// scalarValue returns a placeholder for built-in and common custom scalars
+func scalarValue(name string) interface{} {
+	switch name {
+	case "Int":
+		return 1
+	case "Float":
+		return 1.5
+	case "Boolean":
+		return true
+	case "ID":
+		return "1"
+	case "String":
+		return "string"
+	}
+	lower := strings.ToLower(name)
+	switch {
+	case strings.Contains(lower, "datetime") || strings.Contains(lower, "timestamp"):
+		return "2024-01-01T00:00:00Z"
+	case strings.Contains(lower, "date"):
+		return "2024-01-01"
+	case strings.Contains(lower, "email"):
+		return "user@example.com"
+	case strings.Contains(lower, "url") || strings.Contains(lower, "uri"):
+		return "https://example.com"
+	case strings.Contains(lower, "uuid"):
+		return "00000000-0000-0000-0000-000000000000"
+	case strings.Contains(lower, "json"):
+		return map[string]interface{}{}
+	case strings.Contains(lower, "long") || strings.Contains(lower, "int"):
+		return 1
+	case strings.Contains(lower, "decimal") || strings.Contains(lower, "float"):
+		return 1.5
+	}
+	return "string"
+}
@@ -0,0 +1,100 @@
+package graphql
+
+import (
+	"os"
+	"testing"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/input/formats"
+	"github.com/projectdiscovery/nuclei/v3/pkg/input/types"
+	"github.com/projectdiscovery/nuclei/v3/pkg/utils/json"
+	"github.com/stretchr/testify/require"
+)
+
+func parseFile(t *testing.T, path string, options formats.InputFormatOptions) map[string]*requestBody {
+	t.Helper()
+
+	format := New()
+	format.SetOptions(options)
+
+	file, err := os.Open(path)
+	require.Nilf(t, err, "error opening graphql input file: %v", err)
+	defer func() {
+		_ = file.Close()
+	}()
+
+	bodies := make(map[string]*requestBody)
+	err = format.Parse(file, func(request *types.RequestResponse) bool {
+		require.Equal(t, "POST", request.Request.Method)
+		require.Equal(t, "https://example.com/graphql", request.URL.String())
+		contentType, _ := request.Request.Headers.Get("Content-Type")
+		require.Equal(t, "application/json", contentType)
+
+		var body requestBody
+		require.Nil(t, json.Unmarshal([]byte(request.Request.Body), &body), "could not decode body")
+		bodies[body.OperationName] = &body
+		return false
+	}, path)
+	require.Nil(t, err, "could not parse graphql schema")
+	return bodies
+}
+
+func TestGraphQLParseSDL(t *testing.T) {
+	bodies := parseFile(t, "../testdata/graphql.graphql", formats.InputFormatOptions{
+		Variables: map[string]interface{}{"endpoint": "https://example.com/graphql", "term": "admin"},
+	})
+
+	var names []string
+	for name := range bodies {
+		names = append(names, name)
+	}
+	require.ElementsMatch(t, []string{"user", "posts", "search", "me", "node", "createPost", "deletePost"}, names)
+
+	user := bodies["user"]
+	require.Equal(t, "query user($id: ID!) { user(id: $id) { id name email posts { id title status createdAt } } }", user.Query)
+	require.Equal(t, map[string]interface{}{"id": "1"}, user.Variables)
+
+	search := bodies["search"]
+	require.Equal(t, "query search($term: String!, $limit: Int) { search(term: $term, limit: $limit) { __typename } }", search.Query)
+	require.Equal(t, map[string]interface{}{"term": "admin", "limit": float64(1)}, search.Variables)
+
+	createPost := bodies["createPost"]
+	require.Equal(t, "mutation createPost($input: PostInput!) { createPost(input: $input) { id title status createdAt author { id name email } } }", createPost.Query)
+	require.Equal(t, map[string]interface{}{
+		"input": map[string]interface{}{
+			"title":     "string",
+			"body":      "string",
+			"status":    "DRAFT",
+			"tags":      []interface{}{"string"},
+			"publishAt": "2024-01-01T00:00:00Z",
+		},
+	}, createPost.Variables)
+
+	require.Equal(t, "query me { me { id name email posts { id title status createdAt } } }", bodies["me"].Query)
+	require.Nil(t, bodies["me"].Variables)
+}
+
+func TestGraphQLParseIntrospection(t *testing.T) {
+	bodies := parseFile(t, "../testdata/graphql-introspection.json", formats.InputFormatOptions{
+		Variables:    map[string]interface{}{"endpoint": "https://example.com/graphql"},
+		RequiredOnly: true,
+	})
+	require.Len(t, bodies, 2)
+
+	product := bodies["product"]
+	require.Equal(t, "query product($sku: String!) { product(sku: $sku) { sku price } }", product.Query)
+	require.Equal(t, map[string]interface{}{"sku": "string"}, product.Variables)
+
+	products := bodies["products"]
+	require.Equal(t, "query products { products { sku price } }", products.Query)
+}
+
+func TestGraphQLParseMissingEndpoint(t *testing.T) {
+	file, err := os.Open("../testdata/graphql.graphql")
+	require.Nil(t, err)
+	defer func() {
+		_ = file.Close()
+	}()
+
+	err = New().Parse(file, func(request *types.RequestResponse) bool { return false }, "")
+	require.ErrorContains(t, err, "missing endpoint variable")
+}
@@ -0,0 +1,461 @@
+package graphql
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	graphqlutil "github.com/projectdiscovery/nuclei/v3/pkg/utils/graphql"
+	"github.com/projectdiscovery/nuclei/v3/pkg/utils/json"
+)
+
+// type kinds, same as the introspection __TypeKind values
+const (
+	kindScalar      = "SCALAR"
+	kindObject      = "OBJECT"
+	kindInterface   = "INTERFACE"
+	kindUnion       = "UNION"
+	kindEnum        = "ENUM"
+	kindInputObject = "INPUT_OBJECT"
+	kindList        = "LIST"
+	kindNonNull     = "NON_NULL"
+)
+
+// schema is a GraphQL schema loaded from SDL or an introspection result
+type schema struct {
+	query    string
+	mutation string
+	types    map[string]*typeDef
+}
+
+type typeDef struct {
+	kind        string
+	name        string
+	fields      []*field
+	inputFields []*inputValue
+	enumValues  []string
+}
+
+type field struct {
+	name string
+	args []*inputValue
+	typ  *typeRef
+}
+
+type inputValue struct {
+	name       string
+	typ        *typeRef
+	hasDefault bool
+}
+
+// typeRef is a named, list or non-null type reference
+type typeRef struct {
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	OfType *typeRef `json:"ofType"`
+}
+
+// named returns the name of the underlying named type
+func (t *typeRef) named() string {
+	for t != nil && t.Name == "" {
+		t = t.OfType
+	}
+	if t == nil {
+		return ""
+	}
+	return t.Name
+}
+
+// String returns the type as written in GraphQL (like [ID!]!)
+func (t *typeRef) String() string {
+	if t == nil {
+		return ""
+	}
+	switch t.Kind {
+	case kindNonNull:
+		return t.OfType.String() + "!"
+	case kindList:
+		return "[" + t.OfType.String() + "]"
+	}
+	return t.Name
+}
+
+func (t *typeRef) nonNull() bool {
+	return t != nil && t.Kind == kindNonNull
+}
+
+func newSchema() *schema {
+	return &schema{types: make(map[string]*typeDef)}
+}
+
+// definition returns the type with the given name creating
+// it when needed, extensions may come before the definition
+func (s *schema) definition(kind, name string) *typeDef {
+	def, ok := s.types[name]
+	if !ok {
+		def = &typeDef{kind: kind, name: name}
+		s.types[name] = def
+	}
+	return def
+}
+
+// introspection is the result of an introspection query
+type introspection struct {
+	Data *struct {
+		Schema *introspectionSchema `json:"__schema"`
+	} `json:"data"`
+	Schema *introspectionSchema `json:"__schema"`
+}
+
+type introspectionSchema struct {
+	QueryType *struct {
+		Name string `json:"name"`
+	} `json:"queryType"`
+	MutationType *struct {
+		Name string `json:"name"`
+	} `json:"mutationType"`
+	Types []struct {
+		Kind   string `json:"kind"`
+		Name   string `json:"name"`
+		Fields []struct {
+			Name string                    `json:"name"`
+			Args []introspectionInputValue `json:"args"`
+			Type *typeRef                  `json:"type"`
+		} `json:"fields"`
+		InputFields []introspectionInputValue `json:"inputFields"`
+		EnumValues  []struct {
+			Name string `json:"name"`
+		} `json:"enumValues"`
+	} `json:"types"`
+}
+
+type introspectionInputValue struct {
+	Name         string   `json:"name"`
+	Type         *typeRef `json:"type"`
+	DefaultValue *string  `json:"defaultValue"`
+}
+
+func (v *introspectionInputValue) inputValue() *inputValue {
+	return &inputValue{name: v.Name, typ: v.Type, hasDefault: v.DefaultValue != nil}
+}
+
+// parseIntrospection parses an introspection result, with or without the data wrapper
+func parseIntrospection(data []byte) (*schema, error) {
+	var result introspection
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, errors.Wrap(err, "could not decode introspection result")
+	}
+	raw := result.Schema
+	if raw == nil && result.Data != nil {
+		raw = result.Data.Schema
+	}
+	if raw == nil {
+		return nil, errors.New("no __schema found in introspection result")
+	}
+
+	s := newSchema()
+	if raw.QueryType != nil {
+		s.query = raw.QueryType.Name
+	}
+	if raw.MutationType != nil {
+		s.mutation = raw.MutationType.Name
+	}
+	for _, t := range raw.Types {
+		def := s.definition(t.Kind, t.Name)
+		for _, f := range t.Fields {
+			fd := &field{name: f.Name, typ: f.Type}
+			for _, arg := range f.Args {
+				fd.args = append(fd.args, arg.inputValue())
+			}
+			def.fields = append(def.fields, fd)
+		}
+		for _, f := range t.InputFields {
+			def.inputFields = append(def.inputFields, f.inputValue())
+		}
+		for _, v := range t.EnumValues {
+			def.enumValues = append(def.enumValues, v.Name)
+		}
+	}
+	return s, nil
+}
+
+// parseSDL parses a schema written in the GraphQL schema definition language
+func parseSDL(data string) (*schema, error) {
+	p, err := graphqlutil.NewParser(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse schema")
+	}
+	s := newSchema()
+	sp := &sdlParser{p: p, s: s}
+	for !p.Is(graphqlutil.EOF, "") {
+		if err := sp.definition(); err != nil {
+			return nil, errors.Wrap(err, "could not parse schema")
+		}
+	}
+	// default root operation type names
+	if s.query == "" {
+		if _, ok := s.types["Query"]; ok {
+			s.query = "Query"
+		}
+	}
+	if s.mutation == "" {
+		if _, ok := s.types["Mutation"]; ok {
+			s.mutation = "Mutation"
+		}
+	}
+	return s, nil
+}
+
+type sdlParser struct {
+	p *graphqlutil.Parser
+	s *schema
+}
+
+func (sp *sdlParser) skipDescription() {
+	if !sp.p.Skip(graphqlutil.String, "") {
+		sp.p.Skip(graphqlutil.BlockString, "")
+	}
+}
+
+func (sp *sdlParser) definition() error {
+	p := sp.p
+	sp.skipDescription()
+	p.Skip(graphqlutil.Name, "extend")
+	keyword, err := p.Expect(graphqlutil.Name, "")
+	if err != nil {
+		return err
+	}
+
+	switch keyword.Value {
+	case "schema":
+		if err := p.SkipDirectives(); err != nil {
+			return err
+		}
+		if !p.Skip(graphqlutil.Punct, "{") {
+			return nil
+		}
+		for !p.Skip(graphqlutil.Punct, "}") {
+			operation, err := p.Expect(graphqlutil.Name, "")
+			if err != nil {
+				return err
+			}
+			if _, err := p.Expect(graphqlutil.Punct, ":"); err != nil {
+				return err
+			}
+			name, err := p.Expect(graphqlutil.Name, "")
+			if err != nil {
+				return err
+			}
+			switch operation.Value {
+			case "query":
+				sp.s.query = name.Value
+			case "mutation":
+				sp.s.mutation = name.Value
+			}
+		}
+		return nil
+	case "scalar":
+		name, err := p.Expect(graphqlutil.Name, "")
+		if err != nil {
+			return err
+		}
+		sp.s.definition(kindScalar, name.Value)
+		return p.SkipDirectives()
+	case "type", "interface":
+		kind := kindObject
+		if keyword.Value == "interface" {
+			kind = kindInterface
+		}
+		name, err := p.Expect(graphqlutil.Name, "")
+		if err != nil {
+			return err
+		}
+		def := sp.s.definition(kind, name.Value)
+		if p.Skip(graphqlutil.Name, "implements") {
+			p.Skip(graphqlutil.Punct, "&")
+			for p.Is(graphqlutil.Name, "") {
+				p.Next()
+				p.Skip(graphqlutil.Punct, "&")
+			}
+		}
+		if err := p.SkipDirectives(); err != nil {
+			return err
+		}
+		return sp.fields(def)
+	case "input":
+		name, err := p.Expect(graphqlutil.Name, "")
+		if err != nil {
+			return err
+		}
+		def := sp.s.definition(kindInputObject, name.Value)
+		if err := p.SkipDirectives(); err != nil {
+			return err
+		}
+		if !p.Skip(graphqlutil.Punct, "{") {
+			return nil
+		}
+		for !p.Skip(graphqlutil.Punct, "}") {
+			value, err := sp.inputValue()
+			if err != nil {
+				return err
+			}
+			def.inputFields = append(def.inputFields, value)
+		}
+		return nil
+	case "enum":
+		name, err := p.Expect(graphqlutil.Name, "")
+		if err != nil {
+			return err
+		}
+		def := sp.s.definition(kindEnum, name.Value)
+		if err := p.SkipDirectives(); err != nil {
+			return err
+		}
+		if !p.Skip(graphqlutil.Punct, "{") {
+			return nil
+		}
+		for !p.Skip(graphqlutil.Punct, "}") {
+			sp.skipDescription()
+			value, err := p.Expect(graphqlutil.Name, "")
+			if err != nil {
+				return err
+			}
+			def.enumValues = append(def.enumValues, value.Value)
+			if err := p.SkipDirectives(); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "union":
+		name, err := p.Expect(graphqlutil.Name, "")
+		if err != nil {
+			return err
+		}
+		sp.s.definition(kindUnion, name.Value)
+		if err := p.SkipDirectives(); err != nil {
+			return err
+		}
+		if p.Skip(graphqlutil.Punct, "=") {
+			p.Skip(graphqlutil.Punct, "|")
+			for p.Is(graphqlutil.Name, "") {
+				p.Next()
+				if !p.Skip(graphqlutil.Punct, "|") {
+					break
+				}
+			}
+		}
+		return nil
+	case "directive":
+		if _, err := p.Expect(graphqlutil.Punct, "@"); err != nil {
+			return err
+		}
+		if _, err := p.Expect(graphqlutil.Name, ""); err != nil {
+			return err
+		}
+		if p.Skip(graphqlutil.Punct, "(") {
+			for !p.Skip(graphqlutil.Punct, ")") {
+				if _, err := sp.inputValue(); err != nil {
+					return err
+				}
+			}
+		}
+		p.Skip(graphqlutil.Name, "repeatable")
+		if _, err := p.Expect(graphqlutil.Name, "on"); err != nil {
+			return err
+		}
+		p.Skip(graphqlutil.Punct, "|")
+		for p.Is(graphqlutil.Name, "") {
+			p.Next()
+			if !p.Skip(graphqlutil.Punct, "|") {
+				break
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unexpected %q at %d", keyword.Value, keyword.Start)
+}
+
+// fields parses the { name(args): Type } block of object and interface types
+func (sp *sdlParser) fields(def *typeDef) error {
+	p := sp.p
+	if !p.Skip(graphqlutil.Punct, "{") {
+		return nil
+	}
+	for !p.Skip(graphqlutil.Punct, "}") {
+		sp.skipDescription()
+		name, err := p.Expect(graphqlutil.Name, "")
+		if err != nil {
+			return err
+		}
+		fd := &field{name: name.Value}
+		if p.Skip(graphqlutil.Punct, "(") {
+			for !p.Skip(graphqlutil.Punct, ")") {
+				arg, err := sp.inputValue()
+				if err != nil {
+					return err
+				}
+				fd.args = append(fd.args, arg)
+			}
+		}
+		if _, err := p.Expect(graphqlutil.Punct, ":"); err != nil {
+			return err
+		}
+		if fd.typ, err = sp.typeRef(); err != nil {
+			return err
+		}
+		if err := p.SkipDirectives(); err != nil {
+			return err
+		}
+		def.fields = append(def.fields, fd)
+	}
+	return nil
+}
+
+// inputValue parses an argument or input field: name: Type = default @directives
+func (sp *sdlParser) inputValue() (*inputValue, error) {
+	p := sp.p
+	sp.skipDescription()
+	name, err := p.Expect(graphqlutil.Name, "")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.Expect(graphqlutil.Punct, ":"); err != nil {
+		return nil, err
+	}
+	value := &inputValue{name: name.Value}
+	if value.typ, err = sp.typeRef(); err != nil {
+		return nil, err
+	}
+	if p.Skip(graphqlutil.Punct, "=") {
+		value.hasDefault = true
+		if err := p.SkipValue(); err != nil {
+			return nil, err
+		}
+	}
+	return value, p.SkipDirectives()
+}
+
+func (sp *sdlParser) typeRef() (*typeRef, error) {
+	p := sp.p
+	var ref *typeRef
+	if p.Skip(graphqlutil.Punct, "[") {
+		ofType, err := sp.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.Expect(graphqlutil.Punct, "]"); err != nil {
+			return nil, err
+		}
+		ref = &typeRef{Kind: kindList, OfType: ofType}
+	} else {
+		name, err := p.Expect(graphqlutil.Name, "")
+		if err != nil {
+			return nil, err
+		}
+		// kind of named types is resolved from the schema when needed
+		ref = &typeRef{Name: name.Value}
+	}
+	if p.Skip(graphqlutil.Punct, "!") {
+		ref = &typeRef{Kind: kindNonNull, OfType: ref}
+	}
+	return ref, nil
+}
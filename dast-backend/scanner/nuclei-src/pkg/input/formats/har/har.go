@@ -0,0 +1,356 @@
+package har
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v3/pkg/input/formats"
+	"github.com/projectdiscovery/nuclei/v3/pkg/input/types"
+	"github.com/projectdiscovery/nuclei/v3/pkg/utils/json"
+	mapsutil "github.com/projectdiscovery/utils/maps"
+)
+
+// HarFormat is a HAR (HTTP Archive) 1.2 File parser
+type HarFormat struct {
+	opts formats.InputFormatOptions
+}
+
+// New creates a new HAR File parser
+func New() *HarFormat {
+	return &HarFormat{}
+}
+
+var _ formats.Format = &HarFormat{}
+
+// Name returns the name of the format
+func (j *HarFormat) Name() string {
+	return "har"
+}
+
+func (j *HarFormat) SetOptions(options formats.InputFormatOptions) {
+	j.opts = options
+}
+
+// Parse parses the input and calls the provided callback
+// function for each RawRequest it discovers.
+//
+// Entries are filtered by FilterHosts and FilterMimeTypes, static
+// assets are skipped and identical requests are only returned once.
+func (j *HarFormat) Parse(input io.Reader, resultsCb formats.ParseReqRespCallback, filePath string) error {
+	var archive har
+	if err := json.NewDecoder(input).Decode(&archive); err != nil {
+		return errors.Wrap(err, "could not decode har file")
+	}
+	if archive.Log == nil {
+		return errors.New("no log found in har file")
+	}
+
+	seen := make(map[[32]byte]struct{})
+	for i, e := range archive.Log.Entries {
+		if e == nil || e.Request == nil {
+			continue
+		}
+		parsed, err := url.Parse(e.Request.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			// data:, blob:, ws: and browser internal urls
+			continue
+		}
+		if strings.EqualFold(e.ResourceType, "websocket") {
+			continue
+		}
+		if !j.matchHost(parsed) {
+			continue
+		}
+		mimeType := ""
+		if e.Response != nil {
+			mimeType = baseMimeType(e.Response.Content.MimeType)
+		}
+		if len(j.opts.FilterMimeTypes) > 0 {
+			if !j.matchMimeType(mimeType) {
+				continue
+			}
+		} else if isStatic(e, parsed, mimeType) {
+			continue
+		}
+
+		rr, err := e.requestResponse(parsed)
+		if err != nil {
+			gologger.Warning().Msgf("har: Could not parse entry %d (%s): %s\n", i, e.Request.URL, err)
+			continue
+		}
+
+		key := sha256.Sum256([]byte(rr.Request.Method + " " + rr.URL.String() + "\n" + rr.Request.Body))
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		resultsCb(rr) // TODO: Handle false and true from callback
+	}
+	return nil
+}
+
+// matchHost reports whether the url matches one of the host filters,
+// filters can be a hostname, host:port or a *.example.com wildcard
+func (j *HarFormat) matchHost(u *url.URL) bool {
+	if len(j.opts.FilterHosts) == 0 {
+		return true
+	}
+	hostname := strings.ToLower(u.Hostname())
+	host := strings.ToLower(u.Host)
+	for _, filter := range j.opts.FilterHosts {
+		filter = strings.ToLower(strings.TrimSpace(filter))
+		if filter == "" {
+			continue
+		}
+		if filter == hostname || filter == host {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(filter, "*."); ok && strings.HasSuffix(hostname, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchMimeType reports whether the response mime type matches one
+// of the mime filters, filters can be a full type or a type/* wildcard
+func (j *HarFormat) matchMimeType(mimeType string) bool {
+	for _, filter := range j.opts.FilterMimeTypes {
+		filter = baseMimeType(filter)
+		if filter == "" {
+			continue
+		}
+		if filter == mimeType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(filter, "*"); ok && strings.HasPrefix(mimeType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func baseMimeType(mimeType string) string {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// static resource types recorded by chromium based browsers
+var staticResourceTypes = map[string]struct{}{
+	"image": {}, "stylesheet": {}, "font": {}, "media": {}, "script": {}, "manifest": {},
+}
+
+// static asset extensions
+var staticExtensions = map[string]struct{}{
+	".js": {}, ".mjs": {}, ".css": {}, ".map": {}, ".png": {}, ".jpg": {}, ".jpeg": {},
+	".gif": {}, ".svg": {}, ".ico": {}, ".webp": {}, ".bmp": {}, ".avif": {},
+	".woff": {}, ".woff2": {}, ".ttf": {}, ".eot": {}, ".otf": {},
+	".mp3": {}, ".mp4": {}, ".webm": {}, ".ogg": {}, ".wav": {},
+}
+
+// static asset mime type prefixes
+var staticMimeTypes = []string{
+	"image/", "font/", "audio/", "video/", "text/css",
+	"application/javascript", "text/javascript", "application/x-javascript",
+	"application/font-", "application/x-font-", "application/vnd.ms-fontobject",
+}
+
+// isStatic reports whether the entry is a static asset, only
+// requests without a body are considered static
+func isStatic(e *entry, u *url.URL, mimeType string) bool {
+	if method := strings.ToUpper(e.Request.Method); method != http.MethodGet && method != http.MethodHead {
+		return false
+	}
+	if _, ok := staticResourceTypes[strings.ToLower(e.ResourceType)]; ok {
+		return true
+	}
+	if _, ok := staticExtensions[strings.ToLower(path.Ext(u.Path))]; ok {
+		return true
+	}
+	for _, prefix := range staticMimeTypes {
+		if strings.HasPrefix(mimeType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// har is the root of a HAR 1.2 file
+type har struct {
+	Log *struct {
+		Entries []*entry `json:"entries"`
+	} `json:"log"`
+}
+
+type entry struct {
+	Request      *request  `json:"request"`
+	Response     *response `json:"response"`
+	ResourceType string    `json:"_resourceType"`
+}
+
+type nameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type request struct {
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	Headers  []nameValue `json:"headers"`
+	PostData *struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+		Params   []struct {
+			Name        string `json:"name"`
+			Value       string `json:"value"`
+			FileName    string `json:"fileName"`
+			ContentType string `json:"contentType"`
+		} `json:"params"`
+	} `json:"postData"`
+}
+
+type response struct {
+	Status     int         `json:"status"`
+	StatusText string      `json:"statusText"`
+	Headers    []nameValue `json:"headers"`
+	Content    struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+		Encoding string `json:"encoding"`
+	} `json:"content"`
+}
+
+// headers which are recomputed from the request
+var skipRequestHeaders = map[string]struct{}{
+	"host": {}, "content-length": {},
+}
+
+// requestResponse builds the raw request and the recorded response of the entry
+func (e *entry) requestResponse(u *url.URL) (*types.RequestResponse, error) {
+	method := strings.ToUpper(strings.TrimSpace(e.Request.Method))
+	if method == "" {
+		method = http.MethodGet
+	}
+	contentType, body, err := e.Request.body()
+	if err != nil {
+		return nil, err
+	}
+
+	var raw strings.Builder
+	fmt.Fprintf(&raw, "%s %s HTTP/1.1\r\n", method, strings.ReplaceAll(u.RequestURI(), " ", "%20"))
+	fmt.Fprintf(&raw, "Host: %s\r\n", u.Host)
+	for _, h := range e.Request.Headers {
+		name := strings.TrimSpace(h.Name)
+		// http/2 pseudo headers and empty values can not be written to a raw request
+		if name == "" || strings.HasPrefix(name, ":") || h.Value == "" {
+			continue
+		}
+		if _, ok := skipRequestHeaders[strings.ToLower(name)]; ok {
+			continue
+		}
+		if contentType != "" && strings.EqualFold(name, "Content-Type") {
+			continue
+		}
+		fmt.Fprintf(&raw, "%s: %s\r\n", name, h.Value)
+	}
+	if contentType != "" {
+		fmt.Fprintf(&raw, "Content-Type: %s\r\n", contentType)
+	}
+	if body != "" {
+		fmt.Fprintf(&raw, "Content-Length: %d\r\n", len(body))
+	}
+	raw.WriteString("\r\n")
+	raw.WriteString(body)
+
+	rr, err := types.ParseRawRequestWithURL(raw.String(), u.String())
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse raw request")
+	}
+	if e.Response != nil && e.Response.Status > 0 {
+		rr.Response = e.Response.httpResponse()
+	}
+	return rr, nil
+}
+
+// body returns the request body, a content-type is returned when the
+// body is rebuilt from params and the recorded one can not be used
+func (r *request) body() (string, string, error) {
+	if r.PostData == nil {
+		return "", "", nil
+	}
+	if r.PostData.Text != "" || len(r.PostData.Params) == 0 {
+		return "", r.PostData.Text, nil
+	}
+	mimeType := baseMimeType(r.PostData.MimeType)
+	if mimeType == "multipart/form-data" {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		for _, p := range r.PostData.Params {
+			var err error
+			if p.FileName != "" {
+				var part io.Writer
+				if part, err = writer.CreateFormFile(p.Name, p.FileName); err == nil {
+					_, err = part.Write([]byte(p.Value))
+				}
+			} else {
+				err = writer.WriteField(p.Name, p.Value)
+			}
+			if err != nil {
+				return "", "", errors.Wrap(err, "could not write form data")
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return "", "", errors.Wrap(err, "could not write form data")
+		}
+		return writer.FormDataContentType(), buf.String(), nil
+	}
+	// params of urlencoded bodies are recorded as sent
+	params := make([]string, 0, len(r.PostData.Params))
+	for _, p := range r.PostData.Params {
+		params = append(params, p.Name+"="+p.Value)
+	}
+	return "", strings.Join(params, "&"), nil
+}
+
+// httpResponse converts the recorded response
+func (r *response) httpResponse() *types.HttpResponse {
+	body := r.Content.Text
+	if strings.EqualFold(r.Content.Encoding, "base64") {
+		if decoded, err := base64.StdEncoding.DecodeString(body); err == nil {
+			body = string(decoded)
+		}
+	}
+	statusText := r.StatusText
+	if statusText == "" {
+		statusText = http.StatusText(r.Status)
+	}
+
+	resp := &types.HttpResponse{
+		StatusCode: r.Status,
+		Headers:    mapsutil.NewOrderedMap[string, string](),
+		Body:       body,
+	}
+	var raw strings.Builder
+	fmt.Fprintf(&raw, "HTTP/1.1 %d %s\r\n", r.Status, statusText)
+	for _, h := range r.Headers {
+		if h.Name == "" || strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		resp.Headers.Set(h.Name, h.Value)
+		fmt.Fprintf(&raw, "%s: %s\r\n", h.Name, h.Value)
+	}
+	raw.WriteString("\r\n")
+	raw.WriteString(body)
+	resp.Raw = raw.String()
+	return resp
+}
@@ -0,0 +1,92 @@
+package har
+
+import (
+	"os"
+	"testing"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/input/formats"
+	"github.com/projectdiscovery/nuclei/v3/pkg/input/types"
+	"github.com/stretchr/testify/require"
+)
+
+func parseFile(t *testing.T, format *HarFormat) map[string]*types.RequestResponse {
+	t.Helper()
+
+	harInputFile := "../testdata/walkthrough.har"
+	file, err := os.Open(harInputFile)
+	require.Nilf(t, err, "error opening har input file: %v", err)
+	defer func() {
+		_ = file.Close()
+	}()
+
+	requests := make(map[string]*types.RequestResponse)
+	err = format.Parse(file, func(request *types.RequestResponse) bool {
+		key := request.Request.Method + " " + request.URL.String()
+		require.NotContains(t, requests, key, "duplicate request")
+		requests[key] = request
+		return false
+	}, harInputFile)
+	require.Nil(t, err, "could not parse har file")
+	return requests
+}
+
+func keys(requests map[string]*types.RequestResponse) []string {
+	var got []string
+	for k := range requests {
+		got = append(got, k)
+	}
+	return got
+}
+
+func TestHarParse(t *testing.T) {
+	requests := parseFile(t, New())
+
+	require.ElementsMatch(t, []string{
+		"GET http://localhost:8080/login?next=/dashboard",
+		"POST http://localhost:8080/login",
+		"POST http://localhost:8080/api/items",
+		"GET https://api.example.com/v1/users?id=1",
+		"GET https://www.google-analytics.com/collect?v=1",
+	}, keys(requests), "could not get har urls")
+
+	login := requests["POST http://localhost:8080/login"]
+	require.Equal(t, "username=admin&password=admin%40123", login.Request.Body)
+	contentLength, _ := login.Request.Headers.Get("Content-Length")
+	require.Equal(t, "35", contentLength)
+	require.Equal(t, 302, login.Response.StatusCode)
+
+	items := requests["POST http://localhost:8080/api/items"]
+	require.Equal(t, `{"name":"item"}`, items.Request.Body)
+	_, ok := items.Request.Headers.Get(":path")
+	require.False(t, ok, "pseudo headers should be skipped")
+	cookie, _ := items.Request.Headers.Get("cookie")
+	require.Equal(t, "session=abc", cookie, "first request should be kept")
+	require.Equal(t, 201, items.Response.StatusCode)
+	require.Equal(t, `{"id":1}`, items.Response.Body)
+	require.Contains(t, items.Response.Raw, "HTTP/1.1 201 Created\r\n")
+
+	require.Nil(t, requests["GET https://www.google-analytics.com/collect?v=1"].Response, "failed requests have no response")
+}
+
+func TestHarParseFilters(t *testing.T) {
+	t.Run("hosts", func(t *testing.T) {
+		format := New()
+		format.SetOptions(formats.InputFormatOptions{FilterHosts: []string{"*.example.com", "localhost:8080"}})
+		require.ElementsMatch(t, []string{
+			"GET http://localhost:8080/login?next=/dashboard",
+			"POST http://localhost:8080/login",
+			"POST http://localhost:8080/api/items",
+			"GET https://api.example.com/v1/users?id=1",
+		}, keys(parseFile(t, format)))
+	})
+
+	t.Run("mime types", func(t *testing.T) {
+		format := New()
+		format.SetOptions(formats.InputFormatOptions{FilterMimeTypes: []string{"application/json", "image/*"}})
+		require.ElementsMatch(t, []string{
+			"GET http://localhost:8080/static/logo",
+			"POST http://localhost:8080/api/items",
+			"GET https://api.example.com/v1/users?id=1",
+		}, keys(parseFile(t, format)))
+	})
+}
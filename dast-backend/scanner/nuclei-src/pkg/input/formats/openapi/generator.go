@@ -58,8 +58,8 @@ func GenerateRequestsFromSchema(schema *openapi3.T, opts formats.InputFormatOpti
 				gologger.Verbose().Msgf("openapi: skipping all requests due to missing global auth parameter: %s\n", param.Value.Name)
 				return nil
 			} else {
-				// fatal error
-				gologger.Fatal().Msgf("openapi: missing global auth parameter: %s\n", param.Value.Name)
+				// fatal error, returned to the caller so that library users are not terminated
+				return errors.Errorf("openapi: missing global auth parameter: %s", param.Value.Name)
 			}
 		}
 	}
@@ -0,0 +1,724 @@
+package postman
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"mime/multipart"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v3/pkg/input/formats"
+	"github.com/projectdiscovery/nuclei/v3/pkg/input/types"
+	"github.com/projectdiscovery/nuclei/v3/pkg/utils/json"
+)
+
+// PostmanFormat is a Postman Collection (v2.0 / v2.1) parser
+type PostmanFormat struct {
+	opts formats.InputFormatOptions
+}
+
+// New creates a new Postman Collection parser
+func New() *PostmanFormat {
+	return &PostmanFormat{}
+}
+
+var _ formats.Format = &PostmanFormat{}
+
+// Name returns the name of the format
+func (j *PostmanFormat) Name() string {
+	return "postman"
+}
+
+func (j *PostmanFormat) SetOptions(options formats.InputFormatOptions) {
+	j.opts = options
+}
+
+// Parse parses the input and calls the provided callback
+// function for each RawRequest it discovers.
+//
+// Folders are walked recursively, collection and folder variables are
+// resolved with environment files (VarsFilePaths) and user variables
+// taking precedence, and auth blocks are inherited down the tree.
+func (j *PostmanFormat) Parse(input io.Reader, resultsCb formats.ParseReqRespCallback, filePath string) error {
+	var c collection
+	if err := json.NewDecoder(input).Decode(&c); err != nil {
+		return errors.Wrap(err, "could not decode postman collection")
+	}
+	if len(c.Item) == 0 {
+		return errors.New("no items found in postman collection")
+	}
+
+	overrides := make(map[string]string)
+	for _, path := range j.opts.VarsFilePaths {
+		env, err := readEnvironment(path)
+		if err != nil {
+			return errors.Wrapf(err, "could not read postman environment %s", path)
+		}
+		for k, v := range env {
+			overrides[k] = v
+		}
+	}
+	for k, v := range j.opts.Variables {
+		overrides[k] = fmt.Sprint(v)
+	}
+
+	p := &parser{
+		overrides: overrides,
+		missing:   make(map[string]struct{}),
+		callback:  resultsCb,
+	}
+	p.walk(c.Item, scopeVariables(nil, c.Variable), c.Auth, "")
+
+	if len(p.missing) > 0 {
+		missing := make([]string, 0, len(p.missing))
+		for k := range p.missing {
+			missing = append(missing, k)
+		}
+		sort.Strings(missing)
+		gologger.Warning().Msgf("postman: unresolved variables %s, you can specify these vars using -var flag in (key=value) format\n", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// parser holds state while walking a single collection
+type parser struct {
+	overrides map[string]string
+	missing   map[string]struct{}
+	callback  formats.ParseReqRespCallback
+}
+
+// walk visits items depth first, folders pass their variables
+// and auth down to the requests they contain
+func (p *parser) walk(items []*item, vars map[string]string, inherited *auth, folder string) {
+	for _, it := range items {
+		if it == nil {
+			continue
+		}
+		name := it.Name
+		if folder != "" {
+			name = folder + "/" + it.Name
+		}
+		if it.Request == nil {
+			// folders may define their own auth and variables
+			itemAuth := inherited
+			if it.Auth != nil && !strings.EqualFold(it.Auth.Type, "inherit") {
+				itemAuth = it.Auth
+			}
+			p.walk(it.Item, scopeVariables(vars, it.Variable), itemAuth, name)
+			continue
+		}
+
+		rr, err := p.build(it.Request, scopeVariables(vars, it.Variable), inherited)
+		if err != nil {
+			gologger.Warning().Msgf("postman: Could not build request %s: %s\n", name, err)
+			continue
+		}
+		p.callback(rr)
+	}
+}
+
+// build converts a postman request to a request response
+func (p *parser) build(raw json.Message, vars map[string]string, inherited *auth) (*types.RequestResponse, error) {
+	var req request
+	var rawURL string
+	if err := json.Unmarshal(raw, &rawURL); err == nil {
+		// request can be a plain url string
+		req.Method = http.MethodGet
+	} else if err := json.Unmarshal(raw, &req); err != nil {
+		return nil, errors.Wrap(err, "could not decode request")
+	}
+	r := &resolver{vars: vars, overrides: p.overrides, missing: p.missing}
+
+	target, err := req.buildURL(rawURL, r)
+	if err != nil {
+		return nil, err
+	}
+	method := strings.ToUpper(strings.TrimSpace(req.Method))
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	headers, err := parseHeaders(req.Header)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequest(method, target, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create request")
+	}
+	for _, h := range headers {
+		if h.Disabled || h.Key == "" {
+			continue
+		}
+		httpReq.Header.Add(r.replace(h.Key), r.replace(h.Value))
+	}
+
+	if req.Body != nil && !req.Body.Disabled {
+		if err := req.Body.apply(httpReq, r); err != nil {
+			return nil, err
+		}
+	}
+
+	reqAuth := inherited
+	if req.Auth != nil && !strings.EqualFold(req.Auth.Type, "inherit") {
+		reqAuth = req.Auth
+	}
+	if reqAuth != nil {
+		reqAuth.apply(httpReq, r)
+	}
+
+	dumped, err := httputil.DumpRequestOut(httpReq, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not dump request")
+	}
+	rr, err := types.ParseRawRequestWithURL(string(dumped), httpReq.URL.String())
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse raw request")
+	}
+	return rr, nil
+}
+
+// collection is a postman collection
+type collection struct {
+	Item     []*item    `json:"item"`
+	Auth     *auth      `json:"auth"`
+	Variable []variable `json:"variable"`
+}
+
+// item is either a folder (contains items) or a request
+type item struct {
+	Name     string       `json:"name"`
+	Item     []*item      `json:"item"`
+	Request  json.Message `json:"request"`
+	Auth     *auth        `json:"auth"`
+	Variable []variable   `json:"variable"`
+}
+
+// variable is a collection, folder or environment variable
+type variable struct {
+	Key      string      `json:"key"`
+	Value    interface{} `json:"value"`
+	Disabled bool        `json:"disabled"`
+	// Enabled is used by environment exports instead of disabled
+	Enabled *bool `json:"enabled"`
+}
+
+func (v *variable) active() bool {
+	if v.Enabled != nil {
+		return *v.Enabled
+	}
+	return !v.Disabled
+}
+
+// keyValue is a header, query or urlencoded body parameter
+type keyValue struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+type request struct {
+	URL    json.Message `json:"url"`
+	Method string       `json:"method"`
+	Header json.Message `json:"header"`
+	Body   *body        `json:"body"`
+	Auth   *auth        `json:"auth"`
+}
+
+type urlObject struct {
+	Raw      string       `json:"raw"`
+	Protocol string       `json:"protocol"`
+	Host     json.Message `json:"host"`
+	Port     string       `json:"port"`
+	Path     json.Message `json:"path"`
+	Query    []keyValue   `json:"query"`
+	Variable []keyValue   `json:"variable"`
+}
+
+// scopeVariables returns parent variables overridden by the given list
+func scopeVariables(parent map[string]string, list []variable) map[string]string {
+	if len(list) == 0 && parent != nil {
+		return parent
+	}
+	vars := make(map[string]string, len(parent)+len(list))
+	for k, v := range parent {
+		vars[k] = v
+	}
+	for _, v := range list {
+		if v.Key == "" || !v.active() || v.Value == nil {
+			continue
+		}
+		vars[v.Key] = fmt.Sprint(v.Value)
+	}
+	return vars
+}
+
+// readEnvironment reads the values of an exported postman environment
+func readEnvironment(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var env struct {
+		Values []variable `json:"values"`
+	}
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	return scopeVariables(nil, env.Values), nil
+}
+
+// parseHeaders parses headers given as a list or as a "Key: Value" string
+func parseHeaders(raw json.Message) ([]keyValue, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var list []keyValue
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list, nil
+	}
+	var str string
+	if err := json.Unmarshal(raw, &str); err != nil {
+		return nil, errors.Wrap(err, "could not decode headers")
+	}
+	for _, line := range strings.Split(str, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(key) == "" {
+			continue
+		}
+		list = append(list, keyValue{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value)})
+	}
+	return list, nil
+}
+
+// buildURL builds the request url from the structured url object,
+// falling back to the raw url when host is not available
+func (req *request) buildURL(rawURL string, r *resolver) (string, error) {
+	var u urlObject
+	if rawURL == "" && len(req.URL) > 0 {
+		if err := json.Unmarshal(req.URL, &rawURL); err != nil {
+			if err := json.Unmarshal(req.URL, &u); err != nil {
+				return "", errors.Wrap(err, "could not decode url")
+			}
+			rawURL = u.Raw
+		}
+	}
+
+	pathVars := make(map[string]string, len(u.Variable))
+	for _, v := range u.Variable {
+		pathVars[v.Key] = r.replace(v.Value)
+	}
+
+	var target string
+	host := r.replace(joinParts(u.Host, "."))
+	if host != "" {
+		protocol := u.Protocol
+		if protocol == "" {
+			protocol = "http"
+		}
+		// host is often a {{baseUrl}} variable which already contains the scheme
+		target = host
+		if !strings.Contains(host, "://") {
+			target = protocol + "://" + host
+		}
+		if u.Port != "" {
+			target += ":" + u.Port
+		}
+		if path := joinParts(u.Path, "/"); path != "" {
+			target += "/" + strings.TrimPrefix(path, "/")
+		}
+		var query []string
+		for _, q := range u.Query {
+			if q.Disabled || q.Key == "" {
+				continue
+			}
+			query = append(query, escapeQuery(r.replace(q.Key))+"="+escapeQuery(r.replace(q.Value)))
+		}
+		if len(query) > 0 {
+			target += "?" + strings.Join(query, "&")
+		}
+	} else {
+		target = rawURL
+	}
+	target = replacePathVariables(r.replace(strings.TrimSpace(target)), pathVars)
+	if target == "" {
+		return "", errors.New("empty url")
+	}
+	if !strings.Contains(target, "://") {
+		// postman defaults to http when protocol is omitted
+		target = "http://" + target
+	}
+	return target, nil
+}
+
+// joinParts joins host or path given either as a string or a list of
+// strings / {"value": ...} objects
+func joinParts(raw json.Message, sep string) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		return str
+	}
+	var list []json.Message
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return ""
+	}
+	parts := make([]string, 0, len(list))
+	for _, item := range list {
+		var part string
+		if err := json.Unmarshal(item, &part); err != nil {
+			var obj struct {
+				Value string `json:"value"`
+			}
+			_ = json.Unmarshal(item, &obj)
+			part = obj.Value
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, sep)
+}
+
+// replacePathVariables replaces :name path segments with url variables
+func replacePathVariables(target string, vars map[string]string) string {
+	if len(vars) == 0 {
+		return target
+	}
+	schemeEnd := strings.Index(target, "://") + 3
+	pathStart := strings.Index(target[schemeEnd:], "/")
+	if pathStart < 0 {
+		return target
+	}
+	pathStart += schemeEnd
+	path, rest := target[pathStart:], ""
+	if idx := strings.IndexAny(path, "?#"); idx >= 0 {
+		path, rest = path[:idx], path[idx:]
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") {
+			continue
+		}
+		if value, ok := vars[segment[1:]]; ok && value != "" {
+			segments[i] = value
+		}
+	}
+	return target[:pathStart] + strings.Join(segments, "/") + rest
+}
+
+// escapeQuery escapes only the characters which would break the query string,
+// other characters are sent as written in the collection
+var queryEscaper = strings.NewReplacer(" ", "%20", "&", "%26", "#", "%23")
+
+func escapeQuery(s string) string {
+	return queryEscaper.Replace(s)
+}
+
+type body struct {
+	Mode       string      `json:"mode"`
+	Raw        string      `json:"raw"`
+	URLEncoded []keyValue  `json:"urlencoded"`
+	FormData   []formParam `json:"formdata"`
+	GraphQL    *struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql"`
+	Options struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+	Disabled bool `json:"disabled"`
+}
+
+type formParam struct {
+	Key         string       `json:"key"`
+	Value       string       `json:"value"`
+	Type        string       `json:"type"`
+	Src         json.Message `json:"src"`
+	ContentType string       `json:"contentType"`
+	Disabled    bool         `json:"disabled"`
+}
+
+// content types for raw body languages
+var rawContentTypes = map[string]string{
+	"json":       "application/json",
+	"xml":        "application/xml",
+	"html":       "text/html",
+	"javascript": "application/javascript",
+	"text":       "text/plain",
+}
+
+// apply sets the body and the content-type (unless already set) on the request
+func (b *body) apply(req *http.Request, r *resolver) error {
+	var data, contentType string
+	switch b.Mode {
+	case "raw":
+		data = r.replace(b.Raw)
+		contentType = rawContentTypes[b.Options.Raw.Language]
+	case "urlencoded":
+		var params []string
+		for _, p := range b.URLEncoded {
+			if p.Disabled || p.Key == "" {
+				continue
+			}
+			params = append(params, formEscape(r.replace(p.Key))+"="+formEscape(r.replace(p.Value)))
+		}
+		data = strings.Join(params, "&")
+		contentType = "application/x-www-form-urlencoded"
+	case "formdata":
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		for _, p := range b.FormData {
+			if p.Disabled || p.Key == "" {
+				continue
+			}
+			if err := p.write(writer, r); err != nil {
+				return errors.Wrap(err, "could not write form data")
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return errors.Wrap(err, "could not write form data")
+		}
+		data = buf.String()
+		contentType = writer.FormDataContentType()
+	case "graphql":
+		if b.GraphQL == nil {
+			return nil
+		}
+		payload := map[string]interface{}{"query": r.replace(b.GraphQL.Query)}
+		if variables := strings.TrimSpace(r.replace(b.GraphQL.Variables)); variables != "" {
+			var parsed interface{}
+			if err := json.Unmarshal([]byte(variables), &parsed); err != nil {
+				return errors.Wrap(err, "could not decode graphql variables")
+			}
+			payload["variables"] = parsed
+		}
+		bin, err := json.Marshal(payload)
+		if err != nil {
+			return errors.Wrap(err, "could not encode graphql body")
+		}
+		data = string(bin)
+		contentType = "application/json"
+	default:
+		// file bodies reference local files of the exporter and are not available
+		return nil
+	}
+	if data == "" {
+		return nil
+	}
+	req.Body = io.NopCloser(strings.NewReader(data))
+	req.ContentLength = int64(len(data))
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return nil
+}
+
+// write writes a text field or a file part, files referenced by the
+// collection are local to the exporter so their name is kept with empty content
+func (p *formParam) write(writer *multipart.Writer, r *resolver) error {
+	key := r.replace(p.Key)
+	if p.Type != "file" {
+		if p.ContentType == "" {
+			return writer.WriteField(key, r.replace(p.Value))
+		}
+		header := make(map[string][]string)
+		header["Content-Disposition"] = []string{fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(key))}
+		header["Content-Type"] = []string{p.ContentType}
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		_, err = part.Write([]byte(r.replace(p.Value)))
+		return err
+	}
+	filename := "file"
+	var src string
+	if err := json.Unmarshal(p.Src, &src); err != nil {
+		var list []string
+		if err := json.Unmarshal(p.Src, &list); err == nil && len(list) > 0 {
+			src = list[0]
+		}
+	}
+	if src != "" {
+		filename = filepath.Base(src)
+	}
+	_, err := writer.CreateFormFile(key, filename)
+	return err
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// formEscape escapes urlencoded body parameters, leaving already
+// percent-encoded values untouched
+func formEscape(s string) string {
+	if strings.Contains(s, "%") {
+		return s
+	}
+	return strings.NewReplacer(" ", "+", "&", "%26", "=", "%3D", "+", "%2B", "#", "%23").Replace(s)
+}
+
+// auth is a postman auth block, attributes are a list of
+// key/value pairs (v2.1) or an object (v2.0)
+type auth struct {
+	Type   string       `json:"type"`
+	APIKey json.Message `json:"apikey"`
+	Basic  json.Message `json:"basic"`
+	Bearer json.Message `json:"bearer"`
+	OAuth2 json.Message `json:"oauth2"`
+}
+
+func authParams(raw json.Message) map[string]string {
+	params := make(map[string]string)
+	if len(raw) == 0 {
+		return params
+	}
+	var list []struct {
+		Key   string      `json:"key"`
+		Value interface{} `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &list); err == nil {
+		for _, kv := range list {
+			if kv.Value != nil {
+				params[kv.Key] = fmt.Sprint(kv.Value)
+			}
+		}
+		return params
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(raw, &obj); err == nil {
+		for k, v := range obj {
+			if v != nil {
+				params[k] = fmt.Sprint(v)
+			}
+		}
+	}
+	return params
+}
+
+// apply sets the authentication on the request, headers already
+// defined on the request take precedence
+func (a *auth) apply(req *http.Request, r *resolver) {
+	switch strings.ToLower(a.Type) {
+	case "", "noauth":
+	case "apikey":
+		params := authParams(a.APIKey)
+		key, value := r.replace(params["key"]), r.replace(params["value"])
+		if key == "" {
+			return
+		}
+		if strings.EqualFold(params["in"], "query") {
+			addQuery(req, key, value)
+		} else if req.Header.Get(key) == "" {
+			req.Header.Set(key, value)
+		}
+	case "basic":
+		params := authParams(a.Basic)
+		if req.Header.Get("Authorization") == "" {
+			req.SetBasicAuth(r.replace(params["username"]), r.replace(params["password"]))
+		}
+	case "bearer":
+		params := authParams(a.Bearer)
+		if token := r.replace(params["token"]); token != "" && req.Header.Get("Authorization") == "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	case "oauth2":
+		params := authParams(a.OAuth2)
+		token := r.replace(params["accessToken"])
+		if token == "" {
+			return
+		}
+		if params["addTokenTo"] == "queryParams" {
+			addQuery(req, "access_token", token)
+			return
+		}
+		prefix := params["headerPrefix"]
+		if prefix == "" {
+			prefix = "Bearer"
+		}
+		if req.Header.Get("Authorization") == "" {
+			req.Header.Set("Authorization", strings.TrimSpace(prefix+" "+token))
+		}
+	default:
+		gologger.Verbose().Msgf("postman: unsupported auth type %s\n", a.Type)
+	}
+}
+
+func addQuery(req *http.Request, key, value string) {
+	param := escapeQuery(key) + "=" + escapeQuery(value)
+	if req.URL.RawQuery == "" {
+		req.URL.RawQuery = param
+	} else {
+		req.URL.RawQuery += "&" + param
+	}
+}
+
+var variablePattern = regexp.MustCompile(`\{\{([^{}]+)\}\}`)
+
+// maxResolveDepth limits nested variable references
+const maxResolveDepth = 10
+
+// resolver replaces {{name}} references, user variables and environment
+// files take precedence over folder and collection variables
+type resolver struct {
+	vars      map[string]string
+	overrides map[string]string
+	missing   map[string]struct{}
+}
+
+func (r *resolver) replace(s string) string {
+	for i := 0; i < maxResolveDepth && strings.Contains(s, "{{"); i++ {
+		replaced := variablePattern.ReplaceAllStringFunc(s, func(match string) string {
+			name := strings.TrimSpace(match[2 : len(match)-2])
+			if value, ok := r.lookup(name); ok {
+				return value
+			}
+			r.missing[name] = struct{}{}
+			return match
+		})
+		if replaced == s {
+			break
+		}
+		s = replaced
+	}
+	return s
+}
+
+func (r *resolver) lookup(name string) (string, bool) {
+	if value, ok := r.overrides[name]; ok {
+		return value, true
+	}
+	if value, ok := r.vars[name]; ok {
+		return value, true
+	}
+	// postman dynamic variables
+	switch name {
+	case "$guid", "$randomUUID":
+		return uuid.NewString(), true
+	case "$timestamp":
+		return strconv.FormatInt(time.Now().Unix(), 10), true
+	case "$isoTimestamp":
+		return time.Now().UTC().Format(time.RFC3339), true
+	case "$randomInt":
+		return strconv.Itoa(rand.Intn(1001)), true
+	}
+	return "", false
+}
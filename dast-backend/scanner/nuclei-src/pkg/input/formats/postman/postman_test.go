@@ -0,0 +1,99 @@
+package postman
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/input/formats"
+	"github.com/projectdiscovery/nuclei/v3/pkg/input/types"
+	"github.com/stretchr/testify/require"
+)
+
+func parseFile(t *testing.T, format *PostmanFormat, path string) map[string]*types.RequestResponse {
+	t.Helper()
+
+	file, err := os.Open(path)
+	require.Nilf(t, err, "error opening postman input file: %v", err)
+	defer func() {
+		_ = file.Close()
+	}()
+
+	requests := make(map[string]*types.RequestResponse)
+	err = format.Parse(file, func(request *types.RequestResponse) bool {
+		requests[request.Request.Method+" "+request.URL.String()] = request
+		return false
+	}, path)
+	require.Nil(t, err, "could not parse postman collection")
+	return requests
+}
+
+func header(request *types.RequestResponse, key string) string {
+	value, _ := request.Request.Headers.Get(key)
+	return value
+}
+
+func TestPostmanParse(t *testing.T) {
+	format := New()
+
+	var gotMethodsToURLs []string
+	for k := range parseFile(t, format, "../testdata/postman.json") {
+		gotMethodsToURLs = append(gotMethodsToURLs, k)
+	}
+	require.Len(t, gotMethodsToURLs, 4, "invalid number of requests")
+	for _, k := range gotMethodsToURLs {
+		require.Contains(t, k, "http://127.0.0.1:8000/api/v1/search/")
+	}
+}
+
+func TestPostmanParseNested(t *testing.T) {
+	format := New()
+	format.SetOptions(formats.InputFormatOptions{
+		Variables:     map[string]interface{}{"petName": "kitty", "graphId": "42"},
+		VarsFilePaths: []string{"../testdata/postman-environment.json"},
+	})
+	requests := parseFile(t, format, "../testdata/postman-nested.json")
+
+	var got []string
+	for k := range requests {
+		got = append(got, k)
+	}
+	require.ElementsMatch(t, []string{
+		"GET http://127.0.0.1:8080/api/pets/1?expand=owner",
+		"POST http://127.0.0.1:8080/api/pets",
+		"POST http://127.0.0.1:8080/api/admin/search",
+		"POST http://127.0.0.1:8080/api/admin/photo",
+		"POST https://127.0.0.1:8080/graphql?api_key=environment-key",
+	}, got, "could not get postman urls")
+
+	t.Run("inherited auth", func(t *testing.T) {
+		// environment overrides the collection token
+		get := requests["GET http://127.0.0.1:8080/api/pets/1?expand=owner"]
+		require.Equal(t, "Bearer environment-token", header(get, "Authorization"))
+
+		search := requests["POST http://127.0.0.1:8080/api/admin/search"]
+		require.Equal(t, "Basic YWRtaW46c2VjcmV0", header(search, "Authorization"))
+
+		photo := requests["POST http://127.0.0.1:8080/api/admin/photo"]
+		require.Empty(t, header(photo, "Authorization"))
+	})
+
+	t.Run("bodies", func(t *testing.T) {
+		create := requests["POST http://127.0.0.1:8080/api/pets"]
+		require.Equal(t, `{"name":"kitty"}`, create.Request.Body)
+		require.Equal(t, "application/json", header(create, "Content-Type"))
+		require.Len(t, header(create, "X-Trace"), 36, "could not resolve dynamic variable")
+
+		search := requests["POST http://127.0.0.1:8080/api/admin/search"]
+		require.Equal(t, "q=cat+dog&limit=10", search.Request.Body)
+		require.Equal(t, "application/x-www-form-urlencoded", header(search, "Content-Type"))
+
+		photo := requests["POST http://127.0.0.1:8080/api/admin/photo"]
+		require.True(t, strings.HasPrefix(header(photo, "Content-Type"), "multipart/form-data; boundary="))
+		require.Contains(t, photo.Request.Body, `name="petId"`)
+		require.Contains(t, photo.Request.Body, `name="photo"; filename="cat.png"`)
+
+		graphql := requests["POST https://127.0.0.1:8080/graphql?api_key=environment-key"]
+		require.JSONEq(t, `{"query":"query Pet($id: ID!) { pet(id: $id) { name } }","variables":{"id":"42"}}`, graphql.Request.Body)
+	})
+}
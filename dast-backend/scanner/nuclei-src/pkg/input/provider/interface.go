@@ -163,6 +163,8 @@ func NewInputProvider(opts InputOptions) (InputProvider, error) {
 			RequiredOnly:         opts.Options.FormatUseRequiredOnly,
 			VarsTextTemplating:   opts.Options.VarsTextTemplating,
 			VarsFilePaths:        opts.Options.VarsFilePaths,
+			FilterHosts:          opts.Options.FormatFilterHosts,
+			FilterMimeTypes:      opts.Options.FormatFilterMimeTypes,
 		},
 	})
 }
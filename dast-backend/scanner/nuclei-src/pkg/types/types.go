@@ -427,6 +427,10 @@ type Options struct {
 	VarsTextTemplating bool
 	// VarsFilePaths is  used to inject variables into yaml input files from a file
 	VarsFilePaths goflags.StringSlice
+	// FormatFilterHosts only keeps requests to the given hosts from input files
+	FormatFilterHosts goflags.StringSlice
+	// FormatFilterMimeTypes only keeps requests with the given mime types from input files
+	FormatFilterMimeTypes goflags.StringSlice
 	// PayloadConcurrency is the number of concurrent payloads to run per template
 	PayloadConcurrency int
 	// ProbeConcurrency is the number of concurrent http probes to run with httpx
@@ -665,6 +669,8 @@ func (options *Options) Copy() *Options {
 		PreFetchSecrets:                options.PreFetchSecrets,
 		FormatUseRequiredOnly:          options.FormatUseRequiredOnly,
 		SkipFormatValidation:           options.SkipFormatValidation,
+		FormatFilterHosts:              options.FormatFilterHosts,
+		FormatFilterMimeTypes:          options.FormatFilterMimeTypes,
 		PayloadConcurrency:             options.PayloadConcurrency,
 		ProbeConcurrency:               options.ProbeConcurrency,
 		DAST:                           options.DAST,
@@ -0,0 +1,413 @@
+// Package graphql implements a small GraphQL lexer and token cursor
+// shared by the GraphQL input format and fuzzing dataformat.
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Kind is the kind of a lexed token
+type Kind int
+
+const (
+	// EOF is returned once all tokens are consumed
+	EOF Kind = iota
+	// Punct is a punctuator (! $ & ( ) ... : = @ [ ] { | })
+	Punct
+	// Name is a name or keyword
+	Name
+	// Int is an integer literal
+	Int
+	// Float is a float literal
+	Float
+	// String is a quoted string literal
+	String
+	// BlockString is a """ block string literal
+	BlockString
+)
+
+func (k Kind) String() string {
+	switch k {
+	case EOF:
+		return "EOF"
+	case Punct:
+		return "punctuator"
+	case Name:
+		return "name"
+	case Int:
+		return "int"
+	case Float:
+		return "float"
+	case String:
+		return "string"
+	case BlockString:
+		return "block string"
+	}
+	return "unknown"
+}
+
+// Token is a lexed token, Start and End are byte offsets in the source
+// and Value is the raw source text of the token
+type Token struct {
+	Kind  Kind
+	Value string
+	Start int
+	End   int
+}
+
+// StringValue returns the unquoted value of string and block string tokens
+// and the raw value for other tokens
+func (t Token) StringValue() string {
+	switch t.Kind {
+	case String:
+		return unescape(t.Value[1 : len(t.Value)-1])
+	case BlockString:
+		return strings.ReplaceAll(t.Value[3:len(t.Value)-3], `\"""`, `"""`)
+	}
+	return t.Value
+}
+
+// Tokenize lexes the source into tokens, insignificant characters
+// (whitespace, commas and comments) are skipped
+func Tokenize(src string) ([]Token, error) {
+	var tokens []Token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+			continue
+		case c == '#':
+			for i < len(src) && src[i] != '\n' && src[i] != '\r' {
+				i++
+			}
+			continue
+		case strings.HasPrefix(src[i:], "\uFEFF"):
+			i += len("\uFEFF")
+			continue
+		}
+
+		start := i
+		var kind Kind
+		switch {
+		case strings.HasPrefix(src[i:], "..."):
+			kind, i = Punct, i+3
+		case strings.IndexByte("!$&()[]{}:=@|", c) >= 0:
+			kind, i = Punct, i+1
+		case c == '_' || isLetter(c):
+			for i < len(src) && (src[i] == '_' || isLetter(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			kind = Name
+		case c == '-' || isDigit(c):
+			var err error
+			if kind, i, err = lexNumber(src, i); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(src[i:], `"""`):
+			end := blockStringEnd(src, i+3)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated block string at %d", start)
+			}
+			kind, i = BlockString, end
+		case c == '"':
+			end := stringEnd(src, i+1)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", start)
+			}
+			kind, i = String, end
+		default:
+			r, _ := utf8.DecodeRuneInString(src[i:])
+			return nil, fmt.Errorf("unexpected character %q at %d", r, start)
+		}
+		tokens = append(tokens, Token{Kind: kind, Value: src[start:i], Start: start, End: i})
+	}
+	return tokens, nil
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// lexNumber lexes an int or float literal starting at i
+func lexNumber(src string, i int) (Kind, int, error) {
+	start := i
+	kind := Int
+	if src[i] == '-' {
+		i++
+	}
+	digits := func() int {
+		n := 0
+		for i < len(src) && isDigit(src[i]) {
+			i++
+			n++
+		}
+		return n
+	}
+	if digits() == 0 {
+		return kind, i, fmt.Errorf("invalid number at %d", start)
+	}
+	if i < len(src) && src[i] == '.' {
+		i++
+		kind = Float
+		if digits() == 0 {
+			return kind, i, fmt.Errorf("invalid number at %d", start)
+		}
+	}
+	if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+		i++
+		kind = Float
+		if i < len(src) && (src[i] == '+' || src[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return kind, i, fmt.Errorf("invalid number at %d", start)
+		}
+	}
+	return kind, i, nil
+}
+
+// stringEnd returns the offset after the closing quote of a string
+func stringEnd(src string, i int) int {
+	for i < len(src) {
+		switch src[i] {
+		case '\\':
+			i += 2
+		case '"':
+			return i + 1
+		case '\n', '\r':
+			return -1
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+// blockStringEnd returns the offset after the closing quotes of a block string
+func blockStringEnd(src string, i int) int {
+	for i < len(src) {
+		if strings.HasPrefix(src[i:], `\"""`) {
+			i += 4
+			continue
+		}
+		if strings.HasPrefix(src[i:], `"""`) {
+			return i + 3
+		}
+		i++
+	}
+	return -1
+}
+
+// unescape resolves the escape sequences of a string literal
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			if i+4 < len(s) {
+				if r, err := strconv.ParseUint(s[i+1:i+5], 16, 32); err == nil {
+					b.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+			}
+			b.WriteString(`\u`)
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// Quote returns s as a GraphQL string literal
+func Quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// Parser is a cursor over lexed tokens with helpers
+// used by recursive descent parsers
+type Parser struct {
+	tokens []Token
+	pos    int
+}
+
+// NewParser lexes the source and returns a parser for its tokens
+func NewParser(src string) (*Parser, error) {
+	tokens, err := Tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Parser{tokens: tokens}, nil
+}
+
+// Peek returns the current token without consuming it
+func (p *Parser) Peek() Token {
+	if p.pos >= len(p.tokens) {
+		return Token{Kind: EOF}
+	}
+	return p.tokens[p.pos]
+}
+
+// Next consumes and returns the current token
+func (p *Parser) Next() Token {
+	t := p.Peek()
+	if t.Kind != EOF {
+		p.pos++
+	}
+	return t
+}
+
+// Is reports whether the current token is of the given kind
+// and value, an empty value matches any token of the kind
+func (p *Parser) Is(kind Kind, value string) bool {
+	t := p.Peek()
+	return t.Kind == kind && (value == "" || t.Value == value)
+}
+
+// Skip consumes the current token if it matches
+func (p *Parser) Skip(kind Kind, value string) bool {
+	if p.Is(kind, value) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// Expect consumes the current token or returns an error if it does not match
+func (p *Parser) Expect(kind Kind, value string) (Token, error) {
+	if !p.Is(kind, value) {
+		t := p.Peek()
+		expected := kind.String()
+		if value != "" {
+			expected = strconv.Quote(value)
+		}
+		if t.Kind == EOF {
+			return t, fmt.Errorf("expected %s, got EOF", expected)
+		}
+		return t, fmt.Errorf("expected %s, got %q at %d", expected, t.Value, t.Start)
+	}
+	return p.Next(), nil
+}
+
+// SkipValue consumes a value literal, including lists and objects
+func (p *Parser) SkipValue() error {
+	switch {
+	case p.Skip(Punct, "$"):
+		_, err := p.Expect(Name, "")
+		return err
+	case p.Skip(Punct, "["):
+		for !p.Skip(Punct, "]") {
+			if err := p.SkipValue(); err != nil {
+				return err
+			}
+		}
+		return nil
+	case p.Skip(Punct, "{"):
+		for !p.Skip(Punct, "}") {
+			if _, err := p.Expect(Name, ""); err != nil {
+				return err
+			}
+			if _, err := p.Expect(Punct, ":"); err != nil {
+				return err
+			}
+			if err := p.SkipValue(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	switch t := p.Peek(); t.Kind {
+	case Name, Int, Float, String, BlockString:
+		p.pos++
+		return nil
+	case EOF:
+		return fmt.Errorf("expected value, got EOF")
+	default:
+		return fmt.Errorf("expected value, got %q at %d", t.Value, t.Start)
+	}
+}
+
+// SkipArguments consumes an optional (name: value ...) argument list
+func (p *Parser) SkipArguments() error {
+	if !p.Skip(Punct, "(") {
+		return nil
+	}
+	for !p.Skip(Punct, ")") {
+		if _, err := p.Expect(Name, ""); err != nil {
+			return err
+		}
+		if _, err := p.Expect(Punct, ":"); err != nil {
+			return err
+		}
+		if err := p.SkipValue(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SkipDirectives consumes directives (@name(args)) if present
+func (p *Parser) SkipDirectives() error {
+	for p.Skip(Punct, "@") {
+		if _, err := p.Expect(Name, ""); err != nil {
+			return err
+		}
+		if err := p.SkipArguments(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
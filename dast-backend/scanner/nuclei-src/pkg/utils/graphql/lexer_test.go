@@ -0,0 +1,47 @@
+package graphql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenize(t *testing.T) {
+	src := `query Q($id: ID! = "a\"b") { # comment
+  user(id: $id, first: -10, ratio: 1.5e3, bio: """multi
+line \""" """) { ...F }
+}`
+	tokens, err := Tokenize(src)
+	require.Nil(t, err)
+
+	var kinds []Kind
+	var values []string
+	for _, token := range tokens {
+		kinds = append(kinds, token.Kind)
+		values = append(values, token.StringValue())
+		require.Equal(t, token.Value, src[token.Start:token.End], "invalid token offsets")
+	}
+	require.Equal(t, []string{
+		"query", "Q", "(", "$", "id", ":", "ID", "!", "=", `a"b`, ")", "{",
+		"user", "(", "id", ":", "$", "id", "first", ":", "-10", "ratio", ":", "1.5e3",
+		"bio", ":", "multi\nline \"\"\" ", ")", "{", "...", "F", "}", "}",
+	}, values)
+	require.Equal(t, String, kinds[9])
+	require.Equal(t, Int, kinds[20])
+	require.Equal(t, Float, kinds[23])
+	require.Equal(t, BlockString, kinds[26])
+
+	_, err = Tokenize(`{ user(id: "unterminated) }`)
+	require.NotNil(t, err, "unterminated string should fail")
+	_, err = Tokenize(`{ user(id: 1.) }`)
+	require.NotNil(t, err, "invalid number should fail")
+}
+
+func TestQuote(t *testing.T) {
+	require.Equal(t, `"a\"b\\c\nd"`, Quote("a\"b\\c\nd"))
+
+	tokens, err := Tokenize(Quote("x\u0001\"y"))
+	require.Nil(t, err)
+	require.Len(t, tokens, 1)
+	require.Equal(t, "x\u0001\"y", tokens[0].StringValue())
+}
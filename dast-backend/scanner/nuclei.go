@@ -8,42 +8,181 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
+	"os"
 	"strings"
+	"sync"
+	"time"
 
+	"demo/db/mysqldb"
 	"demo/db/redisdb"
+	"demo/finding"
+	"demo/models"
+	"demo/scanauth"
+	"demo/taskrun"
+	"demo/template"
 
 	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
 	"github.com/projectdiscovery/nuclei/v3/pkg/catalog/disk"
 	"github.com/projectdiscovery/nuclei/v3/pkg/output"
 )
 
-// NucleiScan 只负责把给定的 host:port 列表跑 nuclei，结果写入 Redis
+// NucleiScan 只负责把给定的 host:port 列表跑 nuclei，结果按 runId 写入 MySQL findings 表与 Redis
 // 状态（running/finished/error/stopped）由上层 Run 负责更新
-func NucleiScan(ctx context.Context, taskId string, nucleiTargets []string) error {
-	fmt.Println("[+]nuclei start")
+// 模板过滤、并发、限速与是否聚类由 profile 决定，模板管理中禁用的模板会被排除
+// cp 不为空时会定期保存 nuclei 的 resume 配置，中断后下次执行通过 WithResumeFile 跳过已完成的模板/目标；
+// 同一次执行中的多轮扫描（按指纹拆分）以 pass 区分各自的断点
+// 模板/请求统计与命中结果通过 rep 推送进度；引擎日志（含模板加载错误）与每个主机的请求错误写入执行日志
+func NucleiScan(ctx context.Context, taskId, runId, pass string, nucleiTargets []string, profile *Profile, cp *checkpoint, rep *reporter) error {
+	rep.log.Info("nuclei start", map[string]interface{}{"targets": len(nucleiTargets), "pass": pass})
+	return runNuclei(ctx, taskId, runId, profile, cp, rep, &nucleiInput{
+		pass: pass,
+		load: func(engine *nuclei.NucleiEngine) error {
+			// 用内存里的 targets 构造一个 reader，效果等价于 "-l targets.txt"
+			joined := strings.Join(nucleiTargets, "\n")
+			reader := bufio.NewReader(strings.NewReader(joined))
+			engine.LoadTargetsFromReader(reader, false)
+			return nil
+		},
+	})
+}
+
+// templateRevision 返回执行使用的模板库修订号：首轮扫描记录当前修订号，之后的轮次与续扫沿用该值
+func templateRevision(runId string) (uint64, error) {
+	if runId == "" {
+		return template.Revision()
+	}
+	var run models.TaskRun
+	if err := mysqldb.DB.Select("id", "template_revision").First(&run, "id = ?", runId).Error; err != nil {
+		return 0, err
+	}
+	if run.TemplateRevision != 0 {
+		return run.TemplateRevision, nil
+	}
+	rev, err := template.Revision()
+	if err != nil || rev == 0 {
+		return rev, err
+	}
+	res := mysqldb.DB.Model(&models.TaskRun{}).Where("id = ? AND template_revision = ?", runId, 0).Update("template_revision", rev)
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if res.RowsAffected == 0 {
+		// 已被并发的轮次记录，以库中的值为准
+		if err := mysqldb.DB.Select("id", "template_revision").First(&run, "id = ?", runId).Error; err != nil {
+			return 0, err
+		}
+		return run.TemplateRevision, nil
+	}
+	return rev, nil
+}
+
+// nucleiInput 扫描输入：断点中的轮次、额外的引擎选项，以及加载模板后向引擎加载目标的方式
+type nucleiInput struct {
+	pass    string
+	options []nuclei.NucleiSDKOptions
+	load    func(engine *nuclei.NucleiEngine) error
+}
+
+// runNuclei 创建引擎并执行扫描，NucleiScan 与 APIScan 共用
+func runNuclei(ctx context.Context, taskId, runId string, profile *Profile, cp *checkpoint, rep *reporter, input *nucleiInput) error {
+	// resume 配置需要以文件形式交给 nuclei，断点内容保存在 Redis 中，任何 worker 都能接手
+	resumeFile, err := os.CreateTemp("", "nuclei-resume-*.cfg")
+	if err != nil {
+		return fmt.Errorf("[+]create resume file failed: %w", err)
+	}
+	resumeFile.Close()
+	defer os.Remove(resumeFile.Name())
+	if cp != nil && cp.NucleiResume(input.pass) != "" {
+		if err := os.WriteFile(resumeFile.Name(), []byte(cp.NucleiResume(input.pass)), 0o600); err != nil {
+			return fmt.Errorf("[+]write resume file failed: %w", err)
+		}
+		rep.log.Info("nuclei resume from checkpoint", nil)
+	} else {
+		// 空文件会被当作 resume 配置解析，先删掉
+		os.Remove(resumeFile.Name())
+	}
+
+	// 同一次执行的各轮扫描（以及断点续扫）使用执行开始时记录的模板库修订号
+	rev, err := templateRevision(runId)
+	if err != nil {
+		rep.log.Error("load template revision failed", map[string]interface{}{"error": err.Error()})
+		return fmt.Errorf("[+]load template revision failed: %w", err)
+	}
+	// 上传的模板按修订号写入本机磁盘，独立部署的 worker 才能加载到
+	if err := template.Materialize(rev); err != nil {
+		rep.log.Error("materialize templates failed", map[string]interface{}{"revision": rev, "error": err.Error()})
+		return fmt.Errorf("[+]materialize templates failed: %w", err)
+	}
+	// 已禁用的模板通过 ExcludeIDs 排除
+	disabled, err := template.DisabledAt(rev)
+	if err != nil {
+		rep.log.Warn("load disabled templates failed", map[string]interface{}{"error": err.Error()})
+	}
+	if len(disabled) > 0 {
+		p := *profile
+		p.ExcludeTemplateIDs = append(append([]string{}, profile.ExcludeTemplateIDs...), disabled...)
+		profile = &p
+	}
+
+	// 认证配置：生成临时 secrets 文件，凭据同时从结果与日志中替换掉
+	auth, err := scanauth.Prepare(taskId)
+	if err != nil {
+		rep.log.Error("prepare auth config failed", map[string]interface{}{"error": err.Error()})
+		return fmt.Errorf("[+]prepare auth config failed: %w", err)
+	}
+	defer auth.Cleanup()
 
 	// 创建 nuclei 引擎（带 ctx），并指定本地 poc/templates 目录为 ./poc
-	engine, err := nuclei.NewNucleiEngineCtx(ctx,
-		nuclei.WithCatalog(disk.NewCatalog("./poc")),
+	opts := []nuclei.NucleiSDKOptions{
+		nuclei.WithCatalog(disk.NewCatalog(template.Dir)),
 		nuclei.DisableUpdateCheck(), // 关闭自动检查/下载模板
-		nuclei.WithDisableClustering(),
-	)
+		nuclei.WithResumeFile(resumeFile.Name()),
+		nuclei.UseStatsWriter(newNucleiProgress(rep)),
+		nuclei.WithLogger(rep.log.nucleiLogger()),
+		nuclei.UseOutputWriter(newHostErrorWriter(rep.log)),
+	}
+	opts = append(opts, profile.nucleiOptions()...)
+	opts = append(opts, input.options...)
+	if auth != nil {
+		rep.log.SetRedactor(auth.Redactor)
+		// 预先执行登录流程：登录失败时扫描直接报错，避免以未认证的身份扫完整个任务
+		opts = append(opts, nuclei.LoadSecretsFromFile([]string{auth.File}, true))
+		rep.log.Info("auth enabled", map[string]interface{}{"configs": auth.Count, "dynamic": auth.Dynamic})
+	}
+	engine, err := nuclei.NewNucleiEngineCtx(ctx, opts...)
 	if err != nil {
-		fmt.Println("[+]create nuclei engine error:", err)
+		rep.log.Error("create nuclei engine failed", map[string]interface{}{"error": err.Error()})
 		return err
 	}
 	defer engine.Close()
 
+	if cp != nil {
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			saveResumeLoop(engine, resumeFile.Name(), cp, input.pass, done)
+		}()
+		// 先停掉保存协程再关闭引擎，避免结束后又写回断点
+		defer wg.Wait()
+		defer close(done)
+	}
+
 	// 明确加载目录下的所有模板（确保模板被解析并缓存）
 	if err := engine.LoadAllTemplates(); err != nil {
 		// 如果加载失败，可以选择继续或直接返回错误
 		return fmt.Errorf("[+]load templates failed: %w", err)
 	}
+	rep.log.Info("templates loaded", map[string]interface{}{"templates": len(engine.GetTemplates()), "workflows": len(engine.GetWorkflows())})
+
+	if err := input.load(engine); err != nil {
+		rep.log.Error("load targets failed", map[string]interface{}{"error": err.Error()})
+		return fmt.Errorf("[+]load targets failed: %w", err)
+	}
 
-	// 用内存里的 targets 构造一个 reader，效果等价于 "-l targets.txt"
-	joined := strings.Join(nucleiTargets, "\n")
-	reader := bufio.NewReader(strings.NewReader(joined))
-	engine.LoadTargetsFromReader(reader, false)
+	notifier := newFindingNotifier(taskId, runId)
 
 	// nuclei 结果回调：只写入真正命中的漏洞结果
 	writeCallback := func(ev *output.ResultEvent) {
@@ -55,9 +194,27 @@ func NucleiScan(ctx context.Context, taskId string, nucleiTargets []string) erro
 		if !ev.MatcherStatus {
 			return
 		}
-		// 防止 response 过大
-		if len(ev.Response) > 10240 {
-			ev.Response = ev.Response[:10240]
+		// 登录模板的结果只是认证流程的一部分
+		if auth.IsLogin(ev) {
+			rep.log.Debug("login template matched", map[string]interface{}{"templateId": ev.TemplateID, "host": ev.Host})
+			return
+		}
+		auth.Redact(ev)
+		// 持久化到 MySQL（同时会截断过大的 request/response）
+		f, err := finding.Save(taskId, runId, ev)
+		if err != nil {
+			rep.log.Error("save finding failed", map[string]interface{}{"templateId": ev.TemplateID, "host": ev.Host, "error": err.Error()})
+		}
+		rep.Emit(EventFinding, map[string]interface{}{
+			"templateId": ev.TemplateID,
+			"name":       ev.Info.Name,
+			"severity":   ev.Info.SeverityHolder.Severity.String(),
+			"matchedAt":  ev.Matched,
+			"host":       ev.Host,
+		})
+		// 已标记为误报 / 接受风险的漏洞不再通知
+		if f == nil || !finding.Ignored(f.State) {
+			notifier.Notify(ev)
 		}
 
 		data, err := json.Marshal(ev)
@@ -66,16 +223,44 @@ func NucleiScan(ctx context.Context, taskId string, nucleiTargets []string) erro
 		}
 		jsonStr := string(data)
 
-		// 写入 Redis 结果命中的漏洞列表
-		redisdb.Client.RPush(redisdb.Ctx, "task:"+taskId+":result", jsonStr)
-		// 记录命中结果也同步写到 log
-		redisdb.Client.RPush(redisdb.Ctx, "task:"+taskId+":log", jsonStr)
+		// 写入本次执行的 Redis 结果列表（按 runId 隔离，避免多次执行混在一起）
+		redisdb.Client.RPush(redisdb.Ctx, taskrun.ResultKey(taskId, runId), jsonStr)
 	}
 
 	// 执行扫描
 	if err := engine.ExecuteCallbackWithCtx(ctx, writeCallback); err != nil {
-		fmt.Println("[+]nuclei start error:", err)
+		if ctx.Err() == nil {
+			rep.log.Error("nuclei execute failed", map[string]interface{}{"error": err.Error()})
+		}
 		return err
 	}
+	rep.log.Info("nuclei finished", nil)
 	return nil
 }
+
+// 保存 nuclei resume 配置的间隔
+const resumeSaveInterval = 30 * time.Second
+
+// saveResumeLoop 定期把 nuclei 的扫描进度写入断点，直到 done 关闭
+// 进程崩溃或 worker 失联时，接手的 worker 从最近一次保存的进度继续
+func saveResumeLoop(engine *nuclei.NucleiEngine, path string, cp *checkpoint, pass string, done <-chan struct{}) {
+	ticker := time.NewTicker(resumeSaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		if err := engine.SaveResumeConfig(path); err != nil {
+			log.Printf("[nuclei] save resume config failed task=%s run=%s err=%v", cp.taskId, cp.runId, err)
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		cp.SetNucleiResume(pass, string(data))
+	}
+}
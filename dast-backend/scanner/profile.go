@@ -0,0 +1,255 @@
+/**
+ * 扫描配置（profile）
+ */
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+)
+
+// Profile 控制一次扫描的端口扫描、HTTP 测活与 nuclei 参数
+// 创建任务时序列化后存入 models.Task.Config，启动时再反序列化交给 Run
+type Profile struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	// 端口扫描（naabu）
+	Ports        string   `json:"ports,omitempty"`        // 指定端口/范围，如 "80,443,8000-9000"；设置后忽略 TopPorts
+	TopPorts     string   `json:"topPorts,omitempty"`     // 100 / 1000 / full
+	ExcludePorts []string `json:"excludePorts,omitempty"` // 排除的端口
+	PortRate     int      `json:"portRate,omitempty"`     // 每秒发包数
+	PortThreads  int      `json:"portThreads,omitempty"`  // naabu 内部并发
+	PortTimeout  int      `json:"portTimeout,omitempty"`  // 单个端口探测超时（毫秒）
+
+	// HTTP 测活
+	ProbeWorkers int `json:"probeWorkers,omitempty"` // 并发数
+	ProbeTimeout int `json:"probeTimeout,omitempty"` // HTTP 请求超时（秒）
+
+	// 指纹识别
+	DisableFingerprint  bool `json:"disableFingerprint,omitempty"`  // 跳过指纹识别阶段
+	NarrowByFingerprint bool `json:"narrowByFingerprint,omitempty"` // 只加载带有识别出的产品 tag 的模板（与 Tags 取并集），未识别出产品时不缩小
+
+	// nuclei 模板过滤
+	Tags               []string `json:"tags,omitempty"`
+	ExcludeTags        []string `json:"excludeTags,omitempty"`
+	Severities         []string `json:"severities,omitempty"`
+	ExcludeSeverities  []string `json:"excludeSeverities,omitempty"`
+	TemplateIDs        []string `json:"templateIds,omitempty"`
+	ExcludeTemplateIDs []string `json:"excludeTemplateIds,omitempty"`
+
+	// nuclei 并发与限速
+	TemplateConcurrency int  `json:"templateConcurrency,omitempty"`
+	HostConcurrency     int  `json:"hostConcurrency,omitempty"`
+	RateLimit           int  `json:"rateLimit,omitempty"` // 每秒请求数，0 表示不限速
+	EnableClustering    bool `json:"enableClustering,omitempty"`
+}
+
+// DefaultProfileName 未指定 profile 时使用的内置配置
+const DefaultProfileName = "default"
+
+// 内置 profile：
+//   - default: 保持原有硬编码参数
+//   - critical-only: CI 场景，少量端口 + 仅 critical 模板，追求速度
+//   - deep: 周期性审计，全端口 + 全模板，放慢速率
+var builtinProfiles = map[string]Profile{
+	DefaultProfileName: {
+		Name:                DefaultProfileName,
+		Description:         "默认配置：Top1000 端口，加载全部模板",
+		TopPorts:            "1000",
+		PortRate:            1000,
+		PortThreads:         25,
+		PortTimeout:         5000,
+		ProbeWorkers:        50,
+		ProbeTimeout:        5,
+		TemplateConcurrency: 25,
+		HostConcurrency:     25,
+	},
+	"critical-only": {
+		Name:                "critical-only",
+		Description:         "CI 快速扫描：Top100 端口，仅 critical 模板",
+		TopPorts:            "100",
+		PortRate:            3000,
+		PortThreads:         50,
+		PortTimeout:         1000,
+		ProbeWorkers:        100,
+		ProbeTimeout:        3,
+		Severities:          []string{"critical"},
+		TemplateConcurrency: 50,
+		HostConcurrency:     50,
+		EnableClustering:    true,
+	},
+	"deep": {
+		Name:                "deep",
+		Description:         "深度审计：全端口，全部模板，限速 150 rps",
+		TopPorts:            "full",
+		PortRate:            1000,
+		PortThreads:         50,
+		PortTimeout:         5000,
+		ProbeWorkers:        50,
+		ProbeTimeout:        10,
+		TemplateConcurrency: 25,
+		HostConcurrency:     25,
+		RateLimit:           150,
+	},
+}
+
+// BuiltinProfile 返回指定名称的内置 profile
+func BuiltinProfile(name string) (*Profile, bool) {
+	p, ok := builtinProfiles[name]
+	if !ok {
+		return nil, false
+	}
+	return &p, true
+}
+
+// BuiltinProfiles 返回全部内置 profile
+func BuiltinProfiles() []Profile {
+	list := make([]Profile, 0, len(builtinProfiles))
+	for _, name := range []string{DefaultProfileName, "critical-only", "deep"} {
+		list = append(list, builtinProfiles[name])
+	}
+	return list
+}
+
+// DefaultProfile 返回默认 profile 的副本
+func DefaultProfile() *Profile {
+	p, _ := BuiltinProfile(DefaultProfileName)
+	return p
+}
+
+// ParseProfile 从 Task.Config 解析 profile，空配置返回默认 profile
+func ParseProfile(config string) (*Profile, error) {
+	if strings.TrimSpace(config) == "" {
+		return DefaultProfile(), nil
+	}
+	var p Profile
+	if err := json.Unmarshal([]byte(config), &p); err != nil {
+		return nil, err
+	}
+	p.Normalize()
+	return &p, p.Validate()
+}
+
+// Normalize 用默认值填充未设置的字段
+func (p *Profile) Normalize() {
+	def := builtinProfiles[DefaultProfileName]
+	if p.Name == "" {
+		p.Name = "custom"
+	}
+	if p.Ports == "" && p.TopPorts == "" {
+		p.TopPorts = def.TopPorts
+	}
+	if p.PortRate <= 0 {
+		p.PortRate = def.PortRate
+	}
+	if p.PortThreads <= 0 {
+		p.PortThreads = def.PortThreads
+	}
+	if p.PortTimeout <= 0 {
+		p.PortTimeout = def.PortTimeout
+	}
+	if p.ProbeWorkers <= 0 {
+		p.ProbeWorkers = def.ProbeWorkers
+	}
+	if p.ProbeTimeout <= 0 {
+		p.ProbeTimeout = def.ProbeTimeout
+	}
+	if p.TemplateConcurrency <= 0 {
+		p.TemplateConcurrency = def.TemplateConcurrency
+	}
+	if p.HostConcurrency <= 0 {
+		p.HostConcurrency = def.HostConcurrency
+	}
+	for i, s := range p.Severities {
+		p.Severities[i] = strings.ToLower(strings.TrimSpace(s))
+	}
+	for i, s := range p.ExcludeSeverities {
+		p.ExcludeSeverities[i] = strings.ToLower(strings.TrimSpace(s))
+	}
+}
+
+// Validate 校验 profile 中 nuclei 能识别的取值
+func (p *Profile) Validate() error {
+	switch strings.ToLower(p.TopPorts) {
+	case "", "100", "1000", "full":
+	default:
+		return fmt.Errorf("invalid topPorts %q (100/1000/full)", p.TopPorts)
+	}
+	if p.RateLimit < 0 {
+		return fmt.Errorf("invalid rateLimit %d", p.RateLimit)
+	}
+	if p.Ports != "" {
+		if err := validatePorts("ports", strings.Split(p.Ports, ",")); err != nil {
+			return err
+		}
+	}
+	if err := validatePorts("excludePorts", p.ExcludePorts); err != nil {
+		return err
+	}
+	// 借用 nuclei 自身的 severity 解析做校验
+	for _, csv := range []string{strings.Join(p.Severities, ","), strings.Join(p.ExcludeSeverities, ",")} {
+		s := severity.Severities{}
+		if err := s.Set(csv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePorts 按 naabu 的写法校验端口：80、8000-9000，u: 前缀表示 UDP，端口取值 1-65535
+func validatePorts(field string, segments []string) error {
+	for _, seg := range segments {
+		seg = strings.TrimSpace(seg)
+		lo, hi, isRange := strings.Cut(strings.TrimPrefix(seg, "u:"), "-")
+		if !isRange {
+			hi = lo
+		}
+		from, err1 := strconv.Atoi(lo)
+		to, err2 := strconv.Atoi(hi)
+		if err1 != nil || err2 != nil || from < 1 || to > 65535 || from > to {
+			return fmt.Errorf("invalid %s %q", field, seg)
+		}
+	}
+	return nil
+}
+
+// nucleiOptions 把 profile 转成 nuclei SDK 选项
+func (p *Profile) nucleiOptions() []nuclei.NucleiSDKOptions {
+	opts := []nuclei.NucleiSDKOptions{
+		nuclei.WithTemplateFilters(p.templateFilters()),
+		nuclei.WithConcurrency(nuclei.Concurrency{
+			TemplateConcurrency:           p.TemplateConcurrency,
+			HostConcurrency:               p.HostConcurrency,
+			HeadlessHostConcurrency:       10,
+			HeadlessTemplateConcurrency:   10,
+			JavascriptTemplateConcurrency: 120,
+			TemplatePayloadConcurrency:    25,
+			ProbeConcurrency:              p.ProbeWorkers,
+		}),
+	}
+	if p.RateLimit > 0 {
+		opts = append(opts, nuclei.WithGlobalRateLimit(p.RateLimit, time.Second))
+	}
+	if !p.EnableClustering {
+		opts = append(opts, nuclei.WithDisableClustering())
+	}
+	return opts
+}
+
+func (p *Profile) templateFilters() nuclei.TemplateFilters {
+	return nuclei.TemplateFilters{
+		Severity:          strings.Join(p.Severities, ","),
+		ExcludeSeverities: strings.Join(p.ExcludeSeverities, ","),
+		Tags:              p.Tags,
+		ExcludeTags:       p.ExcludeTags,
+		IDs:               p.TemplateIDs,
+		ExcludeIDs:        p.ExcludeTemplateIDs,
+	}
+}
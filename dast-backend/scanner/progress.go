@@ -0,0 +1,180 @@
+/**
+ * 扫描进度：各阶段把进度事件写入 Redis stream，任意 API 实例都可以通过 SSE 推送给前端
+ */
+package scanner
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"demo/db/redisdb"
+	"demo/taskrun"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 进度事件类型（SSE 的 event 字段）
+const (
+	EventStage       = "stage"       // 进入新阶段
+	EventPortScan    = "portscan"    // 端口扫描进度
+	EventHttpProbe   = "httpprobe"   // HTTP 测活进度
+	EventFingerprint = "fingerprint" // 指纹识别进度、识别结果与用于 nuclei 的 tag
+	EventNuclei      = "nuclei"      // nuclei 模板/请求进度与 ETA
+	EventFinding     = "finding"     // 新命中的漏洞
+	EventStatus      = "status"      // 执行状态变化（running/finished/error/stopped）
+)
+
+const (
+	// 每次执行的事件流最多保留的条数（近似裁剪）
+	eventsMaxLen = 5000
+	// 高频进度事件的最小发送间隔
+	emitInterval = time.Second
+	// nuclei 统计上报间隔
+	nucleiStatsInterval = 3 * time.Second
+)
+
+// reporter 向本次执行的 Redis stream 写入进度事件；runId 为空时不写
+// log 为本次执行的日志，随 reporter 传给各阶段
+type reporter struct {
+	taskId string
+	runId  string
+	log    *runLogger
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func newReporter(taskId, runId string) *reporter {
+	return &reporter{taskId: taskId, runId: runId, log: newRunLogger(taskId, runId), last: map[string]time.Time{}}
+}
+
+// Emit 写入一条事件
+func (r *reporter) Emit(event string, data map[string]interface{}) {
+	if r == nil || r.runId == "" {
+		return
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	if err := redisdb.Client.XAdd(redisdb.Ctx, &redis.XAddArgs{
+		Stream: taskrun.EventsKey(r.taskId, r.runId),
+		MaxLen: eventsMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{"event": event, "data": string(payload)},
+	}).Err(); err != nil {
+		log.Printf("[scanner] emit event failed task=%s run=%s event=%s err=%v", r.taskId, r.runId, event, err)
+	}
+}
+
+// EmitThrottled 同一类事件在 emitInterval 内只发送一次；final 为 true 时总是发送（阶段结束时的最终值）
+func (r *reporter) EmitThrottled(event string, data map[string]interface{}, final bool) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	now := time.Now()
+	if !final && now.Sub(r.last[event]) < emitInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.last[event] = now
+	r.mu.Unlock()
+	r.Emit(event, data)
+}
+
+// nucleiProgress 实现 nuclei 的 progress.Progress 接口，通过 nuclei.UseStatsWriter 注入
+// 不使用 EnableStatsWithOpts：它会用打印到终端的 StatsTicker 替换自定义 writer
+type nucleiProgress struct {
+	rep     *reporter
+	started time.Time
+
+	hosts     atomic.Int64
+	templates atomic.Int64
+	total     atomic.Int64
+	requests  atomic.Int64
+	matched   atomic.Int64
+	errors    atomic.Int64
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stop      chan struct{}
+}
+
+func newNucleiProgress(rep *reporter) *nucleiProgress {
+	return &nucleiProgress{rep: rep, stop: make(chan struct{})}
+}
+
+func (p *nucleiProgress) Init(hostCount int64, rulesCount int, requestCount int64) {
+	p.hosts.Store(hostCount)
+	p.templates.Store(int64(rulesCount))
+	p.total.Store(requestCount)
+	p.startOnce.Do(func() {
+		p.started = time.Now()
+		go p.loop()
+	})
+}
+
+func (p *nucleiProgress) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+}
+
+func (p *nucleiProgress) AddToTotal(delta int64)        { p.total.Add(delta) }
+func (p *nucleiProgress) IncrementRequests()            { p.requests.Add(1) }
+func (p *nucleiProgress) SetRequests(count uint64)      { p.requests.Add(int64(count)) }
+func (p *nucleiProgress) IncrementMatched()             { p.matched.Add(1) }
+func (p *nucleiProgress) IncrementErrorsBy(count int64) { p.errors.Add(count) }
+
+// IncrementFailedRequestsBy 失败的请求同样计入已完成请求（与 nuclei StatsTicker 一致）
+func (p *nucleiProgress) IncrementFailedRequestsBy(count int64) {
+	p.requests.Add(count)
+	p.errors.Add(count)
+}
+
+func (p *nucleiProgress) loop() {
+	ticker := time.NewTicker(nucleiStatsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			p.rep.Emit(EventNuclei, p.snapshot())
+			return
+		case <-ticker.C:
+			p.rep.Emit(EventNuclei, p.snapshot())
+		}
+	}
+}
+
+// snapshot 当前统计，ETA 按已完成请求的平均速率估算
+func (p *nucleiProgress) snapshot() map[string]interface{} {
+	total, done := p.total.Load(), p.requests.Load()
+	elapsed := time.Since(p.started)
+	data := map[string]interface{}{
+		"hosts":     p.hosts.Load(),
+		"templates": p.templates.Load(),
+		"requests":  done,
+		"total":     total,
+		"matched":   p.matched.Load(),
+		"errors":    p.errors.Load(),
+		"elapsed":   int64(elapsed.Seconds()),
+	}
+	if total > 0 {
+		percent := float64(done) * 100 / float64(total)
+		if percent > 100 {
+			percent = 100
+		}
+		data["percent"] = int(percent)
+	}
+	if done > 0 && total > done {
+		rate := float64(done) / elapsed.Seconds()
+		if rate > 0 {
+			data["eta"] = int64(float64(total-done) / rate)
+		}
+	}
+	return data
+}
@@ -0,0 +1,319 @@
+/**
+ * 执行日志：扫描各阶段、nuclei（通过 WithLogger 注入的 gologger）与单个目标的错误，
+ * 按级别写入本次执行的 Redis list（taskrun.LogKey），供日志页分页查看与下载
+ */
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"demo/db/redisdb"
+	"demo/scanauth"
+	"demo/taskrun"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/gologger/formatter"
+	"github.com/projectdiscovery/gologger/levels"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+const (
+	// 每次执行最多保留的日志条数，超出时丢弃最早的
+	logMaxLen = 50000
+	// nuclei 对同一主机的请求错误最多逐条记录的次数，其余只计数，扫描结束时汇总
+	hostErrorLogLimit = 3
+)
+
+// 终端颜色控制符，nuclei 部分日志带颜色
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// runLogger 写入本次执行的日志；runId 为空时只输出到进程日志
+type runLogger struct {
+	taskId string
+	runId  string
+
+	mu       sync.Mutex
+	stage    string
+	redactor *scanauth.Redactor
+}
+
+func newRunLogger(taskId, runId string) *runLogger {
+	return &runLogger{taskId: taskId, runId: runId}
+}
+
+// SetStage 设置之后日志的默认阶段
+func (l *runLogger) SetStage(stage string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.stage = stage
+	l.mu.Unlock()
+}
+
+// SetRedactor 之后写入的日志中去掉认证凭据
+func (l *runLogger) SetRedactor(r *scanauth.Redactor) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.redactor = r
+	l.mu.Unlock()
+}
+
+func (l *runLogger) currentStage() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stage
+}
+
+// redact 替换消息与字符串字段中的凭据
+func (l *runLogger) redact(entry *taskrun.LogEntry) {
+	l.mu.Lock()
+	r := l.redactor
+	l.mu.Unlock()
+	if r == nil {
+		return
+	}
+	entry.Message = r.String(entry.Message)
+	for k, v := range entry.Fields {
+		if s, ok := v.(string); ok {
+			entry.Fields[k] = r.String(s)
+		}
+	}
+}
+
+func (l *runLogger) Debug(msg string, fields map[string]interface{}) {
+	l.Log(taskrun.LogDebug, "", msg, fields)
+}
+
+func (l *runLogger) Info(msg string, fields map[string]interface{}) {
+	l.Log(taskrun.LogInfo, "", msg, fields)
+}
+
+func (l *runLogger) Warn(msg string, fields map[string]interface{}) {
+	l.Log(taskrun.LogWarn, "", msg, fields)
+}
+
+func (l *runLogger) Error(msg string, fields map[string]interface{}) {
+	l.Log(taskrun.LogError, "", msg, fields)
+}
+
+// Log 写入一条日志，stage 为空时取当前阶段；debug 以外的日志同时输出到进程日志
+func (l *runLogger) Log(level, stage, msg string, fields map[string]interface{}) {
+	if l == nil {
+		return
+	}
+	if stage == "" {
+		stage = l.currentStage()
+	}
+	entry := &taskrun.LogEntry{
+		Time:    time.Now().Format("2006-01-02 15:04:05"),
+		Level:   level,
+		Stage:   stage,
+		Message: msg,
+		Fields:  fields,
+	}
+	l.redact(entry)
+	if level != taskrun.LogDebug {
+		log.Printf("[scanner] task=%s run=%s stage=%s level=%s %s%s", l.taskId, l.runId, stage, level, msg, formatFields(fields))
+	}
+	l.write(entry)
+}
+
+func (l *runLogger) write(entry *taskrun.LogEntry) {
+	if l.runId == "" {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	key := taskrun.LogKey(l.taskId, l.runId)
+	pipe := redisdb.Client.Pipeline()
+	pipe.RPush(redisdb.Ctx, key, data)
+	pipe.LTrim(redisdb.Ctx, key, -logMaxLen, -1)
+	if _, err := pipe.Exec(redisdb.Ctx); err != nil {
+		log.Printf("[scanner] write run log failed task=%s run=%s err=%v", l.taskId, l.runId, err)
+	}
+}
+
+// formatFields 进程日志中按 key 排序输出字段
+func formatFields(fields map[string]interface{}) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, fields[k])
+	}
+	return b.String()
+}
+
+// -----------------------------------------------------------
+// nuclei 日志：gologger 的 Formatter + Writer
+// -----------------------------------------------------------
+
+// nucleiLogger 为 nuclei 引擎创建独立的 logger（不影响全局 gologger.DefaultLogger），
+// 模板加载错误、引擎警告等写入本次执行的 nuclei 阶段日志
+func (l *runLogger) nucleiLogger() *gologger.Logger {
+	g := &gologger.Logger{}
+	g.SetFormatter(&nucleiLogFormatter{})
+	g.SetWriter(&nucleiLogWriter{l: l})
+	// gologger 的级别顺序为 fatal < silent < error < info < warning < debug，warning 包含 info
+	g.SetMaxLevel(levels.LevelWarning)
+	return g
+}
+
+type nucleiLogFormatter struct{}
+
+// Format 把 gologger 事件转成 LogEntry（JSON），由 nucleiLogWriter 写入
+func (f *nucleiLogFormatter) Format(ev *formatter.LogEvent) ([]byte, error) {
+	msg := strings.TrimSpace(ansiRe.ReplaceAllString(ev.Message, ""))
+	level := gologgerLevel(ev.Level)
+	// Print() 输出的警告以 [WRN] / [ERR] 开头
+	switch {
+	case strings.HasPrefix(msg, "[WRN]"):
+		level, msg = taskrun.LogWarn, strings.TrimSpace(strings.TrimPrefix(msg, "[WRN]"))
+	case strings.HasPrefix(msg, "[ERR]"):
+		level, msg = taskrun.LogError, strings.TrimSpace(strings.TrimPrefix(msg, "[ERR]"))
+	}
+	if msg == "" {
+		return nil, nil
+	}
+	entry := &taskrun.LogEntry{
+		Time:    time.Now().Format("2006-01-02 15:04:05"),
+		Level:   level,
+		Stage:   StageNuclei,
+		Message: msg,
+	}
+	for k, v := range ev.Metadata {
+		if k == "label" || k == "timestamp" {
+			continue
+		}
+		if entry.Fields == nil {
+			entry.Fields = map[string]interface{}{}
+		}
+		entry.Fields[k] = v
+	}
+	return json.Marshal(entry)
+}
+
+func gologgerLevel(level levels.Level) string {
+	switch level {
+	case levels.LevelFatal, levels.LevelError:
+		return taskrun.LogError
+	case levels.LevelWarning:
+		return taskrun.LogWarn
+	case levels.LevelDebug, levels.LevelVerbose:
+		return taskrun.LogDebug
+	}
+	return taskrun.LogInfo
+}
+
+type nucleiLogWriter struct {
+	l *runLogger
+}
+
+func (w *nucleiLogWriter) Write(data []byte, _ levels.Level) {
+	if len(data) == 0 {
+		return
+	}
+	var entry taskrun.LogEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return
+	}
+	w.l.redact(&entry)
+	w.l.write(&entry)
+}
+
+// -----------------------------------------------------------
+// nuclei 请求错误：通过 UseOutputWriter 注入，与 SDK 的结果回调并存
+// -----------------------------------------------------------
+
+// hostErrorWriter 记录每个主机的请求错误：每个主机前 hostErrorLogLimit 条逐条记录，Close 时汇总错误数
+// 命中结果由 ExecuteCallbackWithCtx 的回调处理，这里不输出
+type hostErrorWriter struct {
+	l *runLogger
+
+	mu     sync.Mutex
+	errors map[string]int
+}
+
+func newHostErrorWriter(l *runLogger) *hostErrorWriter {
+	return &hostErrorWriter{l: l, errors: map[string]int{}}
+}
+
+func (w *hostErrorWriter) Request(templateID, target, requestType string, err error) {
+	if err == nil {
+		return
+	}
+	host := requestHost(target)
+	w.mu.Lock()
+	w.errors[host]++
+	n := w.errors[host]
+	w.mu.Unlock()
+	if n > hostErrorLogLimit {
+		return
+	}
+	w.l.Log(taskrun.LogWarn, StageNuclei, "request failed", map[string]interface{}{
+		"host":       host,
+		"url":        target,
+		"templateId": templateID,
+		"type":       requestType,
+		"error":      err.Error(),
+	})
+}
+
+// Close 在引擎关闭时调用，汇总每个主机的请求错误数
+func (w *hostErrorWriter) Close() {
+	w.mu.Lock()
+	errors := w.errors
+	w.errors = map[string]int{}
+	w.mu.Unlock()
+
+	hosts := make([]string, 0, len(errors))
+	for h := range errors {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	for _, h := range hosts {
+		if errors[h] <= hostErrorLogLimit {
+			continue
+		}
+		w.l.Log(taskrun.LogWarn, StageNuclei, "request errors on host", map[string]interface{}{
+			"host":       h,
+			"errors":     errors[h],
+			"suppressed": errors[h] - hostErrorLogLimit,
+		})
+	}
+}
+
+func (w *hostErrorWriter) Colorizer() aurora.Aurora                                     { return aurora.NewAurora(false) }
+func (w *hostErrorWriter) Write(*output.ResultEvent) error                              { return nil }
+func (w *hostErrorWriter) WriteFailure(*output.InternalWrappedEvent) error              { return nil }
+func (w *hostErrorWriter) RequestStatsLog(statusCode, response string)                  {}
+func (w *hostErrorWriter) WriteStoreDebugData(host, templateID, eventType, data string) {}
+func (w *hostErrorWriter) ResultCount() int                                             { return 0 }
+
+// requestHost 从请求目标中取 host[:port]，无法解析时原样返回
+func requestHost(target string) string {
+	if u, err := url.Parse(target); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return target
+}
@@ -7,21 +7,55 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"net"
 	"net/url"
 	"strings"
 	"sync"
 	"time"
 
+	"demo/apispec"
+	"demo/audit"
+	"demo/db/mysqldb"
 	"demo/db/redisdb"
+	"demo/fingerprint"
+	"demo/issue"
+	"demo/models"
+	"demo/notify"
+	"demo/scope"
+	"demo/taskrun"
 )
 
 // 保存每个任务的 cancel 函数
 // key: taskId, value: context.CancelFunc
 var taskCancels sync.Map
 
+// 取消广播频道：API 与所有 worker 进程都订阅，保证 Cancel 能到达实际执行扫描的进程
+const cancelChannel = "scan:cancel"
+
+// ErrLeaseLost 作为 cancel cause 使用：任务已被其他 worker 接管，本地扫描应静默退出、不再改写状态
+var ErrLeaseLost = errors.New("scan lease lost")
+
+// 扫描阶段，写入 task:{id}:info 的 stage 字段供前端展示进度
+const (
+	StageQueued      = "queued"
+	StagePortScan    = "portscan"
+	StageHttpProbe   = "httpprobe"
+	StageFingerprint = "fingerprint"
+	StageNuclei      = "nuclei"
+	StageDone        = "done"
+)
+
 // Cancel 取消指定 taskId 对应的扫描（无论现在在端口扫描、测活还是 nuclei）
+// 先取消本进程内的扫描，再通过 Redis pub/sub 通知持有该任务的 worker
 func Cancel(taskId string) {
+	cancelLocal(taskId)
+	if err := redisdb.Client.Publish(redisdb.Ctx, cancelChannel, taskId).Err(); err != nil {
+		log.Printf("[scanner] publish cancel failed task=%s err=%v", taskId, err)
+	}
+}
+
+func cancelLocal(taskId string) {
 	if v, ok := taskCancels.Load(taskId); ok {
 		if cancel, ok2 := v.(context.CancelFunc); ok2 && cancel != nil {
 			cancel()
@@ -29,91 +63,187 @@ func Cancel(taskId string) {
 	}
 }
 
+// ListenCancel 订阅取消频道，收到 taskId 后取消本进程内对应的扫描，直到 ctx 结束
+func ListenCancel(ctx context.Context) {
+	sub := redisdb.Client.Subscribe(ctx, cancelChannel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			cancelLocal(msg.Payload)
+		}
+	}
+}
+
 // 总入口：
 // 1. 判断是否指定端口
 // 2. 未指定端口的目标做端口扫描
 // 3. 对所有 host:port 做 HTTP/HTTPS 测活，HTTP 活的转成 URL
-// 4. HTTP URL + 其余 host:port 一起丢给 NucleiScan
-// 5. 更新 Redis 中 task 的状态（pending -> running -> finished/error/stopped）
-func Run(taskId string, rawTargets []string, infoKey string) {
+// 4. 对 HTTP 服务做指纹识别，按 profile 决定是否据此缩小 nuclei 模板范围
+// 5. HTTP URL + 其余 host:port 一起丢给 NucleiScan
+// 6. 更新 Redis 与 MySQL 中 task 的状态（pending -> running -> finished/error/stopped）
+// 各阶段参数由 profile 决定（nil 时使用默认 profile）
+// parent 由 worker 传入，以 ErrLeaseLost 为 cause 取消时不会改写任务状态
+// runId 对应 task_runs 中本次执行，结果与状态都归档到该执行下
+// exclusions 为任务的排除列表，在端口扫描前过滤目标，端口扫描结果也会再过滤一次
+// API 扫描任务（有上传的 APISpec）不经过 1-4 步，见 runAPI
+func Run(parent context.Context, taskId, runId string, rawTargets, exclusions []string, infoKey string, profile *Profile) {
+	if profile == nil {
+		profile = DefaultProfile()
+	}
+
 	// 整个任务级别 context，可被 Cancel 中断
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(parent)
 	taskCancels.Store(taskId, cancel)
 	defer taskCancels.Delete(taskId)
+	defer cancel()
 
 	// 任务开始：标记为 running
-	setStatus(infoKey, "running", "")
+	setStatus(taskId, runId, infoKey, "running", "")
+	rep := newReporter(taskId, runId)
+
+	rep.log.SetStage(StageQueued)
+	rep.log.Info("scan started", map[string]interface{}{"targets": len(rawTargets), "profile": profile.Name})
 
-	// 1. 拆分目标
+	// 读取断点：重启/接管后从最后完成的阶段继续
+	cp := loadCheckpoint(taskId, runId)
+	if stage := cp.Stage(); stage != "" {
+		rep.log.Info("resume from checkpoint", map[string]interface{}{"after": stage})
+	}
+
+	// API 扫描任务：输入为上传的接口文档或流量导出，直接进入 nuclei 阶段
+	spec, err := apispec.Load(taskId)
+	if err != nil {
+		setStatus(taskId, runId, infoKey, "error", fmt.Sprintf("load api spec error: %v", err))
+		return
+	}
+	if spec != nil {
+		runAPI(ctx, taskId, runId, infoKey, spec, exclusions, profile, cp, rep)
+		return
+	}
+
+	// 1. 应用排除列表后拆分目标；排除列表无法解析时不扫描任何目标
+	exclude, err := scope.NewMatcher(exclusions)
+	if err != nil {
+		setStatus(taskId, runId, infoKey, "error", fmt.Sprintf("invalid exclusions: %v", err))
+		return
+	}
+	rawTargets, excluded := exclude.Filter(rawTargets)
+	if len(excluded) > 0 {
+		rep.log.Info("excluded targets", map[string]interface{}{"count": len(excluded)})
+	}
 	withPort, hostOnly := splitTargets(rawTargets)
 
 	// 2. 对 hostOnly 做端口扫描
-	var hostPortTargets []string
-	hostPortTargets = append(hostPortTargets, withPort...)
+	hostPortTargets, resumed := cp.Targets(StagePortScan)
+	if !resumed {
+		hostPortTargets = append(hostPortTargets, withPort...)
 
-	if len(hostOnly) > 0 {
-		openPorts, err := PortScan(ctx, hostOnly)
-		if errors.Is(err, context.Canceled) || ctx.Err() == context.Canceled {
-			setStatus(infoKey, "stopped", "")
-			return
-		}
-		if err != nil {
-			setStatus(infoKey, "error", fmt.Sprintf("port scan error: %v", err))
-			return
+		if len(hostOnly) > 0 {
+			setStage(rep, infoKey, StagePortScan)
+			openPorts, err := PortScan(ctx, hostOnly, profile, rep)
+			if errors.Is(err, context.Canceled) || ctx.Err() == context.Canceled {
+				setStopped(ctx, taskId, runId, infoKey)
+				return
+			}
+			if err != nil {
+				setStatus(taskId, runId, infoKey, "error", fmt.Sprintf("port scan error: %v", err))
+				return
+			}
+			openPorts, _ = exclude.Filter(openPorts)
+			hostPortTargets = append(hostPortTargets, openPorts...)
 		}
-		hostPortTargets = append(hostPortTargets, openPorts...)
+		cp.Done(StagePortScan, hostPortTargets)
 	}
 
 	// 3. 没有任何 host:port，就算扫描完成
 	if len(hostPortTargets) == 0 {
-		setStatus(infoKey, "finished", "")
-		fmt.Println("[scanner] no targets after port scan")
+		// 仍需写入资产清单：之前开放的端口本次全部关闭
+		recordAssets(taskId, runId, hostOnly, withPort, nil, nil, profile)
+		rep.log.Info("no targets after port scan", nil)
+		setStatus(taskId, runId, infoKey, "finished", "")
 		return
 	}
 
 	// 4. HTTP/HTTPS 测活：
-	//    返回一个 map：原始 host:port -> 存活 URL (http:// 或 https://)
-	aliveMap, err := HttpAliveProbe(ctx, hostPortTargets)
-	if errors.Is(err, context.Canceled) || ctx.Err() == context.Canceled {
-		setStatus(infoKey, "stopped", "")
-		return
-	}
-	if err != nil {
-		setStatus(infoKey, "error", fmt.Sprintf("http probe error: %v", err))
-		return
-	}
-
+	//    返回一个 map：原始 host:port -> 测活结果（存活 URL 及状态码、标题、证书等），同时写入资产清单
 	// 5. 组装 nuclei 最终 target 列表：
 	//    - 如果某个 host:port 在 aliveMap 中：用 http(s)://host:port
 	//    - 否则：保留 host:port（给非 HTTP 模板用，比如 redis 等）
-	var nucleiTargets []string
-	for _, hp := range hostPortTargets {
-		if urlStr, ok := aliveMap[hp]; ok && urlStr != "" {
-			nucleiTargets = append(nucleiTargets, urlStr)
-		} else {
-			nucleiTargets = append(nucleiTargets, hp)
+	nucleiTargets, resumed := cp.Targets(StageHttpProbe)
+	if !resumed {
+		setStage(rep, infoKey, StageHttpProbe)
+		aliveMap, err := HttpAliveProbe(ctx, hostPortTargets, profile, rep)
+		if errors.Is(err, context.Canceled) || ctx.Err() == context.Canceled {
+			setStopped(ctx, taskId, runId, infoKey)
+			return
+		}
+		if err != nil {
+			setStatus(taskId, runId, infoKey, "error", fmt.Sprintf("http probe error: %v", err))
+			return
+		}
+
+		for _, hp := range hostPortTargets {
+			if r, ok := aliveMap[hp]; ok && r.Alive && r.URL != "" {
+				nucleiTargets = append(nucleiTargets, r.URL)
+			} else {
+				nucleiTargets = append(nucleiTargets, hp)
+			}
 		}
+		recordAssets(taskId, runId, hostOnly, withPort, hostPortTargets, aliveMap, profile)
+		cp.Done(StageHttpProbe, nucleiTargets)
 	}
 
 	if len(nucleiTargets) == 0 {
-		setStatus(infoKey, "finished", "")
-		fmt.Println("[scanner] no targets for nuclei after http probe")
+		rep.log.Info("no targets for nuclei after http probe", nil)
+		setStatus(taskId, runId, infoKey, "finished", "")
 		return
 	}
 
-	// 6. 调用 nuclei 扫描（这里既有 URL 也有 host:port，让不同协议的模板自己匹配）
-	if err := NucleiScan(ctx, taskId, nucleiTargets); err != nil {
+	// 6. 指纹识别：识别 HTTP 服务的产品与版本并写入资产清单，结果保存在断点中，
+	//    保证续扫时 nuclei 的模板范围与中断前一致
+	var products map[string][]fingerprint.Product
+	if !profile.DisableFingerprint && !cp.Load(StageFingerprint, &products) {
+		setStage(rep, infoKey, StageFingerprint)
+		products, err = Fingerprint(ctx, httpURLs(nucleiTargets), profile, rep)
 		if errors.Is(err, context.Canceled) || ctx.Err() == context.Canceled {
-			setStatus(infoKey, "stopped", "")
-		} else {
-			setStatus(infoKey, "error", fmt.Sprintf("nuclei error: %v", err))
+			setStopped(ctx, taskId, runId, infoKey)
+			return
 		}
-		return
+		recordProducts(taskId, runId, products)
+		cp.Done(StageFingerprint, products)
+	}
+	// 7. 调用 nuclei 扫描（这里既有 URL 也有 host:port，让不同协议的模板自己匹配）；
+	//    按指纹缩小模板范围时只作用于识别出产品的 URL，各轮扫描分别记录断点，续扫时跳过已完成的轮次
+	setStage(rep, infoKey, StageNuclei)
+	for _, pass := range nucleiPasses(nucleiTargets, profile, products) {
+		if cp.NucleiDone(pass.name) {
+			continue
+		}
+		if len(pass.tags) > 0 {
+			rep.log.Info("narrow nuclei templates by fingerprint", map[string]interface{}{"tags": pass.tags, "targets": len(pass.targets)})
+			rep.Emit(EventFingerprint, map[string]interface{}{"tags": pass.tags})
+		}
+		if err := NucleiScan(ctx, taskId, runId, pass.name, pass.targets, pass.profile, cp, rep); err != nil {
+			if errors.Is(err, context.Canceled) || ctx.Err() == context.Canceled {
+				setStopped(ctx, taskId, runId, infoKey)
+			} else {
+				setStatus(taskId, runId, infoKey, "error", fmt.Sprintf("nuclei error: %v", err))
+			}
+			return
+		}
+		cp.SetNucleiDone(pass.name)
 	}
 
-	// 7. 正常完成
-	setStatus(infoKey, "finished", "")
-	fmt.Println("[+] scanner finished")
+	// 8. 正常完成
+	setStatus(taskId, runId, infoKey, "finished", "")
 }
 
 // -----------------------------------------------------------
@@ -178,14 +308,114 @@ func isHostPort(s string) bool {
 	return err == nil
 }
 
-// 状态更新
-func setStatus(infoKey, status, errMsg string) {
+// 状态更新：Redis 中的 info 用于展示，MySQL 中 tasks / task_runs 的状态与起止时间用于持久化
+// 终态先条件更新 task_runs：执行记录已被停止或已被其它 worker 结束（例如被新的执行取代）时，
+// 不再改写 tasks 与 Redis 中的状态，也不触发审计、通知与 issue 同步
+func setStatus(taskId, runId, infoKey, status, errMsg string) {
+	now := time.Now()
+	rep := newReporter(taskId, runId)
+	rep.Emit(EventStatus, map[string]interface{}{"status": status, "error": errMsg})
+	switch status {
+	case "running":
+	case "error":
+		rep.log.Log(taskrun.LogError, StageDone, "scan failed", map[string]interface{}{"error": errMsg})
+	default:
+		rep.log.Log(taskrun.LogInfo, StageDone, "scan "+status, nil)
+	}
+	// 执行已结束，断点不再需要
+	if status != "running" {
+		_ = redisdb.Client.Del(redisdb.Ctx, taskrun.CheckpointKey(taskId, runId)).Err()
+	}
+
+	// MySQL：running 只记录开始时间（状态由 task.StartTask 原子改写）；终态只覆盖仍为 running 的执行记录
+	var err error
+	firstStart := false
+	moved := true
+	if status == "running" {
+		err = mysqldb.DB.Model(&models.Task{}).Where("id = ?", taskId).
+			Updates(map[string]interface{}{"started_at": now, "finished_at": nil}).Error
+		if err == nil {
+			// 从断点恢复的执行保留最初的开始时间
+			res := mysqldb.DB.Model(&models.TaskRun{}).Where("id = ? AND started_at IS NULL", runId).
+				Update("started_at", now)
+			err = res.Error
+			firstStart = res.RowsAffected > 0
+		}
+	} else {
+		res := mysqldb.DB.Model(&models.TaskRun{}).Where("id = ? AND status = ?", runId, "running").
+			Updates(map[string]interface{}{"status": status, "message": errMsg, "finished_at": now})
+		err = res.Error
+		moved = res.RowsAffected == 1
+		if err == nil && moved {
+			err = mysqldb.DB.Model(&models.Task{}).Where("id = ? AND status IN ?", taskId, []string{"running", status}).
+				Updates(map[string]interface{}{"status": status, "finished_at": now}).Error
+		}
+	}
+	if err != nil {
+		log.Printf("[scanner] mysql update status failed task=%s run=%s status=%s err=%v", taskId, runId, status, err)
+		return
+	}
+	if !moved {
+		// 执行记录已结束：Redis 中展示的仍是这次执行时，只把阶段标记为结束，状态以执行记录为准
+		if current, _ := redisdb.Client.HGet(redisdb.Ctx, infoKey, "run_id").Result(); current == runId {
+			var run models.TaskRun
+			if mysqldb.DB.Select("status").First(&run, "id = ?", runId).Error == nil {
+				_ = redisdb.Client.HSet(redisdb.Ctx, infoKey, "status", run.Status, "stage", StageDone,
+					"updated_at", now.Format("2006-01-02 15:04:05")).Err()
+			}
+		}
+		log.Printf("[scanner] run already ended, skip %s side effects task=%s run=%s", status, taskId, runId)
+		return
+	}
+
 	data := map[string]interface{}{
 		"status":     status,
-		"updated_at": time.Now().Format("2006-01-02 15:04:05"),
+		"updated_at": now.Format("2006-01-02 15:04:05"),
 	}
 	if errMsg != "" {
 		data["error_msg"] = errMsg
 	}
+	if status != "running" {
+		data["stage"] = StageDone
+	}
 	_ = redisdb.Client.HMSet(redisdb.Ctx, infoKey, data).Err()
+
+	if status == "running" && !firstStart {
+		audit.Lifecycle(taskId, runId, "resumed", "")
+	} else {
+		audit.Lifecycle(taskId, runId, status, errMsg)
+	}
+
+	// 正常完成：把与上一次执行的 diff 推送给通知，并同步缺陷跟踪 issue
+	switch status {
+	case "finished":
+		taskrun.OnFinished(runId)
+		issue.Enqueue(runId)
+	case "running":
+		// 断点恢复不重复通知开始
+		if firstStart {
+			notify.Publish(&notify.Event{Event: notify.EventStarted, TaskID: taskId, RunID: runId, Status: status})
+		}
+	default:
+		if ev := notify.StatusEvent(status); ev != "" {
+			notify.Publish(&notify.Event{Event: ev, TaskID: taskId, RunID: runId, Status: status, Message: errMsg})
+		}
+	}
+}
+
+// setStopped 扫描被取消时调用；若是因为 lease 丢失被接管，则不改写状态
+func setStopped(ctx context.Context, taskId, runId, infoKey string) {
+	if errors.Is(context.Cause(ctx), ErrLeaseLost) {
+		newRunLogger(taskId, runId).Warn("lease lost, scan taken over by another worker", nil)
+		return
+	}
+	setStatus(taskId, runId, infoKey, "stopped", "")
+}
+
+// setStage 记录当前扫描阶段，推送 stage 事件，并作为之后执行日志的阶段
+func setStage(rep *reporter, infoKey, stage string) {
+	_ = redisdb.Client.HSet(redisdb.Ctx, infoKey, "stage", stage, "updated_at", time.Now().Format("2006-01-02 15:04:05")).Err()
+	rep.Emit(EventStage, map[string]interface{}{"stage": stage})
+	rep.log.SetStage(stage)
+	rep.log.Info("stage started", nil)
 }
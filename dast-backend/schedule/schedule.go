@@ -0,0 +1,276 @@
+package schedule
+
+import (
+	"demo/db/mysqldb"
+	"demo/models"
+	"demo/task"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// 调度循环的检查间隔；cron 最小粒度为分钟，30s 足够
+const tickInterval = 30 * time.Second
+
+// Init 启动定时调度协程（仅 API 进程启动；多实例时靠 next_run_at 的原子更新避免重复触发）
+func Init() {
+	go loop()
+}
+
+func loop() {
+	for {
+		time.Sleep(tickInterval)
+		tick(time.Now())
+	}
+}
+
+// tick 取出所有到期的调度并逐个触发
+func tick(now time.Time) {
+	var due []models.TaskSchedule
+	if err := mysqldb.DB.
+		Where("enabled = ? AND next_run_at IS NOT NULL AND next_run_at <= ?", true, now).
+		Find(&due).Error; err != nil {
+		log.Printf("[schedule.tick] db query failed err=%v", err)
+		return
+	}
+	for _, s := range due {
+		trigger(s, now)
+	}
+}
+
+// trigger 先原子推进 next_run_at 抢占本次触发，再启动任务
+func trigger(s models.TaskSchedule, now time.Time) {
+	next, err := nextRun(s.Cron, s.Timezone, now)
+	if err != nil {
+		log.Printf("[schedule.trigger] invalid schedule task=%s err=%v", s.TaskID, err)
+		_ = mysqldb.DB.Model(&models.TaskSchedule{}).Where("id = ?", s.ID).
+			Updates(map[string]interface{}{"enabled": false, "last_result": "disabled: " + err.Error()}).Error
+		return
+	}
+
+	// 只有把 next_run_at 从旧值改成新值的那个实例负责触发
+	res := mysqldb.DB.Model(&models.TaskSchedule{}).
+		Where("id = ? AND next_run_at = ?", s.ID, s.NextRunAt).
+		Update("next_run_at", next)
+	if res.Error != nil {
+		log.Printf("[schedule.trigger] db update failed task=%s err=%v", s.TaskID, res.Error)
+		return
+	}
+	if res.RowsAffected == 0 {
+		return
+	}
+
+	updates := map[string]interface{}{"last_run_at": now}
+	loc, _ := time.LoadLocation(s.Timezone)
+	if !inWindow(s.WindowStart, s.WindowEnd, now.In(loc)) {
+		updates["last_result"] = "skipped: outside maintenance window"
+		log.Printf("[schedule.trigger] task=%s outside window %s-%s, skip", s.TaskID, s.WindowStart, s.WindowEnd)
+	} else {
+		run, err := task.StartTask(s.TaskID, "schedule")
+		switch {
+		case err == nil:
+			updates["last_run_id"] = run.ID
+			updates["last_result"] = "started"
+			log.Printf("[schedule.trigger] task=%s run=%s started", s.TaskID, run.ID)
+		case errors.Is(err, task.ErrTaskRunning), errors.Is(err, task.ErrStartInProgress):
+			// 上一次执行还没结束，跳过本次触发
+			updates["last_result"] = "skipped: previous run still running"
+			log.Printf("[schedule.trigger] task=%s still running, skip", s.TaskID)
+		default:
+			updates["last_result"] = "failed: " + err.Error()
+			log.Printf("[schedule.trigger] task=%s start failed err=%v", s.TaskID, err)
+		}
+	}
+	if err := mysqldb.DB.Model(&models.TaskSchedule{}).Where("id = ?", s.ID).Updates(updates).Error; err != nil {
+		log.Printf("[schedule.trigger] db update failed task=%s err=%v", s.TaskID, err)
+	}
+}
+
+// nextRun 按时区计算 from 之后的下一次触发时间，支持标准 5 段 cron 与 @daily 等描述符
+func nextRun(expr, tz string, from time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timezone %q: %v", tz, err)
+	}
+	sched, err := cron.ParseStandard(expr)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid cron %q: %v", expr, err)
+	}
+	next := sched.Next(from.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron %q never fires", expr)
+	}
+	return next, nil
+}
+
+// parseClock 解析 HH:MM，返回当天的分钟数
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// inWindow 判断 t 是否落在维护窗口内；窗口为空表示不限制，start > end 表示跨零点（如 22:00-06:00）
+func inWindow(start, end string, t time.Time) bool {
+	if start == "" || end == "" {
+		return true
+	}
+	s, err1 := parseClock(start)
+	e, err2 := parseClock(end)
+	if err1 != nil || err2 != nil {
+		return true
+	}
+	m := t.Hour()*60 + t.Minute()
+	if s <= e {
+		return m >= s && m < e
+	}
+	return m >= s || m < e
+}
+
+// Save - 新建或更新任务的定时调度（每个任务一条，按 taskId upsert）
+func Save() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			TaskID      string `json:"taskId"`
+			Cron        string `json:"cron"`
+			Timezone    string `json:"timezone"`
+			WindowStart string `json:"windowStart"`
+			WindowEnd   string `json:"windowEnd"`
+			Enabled     *bool  `json:"enabled"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+			return
+		}
+		req.TaskID = strings.TrimSpace(req.TaskID)
+		req.Cron = strings.TrimSpace(req.Cron)
+		if req.TaskID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing taskId"})
+			return
+		}
+		if req.Cron == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing cron"})
+			return
+		}
+		if req.Timezone == "" {
+			req.Timezone = time.Local.String()
+		}
+		enabled := req.Enabled == nil || *req.Enabled
+
+		now := time.Now()
+		next, err := nextRun(req.Cron, req.Timezone, now)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if (req.WindowStart == "") != (req.WindowEnd == "") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "windowStart and windowEnd must be set together"})
+			return
+		}
+		for _, s := range []string{req.WindowStart, req.WindowEnd} {
+			if s == "" {
+				continue
+			}
+			if _, err := parseClock(s); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		var t models.Task
+		if err := mysqldb.DB.First(&t, "id = ?", req.TaskID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+			return
+		}
+
+		var nextRunAt *time.Time
+		if enabled {
+			nextRunAt = &next
+		}
+
+		var s models.TaskSchedule
+		err = mysqldb.DB.First(&s, "task_id = ?", req.TaskID).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			s = models.TaskSchedule{
+				TaskID:      req.TaskID,
+				Cron:        req.Cron,
+				Timezone:    req.Timezone,
+				WindowStart: req.WindowStart,
+				WindowEnd:   req.WindowEnd,
+				Enabled:     enabled,
+				NextRunAt:   nextRunAt,
+			}
+			err = mysqldb.DB.Create(&s).Error
+		case err == nil:
+			err = mysqldb.DB.Model(&s).Updates(map[string]interface{}{
+				"cron":         req.Cron,
+				"timezone":     req.Timezone,
+				"window_start": req.WindowStart,
+				"window_end":   req.WindowEnd,
+				"enabled":      enabled,
+				"next_run_at":  nextRunAt,
+			}).Error
+		}
+		if err != nil {
+			log.Printf("[schedule.Save] db save failed task=%s err=%v", req.TaskID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db save schedule failed: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":  "保存成功",
+			"schedule": s,
+		})
+	}
+}
+
+// List - 列出定时调度，可按 taskId 过滤
+func List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := mysqldb.DB.Model(&models.TaskSchedule{})
+		if taskId := c.Query("taskId"); taskId != "" {
+			db = db.Where("task_id = ?", taskId)
+		}
+		schedules := []models.TaskSchedule{}
+		if err := db.Order("created_at desc").Find(&schedules).Error; err != nil {
+			log.Printf("[schedule.List] db query failed err=%v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"schedules": schedules})
+	}
+}
+
+// Delete - 删除任务的定时调度（不影响已产生的执行记录）
+func Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			TaskID string `json:"taskId"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.TaskID) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing taskId"})
+			return
+		}
+
+		res := mysqldb.DB.Where("task_id = ?", req.TaskID).Delete(&models.TaskSchedule{})
+		if res.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": res.Error.Error()})
+			return
+		}
+		if res.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "schedule not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "删除成功", "taskId": req.TaskID})
+	}
+}
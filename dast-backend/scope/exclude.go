@@ -0,0 +1,181 @@
+package scope
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Matcher 任务的排除列表。支持的写法：
+//   - 主机名 example.com，通配 *.example.com（匹配子域名）
+//   - IP、CIDR、IP 段（与 Expand 的写法相同）
+//   - host:port / host:80,443：只排除指定端口
+//
+// CIDR 与 IP 段只匹配以 IP 形式给出或扫描得到的目标，不对域名做解析
+type Matcher struct {
+	hosts     map[string]bool
+	suffixes  []string
+	prefixes  []netip.Prefix
+	ranges    [][2]netip.Addr
+	hostPorts map[string]bool
+}
+
+// NewMatcher 解析排除列表；列表为空时返回 nil（nil Matcher 不排除任何目标）
+func NewMatcher(entries []string) (*Matcher, error) {
+	m := &Matcher{hosts: map[string]bool{}, hostPorts: map[string]bool{}}
+	empty := true
+	for _, raw := range entries {
+		raw = strings.TrimSpace(raw)
+		if raw == "" || strings.HasPrefix(raw, "#") {
+			continue
+		}
+		empty = false
+		if err := m.add(raw); err != nil {
+			return nil, fmt.Errorf("invalid exclusion %q: %w", raw, err)
+		}
+	}
+	if empty {
+		return nil, nil
+	}
+	return m, nil
+}
+
+func (m *Matcher) add(s string) error {
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil || u.Hostname() == "" {
+			return fmt.Errorf("invalid url")
+		}
+		s = u.Hostname()
+	}
+
+	hostSpec, portSpec := splitPortSpec(s)
+	if portSpec != "" {
+		list, err := expandOne(s, MaxTargets)
+		if err != nil {
+			return err
+		}
+		for _, hp := range list {
+			m.hostPorts[strings.ToLower(hp)] = true
+		}
+		return nil
+	}
+
+	switch {
+	case strings.Contains(hostSpec, "/"):
+		prefix, err := netip.ParsePrefix(hostSpec)
+		if err != nil {
+			return err
+		}
+		m.prefixes = append(m.prefixes, prefix.Masked())
+	case strings.HasPrefix(hostSpec, "*."):
+		m.suffixes = append(m.suffixes, strings.ToLower(hostSpec[1:]))
+	default:
+		if start, end, ok, err := parseRange(hostSpec); ok {
+			if err != nil {
+				return err
+			}
+			m.ranges = append(m.ranges, [2]netip.Addr{start, end})
+			return nil
+		}
+		if addr, err := netip.ParseAddr(hostSpec); err == nil {
+			m.prefixes = append(m.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			return nil
+		}
+		m.hosts[strings.ToLower(hostSpec)] = true
+	}
+	return nil
+}
+
+// Excluded 判断目标（URL、host、host:port）是否在排除列表中
+func (m *Matcher) Excluded(target string) bool {
+	if m == nil {
+		return false
+	}
+	host, port := hostPort(target)
+	if host == "" {
+		return false
+	}
+	host = strings.ToLower(host)
+	if m.hosts[host] {
+		return true
+	}
+	for _, suffix := range m.suffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addr = addr.Unmap()
+		for _, p := range m.prefixes {
+			if p.Contains(addr) {
+				return true
+			}
+		}
+		for _, r := range m.ranges {
+			if addr.Compare(r[0]) >= 0 && addr.Compare(r[1]) <= 0 {
+				return true
+			}
+		}
+	}
+	return port != "" && m.hostPorts[net.JoinHostPort(host, port)]
+}
+
+// Filter 拆分出保留与被排除的目标
+func (m *Matcher) Filter(targets []string) (kept, excluded []string) {
+	if m == nil {
+		return targets, nil
+	}
+	for _, t := range targets {
+		if m.Excluded(t) {
+			excluded = append(excluded, t)
+		} else {
+			kept = append(kept, t)
+		}
+	}
+	return kept, excluded
+}
+
+// DecodeList 解析保存在 JSON 列中的字符串数组（如 tasks.exclusions），空值返回 nil；
+// 非法 JSON 返回错误，排除列表是安全边界，调用方不能把它当作空列表
+func DecodeList(raw string) ([]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var list []string
+	if err := json.Unmarshal([]byte(raw), &list); err != nil {
+		return nil, fmt.Errorf("decode list failed: %w", err)
+	}
+	return list, nil
+}
+
+// hostPort 从 URL、host:port 或 host 中取出主机与端口；URL 未带端口时按 scheme 推断
+func hostPort(target string) (string, string) {
+	target = strings.TrimSpace(target)
+	if strings.Contains(target, "://") {
+		u, err := url.Parse(target)
+		if err != nil {
+			return "", ""
+		}
+		port := u.Port()
+		if port == "" {
+			switch u.Scheme {
+			case "http":
+				port = "80"
+			case "https":
+				port = "443"
+			}
+		}
+		return u.Hostname(), port
+	}
+	if h, p, err := net.SplitHostPort(target); err == nil {
+		if _, err := strconv.Atoi(p); err == nil {
+			return h, p
+		}
+	}
+	return strings.Trim(target, "[]"), ""
+}
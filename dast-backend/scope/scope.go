@@ -0,0 +1,263 @@
+/**
+ * 扫描范围：把 CIDR、IP 段、端口范围等写法展开成单个目标，并提供排除列表匹配
+ */
+package scope
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// MaxTargets 单个任务展开后的目标数上限（相当于一个 /16）
+const MaxTargets = 65536
+
+var ErrTooManyTargets = fmt.Errorf("too many targets after expansion (max %d)", MaxTargets)
+
+// 部分编辑器导出的 txt/csv 带 UTF-8 BOM
+const bom = "\ufeff"
+
+// Expand 展开并去重目标，保持原有顺序。支持：
+//   - URL：http(s)://... 原样保留
+//   - host / IP / host:port
+//   - CIDR：10.0.0.0/24
+//   - IP 段：10.0.0.1-50、10.0.0.1-10.0.0.50
+//   - 端口列表与范围：example.com:80,443、10.0.0.1:8000-8010，可与 CIDR / IP 段组合
+//
+// 空行与 # 开头的注释行被忽略；展开后超过 limit 条返回 ErrTooManyTargets
+func Expand(entries []string, limit int) ([]string, error) {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(entries))
+	for _, raw := range entries {
+		raw = strings.TrimSpace(raw)
+		if raw == "" || strings.HasPrefix(raw, "#") {
+			continue
+		}
+		list, err := expandOne(raw, limit-len(out))
+		if err != nil {
+			return nil, fmt.Errorf("invalid target %q: %w", raw, err)
+		}
+		for _, t := range list {
+			if seen[t] {
+				continue
+			}
+			seen[t] = true
+			out = append(out, t)
+		}
+		if len(out) > limit {
+			return nil, ErrTooManyTargets
+		}
+	}
+	return out, nil
+}
+
+func expandOne(s string, remaining int) ([]string, error) {
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil || u.Host == "" {
+			return nil, errors.New("invalid url")
+		}
+		return []string{s}, nil
+	}
+
+	hostSpec, portSpec := splitPortSpec(s)
+	hosts, err := expandHosts(hostSpec, remaining)
+	if err != nil {
+		return nil, err
+	}
+	ports, err := expandPorts(portSpec)
+	if err != nil {
+		return nil, err
+	}
+	if len(ports) == 0 {
+		return hosts, nil
+	}
+	if len(hosts)*len(ports) > remaining {
+		return nil, ErrTooManyTargets
+	}
+	out := make([]string, 0, len(hosts)*len(ports))
+	for _, h := range hosts {
+		for _, p := range ports {
+			out = append(out, net.JoinHostPort(h, strconv.Itoa(p)))
+		}
+	}
+	return out, nil
+}
+
+// splitPortSpec 拆出主机部分与端口部分：[::1]:80、host:80,443、10.0.0.0/24:8000-8010
+// 多个冒号且没有方括号时视为 IPv6 地址，不带端口
+func splitPortSpec(s string) (string, string) {
+	if strings.HasPrefix(s, "[") {
+		if i := strings.Index(s, "]"); i > 0 {
+			host, rest := s[1:i], s[i+1:]
+			return host, strings.TrimPrefix(rest, ":")
+		}
+	}
+	if strings.Count(s, ":") == 1 {
+		i := strings.Index(s, ":")
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// expandHosts 展开主机部分：CIDR、IP 段或单个主机
+func expandHosts(spec string, remaining int) ([]string, error) {
+	if spec == "" {
+		return nil, errors.New("missing host")
+	}
+	if strings.Contains(spec, "/") {
+		prefix, err := netip.ParsePrefix(spec)
+		if err != nil {
+			return nil, err
+		}
+		prefix = prefix.Masked()
+		if hostBits := prefix.Addr().BitLen() - prefix.Bits(); hostBits >= 31 || 1<<hostBits > remaining {
+			return nil, ErrTooManyTargets
+		}
+		var out []string
+		for a := prefix.Addr(); a.IsValid() && prefix.Contains(a); a = a.Next() {
+			out = append(out, a.String())
+		}
+		return out, nil
+	}
+	if start, end, ok, err := parseRange(spec); ok {
+		if err != nil {
+			return nil, err
+		}
+		var out []string
+		for a := start; a.IsValid() && a.Compare(end) <= 0; a = a.Next() {
+			if len(out) >= remaining {
+				return nil, ErrTooManyTargets
+			}
+			out = append(out, a.String())
+		}
+		return out, nil
+	}
+	if strings.ContainsAny(spec, " \t,") {
+		return nil, errors.New("invalid host")
+	}
+	return []string{spec}, nil
+}
+
+// parseRange 解析 IP 段：10.0.0.1-50 或 10.0.0.1-10.0.0.50；ok 为 false 表示不是 IP 段（例如带 - 的域名）
+func parseRange(spec string) (netip.Addr, netip.Addr, bool, error) {
+	left, right, found := strings.Cut(spec, "-")
+	if !found {
+		return netip.Addr{}, netip.Addr{}, false, nil
+	}
+	start, err := netip.ParseAddr(left)
+	if err != nil {
+		return netip.Addr{}, netip.Addr{}, false, nil
+	}
+	end, err := netip.ParseAddr(right)
+	if err != nil {
+		// 只给出最后一段：10.0.0.1-50
+		n, convErr := strconv.Atoi(right)
+		if !start.Is4() || convErr != nil || n < 0 || n > 255 {
+			return start, start, true, errors.New("invalid ip range")
+		}
+		b := start.As4()
+		b[3] = byte(n)
+		end = netip.AddrFrom4(b)
+	}
+	if start.BitLen() != end.BitLen() || end.Less(start) {
+		return start, end, true, errors.New("invalid ip range")
+	}
+	return start, end, true, nil
+}
+
+// expandPorts 解析端口列表：80,443,8000-8010
+func expandPorts(spec string) ([]int, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	seen := map[int]bool{}
+	var out []int
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi := part, part
+		if a, b, found := strings.Cut(part, "-"); found {
+			lo, hi = a, b
+		}
+		from, err1 := strconv.Atoi(strings.TrimSpace(lo))
+		to, err2 := strconv.Atoi(strings.TrimSpace(hi))
+		if err1 != nil || err2 != nil || from < 1 || to > 65535 || from > to {
+			return nil, fmt.Errorf("invalid port %q", part)
+		}
+		for p := from; p <= to; p++ {
+			if !seen[p] {
+				seen[p] = true
+				out = append(out, p)
+			}
+		}
+	}
+	return out, nil
+}
+
+// ParseFile 读取上传的目标文件：.csv 取 target/host/url 列（没有表头时取第一列），其他按每行一个目标处理
+func ParseFile(r io.Reader, filename string) ([]string, error) {
+	if strings.HasSuffix(strings.ToLower(filename), ".csv") {
+		return parseCSV(r)
+	}
+	var out []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(sc.Text(), bom))
+		if line != "" && !strings.HasPrefix(line, "#") {
+			out = append(out, line)
+		}
+	}
+	return out, sc.Err()
+}
+
+func parseCSV(r io.Reader) ([]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	// 有表头时按列名取目标列，否则取第一列
+	col := -1
+	for i, name := range records[0] {
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, bom))) {
+		case "target", "host", "url":
+			if col < 0 {
+				col = i
+			}
+		}
+	}
+	if col >= 0 {
+		records = records[1:]
+	} else {
+		col = 0
+	}
+
+	var out []string
+	for _, rec := range records {
+		if col < len(rec) {
+			if v := strings.TrimSpace(rec[col]); v != "" {
+				out = append(out, v)
+			}
+		}
+	}
+	return out, nil
+}
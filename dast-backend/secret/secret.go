@@ -0,0 +1,170 @@
+/**
+ * 敏感配置加密：缺陷跟踪等第三方凭据以 AES-256-GCM 加密后保存在 MySQL 中
+ * 密钥取自环境变量 DAST_SECRET_KEY；未设置时在 DAST_SECRET_KEY_FILE（默认 ./secret.key）生成并保存随机密钥
+ * 只有 API 进程会生成密钥，独立 worker 必须使用与 API 相同的密钥，缺失时拒绝启动
+ */
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// 密文前缀，便于以后更换算法
+const prefix = "v1:"
+
+// ErrNoKey worker 进程既没有 DAST_SECRET_KEY 也没有密钥文件
+var ErrNoKey = errors.New("secret key not configured: set DAST_SECRET_KEY or DAST_SECRET_KEY_FILE to the key used by the API")
+
+var (
+	keyOnce  sync.Once
+	aead     cipher.AEAD
+	keyErr   error
+	generate bool // 密钥文件不存在时是否生成，仅 API 进程开启
+)
+
+// Init API 进程启动时加载密钥，没有密钥时生成密钥文件
+func Init() {
+	generate = true
+	if _, err := cipherFor(); err != nil {
+		log.Printf("[secret.Init] load secret key failed err=%v", err)
+	}
+}
+
+// InitWorker worker 进程启动时加载密钥；自己生成的密钥解不开 API 保存的凭据，因此缺少密钥时直接退出
+func InitWorker() {
+	if _, err := cipherFor(); err != nil {
+		log.Fatalf("[secret.InitWorker] load secret key failed err=%v", err)
+	}
+}
+
+func cipherFor() (cipher.AEAD, error) {
+	keyOnce.Do(func() {
+		var key []byte
+		key, keyErr = loadKey()
+		if keyErr != nil {
+			return
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			keyErr = err
+			return
+		}
+		aead, keyErr = cipher.NewGCM(block)
+	})
+	return aead, keyErr
+}
+
+// loadKey 任意长度的口令经 SHA-256 得到 32 字节密钥
+func loadKey() ([]byte, error) {
+	if v := os.Getenv("DAST_SECRET_KEY"); v != "" {
+		sum := sha256.Sum256([]byte(v))
+		return sum[:], nil
+	}
+	path := os.Getenv("DAST_SECRET_KEY_FILE")
+	if path == "" {
+		path = "./secret.key"
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("invalid key file %s", path)
+		}
+		return key, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if !generate {
+		return nil, ErrNoKey
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0o600); err != nil {
+		return nil, fmt.Errorf("write key file failed: %w", err)
+	}
+	log.Printf("[secret] DAST_SECRET_KEY not set, generated key file %s (back it up, or encrypted settings cannot be decrypted)", path)
+	return key, nil
+}
+
+// Encrypt 加密并返回可直接入库的字符串；空内容返回空字符串
+func Encrypt(plain []byte) (string, error) {
+	if len(plain) == 0 {
+		return "", nil
+	}
+	a, err := cipherFor()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, a.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := a.Seal(nonce, nonce, plain, nil)
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密 Encrypt 的结果
+func Decrypt(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(s, prefix) {
+		return nil, errors.New("unknown ciphertext format")
+	}
+	a, err := cipherFor()
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(s[len(prefix):])
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < a.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	plain, err := a.Open(nil, data[:a.NonceSize()], data[a.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("decrypt failed (secret key changed?)")
+	}
+	return plain, nil
+}
+
+// Masked 接口返回配置时代替凭据的占位符；更新时传回该值表示沿用原值
+const Masked = "******"
+
+// Mask 返回 keys 对应的非空字段被替换为 Masked 的配置副本
+func Mask(config map[string]interface{}, keys ...string) map[string]interface{} {
+	out := make(map[string]interface{}, len(config))
+	for k, v := range config {
+		out[k] = v
+	}
+	for _, k := range keys {
+		if s, ok := out[k].(string); ok && s != "" {
+			out[k] = Masked
+		}
+	}
+	return out
+}
+
+// Merge 更新配置时，keys 对应字段为 Masked 或未传时沿用 old 中的原值
+func Merge(config, old map[string]interface{}, keys ...string) {
+	for _, k := range keys {
+		v, ok := config[k]
+		if (!ok || v == Masked) && old[k] != nil {
+			config[k] = old[k]
+		}
+	}
+}
@@ -3,11 +3,14 @@ package target
 import (
 	"demo/db/mysqldb"
 	"demo/db/redisdb"
+	"demo/finding"
 	"demo/models"
+	"demo/scope"
+	"demo/taskrun"
 	"encoding/json"
+	"io"
 	"log"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -19,9 +22,114 @@ func GetTaskTargetsKey(taskId string) string {
 	return "task:" + taskId + ":targets"
 }
 
-// Add - 批量添加 targets：先写入 MySQL（持久化），再写入 Redis（用于扫描队列）
+// 上传目标文件的大小上限
+const maxUploadSize = 5 << 20
+
+// addResult addTargets 的结果；status 为 HTTP 状态码
+type addResult struct {
+	status     int
+	added      []string
+	duplicates int
+	err        string
+}
+
+// addTargets 展开并去重后追加 targets：先写入 MySQL（持久化），再写入 Redis（用于扫描队列）
+// 已存在于任务中的目标会被跳过，展开后任务目标总数不能超过 scope.MaxTargets
 // 若 Redis 写入失败，会将 taskId 推入补偿队列并在 MySQL 上将 task 状态置为 pending_sync（best-effort）。
-// 额外记录日志，插入时显式设置 CreatedAt/UpdatedAt。
+func addTargets(taskId string, raw []string) addResult {
+	var task models.Task
+	if err := mysqldb.DB.First(&task, "id = ?", taskId).Error; err != nil {
+		return addResult{status: http.StatusNotFound, err: "task not found"}
+	}
+
+	var existing []string
+	if err := mysqldb.DB.Model(&models.Target{}).Where("task_id = ?", taskId).Pluck("target", &existing).Error; err != nil {
+		log.Printf("[target.Add] db query targets failed task=%s err=%v", taskId, err)
+		return addResult{status: http.StatusInternalServerError, err: err.Error()}
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, t := range existing {
+		seen[t] = true
+	}
+
+	expanded, err := scope.Expand(raw, scope.MaxTargets-len(seen))
+	if err != nil {
+		return addResult{status: http.StatusBadRequest, err: err.Error()}
+	}
+	res := addResult{status: http.StatusOK}
+	for _, t := range expanded {
+		if seen[t] {
+			res.duplicates++
+			continue
+		}
+		res.added = append(res.added, t)
+	}
+	if len(res.added) == 0 {
+		return res
+	}
+
+	// 1) 持久化到 MySQL（批量插入 targets）
+	targetModels := make([]models.Target, 0, len(res.added))
+	now := time.Now()
+	for _, t := range res.added {
+		targetModels = append(targetModels, models.Target{
+			TaskID:    taskId,
+			Target:    t,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+	if err := mysqldb.DB.CreateInBatches(&targetModels, 100).Error; err != nil {
+		log.Printf("[target.Add] db insert targets failed task=%s err=%v", taskId, err)
+		return addResult{status: http.StatusInternalServerError, err: "db insert targets failed: " + err.Error()}
+	}
+
+	// 2) 写入 Redis（任务专属队列）——若失败则记录补偿队列
+	targetsInterface := make([]interface{}, len(res.added))
+	for i, t := range res.added {
+		targetsInterface[i] = t
+	}
+	key := GetTaskTargetsKey(taskId)
+	if err := redisdb.Client.RPush(redisdb.Ctx, key, targetsInterface...).Err(); err != nil {
+		log.Printf("[target.Add] redis push failed task=%s err=%v; enqueue compensator", taskId, err)
+		// 记录补偿项，后台 worker 会用 MySQL 中的 targets 重建 Redis 列表
+		_ = redisdb.Client.RPush(redisdb.Ctx, "task:sync:targets:queue", taskId).Err()
+		// 同时将 MySQL 上的该任务状态标记为 pending_sync（best-effort）
+		_ = mysqldb.DB.Model(&models.Task{}).Where("id = ?", taskId).
+			Update("status", "pending_sync").Error
+		res.status = http.StatusAccepted
+		res.err = "targets stored in DB, but redis push failed; queued for retry"
+		return res
+	}
+
+	log.Printf("[target.Add] success task=%s added=%d duplicates=%d", taskId, len(res.added), res.duplicates)
+	return res
+}
+
+func (r addResult) respond(c *gin.Context, taskId string) {
+	if r.status != http.StatusOK && r.status != http.StatusAccepted {
+		c.JSON(r.status, gin.H{"error": r.err})
+		return
+	}
+	added := r.added
+	if added == nil {
+		added = []string{}
+	}
+	resp := gin.H{
+		"message":    "添加成功",
+		"taskId":     taskId,
+		"targets":    added,
+		"added":      len(added),
+		"duplicates": r.duplicates,
+	}
+	if r.status == http.StatusAccepted {
+		resp["message"] = r.err
+	}
+	c.JSON(r.status, resp)
+}
+
+// Add - 批量添加 targets，支持 CIDR（10.0.0.0/24）、IP 段（10.0.0.1-50）、
+// 端口列表/范围（host:80,443、host:8000-8010），服务端展开并去重
 func Add() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
@@ -37,54 +145,110 @@ func Add() gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "missing taskId"})
 			return
 		}
+		addTargets(taskId, req.Targets).respond(c, taskId)
+	}
+}
 
-		// 1) 持久化到 MySQL（批量插入 targets）
-		targetModels := make([]models.Target, 0, len(req.Targets))
-		now := time.Now()
-		for _, t := range req.Targets {
-			targetModels = append(targetModels, models.Target{
-				TaskID:    taskId,
-				Target:    t,
-				CreatedAt: now,
-				UpdatedAt: now,
-			})
+// Upload - 上传 txt/csv 目标文件批量添加（multipart：taskId + file）
+// txt 每行一个目标，csv 取 target/host/url 列（无表头时取第一列），# 开头为注释
+func Upload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		taskId := c.PostForm("taskId")
+		if taskId == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing taskId"})
+			return
 		}
-		if len(targetModels) > 0 {
-			if err := mysqldb.DB.CreateInBatches(&targetModels, 100).Error; err != nil {
-				log.Printf("[target.Add] db insert targets failed task=%s err=%v", taskId, err)
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "db insert targets failed: " + err.Error()})
-				return
-			}
+		fh, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing file"})
+			return
+		}
+		if fh.Size > maxUploadSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file too large"})
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
+		defer f.Close()
 
-		// 2) 写入 Redis（任务专属队列）——若失败则记录补偿队列
-		targetsInterface := make([]interface{}, len(req.Targets))
-		for i, t := range req.Targets {
-			targetsInterface[i] = t
+		raw, err := scope.ParseFile(io.LimitReader(f, maxUploadSize), fh.Filename)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "parse file failed: " + err.Error()})
+			return
 		}
-		key := GetTaskTargetsKey(taskId)
-		if err := redisdb.Client.RPush(redisdb.Ctx, key, targetsInterface...).Err(); err != nil {
-			log.Printf("[target.Add] redis push failed task=%s err=%v; enqueue compensator", taskId, err)
-			// 记录补偿项，后台 worker 会重试把 targets 推回 Redis
-			_ = redisdb.Client.RPush(redisdb.Ctx, "task:sync:targets:queue", taskId).Err()
-			// 同时将 MySQL 上的该任务状态标记为 pending_sync（best-effort）
-			_ = mysqldb.DB.Model(&models.Task{}).Where("id = ?", taskId).
-				Update("status", "pending_sync").Error
-
-			c.JSON(http.StatusAccepted, gin.H{
-				"message": "targets stored in DB, but redis push failed; queued for retry",
-				"taskId":  taskId,
-				"targets": req.Targets,
-			})
+		if len(raw) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "no targets in file"})
 			return
 		}
+		addTargets(taskId, raw).respond(c, taskId)
+	}
+}
 
-		log.Printf("[target.Add] success task=%s count=%d", taskId, len(req.Targets))
-		c.JSON(http.StatusOK, gin.H{
-			"message": "添加成功",
-			"taskId":  taskId,
-			"targets": req.Targets,
-		})
+// Exclusions - 查看任务的排除列表
+// GET /api/target/exclusions?taskId=
+func Exclusions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		taskId := c.Query("taskId")
+		if taskId == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing taskId"})
+			return
+		}
+		var t models.Task
+		if err := mysqldb.DB.Select("id", "exclusions").First(&t, "id = ?", taskId).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+			return
+		}
+		list, err := scope.DecodeList(t.Exclusions)
+		if err != nil {
+			log.Printf("[target.Exclusions] decode exclusions failed task=%s err=%v", taskId, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid exclusions: " + err.Error()})
+			return
+		}
+		if list == nil {
+			list = []string{}
+		}
+		c.JSON(http.StatusOK, gin.H{"taskId": taskId, "exclusions": list})
+	}
+}
+
+// SetExclusions - 整体替换任务的排除列表，下次执行时在端口扫描前生效
+// POST /api/target/exclusions {"taskId":"...","exclusions":["10.0.0.0/28","*.internal.example.com","10.0.1.5:22"]}
+func SetExclusions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			TaskId     string   `json:"taskId"`
+			Exclusions []string `json:"exclusions"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.TaskId == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing taskId"})
+			return
+		}
+		if _, err := scope.NewMatcher(req.Exclusions); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var value interface{}
+		if len(req.Exclusions) > 0 {
+			b, _ := json.Marshal(req.Exclusions)
+			value = string(b)
+		}
+		res := mysqldb.DB.Model(&models.Task{}).Where("id = ?", req.TaskId).Update("exclusions", value)
+		if res.Error != nil {
+			log.Printf("[target.SetExclusions] db update failed task=%s err=%v", req.TaskId, res.Error)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": res.Error.Error()})
+			return
+		}
+		if res.RowsAffected == 0 {
+			var n int64
+			if mysqldb.DB.Model(&models.Task{}).Where("id = ?", req.TaskId).Count(&n); n == 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+				return
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "保存成功", "taskId": req.TaskId, "exclusions": req.Exclusions})
 	}
 }
 
@@ -142,6 +306,13 @@ func Delete() gin.HandlerFunc {
 			return
 		}
 		taskId := req.TaskId
+		// 与添加时使用同样的写法展开（例如删除整个 CIDR）
+		expanded, err := scope.Expand(req.Targets, scope.MaxTargets)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.Targets = expanded
 
 		// 1) 在 MySQL 中删除这些 targets（如果存在）
 		tx := mysqldb.DB.Begin()
@@ -202,7 +373,9 @@ func Delete() gin.HandlerFunc {
 	}
 }
 
-// Result - 获取扫描结果（保持 Redis 分页实现）
+// Result - 获取扫描结果
+// 默认返回最近一次执行的结果，可通过 runId 指定历史执行
+// 优先从 MySQL findings 表分页读取（details 列即原始 ResultEvent），若 MySQL 无数据则回退到 Redis（兼容旧数据）
 func Result() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		taskId, _ := c.GetQuery("taskId")
@@ -210,19 +383,52 @@ func Result() gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "missing taskId"})
 			return
 		}
+		runId := c.Query("runId")
+		if runId == "" {
+			runId = taskrun.Latest(taskId)
+		}
+		// 没有执行记录的旧任务仍读取任务级结果列表
 		resultKey := "task:" + taskId + ":result"
-
-		pageStr := c.DefaultQuery("page", "1")
-		pageSizeStr := c.DefaultQuery("pageSize", "20")
-		page, err := strconv.Atoi(pageStr)
-		if err != nil || page <= 0 {
-			page = 1
+		if runId != "" {
+			resultKey = taskrun.ResultKey(taskId, runId)
 		}
-		pageSize, err := strconv.Atoi(pageSizeStr)
-		if err != nil || pageSize <= 0 {
-			pageSize = 20
+		page, pageSize := finding.ParsePage(c)
+
+		var dbTotal int64
+		q := finding.Query{TaskID: taskId, RunID: runId}
+		if err := q.Apply(mysqldb.DB.Model(&models.Finding{})).Count(&dbTotal).Error; err == nil && dbTotal > 0 {
+			var dbFindings []models.Finding
+			if err := q.Apply(mysqldb.DB.Model(&models.Finding{})).
+				Order("id asc").
+				Offset((page - 1) * pageSize).
+				Limit(pageSize).
+				Find(&dbFindings).Error; err != nil {
+				log.Printf("[target.Result] db query failed task=%s err=%v", taskId, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			results := make([]output.ResultEvent, 0, len(dbFindings))
+			for _, f := range dbFindings {
+				var ev output.ResultEvent
+				if err := json.Unmarshal([]byte(f.Details), &ev); err != nil {
+					continue
+				}
+				results = append(results, ev)
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"taskId":   taskId,
+				"runId":    runId,
+				"total":    dbTotal,
+				"page":     page,
+				"pageSize": pageSize,
+				"count":    len(results),
+				"results":  results,
+				"source":   "mysql",
+			})
+			return
 		}
 
+		// 回退到 Redis（兼容持久化之前的数据）
 		total, err := redisdb.Client.LLen(redisdb.Ctx, resultKey).Result()
 		if err != nil {
 			log.Printf("[target.Result] redis llen failed task=%s err=%v", taskId, err)
@@ -232,6 +438,7 @@ func Result() gin.HandlerFunc {
 		if total == 0 {
 			c.JSON(http.StatusOK, gin.H{
 				"taskId":   taskId,
+				"runId":    runId,
 				"total":    0,
 				"page":     page,
 				"pageSize": pageSize,
@@ -246,6 +453,7 @@ func Result() gin.HandlerFunc {
 		if start >= total {
 			c.JSON(http.StatusOK, gin.H{
 				"taskId":   taskId,
+				"runId":    runId,
 				"total":    total,
 				"page":     page,
 				"pageSize": pageSize,
@@ -272,6 +480,7 @@ func Result() gin.HandlerFunc {
 
 		c.JSON(http.StatusOK, gin.H{
 			"taskId":   taskId,
+			"runId":    runId,
 			"total":    total,
 			"page":     page,
 			"pageSize": pageSize,
@@ -187,14 +187,22 @@ func compensatorWorker() {
 			continue
 		}
 
-		// 将 MySQL 中的 targets 写回 Redis（去重不是这里的职责，按原逻辑写入）
+		// 用 MySQL 中去重后的 targets 整体替换 Redis 列表，避免部分写入成功时重复追加
 		redisKey := GetTaskTargetsKey(taskId)
+		seen := map[string]bool{}
 		targetsInterface := make([]interface{}, 0, len(dbTargets))
 		for _, t := range dbTargets {
+			if seen[t.Target] {
+				continue
+			}
+			seen[t.Target] = true
 			targetsInterface = append(targetsInterface, t.Target)
 		}
 
-		if err := redisdb.Client.RPush(ctx, redisKey, targetsInterface...).Err(); err != nil {
+		pipe := redisdb.Client.TxPipeline()
+		pipe.Del(ctx, redisKey)
+		pipe.RPush(ctx, redisKey, targetsInterface...)
+		if _, err := pipe.Exec(ctx); err != nil {
 			log.Printf("[compensator] redis push failed task=%s err=%v; move to failed list", taskId, err)
 			// 写入失败队列，供人工介入或后续批量处理
 			_ = redisdb.Client.RPush(ctx, failedKey, taskId).Err()
@@ -0,0 +1,121 @@
+package task
+
+import (
+	"demo/db/redisdb"
+	"demo/scanner"
+	"demo/taskrun"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// SSE 阻塞读取 stream 的超时，超时后发送心跳注释保持连接
+const progressBlock = 15 * time.Second
+
+// Progress - 通过 Server-Sent Events 推送扫描进度
+// GET /api/task/progress?taskId=&runId=   （EventSource 无法设置请求头，token 通过 ?token= 传递）
+// 事件来自 Redis stream task:{id}:run:{runId}:events，任意 API 实例都能提供；
+// 先回放已有事件再持续推送，断线重连时按 Last-Event-ID 续传，执行结束后关闭连接
+func Progress() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		taskId := c.Query("taskId")
+		if taskId == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing taskId"})
+			return
+		}
+		runId := c.Query("runId")
+		if runId == "" {
+			runId = taskrun.Latest(taskId)
+		}
+		if runId == "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "task has no runs"})
+			return
+		}
+		streamKey := taskrun.EventsKey(taskId, runId)
+
+		// "0" 表示从头回放
+		lastId := c.GetHeader("Last-Event-ID")
+		if lastId == "" {
+			lastId = "0"
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no") // 关闭 nginx 缓冲
+		c.Status(http.StatusOK)
+
+		ctx := c.Request.Context()
+		w := c.Writer
+		fmt.Fprintf(w, "retry: 3000\n\n")
+		w.Flush()
+
+		for {
+			streams, err := redisdb.Client.XRead(ctx, &redis.XReadArgs{
+				Streams: []string{streamKey, lastId},
+				Count:   100,
+				Block:   progressBlock,
+			}).Result()
+			if ctx.Err() != nil {
+				return
+			}
+			if err == redis.Nil {
+				// 没有新事件：执行已结束（例如回放完后才连上）则关闭，否则发心跳
+				if runFinished(taskId, runId) {
+					return
+				}
+				fmt.Fprintf(w, ": ping\n\n")
+				w.Flush()
+				continue
+			}
+			if err != nil {
+				log.Printf("[task.Progress] redis xread failed task=%s run=%s err=%v", taskId, runId, err)
+				return
+			}
+
+			finished := false
+			for _, s := range streams {
+				for _, msg := range s.Messages {
+					lastId = msg.ID
+					event, _ := msg.Values["event"].(string)
+					data, _ := msg.Values["data"].(string)
+					fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", msg.ID, event, data)
+					if event == scanner.EventStatus && isTerminal(data) {
+						finished = true
+					}
+				}
+			}
+			w.Flush()
+			if finished {
+				return
+			}
+		}
+	}
+}
+
+// isTerminal 判断 status 事件是否表示执行已结束
+func isTerminal(data string) bool {
+	var ev struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(data), &ev); err != nil {
+		return false
+	}
+	return ev.Status != "" && ev.Status != "running"
+}
+
+// runFinished 当前执行是否已不再运行（被新的执行取代也视为结束）
+func runFinished(taskId, runId string) bool {
+	info, err := redisdb.Client.HMGet(redisdb.Ctx, "task:"+taskId+":info", "status", "run_id").Result()
+	if err != nil || len(info) < 2 {
+		return false
+	}
+	status, _ := info[0].(string)
+	curRun, _ := info[1].(string)
+	return curRun != runId || (status != "" && status != "running")
+}
@@ -0,0 +1,163 @@
+package task
+
+import (
+	"demo/audit"
+	"demo/db/mysqldb"
+	"demo/db/redisdb"
+	"demo/models"
+	"demo/scanner"
+	"demo/scope"
+	"demo/taskrun"
+	"demo/worker"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// 崩溃恢复策略
+const (
+	RecoverResume    = "resume"    // 重新入队，从最后完成的阶段继续
+	RecoverInterrupt = "interrupt" // 标记为 interrupted，由用户决定是否重新启动
+)
+
+// Recover 启动时检测孤儿执行：MySQL 中仍为 running，但既没有有效租约、也不在待领取的作业队列中
+// （进程在扫描中途重启，整个集群重启后只剩 processing 中的作业，或 Redis 数据丢失）。按 mode 重新入队或标记为 interrupted。
+func Recover(mode string) {
+	var runs []models.TaskRun
+	if err := mysqldb.DB.Where("status = ?", "running").Find(&runs).Error; err != nil {
+		log.Printf("[task.Recover] db query runs failed err=%v", err)
+		return
+	}
+
+	active := map[string]bool{}
+	var suspects []models.TaskRun
+	for _, run := range runs {
+		owned, err := worker.Owned(run.TaskID, run.ID)
+		if err != nil {
+			log.Printf("[task.Recover] check owner failed task=%s run=%s err=%v", run.TaskID, run.ID, err)
+			active[run.TaskID] = true
+			continue
+		}
+		if owned {
+			active[run.TaskID] = true
+			continue
+		}
+		suspects = append(suspects, run)
+	}
+	// worker 可能刚领取作业还没写入租约，间隔一轮后再确认
+	if len(suspects) > 0 {
+		log.Printf("[task.Recover] %d runs without owner, confirm after %s", len(suspects), worker.OrphanGrace)
+		time.Sleep(worker.OrphanGrace)
+	}
+
+	for _, run := range suspects {
+		orphan, err := worker.ClaimOrphan(run.TaskID, run.ID)
+		if err != nil {
+			log.Printf("[task.Recover] check owner failed task=%s run=%s err=%v", run.TaskID, run.ID, err)
+		}
+		if err != nil || !orphan {
+			active[run.TaskID] = true
+			continue
+		}
+
+		if mode == RecoverResume {
+			err := resumeRun(run)
+			if err == nil {
+				active[run.TaskID] = true
+				log.Printf("[task.Recover] orphaned run requeued task=%s run=%s", run.TaskID, run.ID)
+				continue
+			}
+			log.Printf("[task.Recover] resume failed task=%s run=%s err=%v", run.TaskID, run.ID, err)
+		}
+		interruptRun(run.TaskID, run.ID, "orphaned after restart")
+		log.Printf("[task.Recover] orphaned run marked interrupted task=%s run=%s", run.TaskID, run.ID)
+	}
+
+	// 没有任何 running 执行记录的 running 任务（执行记录出现之前启动的旧任务）只能标记为 interrupted
+	var tasks []models.Task
+	if err := mysqldb.DB.Where("status = ?", "running").Find(&tasks).Error; err != nil {
+		log.Printf("[task.Recover] db query tasks failed err=%v", err)
+		return
+	}
+	for _, t := range tasks {
+		if active[t.ID] {
+			continue
+		}
+		interruptRun(t.ID, "", "orphaned after restart")
+		log.Printf("[task.Recover] orphaned task marked interrupted task=%s", t.ID)
+	}
+}
+
+// resumeRun 用执行记录中的目标与 profile 快照重新投递作业，worker 领取后会读取断点继续
+func resumeRun(run models.TaskRun) error {
+	infoKey := "task:" + run.TaskID + ":info"
+
+	var targets []string
+	if run.Targets != "" {
+		if err := json.Unmarshal([]byte(run.Targets), &targets); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		targets, err = redisdb.Client.LRange(redisdb.Ctx, "task:"+run.TaskID+":targets", 0, -1).Result()
+		if err != nil {
+			return err
+		}
+	}
+	var t models.Task
+	if err := mysqldb.DB.First(&t, "id = ?", run.TaskID).Error; err != nil {
+		return err
+	}
+	config := run.Config
+	if config == "" {
+		config = t.Config
+	}
+	// 排除列表无法解析时不能续扫，交给调用方标记为 interrupted
+	exclusions, err := scope.DecodeList(t.Exclusions)
+	if err != nil {
+		return fmt.Errorf("invalid exclusions: %w", err)
+	}
+
+	_, _ = redisdb.Client.HSet(redisdb.Ctx, infoKey,
+		"status", "running",
+		"stage", scanner.StageQueued,
+		"worker", "",
+		"run_id", run.ID,
+		"updated_at", time.Now().Format("2006-01-02 15:04:05"),
+	).Result()
+
+	return worker.Enqueue(worker.Job{
+		TaskID:     run.TaskID,
+		RunID:      run.ID,
+		Targets:    targets,
+		Exclusions: exclusions,
+		InfoKey:    infoKey,
+		Config:     config,
+	})
+}
+
+// interruptRun 把任务（及其执行记录）从 running 改为 interrupted；runId 为空时只处理任务本身
+func interruptRun(taskId, runId, message string) {
+	now := time.Now()
+	if runId != "" {
+		if err := mysqldb.DB.Model(&models.TaskRun{}).
+			Where("id = ? AND status = ?", runId, "running").
+			Updates(map[string]interface{}{"status": "interrupted", "message": message, "finished_at": now}).Error; err != nil {
+			log.Printf("[task.Recover] db update run failed task=%s run=%s err=%v", taskId, runId, err)
+		}
+		_ = redisdb.Client.Del(redisdb.Ctx, taskrun.CheckpointKey(taskId, runId)).Err()
+	}
+	if err := mysqldb.DB.Model(&models.Task{}).
+		Where("id = ? AND status = ?", taskId, "running").
+		Updates(map[string]interface{}{"status": "interrupted", "finished_at": now}).Error; err != nil {
+		log.Printf("[task.Recover] db update task failed task=%s err=%v", taskId, err)
+	}
+	_, _ = redisdb.Client.HSet(redisdb.Ctx, "task:"+taskId+":info",
+		"status", "interrupted",
+		"stage", scanner.StageDone,
+		"error_msg", message,
+		"updated_at", now.Format("2006-01-02 15:04:05"),
+	).Result()
+	audit.Lifecycle(taskId, runId, "interrupted", message)
+}
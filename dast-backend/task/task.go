@@ -2,13 +2,22 @@ package task
 
 import (
 	"crypto/rand"
+	"demo/apispec"
+	"demo/audit"
 	"demo/db/mysqldb"
 	"demo/db/redisdb"
 	"demo/models"
+	"demo/notify"
+	"demo/profile"
 	"demo/scanner"
+	"demo/scope"
 	"demo/target"
+	"demo/worker"
 
 	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -32,107 +41,180 @@ func generateTaskID() string {
 	return hex.EncodeToString(b)
 }
 
-func Create() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		var req struct {
-			TaskName string   `json:"taskName"`
-			Targets  []string `json:"targets"`
-		}
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
-			return
-		}
-		if len(req.Targets) == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "missing targets"})
-			return
-		}
-		if strings.TrimSpace(req.TaskName) == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "missing taskName"})
-			return
-		}
+// Created CreateTask 的结果
+type Created struct {
+	Task    *models.Task
+	Targets []string // 展开、去重后的目标
+	Profile string   // 实际使用的 profile 名称
+}
 
-		taskId := generateTaskID()
-		now := time.Now()
+// CreateTask 创建任务：展开目标（CIDR、IP 段、端口列表等）并去重，解析 profile，
+// 写入 MySQL（tasks + targets）与 Redis
+// 参数错误（目标为空、目标或排除列表写法错误、profile 无效等）返回 ErrInvalidTask
+func CreateTask(name string, rawTargets []string, profileName string, rawConfig json.RawMessage, exclusions []string, creator string) (*Created, error) {
+	return createTask(name, rawTargets, profileName, rawConfig, exclusions, creator, nil)
+}
 
-		// 1) 持久化到 MySQL（事务内先写 tasks + targets）
-		taskModel := &models.Task{
-			ID:        taskId,
-			Name:      req.TaskName,
-			Status:    "pending",
-			CreatedAt: now,
-			UpdatedAt: now,
-		}
+// createTask spec 不为空时创建 API 扫描任务，spec 与任务在同一事务中写入
+func createTask(name string, rawTargets []string, profileName string, rawConfig json.RawMessage, exclusions []string, creator string, spec *models.APISpec) (*Created, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("%w: missing taskName", ErrInvalidTask)
+	}
+	targets, err := scope.Expand(rawTargets, scope.MaxTargets)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTask, err)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%w: missing targets", ErrInvalidTask)
+	}
+	if _, err := scope.NewMatcher(exclusions); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTask, err)
+	}
 
-		// 使用事务保证 tasks 与 targets 一致性
-		tx := mysqldb.DB.Begin()
-		if err := tx.Create(taskModel).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db create task failed: " + err.Error()})
-			return
-		}
+	// 解析扫描 profile，保存快照到 tasks.config（后续修改 profile 不影响已创建任务）
+	p, err := profile.Resolve(profileName, rawConfig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTask, err)
+	}
+	config, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
 
-		// 批量插入 targets
-		targetModels := make([]models.Target, 0, len(req.Targets))
-		for _, t := range req.Targets {
-			targetModels = append(targetModels, models.Target{
-				TaskID: taskId,
-				Target: t,
-			})
+	taskId := generateTaskID()
+	now := time.Now()
+
+	// 1) 持久化到 MySQL（事务内先写 tasks + targets）
+	taskModel := &models.Task{
+		ID:        taskId,
+		Name:      name,
+		Status:    "pending",
+		Config:    string(config),
+		Creator:   creator,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if len(exclusions) > 0 {
+		b, _ := json.Marshal(exclusions)
+		taskModel.Exclusions = string(b)
+	}
+	if spec != nil {
+		taskModel.Type = apispec.TaskType
+	}
+
+	// 使用事务保证 tasks 与 targets 一致性
+	tx := mysqldb.DB.Begin()
+	if err := tx.Create(taskModel).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("db create task failed: %w", err)
+	}
+
+	// 批量插入 targets
+	targetModels := make([]models.Target, 0, len(targets))
+	for _, t := range targets {
+		targetModels = append(targetModels, models.Target{
+			TaskID: taskId,
+			Target: t,
+		})
+	}
+	if len(targetModels) > 0 {
+		if err := tx.CreateInBatches(&targetModels, 100).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("db insert targets failed: %w", err)
 		}
-		if len(targetModels) > 0 {
-			if err := tx.CreateInBatches(&targetModels, 100).Error; err != nil {
-				tx.Rollback()
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "db insert targets failed: " + err.Error()})
-				return
-			}
+	}
+	if spec != nil {
+		spec.TaskID = taskId
+		if err := tx.Create(spec).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("db create api spec failed: %w", err)
 		}
-		tx.Commit()
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("db commit failed: %w", err)
+	}
+
+	// 2) 仍按原逻辑写入 Redis（用于队列/扫描）
+	key := target.GetTaskTargetsKey(taskId)
+	targetsInterface := make([]interface{}, len(targets))
+	for i, t := range targets {
+		targetsInterface[i] = t
+	}
+	if err := redisdb.Client.RPush(redisdb.Ctx, key, targetsInterface...).Err(); err != nil {
+		// Redis 写失败不回滚 MySQL，但要记录错误
+		return nil, fmt.Errorf("redis push failed: %w", err)
+	}
 
-		// 2) 仍按原逻辑写入 Redis（用于队列/扫描）
-		key := target.GetTaskTargetsKey(taskId)
-		targetsInterface := make([]interface{}, len(req.Targets))
-		for i, t := range req.Targets {
-			targetsInterface[i] = t
+	// 3) 写任务 info 到 Redis（保持原来用于 API 显示）
+	taskInfo := map[string]interface{}{
+		"taskId":     taskId,
+		"taskName":   name,
+		"created_at": now.Format("2006-01-02 15:04:05"),
+		"updated_at": now.Format("2006-01-02 15:04:05"),
+		"status":     "pending",
+	}
+	if err := redisdb.Client.HSet(redisdb.Ctx, "task:"+taskId+":info", taskInfo).Err(); err != nil {
+		return nil, err
+	}
+	if err := redisdb.Client.RPush(redisdb.Ctx, "tasks:list", taskId).Err(); err != nil {
+		return nil, err
+	}
+	return &Created{Task: taskModel, Targets: targets, Profile: p.Name}, nil
+}
+
+func Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			TaskName   string          `json:"taskName"`
+			Targets    []string        `json:"targets"`
+			Profile    string          `json:"profile"`    // 内置或自定义 profile 名称，默认 default
+			Config     json.RawMessage `json:"config"`     // 可选：直接给出完整的自定义 profile
+			Exclusions []string        `json:"exclusions"` // 可选：排除列表（主机、IP、CIDR、host:port）
 		}
-		if err := redisdb.Client.RPush(redisdb.Ctx, key, targetsInterface...).Err(); err != nil {
-			// Redis 写失败不回滚 MySQL，但要记录错误
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "redis push failed: " + err.Error()})
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
 			return
 		}
 
-		// 3) 写任务 info 到 Redis（保持原来用于 API 显示）
-		taskInfo := map[string]interface{}{
-			"taskId":     taskId,
-			"taskName":   req.TaskName,
-			"created_at": now.Format("2006-01-02 15:04:05"),
-			"updated_at": now.Format("2006-01-02 15:04:05"),
-			"status":     "pending",
-		}
-		if err := redisdb.Client.HSet(redisdb.Ctx, "task:"+taskId+":info", taskInfo).Err(); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		created, err := CreateTask(req.TaskName, req.Targets, req.Profile, req.Config, req.Exclusions, c.GetString("username"))
+		if errors.Is(err, ErrInvalidTask) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		if err := redisdb.Client.RPush(redisdb.Ctx, "tasks:list", taskId).Err(); err != nil {
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
+		audit.SetObject(c, created.Task.ID)
 		c.JSON(http.StatusOK, gin.H{
-			"message":  "任务创建成功",
-			"taskId":   taskId,
-			"taskName": req.TaskName,
-			"created":  now.Format("2006-01-02 15:04:05"),
-			"targets":  req.Targets,
+			"message":     "任务创建成功",
+			"taskId":      created.Task.ID,
+			"taskName":    created.Task.Name,
+			"created":     created.Task.CreatedAt.Format("2006-01-02 15:04:05"),
+			"targets":     created.Targets,
+			"targetCount": len(created.Targets),
+			"profile":     created.Profile,
 		})
 	}
 }
 
 // 获取任务列表
+// 可选参数：creator=xxx 按创建者过滤；mine=1 只看自己创建的任务
 func List() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		creator := c.Query("creator")
+		if c.Query("mine") == "1" {
+			creator = c.GetString("username")
+		}
+
 		// 优先从 MySQL 查询 tasks 元信息
 		var dbTasks []models.Task
-		if err := mysqldb.DB.Order("created_at desc").Find(&dbTasks).Error; err == nil && len(dbTasks) > 0 {
+		query := mysqldb.DB.Order("created_at desc")
+		if creator != "" {
+			query = query.Where("creator = ?", creator)
+		}
+		if err := query.Find(&dbTasks).Error; err == nil && (len(dbTasks) > 0 || creator != "") {
 			resp := make([]gin.H, 0, len(dbTasks))
 			for _, t := range dbTasks {
 				// 为了显示最新运行状态，可优先读取 Redis 中的 task:{id}:info.status（如果存在）
@@ -142,10 +224,17 @@ func List() gin.HandlerFunc {
 						status = s
 					}
 				}
+				profileName := scanner.DefaultProfileName
+				if p, err := scanner.ParseProfile(t.Config); err == nil {
+					profileName = p.Name
+				}
 				resp = append(resp, gin.H{
 					"taskId":     t.ID,
 					"taskName":   t.Name,
 					"status":     status,
+					"type":       t.Type,
+					"profile":    profileName,
+					"creator":    t.Creator,
 					"created_at": t.CreatedAt.Format("2006-01-02 15:04:05"),
 					"updated_at": t.UpdatedAt.Format("2006-01-02 15:04:05"),
 				})
@@ -178,88 +267,165 @@ func List() gin.HandlerFunc {
 	}
 }
 
-// Start 启动任务扫描（防止重复启动）
+// CreateTask / StartTask 失败时返回的错误，接口据此映射 HTTP 状态码
+var (
+	ErrInvalidTask       = errors.New("invalid task")
+	ErrTaskNotFound      = errors.New("task not found or empty")
+	ErrStartInProgress   = errors.New("task start already in progress")
+	ErrTaskRunning       = errors.New("task already running")
+	ErrTaskNotStartable  = errors.New("task cannot be started")
+	ErrInvalidTaskConfig = errors.New("invalid task config")
+)
+
+// StartTask 启动任务扫描（防止重复启动），手动启动与定时调度共用
 // - 使用 Redis 短期锁避免并发竞争。
 // - 使用 MySQL 原子更新（WHERE id=? AND status != 'running'）保证只有一个请求把状态改为 running。
+// - 每次成功启动都会新建一条 task_runs 记录，结果按 runId 归档。
+func StartTask(taskId, triggeredBy string) (*models.TaskRun, error) {
+	ctx := redisdb.Ctx
+	taskKey := "task:" + taskId + ":targets"
+	infoKey := "task:" + taskId + ":info"
+
+	// 1) 先检查是否有 targets（保持原有行为）
+	targets, err := redisdb.Client.LRange(ctx, taskKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis error: %w", err)
+	}
+	if len(targets) == 0 {
+		return nil, ErrTaskNotFound
+	}
+
+	// 2) 尝试抢一个短期启动锁，避免高并发下多个请求同时进入 DB 更新路径。
+	lockKey := "task:lock:" + taskId
+	// TTL 设为 30s（启动过程里很快释放；若启动器异常，锁会自动过期）
+	const lockTTL = 30 * time.Second
+	acquired, err := redisdb.Client.SetNX(ctx, lockKey, "1", lockTTL).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis lock error: %w", err)
+	}
+	if !acquired {
+		// 已有别的请求在竞争或刚刚启动，拒绝重复启动
+		return nil, ErrStartInProgress
+	}
+	// 确保在函数返回前释放锁
+	defer func() {
+		_ = redisdb.Client.Del(ctx, lockKey).Err()
+	}()
+
+	// 3) 使用 MySQL 原子更新：只有当当前状态不是 running 才改为 running
+	res := mysqldb.DB.Model(&models.Task{}).
+		Where("id = ? AND status != ?", taskId, "running").
+		Update("status", "running")
+	if res.Error != nil {
+		return nil, fmt.Errorf("db update failed: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		// 没有更新任何行，说明已经是 running 或不存在
+		var t models.Task
+		if err := mysqldb.DB.First(&t, "id = ?", taskId).Error; err != nil {
+			return nil, ErrTaskNotFound
+		} else if t.Status == "running" {
+			return nil, ErrTaskRunning
+		}
+		return nil, fmt.Errorf("%w (status=%s)", ErrTaskNotStartable, t.Status)
+	}
+
+	// 到此：我们成功把数据库状态改为 running（唯一一次改写）
+	// 读取任务创建时保存的 profile；解析失败则回滚状态，避免任务卡在 running
+	var t models.Task
+	if err := mysqldb.DB.First(&t, "id = ?", taskId).Error; err != nil {
+		return nil, fmt.Errorf("db query failed: %w", err)
+	}
+	if _, err := scanner.ParseProfile(t.Config); err != nil {
+		_ = mysqldb.DB.Model(&models.Task{}).Where("id = ?", taskId).Update("status", "error").Error
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTaskConfig, err)
+	}
+	// 排除列表是安全边界，无法解析时拒绝启动
+	exclusions, err := scope.DecodeList(t.Exclusions)
+	if err != nil {
+		_ = mysqldb.DB.Model(&models.Task{}).Where("id = ?", taskId).Update("status", "error").Error
+		return nil, fmt.Errorf("%w: exclusions: %v", ErrInvalidTaskConfig, err)
+	}
+	if _, err := scope.NewMatcher(exclusions); err != nil {
+		_ = mysqldb.DB.Model(&models.Task{}).Where("id = ?", taskId).Update("status", "error").Error
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTaskConfig, err)
+	}
+	// API 扫描任务的请求地址不能命中排除列表
+	if err := checkSpecExclusions(taskId, exclusions); err != nil {
+		_ = mysqldb.DB.Model(&models.Task{}).Where("id = ?", taskId).Update("status", "error").Error
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTaskConfig, err)
+	}
+
+	// 4) 新建本次执行记录，保存目标与 profile 快照，便于事后审计
+	targetsJSON, _ := json.Marshal(targets)
+	run := &models.TaskRun{
+		ID:          generateTaskID(),
+		TaskID:      taskId,
+		TriggeredBy: triggeredBy,
+		Status:      "running",
+		Targets:     string(targetsJSON),
+		Config:      t.Config,
+	}
+	if err := mysqldb.DB.Create(run).Error; err != nil {
+		_ = mysqldb.DB.Model(&models.Task{}).Where("id = ?", taskId).Update("status", "error").Error
+		return nil, fmt.Errorf("db create run failed: %w", err)
+	}
+
+	// 5) 更新 Redis 中的任务 info，保持前后兼容
+	_, _ = redisdb.Client.HSet(ctx, infoKey,
+		"status", "running",
+		"stage", scanner.StageQueued,
+		"worker", "",
+		"run_id", run.ID,
+		"updated_at", time.Now().Format("2006-01-02 15:04:05"),
+	).Result()
+
+	// 6) 投递扫描作业，由任意 worker 进程领取执行
+	if err := worker.Enqueue(worker.Job{
+		TaskID:     taskId,
+		RunID:      run.ID,
+		Targets:    targets,
+		Exclusions: exclusions,
+		InfoKey:    infoKey,
+		Config:     t.Config,
+	}); err != nil {
+		_ = mysqldb.DB.Model(&models.Task{}).Where("id = ?", taskId).Update("status", "error").Error
+		_ = mysqldb.DB.Model(run).Updates(map[string]interface{}{"status": "error", "message": "enqueue failed"}).Error
+		_, _ = redisdb.Client.HSet(ctx, infoKey, "status", "error", "error_msg", "enqueue failed").Result()
+		return nil, fmt.Errorf("enqueue scan job failed: %w", err)
+	}
+	audit.Lifecycle(taskId, run.ID, "queued", "triggeredBy="+triggeredBy)
+	return run, nil
+}
+
+// Start 启动任务扫描接口
 func Start() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ctx := redisdb.Ctx
 		taskId, _ := c.GetQuery("taskId")
 		if taskId == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "missing taskId"})
 			return
 		}
 
-		taskKey := "task:" + taskId + ":targets"
-		infoKey := "task:" + taskId + ":info"
-
-		// 1) 先检查是否有 targets（保持原有行为）
-		targets, err := redisdb.Client.LRange(ctx, taskKey, 0, -1).Result()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "redis error: " + err.Error()})
-			return
-		}
-		if len(targets) == 0 {
-			c.JSON(http.StatusNotFound, gin.H{"error": "task not found or empty"})
-			return
-		}
-
-		// 2) 尝试抢一个短期启动锁，避免高并发下多个请求同时进入 DB 更新路径。
-		lockKey := "task:lock:" + taskId
-		// TTL 设为 30s（启动过程里很快释放；若启动器异常，锁会自动过期）
-		const lockTTL = 30 * time.Second
-		acquired, err := redisdb.Client.SetNX(ctx, lockKey, "1", lockTTL).Result()
-		if err != nil {
-			// Redis 出问题时，不阻断——但告知错误（可根据你想要的策略改为 500）
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "redis lock error: " + err.Error()})
-			return
-		}
-		if !acquired {
-			// 已有别的请求在竞争或刚刚启动，拒绝重复启动
-			c.JSON(http.StatusBadRequest, gin.H{"error": "task start already in progress"})
+		run, err := StartTask(taskId, "manual")
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrTaskNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
-		}
-		// 确保在函数返回前释放锁（如果我们还持有它）
-		releaseLock := func() {
-			_ = redisdb.Client.Del(ctx, lockKey).Err()
-		}
-		defer releaseLock()
-
-		// 3) 使用 MySQL 原子更新：只有当当前状态不是 running 才改为 running
-		res := mysqldb.DB.Model(&models.Task{}).
-			Where("id = ? AND status != ?", taskId, "running").
-			Update("status", "running")
-		if res.Error != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db update failed: " + res.Error.Error()})
+		case errors.Is(err, ErrStartInProgress), errors.Is(err, ErrTaskRunning),
+			errors.Is(err, ErrTaskNotStartable), errors.Is(err, ErrInvalidTaskConfig):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
-		}
-		if res.RowsAffected == 0 {
-			// 没有更新任何行，说明已经是 running 或不存在
-			// 为了精确判断原因，可以再查一次状态（可选）
-			var t models.Task
-			if err := mysqldb.DB.First(&t, "id = ?", taskId).Error; err != nil {
-				c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
-			} else if t.Status == "running" {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "task already running"})
-			} else {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "task cannot be started (status=" + t.Status + ")"})
-			}
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		// 到此：我们成功把数据库状态改为 running（唯一一次改写）
-		// 释放锁（不需要持有锁到扫描完成）。若你希望锁覆盖整个扫描过程可改为更长 TTL 并在扫描结束时删除。
-		releaseLock()
-
-		// 4) 更新 Redis 中的任务 info，保持前后兼容
-		_, _ = redisdb.Client.HSet(ctx, infoKey, "status", "running", "updated_at", time.Now().Format("2006-01-02 15:04:05")).Result()
-
-		// 5) 启动扫描（异步），并返回
-		go scanner.Run(taskId, targets, infoKey)
-
 		c.JSON(http.StatusOK, gin.H{
-			"message": "任务已开始扫描",
+			"message": "任务已加入扫描队列",
 			"taskId":  taskId,
+			"runId":   run.ID,
 		})
 	}
 }
@@ -293,7 +459,7 @@ func Stop() gin.HandlerFunc {
 		// 3) 原子把 MySQL 状态从 running -> stopped（避免并发冲突）
 		res := mysqldb.DB.Model(&models.Task{}).
 			Where("id = ? AND status = ?", taskId, "running").
-			Updates(map[string]interface{}{"status": "stopped", "updated_at": time.Now()})
+			Updates(map[string]interface{}{"status": "stopped", "updated_at": time.Now(), "finished_at": time.Now()})
 		if res.Error != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "db update failed: " + res.Error.Error()})
 			return
@@ -304,6 +470,21 @@ func Stop() gin.HandlerFunc {
 			return
 		}
 
+		// 同步结束该任务仍在运行的执行记录（作业可能还在队列中，worker 不会再回写）；
+		// worker 的 setStatus 只处理仍为 running 的记录，停止的审计与通知在这里发出
+		var runIds []string
+		_ = mysqldb.DB.Model(&models.TaskRun{}).Where("task_id = ? AND status = ?", taskId, "running").Pluck("id", &runIds).Error
+		for _, runId := range runIds {
+			res := mysqldb.DB.Model(&models.TaskRun{}).
+				Where("id = ? AND status = ?", runId, "running").
+				Updates(map[string]interface{}{"status": "stopped", "finished_at": time.Now()})
+			if res.Error != nil || res.RowsAffected != 1 {
+				continue
+			}
+			audit.Lifecycle(taskId, runId, "stopped", "")
+			notify.Publish(&notify.Event{Event: notify.EventStopped, TaskID: taskId, RunID: runId, Status: "stopped"})
+		}
+
 		// 4) 更新 Redis 中该任务的状态（仅针对该 taskId 的 key）
 		infoKey := "task:" + taskId + ":info"
 		_, _ = redisdb.Client.HSet(redisdb.Ctx, infoKey,
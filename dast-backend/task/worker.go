@@ -5,6 +5,7 @@ import (
 	"demo/db/mysqldb"
 	"demo/db/redisdb"
 	"demo/models"
+	"demo/taskrun"
 	"log"
 	"time"
 )
@@ -108,8 +109,103 @@ func deleteWorker() {
 			continue
 		}
 
-		// 事务删除 Task 与关联 Target
+		// 先记下所有执行记录，事务提交后清理它们的 Redis 结果与日志
+		var runIds []string
+		if err := mysqldb.DB.Model(&models.TaskRun{}).Where("task_id = ?", taskId).Pluck("id", &runIds).Error; err != nil {
+			log.Printf("[deleteWorker] failed to query runs for task %s: %v", taskId, err)
+			_ = redisdb.Client.RPush(ctx, failedKey, taskId).Err()
+			time.Sleep(backoffBase)
+			continue
+		}
+
+		var reportPaths []string
+		if err := mysqldb.DB.Model(&models.Report{}).Where("task_id = ?", taskId).Pluck("path", &reportPaths).Error; err != nil {
+			log.Printf("[deleteWorker] failed to query reports for task %s: %v", taskId, err)
+			_ = redisdb.Client.RPush(ctx, failedKey, taskId).Err()
+			time.Sleep(backoffBase)
+			continue
+		}
+
+		// 事务删除 Task 与关联 Target、Finding 及处置记录、执行记录、报告、定时调度、API 扫描输入、扫描认证、issue 同步配置，
+		// 以及只对该任务生效的屏蔽规则与通知渠道（含投递记录）
 		tx := mysqldb.DB.Begin()
+		if err := tx.Where("task_id = ?", taskId).Delete(&models.Finding{}).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[deleteWorker] failed to delete findings for task %s: %v", taskId, err)
+			_ = redisdb.Client.RPush(ctx, failedKey, taskId).Err()
+			time.Sleep(backoffBase)
+			continue
+		}
+		if err := tx.Where("task_id = ?", taskId).Delete(&models.TaskRun{}).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[deleteWorker] failed to delete runs for task %s: %v", taskId, err)
+			_ = redisdb.Client.RPush(ctx, failedKey, taskId).Err()
+			time.Sleep(backoffBase)
+			continue
+		}
+		if err := tx.Where("task_id = ?", taskId).Delete(&models.Report{}).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[deleteWorker] failed to delete reports for task %s: %v", taskId, err)
+			_ = redisdb.Client.RPush(ctx, failedKey, taskId).Err()
+			time.Sleep(backoffBase)
+			continue
+		}
+		if err := tx.Where("task_id = ?", taskId).Delete(&models.TaskSchedule{}).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[deleteWorker] failed to delete schedule for task %s: %v", taskId, err)
+			_ = redisdb.Client.RPush(ctx, failedKey, taskId).Err()
+			time.Sleep(backoffBase)
+			continue
+		}
+		if err := tx.Where("task_id = ?", taskId).Delete(&models.APISpec{}).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[deleteWorker] failed to delete api spec for task %s: %v", taskId, err)
+			_ = redisdb.Client.RPush(ctx, failedKey, taskId).Err()
+			time.Sleep(backoffBase)
+			continue
+		}
+		if err := tx.Where("task_id = ?", taskId).Delete(&models.ScanAuth{}).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[deleteWorker] failed to delete scan auth for task %s: %v", taskId, err)
+			_ = redisdb.Client.RPush(ctx, failedKey, taskId).Err()
+			time.Sleep(backoffBase)
+			continue
+		}
+		if err := tx.Where("task_id = ?", taskId).Delete(&models.IssueTracker{}).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[deleteWorker] failed to delete issue trackers for task %s: %v", taskId, err)
+			_ = redisdb.Client.RPush(ctx, failedKey, taskId).Err()
+			time.Sleep(backoffBase)
+			continue
+		}
+		if err := tx.Where("task_id = ?", taskId).Delete(&models.FindingActivity{}).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[deleteWorker] failed to delete finding activities for task %s: %v", taskId, err)
+			_ = redisdb.Client.RPush(ctx, failedKey, taskId).Err()
+			time.Sleep(backoffBase)
+			continue
+		}
+		if err := tx.Where("task_id = ?", taskId).Delete(&models.Suppression{}).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[deleteWorker] failed to delete suppressions for task %s: %v", taskId, err)
+			_ = redisdb.Client.RPush(ctx, failedKey, taskId).Err()
+			time.Sleep(backoffBase)
+			continue
+		}
+		if err := tx.Where("channel_id IN (?)", tx.Model(&models.NotifyChannel{}).Select("id").Where("task_id = ?", taskId)).Delete(&models.NotifyDelivery{}).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[deleteWorker] failed to delete notify deliveries for task %s: %v", taskId, err)
+			_ = redisdb.Client.RPush(ctx, failedKey, taskId).Err()
+			time.Sleep(backoffBase)
+			continue
+		}
+		if err := tx.Where("task_id = ?", taskId).Delete(&models.NotifyChannel{}).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[deleteWorker] failed to delete notify channels for task %s: %v", taskId, err)
+			_ = redisdb.Client.RPush(ctx, failedKey, taskId).Err()
+			time.Sleep(backoffBase)
+			continue
+		}
 		if err := tx.Where("task_id = ?", taskId).Delete(&models.Target{}).Error; err != nil {
 			tx.Rollback()
 			log.Printf("[deleteWorker] failed to delete targets for task %s: %v", taskId, err)
@@ -124,7 +220,13 @@ func deleteWorker() {
 			time.Sleep(backoffBase)
 			continue
 		}
-		tx.Commit()
+		if err := tx.Commit().Error; err != nil {
+			log.Printf("[deleteWorker] failed to commit delete for task %s: %v", taskId, err)
+			_ = redisdb.Client.RPush(ctx, failedKey, taskId).Err()
+			time.Sleep(backoffBase)
+			continue
+		}
+		taskrun.RemoveReportFiles(reportPaths)
 
 		// 删除 Redis 相关 key
 		keys := []string{
@@ -133,6 +235,9 @@ func deleteWorker() {
 			"task:" + taskId + ":result",
 			"task:" + taskId + ":log",
 		}
+		for _, runId := range runIds {
+			keys = append(keys, taskrun.ResultKey(taskId, runId), taskrun.LogKey(taskId, runId), taskrun.CheckpointKey(taskId, runId), taskrun.EventsKey(taskId, runId))
+		}
 		if err := redisdb.Client.Del(ctx, keys...).Err(); err != nil {
 			log.Printf("[deleteWorker] failed to delete Redis keys for task %s: %v", taskId, err)
 			_ = redisdb.Client.RPush(ctx, failedKey, taskId).Err()
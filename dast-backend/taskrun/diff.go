@@ -0,0 +1,203 @@
+package taskrun
+
+import (
+	"demo/db/mysqldb"
+	"demo/finding"
+	"demo/models"
+	"demo/notify"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DiffResult 两次执行之间的 findings 差异
+// 以 template-id + matched-at + matcher-name 作为同一漏洞的标识
+type DiffResult struct {
+	TaskID     string           `json:"taskId"`
+	BaseRunID  string           `json:"baseRunId"`
+	HeadRunID  string           `json:"headRunId"`
+	New        []models.Finding `json:"new"`        // head 中新出现
+	Resolved   []models.Finding `json:"resolved"`   // base 中有、head 中已消失
+	Persistent []models.Finding `json:"persistent"` // 两次都存在（取 head 中的记录）
+}
+
+// Summary 各分类数量；通知 payload 只带数量与 new/resolved 列表，不带 persistent
+func (r *DiffResult) Summary() gin.H {
+	return gin.H{
+		"new":        len(r.New),
+		"resolved":   len(r.Resolved),
+		"persistent": len(r.Persistent),
+	}
+}
+
+// FindingKey 同一漏洞在不同执行间的标识
+func FindingKey(f models.Finding) string {
+	return f.TemplateID + "|" + f.MatchedAt + "|" + f.MatcherName
+}
+
+// loadFindings 读取一次执行的 findings（不含 details 大字段），同一 key 只保留第一条
+func loadFindings(runId string) ([]models.Finding, error) {
+	var findings []models.Finding
+	if err := mysqldb.DB.Omit("details").Where("run_id = ?", runId).Order("id asc").Find(&findings).Error; err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	uniq := findings[:0]
+	for _, f := range findings {
+		k := FindingKey(f)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		uniq = append(uniq, f)
+	}
+	return uniq, nil
+}
+
+// DiffRuns 计算 base -> head 的 findings 差异，两次执行必须属于同一任务
+func DiffRuns(base, head *models.TaskRun) (*DiffResult, error) {
+	if base.TaskID != head.TaskID {
+		return nil, errors.New("runs belong to different tasks")
+	}
+	baseFindings, err := loadFindings(base.ID)
+	if err != nil {
+		return nil, err
+	}
+	headFindings, err := loadFindings(head.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &DiffResult{
+		TaskID:     head.TaskID,
+		BaseRunID:  base.ID,
+		HeadRunID:  head.ID,
+		New:        []models.Finding{},
+		Resolved:   []models.Finding{},
+		Persistent: []models.Finding{},
+	}
+	inBase := make(map[string]bool, len(baseFindings))
+	for _, f := range baseFindings {
+		inBase[FindingKey(f)] = true
+	}
+	inHead := make(map[string]bool, len(headFindings))
+	for _, f := range headFindings {
+		k := FindingKey(f)
+		inHead[k] = true
+		if inBase[k] {
+			r.Persistent = append(r.Persistent, f)
+		} else {
+			r.New = append(r.New, f)
+		}
+	}
+	for _, f := range baseFindings {
+		if !inHead[FindingKey(f)] {
+			r.Resolved = append(r.Resolved, f)
+		}
+	}
+	return r, nil
+}
+
+// Previous 返回 run 之前最近一次正常完成的执行
+func Previous(run *models.TaskRun) (*models.TaskRun, error) {
+	var prev models.TaskRun
+	err := mysqldb.DB.Omit("targets", "config").
+		Where("task_id = ? AND id != ? AND status = ? AND created_at <= ?", run.TaskID, run.ID, "finished", run.CreatedAt).
+		Order("created_at desc").
+		First(&prev).Error
+	if err != nil {
+		return nil, err
+	}
+	return &prev, nil
+}
+
+// OnFinished 执行正常结束后调用：与上一次完成的执行做 diff，把摘要随 task.finished 事件写入通知队列
+func OnFinished(runId string) {
+	var run models.TaskRun
+	if err := mysqldb.DB.Omit("targets", "config").First(&run, "id = ?", runId).Error; err != nil {
+		log.Printf("[taskrun.OnFinished] run not found run=%s err=%v", runId, err)
+		return
+	}
+
+	e := &notify.Event{
+		Event:       notify.EventFinished,
+		TaskID:      run.TaskID,
+		RunID:       run.ID,
+		TriggeredBy: run.TriggeredBy,
+		Status:      run.Status,
+	}
+	if counts, err := SeverityCounts([]string{run.ID}); err == nil {
+		e.Counts = notify.SortCounts(counts[run.ID])
+	}
+	// 首次执行没有可对比的基线，只通知完成
+	if base, err := Previous(&run); err == nil {
+		d, err := DiffRuns(base, &run)
+		if err != nil {
+			log.Printf("[taskrun.OnFinished] diff failed run=%s err=%v", runId, err)
+		} else {
+			e.BaseRunID = base.ID
+			e.Summary = map[string]int{"new": len(d.New), "resolved": len(d.Resolved), "persistent": len(d.Persistent)}
+			e.New = d.New
+			e.Resolved = d.Resolved
+			finding.MarkFixed(run.TaskID, d.Resolved)
+		}
+	}
+	notify.Publish(e)
+}
+
+// Diff - 对比两次执行的 findings：新增 / 已修复 / 仍存在
+// GET /api/run/diff?head=&base=   base 为空时与 head 之前最近一次完成的执行对比
+func Diff() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		headId := c.Query("head")
+		if headId == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing head"})
+			return
+		}
+		var head models.TaskRun
+		if err := mysqldb.DB.Omit("targets", "config").First(&head, "id = ?", headId).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "run not found"})
+			return
+		}
+
+		var base *models.TaskRun
+		if baseId := c.Query("base"); baseId != "" {
+			var b models.TaskRun
+			if err := mysqldb.DB.Omit("targets", "config").First(&b, "id = ?", baseId).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "run not found"})
+				return
+			}
+			base = &b
+		} else {
+			b, err := Previous(&head)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "no previous finished run to compare"})
+				return
+			}
+			base = b
+		}
+
+		d, err := DiffRuns(base, &head)
+		if err != nil {
+			if base.TaskID != head.TaskID {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("[taskrun.Diff] failed base=%s head=%s err=%v", base.ID, head.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"taskId":     d.TaskID,
+			"baseRunId":  d.BaseRunID,
+			"headRunId":  d.HeadRunID,
+			"summary":    d.Summary(),
+			"new":        d.New,
+			"resolved":   d.Resolved,
+			"persistent": d.Persistent,
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package taskrun
+
+import (
+	"demo/db/mysqldb"
+	"demo/db/redisdb"
+	"demo/finding"
+	"demo/models"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 每次执行独立的 Redis key，避免多次执行的结果/日志混在 task:{id}:result 中
+func ResultKey(taskId, runId string) string {
+	return "task:" + taskId + ":run:" + runId + ":result"
+}
+
+// LogKey 执行日志（Redis list，每个元素是一条 JSON 格式的 LogEntry）
+func LogKey(taskId, runId string) string {
+	return "task:" + taskId + ":run:" + runId + ":log"
+}
+
+// 执行日志级别
+const (
+	LogDebug = "debug"
+	LogInfo  = "info"
+	LogWarn  = "warn"
+	LogError = "error"
+)
+
+// LogLevels 按严重程度递增排列
+var LogLevels = []string{LogDebug, LogInfo, LogWarn, LogError}
+
+// LogLevelRank 返回级别的严重程度，未知级别返回 -1
+func LogLevelRank(level string) int {
+	for i, l := range LogLevels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// LogEntry 一条执行日志：阶段取扫描阶段（portscan / httpprobe / fingerprint / nuclei ...）
+type LogEntry struct {
+	Time    string                 `json:"time"`
+	Level   string                 `json:"level"`
+	Stage   string                 `json:"stage,omitempty"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// EventsKey 扫描进度事件（Redis stream），供 SSE 推送
+func EventsKey(taskId, runId string) string {
+	return "task:" + taskId + ":run:" + runId + ":events"
+}
+
+// CheckpointKey 扫描断点（各阶段输出与 nuclei resume 配置），用于崩溃恢复
+func CheckpointKey(taskId, runId string) string {
+	return "task:" + taskId + ":run:" + runId + ":checkpoint"
+}
+
+// Latest 返回任务最近一次执行的 runId；没有执行记录时返回空串
+func Latest(taskId string) string {
+	if runId, _ := redisdb.Client.HGet(redisdb.Ctx, "task:"+taskId+":info", "run_id").Result(); runId != "" {
+		return runId
+	}
+	var run models.TaskRun
+	if err := mysqldb.DB.Where("task_id = ?", taskId).Order("created_at desc").First(&run).Error; err != nil {
+		return ""
+	}
+	return run.ID
+}
+
+// SeverityCounts 按 run_id + severity 聚合 findings 数量（不含误报与接受风险）
+func SeverityCounts(runIds []string) (map[string]map[string]int64, error) {
+	counts := map[string]map[string]int64{}
+	if len(runIds) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		RunID    string
+		Severity string
+		Count    int64
+	}
+	if err := mysqldb.DB.Model(&models.Finding{}).
+		Select("run_id, severity, COUNT(*) AS count").
+		Where("run_id IN ? AND state NOT IN ?", runIds, finding.IgnoredStates).
+		Group("run_id, severity").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		if counts[r.RunID] == nil {
+			counts[r.RunID] = map[string]int64{}
+		}
+		counts[r.RunID][r.Severity] = r.Count
+	}
+	return counts, nil
+}
+
+// summary 把执行记录与其 findings 统计拼成返回给前端的结构
+func summary(run models.TaskRun, counts map[string]int64) gin.H {
+	if counts == nil {
+		counts = map[string]int64{}
+	}
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	resp := gin.H{
+		"run":        run,
+		"findings":   total,
+		"severities": counts,
+	}
+	if run.StartedAt != nil && run.FinishedAt != nil {
+		resp["durationSeconds"] = int64(run.FinishedAt.Sub(*run.StartedAt).Seconds())
+	}
+	return resp
+}
+
+// List - 列出任务的执行历史，可按执行创建时间过滤（审计：某天的扫描发现了什么）
+// GET /api/run/list?taskId=&status=&start=&end=&page=&pageSize=
+func List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		taskId := c.Query("taskId")
+		if taskId == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing taskId"})
+			return
+		}
+		db := mysqldb.DB.Model(&models.TaskRun{}).Where("task_id = ?", taskId)
+		if status := c.Query("status"); status != "" {
+			db = db.Where("status = ?", status)
+		}
+		if s := c.Query("start"); s != "" {
+			t, err := finding.ParseTime(s)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start: " + err.Error()})
+				return
+			}
+			db = db.Where("created_at >= ?", *t)
+		}
+		if s := c.Query("end"); s != "" {
+			t, err := finding.ParseTime(s)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end: " + err.Error()})
+				return
+			}
+			db = db.Where("created_at <= ?", *t)
+		}
+		page, pageSize := finding.ParsePage(c)
+
+		var total int64
+		if err := db.Count(&total).Error; err != nil {
+			log.Printf("[taskrun.List] db count failed task=%s err=%v", taskId, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		runs := []models.TaskRun{}
+		if err := db.Omit("targets", "config").
+			Order("created_at desc").
+			Offset((page - 1) * pageSize).
+			Limit(pageSize).
+			Find(&runs).Error; err != nil {
+			log.Printf("[taskrun.List] db query failed task=%s err=%v", taskId, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		ids := make([]string, 0, len(runs))
+		for _, r := range runs {
+			ids = append(ids, r.ID)
+		}
+		counts, err := SeverityCounts(ids)
+		if err != nil {
+			log.Printf("[taskrun.List] db count findings failed task=%s err=%v", taskId, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		resp := make([]gin.H, 0, len(runs))
+		for _, r := range runs {
+			resp = append(resp, summary(r, counts[r.ID]))
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"taskId":   taskId,
+			"total":    total,
+			"page":     page,
+			"pageSize": pageSize,
+			"runs":     resp,
+		})
+	}
+}
+
+// Get - 查看单次执行详情（含目标与 profile 快照）
+// GET /api/run/get?runId=
+func Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		runId := c.Query("runId")
+		if runId == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing runId"})
+			return
+		}
+		var run models.TaskRun
+		if err := mysqldb.DB.First(&run, "id = ?", runId).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "run not found"})
+			return
+		}
+		counts, err := SeverityCounts([]string{runId})
+		if err != nil {
+			log.Printf("[taskrun.Get] db count findings failed run=%s err=%v", runId, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, summary(run, counts[runId]))
+	}
+}
+
+// Compare - 对比同一任务的两次执行（状态、耗时、各等级 findings 数量）
+// GET /api/run/compare?base=&head=
+func Compare() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		baseId, headId := c.Query("base"), c.Query("head")
+		if baseId == "" || headId == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing base or head"})
+			return
+		}
+		var runs []models.TaskRun
+		if err := mysqldb.DB.Omit("targets", "config").Where("id IN ?", []string{baseId, headId}).Find(&runs).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		var base, head *models.TaskRun
+		for i := range runs {
+			if runs[i].ID == baseId {
+				base = &runs[i]
+			}
+			if runs[i].ID == headId {
+				head = &runs[i]
+			}
+		}
+		if base == nil || head == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "run not found"})
+			return
+		}
+		if base.TaskID != head.TaskID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "runs belong to different tasks"})
+			return
+		}
+
+		counts, err := SeverityCounts([]string{baseId, headId})
+		if err != nil {
+			log.Printf("[taskrun.Compare] db count findings failed base=%s head=%s err=%v", baseId, headId, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		// 各等级数量变化：head - base
+		delta := map[string]int64{}
+		for sev, n := range counts[headId] {
+			delta[sev] += n
+		}
+		for sev, n := range counts[baseId] {
+			delta[sev] -= n
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"taskId": base.TaskID,
+			"base":   summary(*base, counts[baseId]),
+			"head":   summary(*head, counts[headId]),
+			"delta":  delta,
+		})
+	}
+}
+
+// RemoveReportFiles 删除报告文件及其所在的执行目录（目录为空时）；记录已删除，失败只记日志
+func RemoveReportFiles(paths []string) {
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			log.Printf("[taskrun.RemoveReportFiles] remove report file failed path=%s err=%v", p, err)
+			continue
+		}
+		_ = os.Remove(filepath.Dir(p))
+	}
+}
+
+// Delete - 删除单次执行及其 findings、报告、Redis 结果与日志（正在运行的执行不可删除）
+// POST /api/run/delete {"runId": ""}
+func Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			RunID string `json:"runId"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.RunID) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing runId"})
+			return
+		}
+
+		var run models.TaskRun
+		if err := mysqldb.DB.First(&run, "id = ?", req.RunID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "run not found"})
+			return
+		}
+		if run.Status == "running" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "run is still running, stop the task first"})
+			return
+		}
+
+		// 先记下报告文件，事务提交后再删除
+		var reportPaths []string
+		if err := mysqldb.DB.Model(&models.Report{}).Where("run_id = ?", run.ID).Pluck("path", &reportPaths).Error; err != nil {
+			log.Printf("[taskrun.Delete] db query reports failed run=%s err=%v", run.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db query reports failed: " + err.Error()})
+			return
+		}
+
+		// 事务删除 findings、报告与执行记录；仅删除非 running 的记录，避免与并发的启动冲突
+		tx := mysqldb.DB.Begin()
+		if err := tx.Where("run_id = ?", run.ID).Delete(&models.Finding{}).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[taskrun.Delete] db delete findings failed run=%s err=%v", run.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db delete findings failed: " + err.Error()})
+			return
+		}
+		if err := tx.Where("run_id = ?", run.ID).Delete(&models.Report{}).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[taskrun.Delete] db delete reports failed run=%s err=%v", run.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db delete reports failed: " + err.Error()})
+			return
+		}
+		res := tx.Where("id = ? AND status != ?", run.ID, "running").Delete(&models.TaskRun{})
+		if res.Error != nil {
+			tx.Rollback()
+			log.Printf("[taskrun.Delete] db delete run failed run=%s err=%v", run.ID, res.Error)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db delete run failed: " + res.Error.Error()})
+			return
+		}
+		if res.RowsAffected == 0 {
+			tx.Rollback()
+			c.JSON(http.StatusConflict, gin.H{"error": "run state changed concurrently, please retry"})
+			return
+		}
+		if err := tx.Commit().Error; err != nil {
+			log.Printf("[taskrun.Delete] tx commit failed run=%s err=%v", run.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db commit failed: " + err.Error()})
+			return
+		}
+		RemoveReportFiles(reportPaths)
+
+		if err := redisdb.Client.Del(redisdb.Ctx, ResultKey(run.TaskID, run.ID), LogKey(run.TaskID, run.ID), CheckpointKey(run.TaskID, run.ID), EventsKey(run.TaskID, run.ID)).Err(); err != nil {
+			log.Printf("[taskrun.Delete] redis del failed run=%s err=%v", run.ID, err)
+		}
+		// 删除的是最近一次执行时，清掉 info 中的 run_id，让结果/日志接口回退到上一次执行
+		if cur, _ := redisdb.Client.HGet(redisdb.Ctx, "task:"+run.TaskID+":info", "run_id").Result(); cur == run.ID {
+			_ = redisdb.Client.HDel(redisdb.Ctx, "task:"+run.TaskID+":info", "run_id").Err()
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "删除成功", "runId": run.ID, "taskId": run.TaskID})
+	}
+}
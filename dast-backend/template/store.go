@@ -0,0 +1,481 @@
+/**
+ * 模板管理：维护 ./poc 下 nuclei 模板的索引与版本记录，上传的模板经 nuclei 解析校验后写入磁盘
+ */
+package template
+
+import (
+	"context"
+	"crypto/sha256"
+	"demo/db/mysqldb"
+	"demo/models"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+	"github.com/projectdiscovery/nuclei/v3/pkg/catalog/disk"
+	"github.com/projectdiscovery/nuclei/v3/pkg/templates/signer"
+	"gopkg.in/yaml.v3"
+)
+
+// Dir 模板库目录，NucleiScan 从这里加载模板
+const Dir = "./poc"
+
+// 上传的新模板保存在 Dir 下的子目录
+const customDir = "custom"
+
+// 模板来源
+const (
+	SourceBuiltin = "builtin"
+	SourceCustom  = "custom"
+)
+
+// 版本记录的动作
+const (
+	ActionSync    = "sync"    // 启动或手动同步时发现磁盘上的模板新增/变化
+	ActionUpload  = "upload"  // 通过 API 上传
+	ActionEnable  = "enable"  // 启用
+	ActionDisable = "disable" // 禁用
+	ActionDelete  = "delete"  // 通过 API 删除
+	ActionRemove  = "remove"  // 同步时发现磁盘上的文件已不存在
+)
+
+// nuclei 模板 id 的格式，同时用作上传文件名，避免路径穿越
+var idRe = regexp.MustCompile(`^([a-zA-Z0-9]+[-_])*[a-zA-Z0-9]+$`)
+
+var (
+	// 同步与上传/删除互斥，避免同时改写索引
+	syncMu sync.Mutex
+
+	// 用于解析校验与签名的 nuclei 引擎，首次使用时创建
+	engineOnce sync.Once
+	engine     *nuclei.NucleiEngine
+	engineErr  error
+	parseMu    sync.Mutex
+
+	// 模板签名：设置 DAST_TEMPLATE_SIGN_CERT / DAST_TEMPLATE_SIGN_KEY 后可在上传时签名
+	tmplSigner *signer.TemplateSigner
+)
+
+// LoadSigner 读取签名证书与私钥，并把证书加入 nuclei 的验证列表，使签名后的模板在扫描时被视为已验证
+// API 与 worker 进程都需要调用
+func LoadSigner() {
+	cert, key := os.Getenv("DAST_TEMPLATE_SIGN_CERT"), os.Getenv("DAST_TEMPLATE_SIGN_KEY")
+	if cert == "" || key == "" {
+		return
+	}
+	s, err := signer.NewTemplateSignerFromFiles(cert, key)
+	if err != nil {
+		log.Printf("[template.LoadSigner] load signer failed cert=%s err=%v", cert, err)
+		return
+	}
+	if err := signer.AddSignerToDefault(s); err != nil {
+		log.Printf("[template.LoadSigner] add verifier failed err=%v", err)
+		return
+	}
+	tmplSigner = s
+	log.Printf("[template.LoadSigner] template signer loaded cert=%s", cert)
+}
+
+// Init 后台把模板索引与磁盘同步
+func Init() {
+	go func() {
+		if _, err := Sync(""); err != nil {
+			log.Printf("[template.Init] sync templates failed err=%v", err)
+		}
+	}()
+}
+
+// meta 从模板 YAML 中提取的索引字段
+type meta struct {
+	ID       string
+	Name     string
+	Author   string
+	Severity string
+	Tags     string
+	CVE      string
+	Protocol string
+}
+
+// 顶层字段对应的协议，按出现顺序取第一个
+var protocolKeys = []string{"http", "requests", "dns", "file", "network", "tcp", "headless", "ssl", "websocket", "whois", "code", "javascript", "flow", "workflows"}
+
+// parseMeta 只解析 id 与 info，比完整编译模板快得多；不是模板（没有 id 或 info.name）时返回错误
+func parseMeta(data []byte) (*meta, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	id, _ := doc["id"].(string)
+	info, _ := doc["info"].(map[string]interface{})
+	if id == "" || info == nil {
+		return nil, errors.New("not a template")
+	}
+	name, _ := info["name"].(string)
+	if name == "" {
+		return nil, errors.New("not a template")
+	}
+	severity, _ := info["severity"].(string)
+	m := &meta{
+		ID:       id,
+		Name:     truncate(name, 512),
+		Author:   truncate(joinList(info["author"]), 512),
+		Severity: strings.ToLower(severity),
+		Tags:     truncate(joinList(info["tags"]), 1024),
+	}
+	if cls, ok := info["classification"].(map[string]interface{}); ok {
+		m.CVE = truncate(strings.ToUpper(joinList(cls["cve-id"])), 512)
+	}
+	for _, k := range protocolKeys {
+		if _, ok := doc[k]; ok {
+			m.Protocol = k
+			break
+		}
+	}
+	switch m.Protocol {
+	case "requests":
+		m.Protocol = "http"
+	case "tcp":
+		m.Protocol = "network"
+	case "workflows":
+		m.Protocol = "workflow"
+	}
+	return m, nil
+}
+
+// joinList 把 "a,b" 或 [a, b] 统一成不带空格的逗号分隔字符串
+func joinList(v interface{}) string {
+	var items []string
+	switch x := v.(type) {
+	case string:
+		items = strings.Split(x, ",")
+	case []interface{}:
+		for _, it := range x {
+			items = append(items, fmt.Sprint(it))
+		}
+	}
+	out := items[:0]
+	for _, it := range items {
+		if it = strings.TrimSpace(it); it != "" {
+			out = append(out, it)
+		}
+	}
+	return strings.Join(out, ",")
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// SyncResult 同步结果
+type SyncResult struct {
+	Added   int `json:"added"`
+	Updated int `json:"updated"`
+	Removed int `json:"removed"`
+	Skipped int `json:"skipped"` // id 重复的文件
+}
+
+// Sync 扫描 Dir，把新增、内容变化与已删除的模板写入索引，每个变化记一条版本记录
+func Sync(creator string) (*SyncResult, error) {
+	syncMu.Lock()
+	defer syncMu.Unlock()
+
+	var existing []models.Template
+	if err := mysqldb.DB.Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.Template, len(existing))
+	for i := range existing {
+		byID[existing[i].TemplateID] = &existing[i]
+	}
+
+	res := &SyncResult{}
+	seen := map[string]bool{}
+	var created []models.Template
+	var versions []models.TemplateVersion
+	err := filepath.WalkDir(Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != Dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		m, err := parseMeta(data)
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(Dir, path)
+		rel = filepath.ToSlash(rel)
+		if seen[m.ID] {
+			res.Skipped++
+			return nil
+		}
+		seen[m.ID] = true
+
+		sum := checksum(data)
+		cur := byID[m.ID]
+		if cur == nil {
+			t := newTemplate(m, rel, sum)
+			created = append(created, t)
+			versions = append(versions, models.TemplateVersion{
+				TemplateID: m.ID, Version: 1, Action: ActionSync, Path: rel, SHA256: sum, Creator: creator,
+			})
+			res.Added++
+			return nil
+		}
+		if cur.SHA256 == sum && cur.Path == rel {
+			return nil
+		}
+		version := cur.Version + 1
+		if err := mysqldb.DB.Model(cur).Updates(map[string]interface{}{
+			"path": rel, "name": m.Name, "author": m.Author, "severity": m.Severity, "tags": m.Tags,
+			"cve": m.CVE, "protocol": m.Protocol, "version": version, "sha256": sum,
+		}).Error; err != nil {
+			return err
+		}
+		versions = append(versions, models.TemplateVersion{
+			TemplateID: m.ID, Version: version, Action: ActionSync, Path: rel, SHA256: sum, Creator: creator,
+		})
+		res.Updated++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for id, t := range byID {
+		if seen[id] {
+			continue
+		}
+		if err := mysqldb.DB.Delete(t).Error; err != nil {
+			return nil, err
+		}
+		versions = append(versions, models.TemplateVersion{
+			TemplateID: id, Version: t.Version, Action: ActionRemove, Path: t.Path, SHA256: t.SHA256, Creator: creator,
+		})
+		res.Removed++
+	}
+
+	if len(created) > 0 {
+		if err := mysqldb.DB.CreateInBatches(&created, 500).Error; err != nil {
+			return nil, err
+		}
+	}
+	if len(versions) > 0 {
+		if err := mysqldb.DB.CreateInBatches(&versions, 500).Error; err != nil {
+			return nil, err
+		}
+	}
+	log.Printf("[template.Sync] templates synced added=%d updated=%d removed=%d skipped=%d",
+		res.Added, res.Updated, res.Removed, res.Skipped)
+	return res, nil
+}
+
+func newTemplate(m *meta, path, sum string) models.Template {
+	source := SourceBuiltin
+	if strings.HasPrefix(path, customDir+"/") {
+		source = SourceCustom
+	}
+	return models.Template{
+		TemplateID: m.ID, Path: path, Name: m.Name, Author: m.Author, Severity: m.Severity,
+		Tags: m.Tags, CVE: m.CVE, Protocol: m.Protocol, Source: source, Enabled: true,
+		Version: 1, SHA256: sum,
+	}
+}
+
+// parseEngine 返回用于校验的 nuclei 引擎（不加载模板与目标）
+func parseEngine() (*nuclei.NucleiEngine, error) {
+	engineOnce.Do(func() {
+		engine, engineErr = nuclei.NewNucleiEngineCtx(context.Background(),
+			nuclei.WithCatalog(disk.NewCatalog(Dir)),
+			nuclei.DisableUpdateCheck(),
+		)
+	})
+	return engine, engineErr
+}
+
+// validate 用 nuclei 完整解析模板，需要时签名；返回最终写入磁盘的内容与签名验证状态
+func validate(data []byte, sign bool) ([]byte, bool, error) {
+	e, err := parseEngine()
+	if err != nil {
+		return nil, false, fmt.Errorf("create nuclei engine failed: %w", err)
+	}
+	parseMu.Lock()
+	defer parseMu.Unlock()
+
+	if sign {
+		if tmplSigner == nil {
+			return nil, false, errors.New("template signing is not configured")
+		}
+		if data, err = e.SignTemplate(tmplSigner, data); err != nil {
+			return nil, false, fmt.Errorf("sign template failed: %w", err)
+		}
+	}
+	tmpl, err := e.ParseTemplate(data)
+	if err != nil {
+		return nil, false, err
+	}
+	if tmpl == nil {
+		return nil, false, errors.New("empty template")
+	}
+	return data, tmpl.Verified, nil
+}
+
+// DisabledAt 返回修订号 rev 时已禁用模板的 id（各模板在 rev 之前最后一次启用/禁用记录为禁用），扫描时通过 ExcludeIDs 排除
+func DisabledAt(rev uint64) ([]string, error) {
+	var ids []string
+	latest := mysqldb.DB.Model(&models.TemplateVersion{}).Select("MAX(id)").
+		Where("id <= ? AND action IN ?", rev, []string{ActionEnable, ActionDisable}).Group("template_id")
+	err := mysqldb.DB.Model(&models.TemplateVersion{}).Where("id IN (?) AND action = ?", latest, ActionDisable).
+		Pluck("template_id", &ids).Error
+	sort.Strings(ids)
+	return ids, err
+}
+
+// Materialize 按修订号 rev 把上传的模板写入本机的 Dir：内容取自版本记录，
+// 已删除的模板从磁盘移除。独立部署的 worker 磁盘上没有 API 上传的文件，加载模板前需要调用
+func Materialize(rev uint64) error {
+	syncMu.Lock()
+	defer syncMu.Unlock()
+
+	// 各上传模板在 rev 之前的最后一条记录，决定模板是否仍然存在
+	var states []models.TemplateVersion
+	latest := mysqldb.DB.Model(&models.TemplateVersion{}).Select("MAX(id)").
+		Where("id <= ? AND path LIKE ?", rev, customDir+"/%").Group("template_id")
+	if err := mysqldb.DB.Omit("content").Where("id IN (?)", latest).Find(&states).Error; err != nil {
+		return err
+	}
+	uploads := mysqldb.DB.Model(&models.TemplateVersion{}).Select("MAX(id)").
+		Where("id <= ? AND path LIKE ? AND action = ?", rev, customDir+"/%", ActionUpload).Group("template_id")
+	var contents []models.TemplateVersion
+	if err := mysqldb.DB.Omit("content").Where("id IN (?)", uploads).Find(&contents).Error; err != nil {
+		return err
+	}
+	byID := make(map[string]*models.TemplateVersion, len(contents))
+	for i := range contents {
+		byID[contents[i].TemplateID] = &contents[i]
+	}
+
+	var written, removed int
+	for _, st := range states {
+		full, ok := customPath(st.Path)
+		if !ok {
+			continue
+		}
+		if st.Action == ActionDelete || st.Action == ActionRemove {
+			if err := os.Remove(full); err == nil {
+				removed++
+			} else if !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		v := byID[st.TemplateID]
+		if v == nil {
+			// 直接放进 custom 目录、没有上传记录的文件，只能以磁盘为准
+			continue
+		}
+		if full, ok = customPath(v.Path); !ok {
+			continue
+		}
+		if data, err := os.ReadFile(full); err == nil && checksum(data) == v.SHA256 {
+			continue
+		}
+		if err := mysqldb.DB.Select("content").First(v, v.ID).Error; err != nil {
+			return err
+		}
+		if v.Content == "" {
+			continue
+		}
+		tmp, err := writeTemp(full, v.TemplateID, []byte(v.Content))
+		if err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, full); err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("replace template file failed: %w", err)
+		}
+		written++
+	}
+	if written > 0 || removed > 0 {
+		log.Printf("[template.Materialize] templates materialized revision=%d written=%d removed=%d", rev, written, removed)
+	}
+	return nil
+}
+
+// customPath 把版本记录中的相对路径转换为磁盘路径，只接受 custom 目录下的路径
+func customPath(rel string) (string, bool) {
+	clean := filepath.ToSlash(filepath.Clean(filepath.FromSlash(rel)))
+	if !strings.HasPrefix(clean, customDir+"/") {
+		return "", false
+	}
+	return filepath.Join(Dir, filepath.FromSlash(clean)), true
+}
+
+// writeTemp 在目标文件所在目录写入临时文件，返回临时文件路径，由调用方替换或删除
+// 临时文件不以 .yaml 结尾，不会被 nuclei 或 Sync 加载
+func writeTemp(full, id string, data []byte) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(full), "."+id+"-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// Revision 当前模板库修订号（template_versions 的最大 ID），没有任何记录时为 0
+func Revision() (uint64, error) {
+	var rev uint64
+	err := mysqldb.DB.Model(&models.TemplateVersion{}).Select("COALESCE(MAX(id), 0)").Scan(&rev).Error
+	return rev, err
+}
+
+// VersionAt 返回修订号 rev 时某个模板的状态（该模板在 rev 之前的最后一条记录）
+func VersionAt(templateId string, rev uint64) (*models.TemplateVersion, error) {
+	var v models.TemplateVersion
+	err := mysqldb.DB.Where("template_id = ? AND id <= ?", templateId, rev).Order("id desc").First(&v).Error
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
@@ -0,0 +1,445 @@
+package template
+
+import (
+	"demo/db/mysqldb"
+	"demo/finding"
+	"demo/models"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 上传模板的大小上限
+const maxTemplateSize = 1 << 20
+
+// List - 模板列表与搜索
+// GET /api/template/list?keyword=&id=&tag=&severity=&cve=&protocol=&source=&enabled=&page=&pageSize=
+// keyword 匹配 id 与名称；tag 精确匹配其中一个 tag；cve 支持前缀，如 CVE-2024
+func List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := mysqldb.DB.Model(&models.Template{})
+		if kw := c.Query("keyword"); kw != "" {
+			db = db.Where("template_id LIKE ? OR name LIKE ?", "%"+kw+"%", "%"+kw+"%")
+		}
+		if id := c.Query("id"); id != "" {
+			db = db.Where("template_id = ?", id)
+		}
+		if tag := c.Query("tag"); tag != "" {
+			db = db.Where("FIND_IN_SET(?, tags) > 0", strings.ToLower(tag))
+		}
+		if sev := c.Query("severity"); sev != "" {
+			db = db.Where("severity IN ?", strings.Split(strings.ToLower(sev), ","))
+		}
+		if cve := c.Query("cve"); cve != "" {
+			db = db.Where("cve LIKE ?", "%"+strings.ToUpper(cve)+"%")
+		}
+		if protocol := c.Query("protocol"); protocol != "" {
+			db = db.Where("protocol = ?", protocol)
+		}
+		if source := c.Query("source"); source != "" {
+			db = db.Where("source = ?", source)
+		}
+		if s := c.Query("enabled"); s != "" {
+			enabled, err := strconv.ParseBool(s)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid enabled"})
+				return
+			}
+			db = db.Where("enabled = ?", enabled)
+		}
+
+		page, pageSize := finding.ParsePage(c)
+		db = db.Session(&gorm.Session{})
+		var total int64
+		if err := db.Count(&total).Error; err != nil {
+			log.Printf("[template.List] db count failed err=%v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		list := []models.Template{}
+		if err := db.Order("template_id asc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error; err != nil {
+			log.Printf("[template.List] db query failed err=%v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		rev, _ := Revision()
+		c.JSON(http.StatusOK, gin.H{"total": total, "page": page, "pageSize": pageSize, "revision": rev, "templates": list})
+	}
+}
+
+// View - 查看模板内容
+// GET /api/template/view?id=&version=&runId=
+// 默认返回当前版本；version 指定历史版本；runId 返回该次执行使用的版本
+func View() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Query("id")
+		if id == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
+			return
+		}
+
+		var cur models.Template
+		err := mysqldb.DB.First(&cur, "template_id = ?", id).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		found := err == nil
+
+		var ver *models.TemplateVersion
+		switch {
+		case c.Query("runId") != "":
+			var run models.TaskRun
+			if err := mysqldb.DB.Select("id", "template_revision").First(&run, "id = ?", c.Query("runId")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "run not found"})
+				return
+			}
+			if run.TemplateRevision == 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": "run has no template revision"})
+				return
+			}
+			if ver, err = VersionAt(id, run.TemplateRevision); err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "template not found in run"})
+				return
+			}
+		case c.Query("version") != "":
+			v, err := strconv.Atoi(c.Query("version"))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version"})
+				return
+			}
+			var tv models.TemplateVersion
+			if err := mysqldb.DB.Where("template_id = ? AND version = ?", id, v).Order("id desc").First(&tv).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "version not found"})
+				return
+			}
+			ver = &tv
+		case !found:
+			c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+			return
+		}
+
+		resp := gin.H{"id": id}
+		if found {
+			resp["template"] = cur
+		}
+		if ver == nil {
+			data, err := os.ReadFile(filepath.Join(Dir, filepath.FromSlash(cur.Path)))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "read template failed: " + err.Error()})
+				return
+			}
+			resp["version"] = cur.Version
+			resp["content"] = string(data)
+			c.JSON(http.StatusOK, resp)
+			return
+		}
+
+		resp["version"] = ver.Version
+		resp["revision"] = ver.ID
+		resp["action"] = ver.Action
+		resp["sha256"] = ver.SHA256
+		switch {
+		case ver.Content != "":
+			resp["content"] = ver.Content
+		case found && cur.SHA256 == ver.SHA256:
+			// 磁盘同步的版本不保存内容，与当前文件一致时直接读取
+			if data, err := os.ReadFile(filepath.Join(Dir, filepath.FromSlash(cur.Path))); err == nil {
+				resp["content"] = string(data)
+			}
+		}
+		if _, ok := resp["content"]; !ok {
+			resp["message"] = "content of this version is not retained"
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// Versions - 模板的变更记录
+// GET /api/template/versions?id=
+func Versions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Query("id")
+		if id == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
+			return
+		}
+		list := []models.TemplateVersion{}
+		if err := mysqldb.DB.Omit("content").Where("template_id = ?", id).Order("id desc").Find(&list).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": id, "versions": list})
+	}
+}
+
+// Upload - 上传新模板或更新已有模板（按模板 id 匹配），经 nuclei 解析校验后写入磁盘并生成新版本
+// multipart：file + sign；或 JSON：{"content":"id: ...","sign":true}
+// sign 为 true 时用服务端配置的证书签名（code 协议模板必须签名才会被执行）
+func Upload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data []byte
+		var sign bool
+		if strings.HasPrefix(c.ContentType(), "multipart/") {
+			fh, err := c.FormFile("file")
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "missing file"})
+				return
+			}
+			if fh.Size > maxTemplateSize {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "template too large"})
+				return
+			}
+			f, err := fh.Open()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			data, err = io.ReadAll(io.LimitReader(f, maxTemplateSize))
+			f.Close()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			sign, _ = strconv.ParseBool(c.PostForm("sign"))
+		} else {
+			var req struct {
+				Content string `json:"content"`
+				Sign    bool   `json:"sign"`
+			}
+			if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Content) == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "missing content"})
+				return
+			}
+			if len(req.Content) > maxTemplateSize {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "template too large"})
+				return
+			}
+			data, sign = []byte(req.Content), req.Sign
+		}
+
+		m, err := parseMeta(data)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template: " + err.Error()})
+			return
+		}
+		if !idRe.MatchString(m.ID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template id"})
+			return
+		}
+		data, verified, err := validate(data, sign)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template: " + err.Error()})
+			return
+		}
+
+		t, created, err := save(m, data, verified, c.GetString("username"))
+		if errors.Is(err, ErrBuiltinTemplate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			log.Printf("[template.Upload] save template failed id=%s err=%v", m.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if t == nil {
+			c.JSON(http.StatusOK, gin.H{"message": "模板内容未变化", "id": m.ID})
+			return
+		}
+		log.Printf("[template.Upload] template saved id=%s version=%d created=%v user=%s", t.TemplateID, t.Version, created, c.GetString("username"))
+		c.JSON(http.StatusOK, gin.H{"message": "上传成功", "template": t})
+	}
+}
+
+// ErrBuiltinTemplate 上传的模板 id 与模板库自带的模板相同
+var ErrBuiltinTemplate = errors.New("template id conflicts with a builtin template, use another id")
+
+// save 写入模板文件并更新索引；内容未变化时返回 nil
+// 只能覆盖通过 API 上传的模板；文件先写入临时文件，事务提交后再替换，避免索引与磁盘不一致
+// 内容同时保存在版本记录中，其他机器上的 worker 通过 Materialize 写入各自的磁盘
+func save(m *meta, data []byte, verified bool, creator string) (*models.Template, bool, error) {
+	syncMu.Lock()
+	defer syncMu.Unlock()
+
+	sum := checksum(data)
+	var t models.Template
+	err := mysqldb.DB.First(&t, "template_id = ?", m.ID).Error
+	created := errors.Is(err, gorm.ErrRecordNotFound)
+	if err != nil && !created {
+		return nil, false, err
+	}
+	if !created && !strings.HasPrefix(t.Path, customDir+"/") {
+		return nil, false, ErrBuiltinTemplate
+	}
+	if !created && t.SHA256 == sum {
+		return nil, false, nil
+	}
+
+	// 上传的模板都保存在 custom 目录，已有模板原地覆盖
+	path := customDir + "/" + m.ID + ".yaml"
+	if !created {
+		path = t.Path
+	}
+	full := filepath.Join(Dir, filepath.FromSlash(path))
+	tmp, err := writeTemp(full, m.ID, data)
+	if err != nil {
+		return nil, false, err
+	}
+	defer os.Remove(tmp)
+
+	tx := mysqldb.DB.Begin()
+	if created {
+		t = newTemplate(m, path, sum)
+		t.Source = SourceCustom
+		t.Verified = verified
+		err = tx.Create(&t).Error
+	} else {
+		t.Version++
+		err = tx.Model(&t).Updates(map[string]interface{}{
+			"name": m.Name, "author": m.Author, "severity": m.Severity, "tags": m.Tags, "cve": m.CVE,
+			"protocol": m.Protocol, "verified": verified, "version": t.Version, "sha256": sum,
+		}).Error
+	}
+	if err == nil {
+		err = tx.Create(&models.TemplateVersion{
+			TemplateID: m.ID, Version: t.Version, Action: ActionUpload, Path: path, SHA256: sum,
+			Content: string(data), Verified: verified, Creator: creator,
+		}).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, false, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, false, err
+	}
+	if err := os.Rename(tmp, full); err != nil {
+		return nil, false, fmt.Errorf("replace template file failed: %w", err)
+	}
+	return &t, created, mysqldb.DB.First(&t, t.ID).Error
+}
+
+// Enable - 启用/禁用模板，禁用的模板在之后的扫描中不会加载
+// POST /api/template/enable {"id":"CVE-2021-44228","enabled":false}
+func Enable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			ID      string `json:"id"`
+			Enabled *bool  `json:"enabled"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.ID == "" || req.Enabled == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing id or enabled"})
+			return
+		}
+
+		syncMu.Lock()
+		defer syncMu.Unlock()
+		var t models.Template
+		if err := mysqldb.DB.First(&t, "template_id = ?", req.ID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+			return
+		}
+		if t.Enabled == *req.Enabled {
+			c.JSON(http.StatusOK, gin.H{"message": "状态未变化", "template": t})
+			return
+		}
+		action := ActionDisable
+		if *req.Enabled {
+			action = ActionEnable
+		}
+
+		tx := mysqldb.DB.Begin()
+		err := tx.Model(&t).Update("enabled", *req.Enabled).Error
+		if err == nil {
+			err = tx.Create(&models.TemplateVersion{
+				TemplateID: t.TemplateID, Version: t.Version, Action: action, Path: t.Path, SHA256: t.SHA256,
+				Verified: t.Verified, Creator: c.GetString("username"),
+			}).Error
+		}
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := tx.Commit().Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("[template.Enable] template %s id=%s user=%s", action, t.TemplateID, c.GetString("username"))
+		c.JSON(http.StatusOK, gin.H{"message": "保存成功", "template": t})
+	}
+}
+
+// Delete - 删除通过 API 上传的模板（模板库自带的模板只能禁用），版本记录保留
+// POST /api/template/delete {"id":"..."}
+func Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			ID string `json:"id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.ID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
+			return
+		}
+
+		syncMu.Lock()
+		defer syncMu.Unlock()
+		var t models.Template
+		if err := mysqldb.DB.First(&t, "template_id = ?", req.ID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+			return
+		}
+		if !strings.HasPrefix(t.Path, customDir+"/") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "only uploaded templates can be deleted, disable builtin templates instead"})
+			return
+		}
+
+		if err := os.Remove(filepath.Join(Dir, filepath.FromSlash(t.Path))); err != nil && !os.IsNotExist(err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "remove template file failed: " + err.Error()})
+			return
+		}
+		tx := mysqldb.DB.Begin()
+		err := tx.Delete(&t).Error
+		if err == nil {
+			err = tx.Create(&models.TemplateVersion{
+				TemplateID: t.TemplateID, Version: t.Version, Action: ActionDelete, Path: t.Path, SHA256: t.SHA256,
+				Creator: c.GetString("username"),
+			}).Error
+		}
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := tx.Commit().Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("[template.Delete] template deleted id=%s user=%s", t.TemplateID, c.GetString("username"))
+		c.JSON(http.StatusOK, gin.H{"message": "删除成功", "id": t.TemplateID})
+	}
+}
+
+// SyncNow - 手动把索引与磁盘同步（例如更新了模板库之后）
+// POST /api/template/sync
+func SyncNow() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res, err := Sync(c.GetString("username"))
+		if err != nil {
+			log.Printf("[template.SyncNow] sync failed err=%v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "同步完成", "result": res})
+	}
+}
@@ -0,0 +1,348 @@
+package user
+
+import (
+	"demo/db/mysqldb"
+	"demo/db/redisdb"
+	"demo/models"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 角色：viewer 只读结果；operator 可创建/启动/停止任务；admin 额外管理用户与模板
+const (
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+	RoleViewer   = "viewer"
+)
+
+var roleLevel = map[string]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// 首次启动且 users 表为空时创建的管理员（沿用原来的固定账号，可通过环境变量覆盖）
+const (
+	defaultAdminUsername = "Yuy0ung"
+	defaultAdminPassword = "Yuy0ung@test123"
+)
+
+const minPasswordLen = 8
+
+// Init 初始化账号体系：users 表为空时创建默认管理员
+func Init() {
+	var count int64
+	if err := mysqldb.DB.Model(&models.User{}).Count(&count).Error; err != nil {
+		log.Printf("[user.Init] db count users failed err=%v", err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+
+	username, password := os.Getenv("DAST_ADMIN_USER"), os.Getenv("DAST_ADMIN_PASSWORD")
+	if username == "" {
+		username = defaultAdminUsername
+	}
+	if password == "" {
+		password = defaultAdminPassword
+		log.Printf("[user.Init] using default admin password, please change it after login")
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		log.Printf("[user.Init] hash password failed err=%v", err)
+		return
+	}
+	if err := mysqldb.DB.Create(&models.User{Username: username, PasswordHash: hash, Role: RoleAdmin}).Error; err != nil {
+		log.Printf("[user.Init] create admin failed err=%v", err)
+		return
+	}
+	log.Printf("[user.Init] created admin user=%s", username)
+}
+
+func hashPassword(password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func checkPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+func validRole(role string) bool {
+	_, ok := roleLevel[role]
+	return ok
+}
+
+// Require 路由级权限：当前用户角色不低于 role 才放行，需挂在 AuthMiddleware 之后
+func Require(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if roleLevel[c.GetString("role")] < roleLevel[role] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission denied, requires role " + role})
+			return
+		}
+		c.Next()
+	}
+}
+
+// revokeSessions 踢掉用户的所有登录会话
+func revokeSessions(username string) {
+	tokens, err := redisdb.Client.SMembers(redisdb.Ctx, sessionsKey(username)).Result()
+	if err != nil {
+		log.Printf("[user] load sessions failed user=%s err=%v", username, err)
+		return
+	}
+	keys := []string{sessionsKey(username)}
+	for _, t := range tokens {
+		keys = append(keys, sessionPrefix+t)
+	}
+	_ = redisdb.Client.Del(redisdb.Ctx, keys...).Err()
+}
+
+// adminCount 统计启用中的管理员数量，避免把最后一个管理员降级/禁用/删除
+func adminCount() (int64, error) {
+	var n int64
+	err := mysqldb.DB.Model(&models.User{}).Where("role = ? AND disabled = ?", RoleAdmin, false).Count(&n).Error
+	return n, err
+}
+
+// List - 列出所有用户（admin）
+func List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		users := []models.User{}
+		if err := mysqldb.DB.Order("id asc").Find(&users).Error; err != nil {
+			log.Printf("[user.List] db query failed err=%v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"users": users})
+	}
+}
+
+// Create - 新建用户（admin）
+func Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Role     string `json:"role"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+			return
+		}
+		req.Username = strings.TrimSpace(req.Username)
+		if req.Username == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing username"})
+			return
+		}
+		if len(req.Password) < minPasswordLen {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "password too short"})
+			return
+		}
+		if req.Role == "" {
+			req.Role = RoleViewer
+		}
+		if !validRole(req.Role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role (admin/operator/viewer)"})
+			return
+		}
+
+		hash, err := hashPassword(req.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		var exists int64
+		if err := mysqldb.DB.Model(&models.User{}).Where("username = ?", req.Username).Count(&exists).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if exists > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "username already exists"})
+			return
+		}
+		u := models.User{Username: req.Username, PasswordHash: hash, Role: req.Role}
+		if err := mysqldb.DB.Create(&u).Error; err != nil {
+			log.Printf("[user.Create] db create failed user=%s err=%v", req.Username, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db create user failed: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "创建成功", "user": u})
+	}
+}
+
+// Update - 修改用户角色、重置密码或禁用/启用（admin）
+func Update() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Username string  `json:"username"`
+			Role     *string `json:"role"`
+			Password *string `json:"password"`
+			Disabled *bool   `json:"disabled"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Username) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing username"})
+			return
+		}
+
+		var u models.User
+		if err := mysqldb.DB.First(&u, "username = ?", req.Username).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+
+		updates := map[string]interface{}{}
+		if req.Role != nil {
+			if !validRole(*req.Role) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role (admin/operator/viewer)"})
+				return
+			}
+			updates["role"] = *req.Role
+		}
+		if req.Password != nil {
+			if len(*req.Password) < minPasswordLen {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "password too short"})
+				return
+			}
+			hash, err := hashPassword(*req.Password)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			updates["password_hash"] = hash
+		}
+		if req.Disabled != nil {
+			updates["disabled"] = *req.Disabled
+		}
+		if len(updates) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"})
+			return
+		}
+
+		// 不允许去掉最后一个可用的管理员
+		demoted := req.Role != nil && *req.Role != RoleAdmin
+		disabled := req.Disabled != nil && *req.Disabled
+		if u.Role == RoleAdmin && !u.Disabled && (demoted || disabled) {
+			if n, err := adminCount(); err != nil || n <= 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "cannot remove the last admin"})
+				return
+			}
+		}
+
+		if err := mysqldb.DB.Model(&u).Updates(updates).Error; err != nil {
+			log.Printf("[user.Update] db update failed user=%s err=%v", u.Username, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		// 重置密码或禁用后强制重新登录
+		if req.Password != nil || disabled {
+			revokeSessions(u.Username)
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "更新成功", "username": u.Username})
+	}
+}
+
+// Delete - 删除用户（admin，不能删除自己）
+func Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Username string `json:"username"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Username) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing username"})
+			return
+		}
+		if req.Username == c.GetString("username") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot delete yourself"})
+			return
+		}
+
+		var u models.User
+		if err := mysqldb.DB.First(&u, "username = ?", req.Username).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		if u.Role == RoleAdmin && !u.Disabled {
+			if n, err := adminCount(); err != nil || n <= 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "cannot remove the last admin"})
+				return
+			}
+		}
+		if err := mysqldb.DB.Delete(&u).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		revokeSessions(u.Username)
+		if err := mysqldb.DB.Where("username = ?", u.Username).Delete(&models.APIToken{}).Error; err != nil {
+			log.Printf("[user.Delete] db delete tokens failed user=%s err=%v", u.Username, err)
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "删除成功", "username": u.Username})
+	}
+}
+
+// Me - 当前登录用户信息
+func Me() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var u models.User
+		if err := mysqldb.DB.First(&u, "username = ?", c.GetString("username")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"user": u})
+	}
+}
+
+// ChangePassword - 修改自己的密码，成功后其他会话全部失效
+func ChangePassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			OldPassword string `json:"oldPassword"`
+			NewPassword string `json:"newPassword"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+			return
+		}
+		if len(req.NewPassword) < minPasswordLen {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "password too short"})
+			return
+		}
+
+		var u models.User
+		err := mysqldb.DB.First(&u, "username = ?", c.GetString("username")).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !checkPassword(u.PasswordHash, req.OldPassword) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "wrong old password"})
+			return
+		}
+		hash, err := hashPassword(req.NewPassword)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := mysqldb.DB.Model(&u).Update("password_hash", hash).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		revokeSessions(u.Username)
+		c.JSON(http.StatusOK, gin.H{"message": "密码已修改，请重新登录"})
+	}
+}
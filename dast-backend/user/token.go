@@ -0,0 +1,198 @@
+package user
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"demo/db/mysqldb"
+	"demo/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// API token 以固定前缀开头，AuthMiddleware 据此区分登录 session 与 API token
+const apiTokenPrefix = "dast_"
+
+// last_used_at 最多每分钟更新一次，避免每个请求都写库
+const tokenTouchInterval = time.Minute
+
+// token 作用域及其对应的最高角色；实际角色不会超过 token 所属用户的角色
+// API token 不能用于用户管理和 token 管理，这些操作只接受登录 session
+const (
+	ScopeRead = "read" // 查看任务、结果、日志
+	ScopeScan = "scan" // 另外可创建/启动/停止任务、调用 CI 接口
+)
+
+var scopeRole = map[string]string{
+	ScopeRead: RoleViewer,
+	ScopeScan: RoleOperator,
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenRole 计算 token 的有效角色：作用域允许的最高角色与用户角色取较低者
+func tokenRole(scopes, userRole string) string {
+	role := ""
+	for _, s := range strings.Split(scopes, ",") {
+		if r, ok := scopeRole[s]; ok && roleLevel[r] > roleLevel[role] {
+			role = r
+		}
+	}
+	if roleLevel[userRole] < roleLevel[role] {
+		role = userRole
+	}
+	return role
+}
+
+// authAPIToken 校验 API token，返回 token 所属用户与有效角色
+func authAPIToken(c *gin.Context, token string) (*models.User, string, bool) {
+	var t models.APIToken
+	if err := mysqldb.DB.First(&t, "token_hash = ?", hashToken(token)).Error; err != nil {
+		return nil, "", false
+	}
+	now := time.Now()
+	if t.RevokedAt != nil || (t.ExpiresAt != nil && now.After(*t.ExpiresAt)) {
+		return nil, "", false
+	}
+	var u models.User
+	if err := mysqldb.DB.First(&u, "username = ?", t.Username).Error; err != nil || u.Disabled {
+		return nil, "", false
+	}
+
+	if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) >= tokenTouchInterval {
+		if err := mysqldb.DB.Model(&models.APIToken{}).Where("id = ?", t.ID).
+			Updates(map[string]interface{}{"last_used_at": now, "last_used_ip": c.ClientIP()}).Error; err != nil {
+			log.Printf("[user.authAPIToken] db update last used failed token=%d err=%v", t.ID, err)
+		}
+	}
+	return &u, tokenRole(t.Scopes, u.Role), true
+}
+
+// SessionOnly 只允许登录 session 访问（拒绝 API token），用于 token 管理等敏感接口
+func SessionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth") == "token" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "api token not allowed, please login"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// CreateToken - 为当前用户创建 API token，明文只在创建时返回一次
+// POST /api/token/create {"name":"gitlab-ci","scopes":["scan"],"expiresInDays":90}
+func CreateToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Name          string   `json:"name"`
+			Scopes        []string `json:"scopes"`
+			ExpiresInDays int      `json:"expiresInDays"` // 0 表示永不过期
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
+			return
+		}
+		if len(req.Scopes) == 0 {
+			req.Scopes = []string{ScopeRead}
+		}
+		role := c.GetString("role")
+		for _, s := range req.Scopes {
+			r, ok := scopeRole[s]
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid scope (read/scan): " + s})
+				return
+			}
+			if roleLevel[r] > roleLevel[role] {
+				c.JSON(http.StatusForbidden, gin.H{"error": "scope exceeds your role: " + s})
+				return
+			}
+		}
+		if req.ExpiresInDays < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid expiresInDays"})
+			return
+		}
+
+		token := apiTokenPrefix + generateToken()[:40]
+		t := models.APIToken{
+			Name:      req.Name,
+			Username:  c.GetString("username"),
+			TokenHash: hashToken(token),
+			Prefix:    token[:len(apiTokenPrefix)+8],
+			Scopes:    strings.Join(req.Scopes, ","),
+		}
+		if req.ExpiresInDays > 0 {
+			exp := time.Now().AddDate(0, 0, req.ExpiresInDays)
+			t.ExpiresAt = &exp
+		}
+		if err := mysqldb.DB.Create(&t).Error; err != nil {
+			log.Printf("[user.CreateToken] db create failed user=%s err=%v", t.Username, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db create token failed: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message": "创建成功，token 只显示一次，请妥善保存",
+			"token":   token,
+			"info":    t,
+		})
+	}
+}
+
+// ListTokens - 列出当前用户的 API token；admin 可通过 ?all=1 查看所有用户的 token
+func ListTokens() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := mysqldb.DB.Order("id desc")
+		if !(c.Query("all") == "1" && c.GetString("role") == RoleAdmin) {
+			db = db.Where("username = ?", c.GetString("username"))
+		}
+		tokens := []models.APIToken{}
+		if err := db.Find(&tokens).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+	}
+}
+
+// RevokeToken - 吊销 API token（自己的，admin 可吊销任意 token）
+// POST /api/token/revoke {"id":1}
+func RevokeToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			ID uint64 `json:"id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.ID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
+			return
+		}
+		var t models.APIToken
+		if err := mysqldb.DB.First(&t, "id = ?", req.ID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "token not found"})
+			return
+		}
+		if t.Username != c.GetString("username") && c.GetString("role") != RoleAdmin {
+			c.JSON(http.StatusNotFound, gin.H{"error": "token not found"})
+			return
+		}
+		if t.RevokedAt != nil {
+			c.JSON(http.StatusOK, gin.H{"message": "token already revoked", "id": t.ID})
+			return
+		}
+		if err := mysqldb.DB.Model(&t).Update("revoked_at", time.Now()).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "已吊销", "id": t.ID})
+	}
+}
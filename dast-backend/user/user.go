@@ -7,20 +7,23 @@ import (
 	"strings"
 	"time"
 
+	"demo/db/mysqldb"
 	"demo/db/redisdb"
+	"demo/models"
 
 	"github.com/gin-gonic/gin"
 )
 
-// 固定用户 & 密码
 const (
-	fixedUsername = "Yuy0ung"
-	fixedPassword = "Yuy0ung@test123"
-
 	sessionPrefix = "session:"
 	sessionTTL    = 24 * time.Hour
 )
 
+// sessionsKey 记录用户的所有 session token，禁用/删除用户或修改密码时据此踢下线
+func sessionsKey(username string) string {
+	return "user:" + username + ":sessions"
+}
+
 // 生成随机 token
 func generateToken() string {
 	b := make([]byte, 32)
@@ -50,7 +53,7 @@ func extractToken(c *gin.Context) string {
 }
 
 // 登录：POST /api/login
-// body 可以是 form 或 json：username=Yuy0ung&password=Yuy0ung@test123
+// body 可以是 form 或 json：username=xxx&password=xxx
 func Login() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 兼容 form + json
@@ -63,26 +66,34 @@ func Login() gin.HandlerFunc {
 			return
 		}
 
-		if req.Username != fixedUsername || req.Password != fixedPassword {
+		var u models.User
+		if err := mysqldb.DB.First(&u, "username = ?", req.Username).Error; err != nil || !checkPassword(u.PasswordHash, req.Password) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid username or password"})
 			return
 		}
+		if u.Disabled {
+			c.JSON(http.StatusForbidden, gin.H{"error": "user disabled"})
+			return
+		}
 
 		// 生成 token 并写入 Redis
 		token := generateToken()
 		key := sessionPrefix + token
 
-		if err := redisdb.Client.Set(redisdb.Ctx, key, fixedUsername, sessionTTL).Err(); err != nil {
+		if err := redisdb.Client.Set(redisdb.Ctx, key, u.Username, sessionTTL).Err(); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
 			return
 		}
+		_ = redisdb.Client.SAdd(redisdb.Ctx, sessionsKey(u.Username), token).Err()
+		_ = mysqldb.DB.Model(&u).Update("last_login_at", time.Now()).Error
 
 		// 可选：同时种一个 cookie，方便浏览器自动带 token
 		// c.SetCookie("token", token, int(sessionTTL.Seconds()), "/", "", false, true)
 
 		c.JSON(http.StatusOK, gin.H{
 			"message":  "login success",
-			"username": fixedUsername,
+			"username": u.Username,
+			"role":     u.Role,
 			"token":    token,
 		})
 	}
@@ -94,6 +105,9 @@ func Logout() gin.HandlerFunc {
 		token := extractToken(c)
 		if token != "" {
 			key := sessionPrefix + token
+			if username, err := redisdb.Client.Get(redisdb.Ctx, key).Result(); err == nil {
+				_ = redisdb.Client.SRem(redisdb.Ctx, sessionsKey(username), token).Err()
+			}
 			_ = redisdb.Client.Del(redisdb.Ctx, key).Err()
 		}
 
@@ -126,6 +140,20 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// API token：长期有效，按作用域限制角色
+		if strings.HasPrefix(token, apiTokenPrefix) {
+			u, role, ok := authAPIToken(c, token)
+			if !ok {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid, expired or revoked api token"})
+				return
+			}
+			c.Set("username", u.Username)
+			c.Set("role", role)
+			c.Set("auth", "token")
+			c.Next()
+			return
+		}
+
 		key := sessionPrefix + token
 		username, err := redisdb.Client.Get(redisdb.Ctx, key).Result()
 		if err != nil || username == "" {
@@ -133,11 +161,21 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// 每次请求都从 MySQL 读取角色，修改角色/禁用用户立即生效
+		var u models.User
+		if err := mysqldb.DB.First(&u, "username = ?", username).Error; err != nil || u.Disabled {
+			_ = redisdb.Client.Del(redisdb.Ctx, key).Err()
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+			return
+		}
+
 		// 刷新一下过期时间（滑动过期，可选）
 		_ = redisdb.Client.Expire(redisdb.Ctx, key, sessionTTL).Err()
 
-		// 把用户名塞进上下文，后面的 handler 可以用 c.Get("username")
-		c.Set("username", username)
+		// 把用户名与角色塞进上下文，后面的 handler 可以用 c.Get("username") / c.Get("role")
+		c.Set("username", u.Username)
+		c.Set("role", u.Role)
+		c.Set("auth", "session")
 
 		c.Next()
 	}
@@ -0,0 +1,217 @@
+package worker
+
+import (
+	"context"
+	"demo/db/redisdb"
+	"demo/scanner"
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis 中的扫描作业队列：
+//   - scan:jobs:queue        待领取的作业（API 端 RPUSH）
+//   - scan:jobs:processing   已被 worker 领取、正在执行的作业（BLMOVE 原子转移）
+//   - scan:jobs:delayed      延迟重试的作业（ZSET，score 为可重新入队的毫秒时间戳），到期后放回 queue
+//   - scan:job:{taskId}:lease  租约，值为持有者 workerId，worker 心跳续期；过期即视为 worker 已死亡
+const (
+	queueKey      = "scan:jobs:queue"
+	processingKey = "scan:jobs:processing"
+	delayedKey    = "scan:jobs:delayed"
+
+	leaseTTL          = 30 * time.Second
+	heartbeatInterval = 10 * time.Second
+	reapInterval      = 15 * time.Second
+	retryDelay        = 5 * time.Second
+	promoteInterval   = time.Second
+)
+
+// Job 一次扫描作业，由 task.Start 入队，worker 领取后执行 scanner.Run
+type Job struct {
+	TaskID     string   `json:"taskId"`
+	RunID      string   `json:"runId"`
+	Targets    []string `json:"targets"`
+	InfoKey    string   `json:"infoKey"`
+	Config     string   `json:"config,omitempty"` // profile 快照（models.Task.Config）
+	Exclusions []string `json:"exclusions,omitempty"`
+	EnqueuedAt int64    `json:"enqueuedAt"`
+}
+
+func leaseKey(taskId string) string {
+	return "scan:job:" + taskId + ":lease"
+}
+
+// Enqueue 把作业推入队列，等待任意 worker 领取
+func Enqueue(job Job) error {
+	job.EnqueuedAt = time.Now().Unix()
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return redisdb.Client.RPush(redisdb.Ctx, queueKey, data).Err()
+}
+
+// OrphanGrace 判定孤儿作业前的等待时间：worker 领取（BLMOVE）与写租约之间存在短暂窗口，
+// 与 reaper 相同，间隔一轮后仍没有租约才视为孤儿
+const OrphanGrace = reapInterval
+
+// Owned 判断某次执行是否仍有归属：租约有效，或作业还在 queue / delayed 中
+// 启动时的崩溃恢复据此区分孤儿执行与正常排队/运行中的执行；
+// 留在 processing 中但没有租约的作业不算有归属，需要在 OrphanGrace 之后用 ClaimOrphan 确认
+func Owned(taskId, runId string) (bool, error) {
+	exists, err := redisdb.Client.Exists(redisdb.Ctx, leaseKey(taskId)).Result()
+	if err != nil {
+		return false, err
+	}
+	if exists > 0 {
+		return true, nil
+	}
+	return queued(taskId, runId)
+}
+
+// ClaimOrphan 确认执行已成为孤儿并接管：仍没有租约、也不在 queue / delayed 中时，
+// 从 processing 移除该作业（worker 已死亡）后返回 true，由调用方按恢复策略处理；
+// processing 中的作业已被 reaper 放回队列时返回 false
+func ClaimOrphan(taskId, runId string) (bool, error) {
+	owned, err := Owned(taskId, runId)
+	if err != nil || owned {
+		return false, err
+	}
+	ctx := redisdb.Ctx
+	processing, err := redisdb.Client.LRange(ctx, processingKey, 0, -1).Result()
+	if err != nil {
+		return false, err
+	}
+	for _, raw := range processing {
+		if !matchJob(raw, taskId, runId) {
+			continue
+		}
+		n, err := redisdb.Client.LRem(ctx, processingKey, 0, raw).Result()
+		if err != nil {
+			return false, err
+		}
+		if n == 0 {
+			// 已被 reaper 移走并重新入队
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// queued 作业是否在 queue 或 delayed 中等待领取
+func queued(taskId, runId string) (bool, error) {
+	ctx := redisdb.Ctx
+	list, err := redisdb.Client.LRange(ctx, queueKey, 0, -1).Result()
+	if err != nil {
+		return false, err
+	}
+	delayed, err := redisdb.Client.ZRange(ctx, delayedKey, 0, -1).Result()
+	if err != nil {
+		return false, err
+	}
+	for _, raw := range append(list, delayed...) {
+		if matchJob(raw, taskId, runId) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func matchJob(raw, taskId, runId string) bool {
+	var job Job
+	return json.Unmarshal([]byte(raw), &job) == nil && job.TaskID == taskId && job.RunID == runId
+}
+
+// Init 启动租约回收与延迟作业协程（API 与 worker 进程都会启动，重复回收由 LREM / ZREM 返回值去重）
+func Init() {
+	go reaper()
+	go promoter()
+}
+
+// delay 把作业放入延迟集合，d 之后由 promoter 放回队列
+func delay(raw string, d time.Duration) error {
+	return redisdb.Client.ZAdd(redisdb.Ctx, delayedKey, redis.Z{
+		Score:  float64(time.Now().Add(d).UnixMilli()),
+		Member: raw,
+	}).Err()
+}
+
+// promoter 把到期的延迟作业放回队列：从 delayed 移除成功的那个进程负责入队
+func promoter() {
+	ctx := context.Background()
+	for {
+		time.Sleep(promoteInterval)
+
+		raws, err := redisdb.Client.ZRangeByScore(ctx, delayedKey, &redis.ZRangeBy{
+			Min: "-inf",
+			Max: strconv.FormatInt(time.Now().UnixMilli(), 10),
+		}).Result()
+		if err != nil {
+			log.Printf("[worker.promoter] redis zrange failed err=%v", err)
+			continue
+		}
+		for _, raw := range raws {
+			n, err := redisdb.Client.ZRem(ctx, delayedKey, raw).Result()
+			if err != nil || n == 0 {
+				continue
+			}
+			if err := redisdb.Client.RPush(ctx, queueKey, raw).Err(); err != nil {
+				log.Printf("[worker.promoter] requeue failed err=%v", err)
+				_ = delay(raw, retryDelay)
+			}
+		}
+	}
+}
+
+// reaper 扫描 processing 列表，把租约已过期的作业放回队列
+// worker 领取（BLMOVE）与写租约之间存在短暂窗口，因此连续两轮都没有租约才回收
+func reaper() {
+	ctx := context.Background()
+	suspects := map[string]bool{}
+
+	for {
+		time.Sleep(reapInterval)
+
+		raws, err := redisdb.Client.LRange(ctx, processingKey, 0, -1).Result()
+		if err != nil {
+			log.Printf("[worker.reaper] redis lrange failed err=%v", err)
+			continue
+		}
+
+		next := map[string]bool{}
+		for _, raw := range raws {
+			var job Job
+			if err := json.Unmarshal([]byte(raw), &job); err != nil || job.TaskID == "" {
+				log.Printf("[worker.reaper] drop invalid job payload=%s", raw)
+				_ = redisdb.Client.LRem(ctx, processingKey, 1, raw).Err()
+				continue
+			}
+
+			exists, err := redisdb.Client.Exists(ctx, leaseKey(job.TaskID)).Result()
+			if err != nil || exists > 0 {
+				continue
+			}
+			if !suspects[raw] {
+				next[raw] = true
+				continue
+			}
+
+			// 租约过期：从 processing 移除成功的那个 reaper 负责重新入队
+			n, err := redisdb.Client.LRem(ctx, processingKey, 1, raw).Result()
+			if err != nil || n == 0 {
+				continue
+			}
+			if err := redisdb.Client.RPush(ctx, queueKey, raw).Err(); err != nil {
+				log.Printf("[worker.reaper] requeue failed task=%s err=%v", job.TaskID, err)
+				_ = redisdb.Client.RPush(ctx, processingKey, raw).Err()
+				continue
+			}
+			_ = redisdb.Client.HSet(ctx, job.InfoKey, "stage", scanner.StageQueued, "worker", "").Err()
+			log.Printf("[worker.reaper] lease expired, requeued task=%s", job.TaskID)
+		}
+		suspects = next
+	}
+}
@@ -0,0 +1,291 @@
+package worker
+
+import (
+	"context"
+	"crypto/rand"
+	"demo/db/mysqldb"
+	"demo/db/redisdb"
+	"demo/models"
+	"demo/scanner"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// worker 注册信息：scan:workers 集合保存所有 workerId，scan:worker:{id} 保存心跳详情（带 TTL）
+const (
+	workersKey = "scan:workers"
+	workerTTL  = 3 * heartbeatInterval
+)
+
+func workerKey(id string) string {
+	return "scan:worker:" + id
+}
+
+// Worker 一个扫描 worker 进程，可同时执行 concurrency 个作业
+type Worker struct {
+	ID          string
+	concurrency int
+	startedAt   time.Time
+
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// New 创建 worker，id 形如 hostname-pid-随机串
+func New(concurrency int) *Worker {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return &Worker{
+		ID:          fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b)),
+		concurrency: concurrency,
+		startedAt:   time.Now(),
+		running:     map[string]bool{},
+	}
+}
+
+// Run 阻塞运行：订阅取消频道、上报心跳，并用 concurrency 个协程循环领取作业
+func (w *Worker) Run(ctx context.Context) {
+	log.Printf("[worker] %s started concurrency=%d", w.ID, w.concurrency)
+	go scanner.ListenCancel(ctx)
+	go w.heartbeat(ctx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+
+	_ = redisdb.Client.SRem(context.Background(), workersKey, w.ID).Err()
+	_ = redisdb.Client.Del(context.Background(), workerKey(w.ID)).Err()
+	log.Printf("[worker] %s stopped", w.ID)
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		// 原子地把作业从 queue 转移到 processing，worker 崩溃时由 reaper 回收
+		raw, err := redisdb.Client.BLMove(ctx, queueKey, processingKey, "LEFT", "RIGHT", 5*time.Second).Result()
+		if err != nil {
+			if err != redis.Nil && ctx.Err() == nil {
+				log.Printf("[worker] %s claim failed err=%v", w.ID, err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+		w.process(ctx, raw)
+	}
+}
+
+// process 执行一个作业：写租约 -> 校验任务状态 -> 心跳续租并运行扫描 -> 清理
+func (w *Worker) process(ctx context.Context, raw string) {
+	done := true
+	defer func() {
+		if done {
+			_ = redisdb.Client.LRem(context.Background(), processingKey, 1, raw).Err()
+		}
+	}()
+
+	var job Job
+	if err := json.Unmarshal([]byte(raw), &job); err != nil || job.TaskID == "" {
+		log.Printf("[worker] %s drop invalid job payload=%s", w.ID, raw)
+		return
+	}
+
+	lease := leaseKey(job.TaskID)
+	ok, err := redisdb.Client.SetNX(ctx, lease, w.ID, leaseTTL).Result()
+	if err != nil {
+		// 无法确认租约归属：作业留在 processing 中，由 reaper 在 Redis 恢复后回收
+		if ctx.Err() == nil {
+			log.Printf("[worker] %s task=%s acquire lease failed err=%v", w.ID, job.TaskID, err)
+			time.Sleep(time.Second)
+		}
+		done = false
+		return
+	}
+	if !ok {
+		// 另一个 worker 仍持有该任务（例如旧租约尚未过期），延迟后再放回队列，避免空闲 worker 反复领取
+		done = false
+		_ = redisdb.Client.LRem(context.Background(), processingKey, 1, raw).Err()
+		if err := delay(raw, retryDelay); err != nil {
+			log.Printf("[worker] %s task=%s delay job failed err=%v", w.ID, job.TaskID, err)
+			_ = redisdb.Client.RPush(context.Background(), processingKey, raw).Err()
+			return
+		}
+		log.Printf("[worker] %s task=%s lease held by another worker, retry in %s", w.ID, job.TaskID, retryDelay)
+		return
+	}
+	defer func() {
+		// 仅删除自己持有的租约
+		if owner, _ := redisdb.Client.Get(context.Background(), lease).Result(); owner == w.ID {
+			_ = redisdb.Client.Del(context.Background(), lease).Err()
+		}
+	}()
+
+	// 排队期间任务可能已被停止或删除，或者已经开始了新一次执行
+	var run models.TaskRun
+	if err := mysqldb.DB.First(&run, "id = ?", job.RunID).Error; err != nil || run.Status != "running" {
+		log.Printf("[worker] %s task=%s run=%s no longer running, skip", w.ID, job.TaskID, job.RunID)
+		return
+	}
+
+	profile, err := scanner.ParseProfile(job.Config)
+	if err != nil {
+		log.Printf("[worker] %s task=%s invalid profile err=%v", w.ID, job.TaskID, err)
+		profile = scanner.DefaultProfile()
+	}
+
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go w.renewLease(runCtx, cancel, job.TaskID)
+
+	w.setRunning(job.TaskID, true)
+	defer w.setRunning(job.TaskID, false)
+
+	_ = redisdb.Client.HSet(ctx, job.InfoKey, "worker", w.ID).Err()
+	log.Printf("[worker] %s task=%s claimed", w.ID, job.TaskID)
+	scanner.Run(runCtx, job.TaskID, job.RunID, job.Targets, job.Exclusions, job.InfoKey, profile)
+	log.Printf("[worker] %s task=%s done", w.ID, job.TaskID)
+}
+
+// renewLease 定期续租；发现租约已被他人持有时以 ErrLeaseLost 取消本地扫描
+func (w *Worker) renewLease(ctx context.Context, cancel context.CancelCauseFunc, taskId string) {
+	lease := leaseKey(taskId)
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		owner, err := redisdb.Client.Get(ctx, lease).Result()
+		if err == redis.Nil {
+			// 租约已过期但还没被别人领取：重新抢回
+			if ok, _ := redisdb.Client.SetNX(ctx, lease, w.ID, leaseTTL).Result(); ok {
+				continue
+			}
+			owner, err = redisdb.Client.Get(ctx, lease).Result()
+		}
+		if err != nil {
+			log.Printf("[worker] %s task=%s renew lease failed err=%v", w.ID, taskId, err)
+			continue
+		}
+		if owner != w.ID {
+			log.Printf("[worker] %s task=%s lease taken by %s, abort", w.ID, taskId, owner)
+			cancel(scanner.ErrLeaseLost)
+			return
+		}
+		_ = redisdb.Client.Expire(ctx, lease, leaseTTL).Err()
+	}
+}
+
+// heartbeat 定期上报 worker 存活信息与正在执行的任务
+func (w *Worker) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		tasks := w.runningTasks()
+		data, _ := json.Marshal(tasks)
+		key := workerKey(w.ID)
+		pipe := redisdb.Client.TxPipeline()
+		pipe.SAdd(ctx, workersKey, w.ID)
+		pipe.HSet(ctx, key,
+			"id", w.ID,
+			"concurrency", w.concurrency,
+			"tasks", string(data),
+			"started_at", w.startedAt.Format("2006-01-02 15:04:05"),
+			"heartbeat_at", time.Now().Format("2006-01-02 15:04:05"),
+		)
+		pipe.Expire(ctx, key, workerTTL)
+		if _, err := pipe.Exec(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("[worker] %s heartbeat failed err=%v", w.ID, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) setRunning(taskId string, running bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if running {
+		w.running[taskId] = true
+	} else {
+		delete(w.running, taskId)
+	}
+}
+
+func (w *Worker) runningTasks() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	tasks := make([]string, 0, len(w.running))
+	for id := range w.running {
+		tasks = append(tasks, id)
+	}
+	sort.Strings(tasks)
+	return tasks
+}
+
+// List - 列出在线 worker 及队列长度；心跳已过期的 worker 会顺便从集合中移除
+func List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := redisdb.Ctx
+		ids, err := redisdb.Client.SMembers(ctx, workersKey).Result()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		sort.Strings(ids)
+
+		workers := make([]gin.H, 0, len(ids))
+		for _, id := range ids {
+			info, err := redisdb.Client.HGetAll(ctx, workerKey(id)).Result()
+			if err != nil || len(info) == 0 {
+				_ = redisdb.Client.SRem(ctx, workersKey, id).Err()
+				continue
+			}
+			var tasks []string
+			_ = json.Unmarshal([]byte(info["tasks"]), &tasks)
+			workers = append(workers, gin.H{
+				"id":          id,
+				"concurrency": info["concurrency"],
+				"tasks":       tasks,
+				"startedAt":   info["started_at"],
+				"heartbeatAt": info["heartbeat_at"],
+			})
+		}
+
+		queued, _ := redisdb.Client.LLen(ctx, queueKey).Result()
+		processing, _ := redisdb.Client.LLen(ctx, processingKey).Result()
+
+		c.JSON(http.StatusOK, gin.H{
+			"workers":    workers,
+			"queued":     queued,
+			"processing": processing,
+		})
+	}
+}